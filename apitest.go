@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/codegangsta/cli"
+)
+
+// responseSnapshot is the response of a route to its example request, as saved by
+// 'sam local test --snapshot'. Values that change on every run, such as UUIDs and
+// timestamps, are normalized, so that only real changes show up.
+type responseSnapshot struct {
+	Request         string            `json:"request"`
+	Status          int               `json:"status"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            interface{}       `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded,omitempty"`
+}
+
+// snapshotHeadersToSkip change on every run, or with the body, so aren't kept in snapshots
+var snapshotHeadersToSkip = map[string]bool{
+	"Date":           true,
+	"Content-Length": true,
+}
+
+var (
+	// snapshotUUIDEx matches UUIDs, e.g. of generated IDs, in responses
+	snapshotUUIDEx = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+	// snapshotTimestampEx matches ISO 8601 timestamps in responses
+	snapshotTimestampEx = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+)
+
+// snapshotDiffContext is how many unchanged lines are shown around each change
+const snapshotDiffContext = 2
+
+func testAPI(c *cli.Context) {
+
+	// Function logs would drown out the results, so they're only kept with --log-file
+	logs := io.Writer(ioutil.Discard)
+	if logarg := c.String("log-file"); logarg != "" {
+		logFile, err := os.Create(logarg)
+		if err != nil {
+			log.Fatalf("Failed to open log file %s: %s\n", logarg, err)
+		}
+		logs = logFile
+	}
+
+	logger := newLogger(c)
+
+	filename := getTemplateFilename(c.String("template"))
+	template := openTemplate(c, filename, logger)
+
+	listeners, _ := parseListeners(nil, nil)
+	endpoints, err := getEndpoints(template, listeners, map[string]*listener{}, c.Bool("prefix-routing"))
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+	if len(endpoints) == 0 {
+		errMsg.Fprintf(os.Stderr, "ERROR: No routes were found in %s\n", filename)
+		os.Exit(1)
+	}
+
+	contract, err := newResponseContract(template, c.String("validate-responses"))
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	// Request IDs and times come from a seed, so functions that echo them don't change
+	// their responses on every run
+	deterministic := newDeterministicSource(0)
+	handler := newLocalAPI(c, template, filename, logs, router.NewServerlessRouterOpt{
+		UsePrefix:    c.Bool("prefix-routing"),
+		Log:          logger,
+		NewRequestID: deterministic.RequestID,
+		Now:          deterministic.Now,
+		NewTraceID:   deterministic.TraceID,
+	}, func(name string, handler router.EventHandlerFunc) router.EventHandlerFunc {
+		return handler
+	})
+
+	if c.String("log-file") == "" {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	dir := c.String("snapshot-dir")
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(filename), "snapshots")
+	}
+
+	requests, _ := getExampleRequests(endpoints)
+	failures := 0
+
+	for i, r := range requests {
+
+		path := pathParameter.ReplaceAllString(r.Path, examplePathParameter)
+		request := r.Method + " " + path
+		response := sendExampleRequest(handler, r.Method, path, r.Body)
+
+		if contract != nil {
+			if errs := contract.Validate(r.Method, r.Path, response.Code, response.Header().Get("Content-Type"), response.Body.Bytes()); len(errs) > 0 {
+				errMsg.Fprintf(os.Stderr, "MISMATCH %s (%d):\n", request, response.Code)
+				for _, err := range errs {
+					fmt.Fprintf(os.Stderr, "    %s\n", err)
+				}
+				if contract.fail {
+					failures++
+					continue
+				}
+			}
+		}
+
+		if !c.Bool("snapshot") {
+			if response.Code >= http.StatusInternalServerError {
+				errMsg.Fprintf(os.Stderr, "FAIL %s (%d)\n", request, response.Code)
+				failures++
+				continue
+			}
+			successMsg.Fprintf(os.Stderr, "OK %s (%d)\n", request, response.Code)
+			continue
+		}
+
+		file := filepath.Join(dir, openAPIOperationID(endpoints[i].Method, endpoints[i].Path)+".json")
+		status, diff, err := checkSnapshot(file, newResponseSnapshot(request, response), c.Bool("update-snapshots"))
+		switch {
+		case err != nil:
+			errMsg.Fprintf(os.Stderr, "ERROR %s: %s\n", request, err)
+			failures++
+		case diff != nil:
+			errMsg.Fprintf(os.Stderr, "CHANGED %s (%s):\n", request, file)
+			for _, line := range diff {
+				fmt.Fprintf(os.Stderr, "    %s\n", line)
+			}
+			failures++
+		default:
+			successMsg.Fprintf(os.Stderr, "%s %s (%d)\n", status, request, response.Code)
+		}
+
+	}
+
+	invoker.ActiveContainers.CleanUp()
+
+	fmt.Fprintf(os.Stderr, "\nTested %d routes, %d failed\n", len(requests), failures)
+	if failures > 0 {
+		if c.Bool("snapshot") {
+			fmt.Fprintf(os.Stderr, "Run with --update-snapshots if the changes are expected\n")
+		}
+		os.Exit(1)
+	}
+
+}
+
+// sendExampleRequest sends a route's example request straight to the local API
+func sendExampleRequest(handler http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	return response
+
+}
+
+// newResponseSnapshot returns the normalized snapshot of a response. JSON bodies are kept as
+// JSON, so that they're indented (with sorted keys) in the snapshot file, and binary ones
+// are base64 encoded.
+func newResponseSnapshot(request string, response *httptest.ResponseRecorder) *responseSnapshot {
+
+	snapshot := &responseSnapshot{
+		Request: request,
+		Status:  response.Code,
+		Headers: map[string]string{},
+	}
+
+	for name, values := range response.HeaderMap {
+		if !snapshotHeadersToSkip[http.CanonicalHeaderKey(name)] {
+			snapshot.Headers[http.CanonicalHeaderKey(name)] = normalizeSnapshotValue(strings.Join(values, ", "))
+		}
+	}
+
+	body := response.Body.Bytes()
+	if !utf8.Valid(body) {
+		snapshot.Body = base64.StdEncoding.EncodeToString(body)
+		snapshot.IsBase64Encoded = true
+		return snapshot
+	}
+
+	text := normalizeSnapshotValue(string(body))
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err == nil && len(bytes.TrimSpace(body)) > 0 {
+		snapshot.Body = value
+	} else {
+		snapshot.Body = text
+	}
+
+	return snapshot
+
+}
+
+// normalizeSnapshotValue replaces the UUIDs and timestamps in a value with placeholders
+func normalizeSnapshotValue(value string) string {
+	value = snapshotUUIDEx.ReplaceAllString(value, "<uuid>")
+	return snapshotTimestampEx.ReplaceAllString(value, "<timestamp>")
+}
+
+// checkSnapshot compares a snapshot with the one saved in a file. Snapshots are saved the
+// first time, or every time with update. It returns whether the snapshot was NEW, OK or
+// UPDATED, or the differences from the saved one if it CHANGED.
+func checkSnapshot(file string, snapshot *responseSnapshot, update bool) (string, []string, error) {
+
+	actual := marshalSnapshot(snapshot)
+
+	saved, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return "", nil, err
+	}
+
+	if err == nil && !update {
+		// The saved snapshot is formatted the same way, in case it was edited by hand
+		expected := &responseSnapshot{}
+		if err := json.Unmarshal(saved, expected); err != nil {
+			return "", nil, fmt.Errorf("invalid snapshot %s: %s", file, err)
+		}
+		formatted := marshalSnapshot(expected)
+
+		if bytes.Equal(formatted, actual) {
+			return "OK", nil, nil
+		}
+		return "CHANGED", diffLines(strings.Split(string(formatted), "\n"), strings.Split(string(actual), "\n")), nil
+	}
+
+	status := "NEW"
+	if err == nil {
+		if bytes.Equal(saved, actual) {
+			return "OK", nil, nil
+		}
+		status = "UPDATED"
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", nil, err
+	}
+	if err := ioutil.WriteFile(file, actual, 0644); err != nil {
+		return "", nil, err
+	}
+	return status, nil, nil
+
+}
+
+// marshalSnapshot formats a snapshot as indented JSON, without escaping the placeholders of
+// normalized values
+func marshalSnapshot(snapshot *responseSnapshot) []byte {
+	data := &bytes.Buffer{}
+	encoder := json.NewEncoder(data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(snapshot)
+	return data.Bytes()
+}
+
+// diffLines returns the differences between two texts, as lines that were removed from the
+// expected text (-) and added in the actual one (+), with a few unchanged lines around them
+func diffLines(expected []string, actual []string) []string {
+
+	// lengths[i][j] is the length of the longest common subsequence of expected[i:] and actual[j:]
+	lengths := make([][]int, len(expected)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if expected[i] == actual[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	lines := []string{}
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) && expected[i] == actual[j]:
+			lines = append(lines, "  "+expected[i])
+			i++
+			j++
+		case i < len(expected) && (j == len(actual) || lengths[i+1][j] >= lengths[i][j+1]):
+			lines = append(lines, "- "+expected[i])
+			i++
+		default:
+			lines = append(lines, "+ "+actual[j])
+			j++
+		}
+	}
+
+	// Only keep the unchanged lines that are close to a change
+	changed := []int{}
+	for n, line := range lines {
+		if line[0] != ' ' {
+			changed = append(changed, n)
+		}
+	}
+
+	diff := []string{}
+	last := -1
+	for n, line := range lines {
+		near := sort.SearchInts(changed, n-snapshotDiffContext)
+		if near == len(changed) || changed[near] > n+snapshotDiffContext {
+			continue
+		}
+		if last >= 0 && n > last+1 {
+			diff = append(diff, "...")
+		}
+		diff = append(diff, line)
+		last = n
+	}
+
+	return diff
+
+}
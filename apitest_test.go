@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot testing", func() {
+
+	response := func(status int, contentType string, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Content-Type", contentType)
+		rec.Header().Set("Date", "Wed, 01 Jan 2020 00:00:00 GMT")
+		rec.WriteHeader(status)
+		rec.WriteString(body)
+		return rec
+	}
+
+	Context("taking snapshots of responses", func() {
+
+		It("keeps JSON bodies as JSON, with UUIDs and timestamps normalized", func() {
+			snapshot := newResponseSnapshot("GET /orders/example", response(200, "application/json",
+				`{"id":"0194fdc2-fa2f-4cc0-81d3-ff12045b73c8","created":"2024-05-01T12:30:00.123Z","total":12}`))
+
+			Expect(snapshot.Status).To(Equal(200))
+			Expect(snapshot.Headers).To(Equal(map[string]string{"Content-Type": "application/json"}))
+			Expect(snapshot.Body).To(Equal(map[string]interface{}{"id": "<uuid>", "created": "<timestamp>", "total": float64(12)}))
+		})
+
+		It("keeps other bodies as text", func() {
+			snapshot := newResponseSnapshot("GET /", response(200, "text/plain", "hello"))
+			Expect(snapshot.Body).To(Equal("hello"))
+		})
+
+		It("base64 encodes binary bodies", func() {
+			snapshot := newResponseSnapshot("GET /", response(200, "image/png", "\xff\xd8"))
+			Expect(snapshot.Body).To(Equal("/9g="))
+			Expect(snapshot.IsBase64Encoded).To(BeTrue())
+		})
+
+	})
+
+	Context("checking snapshots", func() {
+
+		var dir string
+		var file string
+
+		BeforeEach(func() {
+			dir, _ = ioutil.TempDir("", "snapshots")
+			file = filepath.Join(dir, "snapshots", "getOrdersId.json")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		snapshot := func(total string) *responseSnapshot {
+			return newResponseSnapshot("GET /orders/example", response(http.StatusOK, "application/json", `{"id":"1","total":`+total+`}`))
+		}
+
+		It("saves new snapshots, and compares with them after that", func() {
+			status, diff, err := checkSnapshot(file, snapshot("12"), false)
+			Expect(err).To(BeNil())
+			Expect(status).To(Equal("NEW"))
+			Expect(diff).To(BeNil())
+			Expect(file).To(BeAnExistingFile())
+
+			status, diff, err = checkSnapshot(file, snapshot("12"), false)
+			Expect(err).To(BeNil())
+			Expect(status).To(Equal("OK"))
+			Expect(diff).To(BeNil())
+		})
+
+		It("reports the differences from the saved snapshot", func() {
+			checkSnapshot(file, snapshot("12"), false)
+
+			status, diff, err := checkSnapshot(file, snapshot("15"), false)
+			Expect(err).To(BeNil())
+			Expect(status).To(Equal("CHANGED"))
+			Expect(diff).To(ContainElement(`-     "total": 12`))
+			Expect(diff).To(ContainElement(`+     "total": 15`))
+
+			data, _ := ioutil.ReadFile(file)
+			Expect(string(data)).To(ContainSubstring(`"total": 12`))
+		})
+
+		It("updates snapshots", func() {
+			checkSnapshot(file, snapshot("12"), false)
+
+			status, _, err := checkSnapshot(file, snapshot("15"), true)
+			Expect(err).To(BeNil())
+			Expect(status).To(Equal("UPDATED"))
+
+			data, _ := ioutil.ReadFile(file)
+			Expect(string(data)).To(ContainSubstring(`"total": 15`))
+		})
+
+		It("doesn't escape the placeholders of normalized values", func() {
+			checkSnapshot(file, newResponseSnapshot("GET /", response(200, "text/plain", "id 0194fdc2-fa2f-4cc0-81d3-ff12045b73c8")), false)
+			data, _ := ioutil.ReadFile(file)
+			Expect(string(data)).To(ContainSubstring(`"body": "id <uuid>"`))
+		})
+
+	})
+
+	Context("diffing lines", func() {
+
+		It("shows changed lines with the unchanged lines around them", func() {
+			expected := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+			actual := []string{"a", "b", "c", "d", "e", "F", "g", "h"}
+			Expect(diffLines(expected, actual)).To(Equal([]string{"  d", "  e", "- f", "+ F", "  g", "  h"}))
+		})
+
+		It("separates changes that are far apart", func() {
+			expected := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}
+			actual := []string{"A", "b", "c", "d", "e", "f", "g", "h", "I"}
+			Expect(diffLines(expected, actual)).To(Equal([]string{"- a", "+ A", "  b", "  c", "...", "  g", "  h", "- i", "+ I"}))
+		})
+
+	})
+
+})
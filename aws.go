@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// awsCLI runs AWS CLI commands, for the AWS APIs that SAM Local doesn't call directly
+type awsCLI struct {
+	Profile string
+	Region  string
+}
+
+// Run runs an AWS CLI command, and returns its output. If the command fails, the
+// error contains what the AWS CLI printed to stderr.
+func (a *awsCLI) Run(args ...string) (string, error) {
+
+	if a.Profile != "" {
+		args = append(args, "--profile", a.Profile)
+	}
+	if a.Region != "" {
+		args = append(args, "--region", a.Region)
+	}
+
+	cmd := exec.Command("aws", args...)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+
+	return stdout.String(), nil
+
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+)
+
+// batchResult is the result of invoking a function with one of the events of --event-dir
+type batchResult struct {
+	Event    string
+	Output   []byte
+	Duration time.Duration
+	Outcome  invoker.Outcome
+	Err      error
+}
+
+// Failed returns true if the invocation failed, or the function returned an error
+func (r *batchResult) Failed() bool {
+	return r.Err != nil || r.Outcome != invoker.OutcomeSuccess
+}
+
+// listEventFiles returns the JSON files in a directory, sorted by name
+func listEventFiles(dir string) ([]string, error) {
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no JSON event files found in %s", dir)
+	}
+
+	sort.Strings(files)
+	return files, nil
+
+}
+
+// invokeBatch invokes a function once per event file, running up to parallel
+// invocations at the same time. Each invocation gets its own container, and
+// its logs are prefixed with the name of the event file. The results are
+// returned in the same order as the event files. If a schema is provided, events
+// that don't match it fail without invoking the function. Each invocation goes through
+// the pre-invoke and post-invoke hooks of the plugins.
+func invokeBatch(opt invoker.NewRuntimeOpt, files []string, parallel int, profile string, schema *eventSchema, hooks plugins) []*batchResult {
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]*batchResult, len(files))
+
+	// Get the runtime's backend ready (pulling its image) once, before the invocations
+	// start, so that concurrent ones neither pull it again nor start without it
+	if !opt.SkipPullImage {
+		if _, err := invoker.NewRuntime(opt); err != nil {
+			for i, file := range files {
+				results[i] = &batchResult{Event: file, Err: err}
+			}
+			return results
+		}
+		opt.SkipPullImage = true
+	}
+
+	slots := make(chan struct{}, parallel)
+
+	var wg sync.WaitGroup
+	for i, file := range files {
+
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(i int, file string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			results[i] = invokeBatchEvent(opt, file, profile, schema, hooks)
+		}(i, file)
+
+	}
+
+	wg.Wait()
+	return results
+
+}
+
+// invokeBatchEvent invokes a function with a single event file
+func invokeBatchEvent(opt invoker.NewRuntimeOpt, file string, profile string, schema *eventSchema, hooks plugins) *batchResult {
+
+	result := &batchResult{Event: file}
+
+	event, err := ioutil.ReadFile(file)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if schema != nil {
+		if errs := schema.Validate(event); len(errs) > 0 {
+			result.Err = fmt.Errorf("event doesn't match the schema: %s", strings.Join(errs, "; "))
+			return result
+		}
+	}
+
+	input, environment, err := hooks.PreInvoke(opt.LogicalID, string(event))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	opt.Environment = environment
+
+	runt, err := invoker.NewRuntime(opt)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	start := time.Now()
+	stdoutTxt, stderrTxt, err := runt.Invoke(input, profile)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	logs := newPrefixWriter(opt.Logger, opt.LogicalID, filepath.Base(file))
+	output := &bytes.Buffer{}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		io.Copy(logs, stderrTxt)
+		wg.Done()
+	}()
+
+	go func() {
+		io.Copy(output, stdoutTxt)
+		wg.Done()
+	}()
+
+	wg.Wait()
+	logs.Flush()
+
+	result.Outcome = runt.Outcome(output.Bytes())
+	runt.CleanUp()
+
+	result.Output = bytes.TrimSpace(output.Bytes())
+	result.Duration = time.Since(start)
+	hooks.PostInvoke(opt.LogicalID, input, result.Output, result.Outcome, result.Duration)
+	return result
+
+}
+
+// writeBatchResults writes the output of every invocation, followed by a summary
+func writeBatchResults(w io.Writer, results []*batchResult) {
+
+	failed := 0
+	for _, result := range results {
+
+		status := "OK"
+		if result.Failed() {
+			status = "FAILED"
+			failed++
+		}
+
+		fmt.Fprintf(w, "%s %s (%d ms)\n", status, result.Event, result.Duration/time.Millisecond)
+		if result.Err != nil {
+			fmt.Fprintf(w, "    %s\n", result.Err)
+		} else if result.Outcome == invoker.OutcomeTimeout || result.Outcome == invoker.OutcomeCrash || result.Outcome == invoker.OutcomeCancelled {
+			fmt.Fprintf(w, "    %s\n", result.Outcome)
+		} else {
+			fmt.Fprintf(w, "    %s\n", result.Output)
+		}
+
+	}
+
+	fmt.Fprintf(w, "\n%d events, %d succeeded, %d failed\n", len(results), len(results)-failed, failed)
+
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/goformation/cloudformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Batch invocation", func() {
+
+	It("lists the JSON event files in order", func() {
+		dir, _ := ioutil.TempDir("", "events")
+		defer os.RemoveAll(dir)
+		for _, name := range []string{"b.json", "a.json", "notes.txt"} {
+			ioutil.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644)
+		}
+
+		files, err := listEventFiles(dir)
+		Expect(err).To(BeNil())
+		Expect(files).To(Equal([]string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}))
+	})
+
+	It("fails when there are no events", func() {
+		dir, _ := ioutil.TempDir("", "events")
+		defer os.RemoveAll(dir)
+		_, err := listEventFiles(dir)
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("pulls the runtime image once, before any of the events are invoked", func() {
+		backend := &pullingBackend{}
+		invoker.RegisterRuntimeBackend("pulling", backend)
+
+		dir, _ := ioutil.TempDir("", "events")
+		defer os.RemoveAll(dir)
+		files := []string{}
+		for _, name := range []string{"a.json", "b.json", "c.json"} {
+			files = append(files, filepath.Join(dir, name))
+			ioutil.WriteFile(files[len(files)-1], []byte("{}"), 0644)
+		}
+
+		opt := invoker.NewRuntimeOpt{
+			LogicalID: "Batch",
+			Function:  cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10", Handler: "index.handler", Timeout: 3},
+			Logger:    ioutil.Discard,
+			Backend:   "pulling",
+		}
+		results := invokeBatch(opt, files, 3, "", nil, nil)
+
+		Expect(results).To(HaveLen(3))
+		for _, result := range results {
+			Expect(result.Err).To(BeNil())
+		}
+		Expect(backend.calls[0]).To(Equal("pull"))
+		Expect(backend.calls).To(ConsistOf("pull", "start", "invoke", "start", "invoke", "start", "invoke"))
+	})
+
+	It("summarises the results", func() {
+		out := &bytes.Buffer{}
+		writeBatchResults(out, []*batchResult{
+			{Event: "a.json", Output: []byte(`"ok"`)},
+			{Event: "b.json", Output: []byte(`{"errorMessage": "boom"}`), Outcome: invoker.OutcomeHandledError},
+			{Event: "c.json", Err: errors.New("no such file")},
+		})
+		Expect(out.String()).To(ContainSubstring("OK a.json"))
+		Expect(out.String()).To(ContainSubstring("FAILED c.json"))
+		Expect(out.String()).To(HaveSuffix("3 events, 1 succeeded, 2 failed\n"))
+	})
+
+})
+
+// pullingBackend is a runtime backend that records whether the runtimes it starts pull
+// their image, and their invocations
+type pullingBackend struct {
+	lock  sync.Mutex
+	calls []string
+}
+
+func (b *pullingBackend) record(call string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.calls = append(b.calls, call)
+}
+
+func (b *pullingBackend) Start(r *invoker.Runtime) error {
+	if r.SkipPullImage {
+		b.record("start")
+	} else {
+		b.record("pull")
+	}
+	return nil
+}
+
+func (b *pullingBackend) Invoke(r *invoker.Runtime, event string, profile string) (io.ReadCloser, error) {
+	b.record("invoke")
+	return ioutil.NopCloser(strings.NewReader(`"ok"`)), nil
+}
+
+func (b *pullingBackend) Logs(r *invoker.Runtime) io.ReadCloser {
+	return ioutil.NopCloser(strings.NewReader(""))
+}
+
+func (b *pullingBackend) Wait(r *invoker.Runtime) (int, error) {
+	return 0, nil
+}
+
+func (b *pullingBackend) Stop(r *invoker.Runtime) {}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/codegangsta/cli"
+)
+
+// benchmark drives requests through the local router, and keeps track of how long each
+// one took and whether it was a cold start
+type benchmark struct {
+	sync.Mutex
+
+	// finished counts the finished invocations of each function. Requests that start before
+	// a function has finished any invocation are cold starts.
+	finished map[string]int
+
+	results []*benchResult
+}
+
+// benchResult is the outcome of a single request
+type benchResult struct {
+	Function string
+	Status   int
+	Latency  time.Duration
+	Cold     bool
+}
+
+// benchResponse records the response to a request, and which function handled it
+type benchResponse struct {
+	*httptest.ResponseRecorder
+	Function string
+	Cold     bool
+}
+
+// benchRequest is the request the benchmark sends
+type benchRequest struct {
+	Method  string
+	Path    string
+	Body    string
+	Headers map[string]string
+}
+
+func bench(c *cli.Context) {
+
+	if c.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "ERROR: Usage: sam local bench METHOD /path\n")
+		os.Exit(1)
+	}
+
+	request := &benchRequest{
+		Method:  strings.ToUpper(c.Args().Get(0)),
+		Path:    c.Args().Get(1),
+		Body:    c.String("body"),
+		Headers: map[string]string{},
+	}
+
+	for _, header := range c.StringSlice("header") {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 {
+			fmt.Fprintf(os.Stderr, "ERROR: Invalid --header '%s' (must be 'Name: value')\n", header)
+			os.Exit(1)
+		}
+		request.Headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	concurrency := c.Int("concurrency")
+	if concurrency < 1 {
+		fmt.Fprintf(os.Stderr, "ERROR: --concurrency must be at least 1\n")
+		os.Exit(1)
+	}
+
+	// Function logs would drown out the results, so they're only kept with --log-file
+	logs := io.Writer(ioutil.Discard)
+	if logarg := c.String("log-file"); logarg != "" {
+		logFile, err := os.Create(logarg)
+		if err != nil {
+			log.Fatalf("Failed to open log file %s: %s\n", logarg, err)
+		}
+		logs = logFile
+	}
+
+	logger := newLogger(c)
+
+	filename := getTemplateFilename(c.String("template"))
+	template := openTemplate(c, filename, logger)
+
+	if runtimeBackendName(c) == "native" {
+		warnMsg.Fprintf(os.Stderr, "Running functions on this machine without Docker: their performance won't match Lambda's\n")
+	}
+
+	b := newBenchmark()
+	handler := newLocalAPI(c, template, filename, logs, router.NewServerlessRouterOpt{UsePrefix: c.Bool("prefix-routing"), Log: logger}, b.Wrap)
+
+	if c.String("log-file") == "" {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	fmt.Fprintf(os.Stderr, "Sending %s %s with %d concurrent requests for %s...\n", request.Method, request.Path, concurrency, c.Duration("duration"))
+
+	elapsed := b.Run(handler, request, concurrency, c.Duration("duration"), c.Int("requests"))
+	invoker.ActiveContainers.CleanUp()
+
+	writeBenchReport(os.Stdout, b.results, elapsed, concurrency)
+
+}
+
+// newLocalAPI mounts every function of the template on a router, as 'sam local start-api'
+// would, so that requests can be sent straight to it without listening on a port. Each
+// function's handler is wrapped with wrap.
+func newLocalAPI(c *cli.Context, template *cloudformation.Template, filename string, logs io.Writer, opt router.NewServerlessRouterOpt, wrap func(string, router.EventHandlerFunc) router.EventHandlerFunc) http.Handler {
+
+	backend := runtimeBackendName(c)
+	if _, err := invoker.DockerVersion(); backend == "docker" && err != nil {
+		log.Printf("Running AWS SAM projects locally requires Docker. Have you got it installed?\n")
+		log.Printf("%s\n", err)
+		os.Exit(1)
+	}
+
+	cwd := filepath.Dir(filename)
+	if c.String("docker-volume-basedir") != "" {
+		cwd = c.String("docker-volume-basedir")
+	}
+
+	listeners, _ := parseListeners(nil, nil)
+	listeners[0].Router = router.NewServerlessRouter(opt)
+
+	if err := mountAPIs(template.GetAllAWSServerlessApiResources(), listeners, map[string]*listener{}); err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	for name, function := range template.GetAllAWSServerlessFunctionResources() {
+
+		runt, err := invoker.NewRuntime(invoker.NewRuntimeOpt{
+			Cwd:             cwd,
+			LogicalID:       name,
+			Function:        function,
+			Logger:          logs,
+			EnvOverrideFile: c.String("env-vars"),
+			SkipPullImage:   c.Bool("skip-pull-image"),
+			DockerNetwork:   c.String("docker-network"),
+			Backend:         backend,
+			Log:             opt.Log,
+		})
+		if err != nil {
+			warnMsg.Printf("Ignoring %s (%s) due to %s runtime init error: %s\n", name, function.Handler, function.Runtime, err)
+			continue
+		}
+
+		handler := invokeHTTP(runt, c.String("profile"), nil, nil)
+		mountFunction(function, listeners, map[string]*listener{}, wrap(name, handler))
+
+	}
+
+	return listeners[0].Router.Router()
+
+}
+
+func newBenchmark() *benchmark {
+	return &benchmark{finished: map[string]int{}}
+}
+
+// Wrap wraps a function's handler to record which function handled each request, and
+// whether it was a cold start
+func (b *benchmark) Wrap(function string, handler router.EventHandlerFunc) router.EventHandlerFunc {
+
+	return func(w http.ResponseWriter, event *router.Event) {
+
+		b.Lock()
+		cold := b.finished[function] == 0
+		b.Unlock()
+
+		if response, ok := w.(*benchResponse); ok {
+			response.Function = function
+			response.Cold = cold
+		}
+
+		handler(w, event)
+
+		b.Lock()
+		b.finished[function]++
+		b.Unlock()
+
+	}
+
+}
+
+// Run sends the request with the given concurrency until the duration is up, or (if
+// requests isn't 0) that many requests have been sent. Each worker waits for its response
+// before sending another request. Interrupting the benchmark stops sending requests, and
+// waits for those in flight. It returns how long the benchmark ran for.
+func (b *benchmark) Run(handler http.Handler, request *benchRequest, concurrency int, duration time.Duration, requests int) time.Duration {
+
+	started := time.Now()
+	deadline := started.Add(duration)
+
+	stop := make(chan struct{})
+	signals := interrupted()
+	go func() {
+		select {
+		case <-signals:
+			close(stop)
+		case <-stop:
+		}
+	}()
+
+	var sent sync.Mutex
+	count := 0
+	next := func() bool {
+		sent.Lock()
+		defer sent.Unlock()
+		select {
+		case <-stop:
+			return false
+		default:
+		}
+		if time.Now().After(deadline) || (requests > 0 && count >= requests) {
+			return false
+		}
+		count++
+		return true
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next() {
+				result := b.send(handler, request)
+				b.Lock()
+				b.results = append(b.results, result)
+				b.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-stop:
+	default:
+		close(stop)
+	}
+
+	return time.Since(started)
+
+}
+
+// send sends a single request and records its response
+func (b *benchmark) send(handler http.Handler, request *benchRequest) *benchResult {
+
+	req := httptest.NewRequest(request.Method, request.Path, strings.NewReader(request.Body))
+	for name, value := range request.Headers {
+		req.Header.Set(name, value)
+	}
+
+	response := &benchResponse{ResponseRecorder: httptest.NewRecorder()}
+	started := time.Now()
+	handler.ServeHTTP(response, req)
+
+	return &benchResult{
+		Function: response.Function,
+		Status:   response.Code,
+		Latency:  time.Since(started),
+		Cold:     response.Cold,
+	}
+
+}
+
+// percentile returns the latency that the given percentage of results were faster than
+// or as fast as, using the nearest-rank method. The latencies must be sorted.
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(latencies))))
+	if rank < 1 {
+		rank = 1
+	}
+	return latencies[rank-1]
+}
+
+// writeBenchReport writes the throughput, error rate, status codes, cold and warm start
+// counts and latency percentiles of the results
+func writeBenchReport(w io.Writer, results []*benchResult, elapsed time.Duration, concurrency int) {
+
+	all := []time.Duration{}
+	cold := []time.Duration{}
+	warm := []time.Duration{}
+	statuses := map[int]int{}
+	errors := 0
+
+	for _, r := range results {
+		all = append(all, r.Latency)
+		if r.Cold {
+			cold = append(cold, r.Latency)
+		} else if r.Function != "" {
+			warm = append(warm, r.Latency)
+		}
+		statuses[r.Status]++
+		if r.Status >= 500 {
+			errors++
+		}
+	}
+
+	fmt.Fprintf(w, "Requests:     %d in %s (%.1f/s) with concurrency %d\n", len(results), elapsed/time.Millisecond*time.Millisecond, float64(len(results))/elapsed.Seconds(), concurrency)
+
+	errorRate := 0.0
+	if len(results) > 0 {
+		errorRate = float64(errors) / float64(len(results)) * 100
+	}
+	fmt.Fprintf(w, "Errors:       %d (%.1f%%)\n", errors, errorRate)
+
+	codes := []int{}
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	counts := []string{}
+	for _, code := range codes {
+		counts = append(counts, fmt.Sprintf("%d: %d", code, statuses[code]))
+	}
+	fmt.Fprintf(w, "Status codes: %s\n", strings.Join(counts, ", "))
+	fmt.Fprintf(w, "Cold starts:  %d (warm: %d)\n\n", len(cold), len(warm))
+
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(table, "LATENCY\tP50\tP90\tP95\tP99\tMAX\t\n")
+	for _, row := range []struct {
+		name      string
+		latencies []time.Duration
+	}{{"all", all}, {"cold", cold}, {"warm", warm}} {
+		if len(row.latencies) == 0 {
+			continue
+		}
+		sort.Sort(byDuration(row.latencies))
+		fmt.Fprintf(table, "%s\t", row.name)
+		for _, p := range []float64{50, 90, 95, 99, 100} {
+			fmt.Fprintf(table, "%s\t", percentile(row.latencies, p)/time.Millisecond*time.Millisecond)
+		}
+		fmt.Fprintf(table, "\n")
+	}
+	table.Flush()
+
+}
+
+// byDuration sorts durations, shortest first
+type byDuration []time.Duration
+
+func (d byDuration) Len() int           { return len(d) }
+func (d byDuration) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d byDuration) Less(i, j int) bool { return d[i] < d[j] }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/router"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Benchmarking", func() {
+
+	It("sends requests through the router and tells cold starts from warm", func() {
+		b := newBenchmark()
+
+		handler := b.Wrap("Hello", func(w http.ResponseWriter, e *router.Event) {
+			time.Sleep(10 * time.Millisecond)
+			w.WriteHeader(http.StatusCreated)
+		})
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/hello", func(w http.ResponseWriter, req *http.Request) {
+			handler(w, &router.Event{HTTPMethod: req.Method, Path: req.URL.Path})
+		})
+
+		elapsed := b.Run(mux, &benchRequest{Method: "GET", Path: "/hello"}, 2, time.Minute, 6)
+		Expect(elapsed).To(BeNumerically("<", time.Minute))
+		Expect(b.results).To(HaveLen(6))
+
+		cold := 0
+		for _, result := range b.results {
+			Expect(result.Function).To(Equal("Hello"))
+			Expect(result.Status).To(Equal(http.StatusCreated))
+			Expect(result.Latency).To(BeNumerically(">=", 10*time.Millisecond))
+			if result.Cold {
+				cold++
+			}
+		}
+		Expect(cold).To(Equal(2))
+	})
+
+	It("stops when the duration is up", func() {
+		b := newBenchmark()
+		slow := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+		})
+		b.Run(slow, &benchRequest{Method: "GET", Path: "/"}, 1, 50*time.Millisecond, 0)
+		Expect(len(b.results)).To(BeNumerically(">=", 2))
+		Expect(len(b.results)).To(BeNumerically("<=", 4))
+	})
+
+	It("works out percentiles", func() {
+		latencies := []time.Duration{}
+		for i := 1; i <= 100; i++ {
+			latencies = append(latencies, time.Duration(i)*time.Millisecond)
+		}
+		Expect(percentile(latencies, 50)).To(Equal(50 * time.Millisecond))
+		Expect(percentile(latencies, 99)).To(Equal(99 * time.Millisecond))
+		Expect(percentile(latencies, 100)).To(Equal(100 * time.Millisecond))
+		Expect(percentile(latencies[:1], 50)).To(Equal(time.Millisecond))
+		Expect(percentile(nil, 50)).To(Equal(time.Duration(0)))
+	})
+
+	It("reports errors, status codes and cold starts", func() {
+		results := []*benchResult{
+			{Function: "Hello", Status: 200, Latency: 900 * time.Millisecond, Cold: true},
+			{Function: "Hello", Status: 200, Latency: 100 * time.Millisecond},
+			{Function: "Hello", Status: 502, Latency: 120 * time.Millisecond},
+			{Status: 404, Latency: time.Millisecond},
+		}
+
+		out := &bytes.Buffer{}
+		writeBenchReport(out, results, 2*time.Second, 2)
+
+		Expect(out.String()).To(ContainSubstring("Requests:     4 in 2s (2.0/s) with concurrency 2\n"))
+		Expect(out.String()).To(ContainSubstring("Errors:       1 (25.0%)\n"))
+		Expect(out.String()).To(ContainSubstring("Status codes: 200: 2, 404: 1, 502: 1\n"))
+		Expect(out.String()).To(ContainSubstring("Cold starts:  1 (warm: 2)\n"))
+		Expect(out.String()).To(MatchRegexp(`cold\s+900ms\s+900ms`))
+		Expect(out.String()).To(MatchRegexp(`warm\s+100ms\s+120ms`))
+	})
+
+})
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/build"
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/aws-sam-local/loader"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/codegangsta/cli"
+	"golang.org/x/net/context"
+)
+
+func buildFunctions(c *cli.Context) {
+
+	filename := getTemplateFilename(c.String("template"))
+	template := loadTemplate(c, filename, nil)
+	builder := newFunctionBuilder(c, template, filename, filepath.Dir(filename), os.Stderr)
+
+	names := c.Args()
+	if len(names) == 0 {
+		for name := range builder.functions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	failed := false
+	for _, name := range names {
+		f, ok := builder.functions[name]
+		if !ok {
+			errMsg.Fprintf(os.Stderr, "ERROR: Could not find a function with local code with logical ID '%s'\n", name)
+			failed = true
+			continue
+		}
+
+		result, err := builder.cache.Build(context.Background(), f, os.Stderr)
+		switch {
+		case err == build.ErrNoBuilder:
+			fmt.Fprintf(os.Stderr, "%s (%s) doesn't need to be built\n", name, f.Runtime)
+		case err != nil:
+			errMsg.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			failed = true
+		case result.Cached:
+			fmt.Fprintf(os.Stderr, "%s is up to date: %s\n", name, builtDir(result))
+		default:
+			successMsg.Fprintf(os.Stderr, "Built %s with the %s builder in %s: %s\n", name, result.Builder, result.Duration/time.Millisecond*time.Millisecond, builtDir(result))
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+
+}
+
+// builtDir returns the directory a function was built into: its dependency layer, if it has
+// one, as it runs from its source code
+func builtDir(result *build.Result) string {
+	if result.Layer != "" {
+		return result.Layer
+	}
+	return result.Artifact
+}
+
+// functionBuilder builds the functions of a template from their source code, before they're
+// invoked (with --build)
+type functionBuilder struct {
+	cache *build.Cache
+	log   io.Writer
+
+	// functions are the functions whose code is a local directory, by logical ID
+	functions map[string]*build.Function
+}
+
+// newFunctionBuilder returns a builder for the functions of a template, whose artifacts are
+// kept in --build-dir. Without it, each template gets a directory in SAM Local's cache. With
+// --dependency-layer, functions' dependencies are built into layers of their own.
+func newFunctionBuilder(c *cli.Context, template *loader.Template, filename string, cwd string, log io.Writer) *functionBuilder {
+
+	dir := c.String("build-dir")
+	if dir == "" {
+		abs, _ := filepath.Abs(filename)
+		dir = filepath.Join(getCacheDir(), "builds", fmt.Sprintf("%x", sha256.Sum256([]byte(abs)))[:16])
+	}
+
+	// The caches of build tools, such as pip's wheels, are shared by every template
+	build.ToolCacheDir = filepath.Join(getCacheDir(), "tools")
+
+	b := &functionBuilder{cache: build.NewCache(dir), log: log, functions: map[string]*build.Function{}}
+	for name, function := range template.Functions {
+		if f := newBuildFunction(template, name, function, cwd); f != nil {
+			f.DependencyLayer = c.Bool("dependency-layer")
+			b.functions[name] = f
+		}
+	}
+
+	return b
+
+}
+
+// newBuildFunction returns the function to build for a function in a template, or nil if its
+// code isn't in a local directory (e.g. it's a ZIP file, or in S3)
+func newBuildFunction(template *loader.Template, name string, function cloudformation.AWSServerlessFunction, cwd string) *build.Function {
+
+	dir := cwd
+	if function.CodeUri != nil {
+		if function.CodeUri.String == nil {
+			return nil
+		}
+		dir = filepath.Join(cwd, *function.CodeUri.String)
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil
+	}
+
+	return &build.Function{
+		LogicalID: name,
+		Runtime:   function.Runtime,
+		Handler:   function.Handler,
+		CodeDir:   dir,
+		Metadata:  template.Metadata(name),
+	}
+
+}
+
+// build builds a function, unless it was built from the same source code before. It returns
+// nil if the function doesn't need to be built.
+func (b *functionBuilder) build(ctx context.Context, name string) (*build.Result, error) {
+
+	f, ok := b.functions[name]
+	if !ok {
+		return nil, nil
+	}
+
+	result, err := b.cache.Build(ctx, f, b.log)
+	if err == build.ErrNoBuilder {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Cached {
+		log.Printf("Built %s with the %s builder in %s\n", name, result.Builder, result.Duration/time.Millisecond*time.Millisecond)
+	}
+	return result, nil
+
+}
+
+// Setup is an invocationSetup that builds the function of a request's copy of its runtime,
+// so that changes to its source code are picked up, and runs it from the artifact. The
+// artifact is kept until the invocation is over, even if the function is built again.
+func (b *functionBuilder) Setup(ctx context.Context, r *invoker.Runtime) (func(), error) {
+
+	result, err := b.build(ctx, r.LogicalID)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return func() {}, nil
+	}
+
+	r.Cwd = result.Artifact
+	r.DecompressedCwd = ""
+	r.Layer = result.Layer
+	runFromArtifact(&r.Function, result)
+	return func() { b.cache.Release(result) }, nil
+
+}
+
+// runFromArtifact changes a function to run from the artifact it was built into, with the
+// environment variables that the artifact needs under its own
+func runFromArtifact(function *cloudformation.AWSServerlessFunction, result *build.Result) {
+
+	function.CodeUri = nil
+	if len(result.Env) == 0 {
+		return
+	}
+
+	// The variables are copied, as the template's function may be shared
+	variables := map[string]string{}
+	for name, value := range result.Env {
+		variables[name] = value
+	}
+	if function.Environment != nil {
+		for name, value := range function.Environment.Variables {
+			variables[name] = value
+		}
+	}
+	function.Environment = &cloudformation.AWSServerlessFunction_FunctionEnvironment{Variables: variables}
+
+}
@@ -0,0 +1,170 @@
+// Package build builds the code of functions into artifacts that SAM Local can run, such as
+// the binaries of Go functions, and caches them by the content of their source code:
+//
+//	cache := build.NewCache(dir)
+//	result, err := cache.Build(ctx, &build.Function{
+//		LogicalID: "Hello",
+//		Runtime:   "go1.x",
+//		Handler:   "hello",
+//		CodeDir:   "./hello",
+//	}, os.Stderr)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.Artifact) // the directory to mount as /var/task
+//
+// A function is only built again when its source code, its build configuration or the
+// builder's configuration changes, so rebuilding functions that weren't edited is cheap.
+package build
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// ErrNoBuilder is returned when none of the builders can build a function. Functions that
+// don't need to be built, like most Node.js and Python ones, can be run from their source.
+var ErrNoBuilder = errors.New("no builder can build the function")
+
+// Function is a function to build
+type Function struct {
+	LogicalID string
+	Runtime   string
+	Handler   string
+
+	// CodeDir is the directory of the function's source code (its CodeUri)
+	CodeDir string
+
+	// Metadata is the Metadata of the function's resource in the template, which can
+	// configure its build (e.g. BuildMethod)
+	Metadata map[string]interface{}
+	// DependencyLayer builds the function's dependencies into a layer of their own, when its
+	// builder is a LayerBuilder, and runs the function from its source code
+	DependencyLayer bool
+}
+
+// Builder builds the code of functions into artifacts
+type Builder interface {
+
+	// Detect returns whether the builder can build the function
+	Detect(f *Function) bool
+
+	// Config returns what the function's artifact depends on besides its source code and
+	// the Function itself, such as the version of the build tools, so that changing it
+	// builds the function again
+	Config(f *Function) string
+
+	// Build builds the function into the artifact directory, which exists and is empty,
+	// writing the output of the build tools to log
+	Build(ctx context.Context, f *Function, artifact string, log io.Writer) error
+}
+
+// EnvBuilder is implemented by builders whose artifacts need environment variables to run,
+// such as where the gems that the Ruby builder installs are
+type EnvBuilder interface {
+
+	// Env returns the environment variables that the function's artifact needs when it's
+	// invoked. The function's own variables override them.
+	Env(f *Function) map[string]string
+}
+
+// LayerBuilder is implemented by builders that can build a function's dependencies (e.g. the
+// requirements of Python functions) into a layer of their own, which is mounted at /opt. The
+// function then runs from its source code, so editing it doesn't need a build, and the layer
+// is only built again when the dependencies change.
+type LayerBuilder interface {
+
+	// LayerFiles returns the files that the function's dependencies are declared in,
+	// relative to its CodeDir, which the layer is built from
+	LayerFiles(f *Function) []string
+
+	// BuildLayer builds the function's dependencies into the layer directory, which exists
+	// and is empty, laid out as Lambda expects layers to be (e.g. python/ for Python)
+	BuildLayer(ctx context.Context, f *Function, layer string, log io.Writer) error
+}
+
+// namedBuilder is a registered builder
+type namedBuilder struct {
+	name    string
+	builder Builder
+}
+
+// builders are the builders that can build functions, in the order they're tried in. The
+// ones that are chosen with a function's BuildMethod come first.
+var builders = []namedBuilder{
+	{"makefile", makefileBuilder{}},
+	{"esbuild", esbuildBuilder{}},
+	{"go", goBuilder{}},
+	{"python", pythonBuilder{}},
+	{"java", javaBuilder{}},
+	{"dotnet", dotnetBuilder{}},
+	{"ruby", rubyBuilder{}},
+}
+
+var buildersLock sync.Mutex
+
+// RegisterBuilder makes a builder available under the given name. Builders are tried in the
+// order they're registered in, after the built-in ones, and the first that can build a
+// function builds it. It's meant to be called from init().
+func RegisterBuilder(name string, builder Builder) {
+
+	buildersLock.Lock()
+	defer buildersLock.Unlock()
+
+	for _, b := range builders {
+		if b.name == name {
+			panic(fmt.Sprintf("build: builder '%s' is already registered", name))
+		}
+	}
+
+	builders = append(builders, namedBuilder{name, builder})
+
+}
+
+// Builders returns the names of the registered builders, sorted
+func Builders() []string {
+
+	buildersLock.Lock()
+	defer buildersLock.Unlock()
+
+	names := []string{}
+	for _, b := range builders {
+		names = append(names, b.name)
+	}
+	sort.Strings(names)
+	return names
+
+}
+
+// Find returns the builder that builds a function, and its name, or ErrNoBuilder
+func Find(f *Function) (string, Builder, error) {
+
+	buildersLock.Lock()
+	defer buildersLock.Unlock()
+
+	for _, b := range builders {
+		if b.builder.Detect(f) {
+			return b.name, b.builder, nil
+		}
+	}
+
+	return "", nil, ErrNoBuilder
+
+}
+
+// BuildError is returned when building a function fails
+type BuildError struct {
+	Function string
+	Builder  string
+	Err      error
+}
+
+func (e *BuildError) Error() string {
+	return fmt.Sprintf("building %s with the %s builder failed: %s", e.Function, e.Builder, strings.TrimSpace(e.Err.Error()))
+}
@@ -0,0 +1,399 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// skippedDirs are directories that are never part of a function's source code
+var skippedDirs = map[string]bool{
+	".git":     true,
+	".aws-sam": true,
+}
+
+// Cache keeps the artifacts of functions in a directory, by a hash of their source code and
+// build configuration. Each function's artifacts are kept in a directory of their own, named
+// after its logical ID, and only the latest one is kept, along with older ones that are still
+// in use.
+type Cache struct {
+	Dir string
+
+	lock sync.Mutex
+
+	// files are the hashes of source files, which are only read again when their size or
+	// modification time changes
+	files map[string]fileHash
+
+	// building are the locks of functions, so that each is only built once at a time
+	building map[string]*sync.Mutex
+
+	// used counts the users of each artifact (or layer) that Build returned and that
+	// haven't been released yet, and latest is the latest artifact in each function's
+	// directory. Artifacts are only removed once they're neither used nor the latest.
+	used   map[string]int
+	latest map[string]string
+}
+
+// fileHash is the hash of a file's contents, when it had a size and modification time
+type fileHash struct {
+	size    int64
+	modTime time.Time
+	sum     []byte
+}
+
+// Result is the result of building a function
+type Result struct {
+	// Builder is the name of the builder that built the function
+	Builder string
+
+	// Artifact is the directory that the function was built into, or its CodeDir when its
+	// dependencies were built into a layer
+	Artifact string
+
+	// Layer is the directory that the function's dependencies were built into, which is
+	// mounted at /opt, if the function has DependencyLayer
+	Layer string
+
+	// Cached is whether the artifact (or layer) was already in the cache, so the function
+	// wasn't built
+	Cached bool
+
+	// Duration is how long building the function took
+	Duration time.Duration
+
+	// Env are the environment variables that the artifact needs when it's invoked, if the
+	// builder is an EnvBuilder
+	Env map[string]string
+}
+
+// NewCache returns a cache that keeps artifacts in dir
+func NewCache(dir string) *Cache {
+	return &Cache{Dir: dir, files: map[string]fileHash{}, building: map[string]*sync.Mutex{}, used: map[string]int{}, latest: map[string]string{}}
+}
+
+// Build returns the artifact of a function, building it unless the cache already has one
+// for the function's current source code and configuration. Functions with DependencyLayer
+// run from their source code, and their dependency layer is built instead. The artifact is
+// kept until the result is released, even if the function is built again in the meantime.
+func (c *Cache) Build(ctx context.Context, f *Function, log io.Writer) (*Result, error) {
+
+	name, builder, err := Find(f)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := c.functionLock(f.LogicalID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	result := &Result{Builder: name}
+	if b, ok := builder.(EnvBuilder); ok {
+		result.Env = b.Env(f)
+	}
+
+	var key string
+	if layers, ok := builder.(LayerBuilder); ok && f.DependencyLayer {
+		if key, err = c.layerKey(f, name, builder, layers); err != nil {
+			return nil, err
+		}
+
+		result.Artifact = f.CodeDir
+		result.Layer, result.Cached, result.Duration, err = c.buildInto(filepath.Join(c.Dir, f.LogicalID+"-layer"), key, func(dir string) error {
+			return layers.BuildLayer(ctx, f, dir, log)
+		})
+	} else {
+		if key, err = c.key(f, name, builder); err != nil {
+			return nil, err
+		}
+
+		result.Artifact, result.Cached, result.Duration, err = c.buildInto(filepath.Join(c.Dir, f.LogicalID), key, func(dir string) error {
+			return builder.Build(ctx, f, dir, log)
+		})
+	}
+
+	if err, ok := err.(buildFailure); ok {
+		return nil, &BuildError{Function: f.LogicalID, Builder: name, Err: err.error}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+
+}
+
+// buildFailure is the error of a build that failed, rather than of the cache itself
+type buildFailure struct {
+	error
+}
+
+// Release tells the cache that the artifact (or layer) of a result isn't used anymore, so
+// it can be removed once a newer one replaces it
+func (c *Cache) Release(result *Result) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, target := range []string{result.Artifact, result.Layer} {
+		uses, ok := c.used[target]
+		if !ok {
+			continue
+		}
+		if uses > 1 {
+			c.used[target] = uses - 1
+			continue
+		}
+		delete(c.used, target)
+		if c.latest[filepath.Dir(target)] != target {
+			os.RemoveAll(target)
+		}
+	}
+
+}
+
+// use marks the directory named key in dir as the latest one, and as used until it's
+// released. It returns false if the directory isn't there.
+func (c *Cache) use(dir string, key string) bool {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	target := filepath.Join(dir, key)
+	if _, err := os.Stat(target); err != nil {
+		return false
+	}
+	c.latest[dir] = target
+	c.used[target]++
+	return true
+
+}
+
+// buildInto returns the directory named key in dir, building it with build unless it's
+// already there. Only the latest directory, and the ones still in use, are kept in dir.
+func (c *Cache) buildInto(dir string, key string, build func(dir string) error) (string, bool, time.Duration, error) {
+
+	target := filepath.Join(dir, key)
+	if c.use(dir, key) {
+		return target, true, 0, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", false, 0, err
+	}
+
+	// Build into a temporary directory, so that failed builds never look like artifacts
+	tmp, err := ioutil.TempDir(dir, "building-")
+	if err != nil {
+		return "", false, 0, err
+	}
+
+	started := time.Now()
+	if err := build(tmp); err != nil {
+		os.RemoveAll(tmp)
+		return "", false, 0, buildFailure{err}
+	}
+	duration := time.Since(started)
+
+	// Temporary directories are only readable by their owner, which containers may not be
+	if err := os.Chmod(tmp, 0755); err != nil {
+		os.RemoveAll(tmp)
+		return "", false, 0, err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.RemoveAll(tmp)
+		return "", false, 0, err
+	}
+
+	c.use(dir, key)
+	c.prune(dir, key)
+	return target, false, duration, nil
+
+}
+
+// functionLock returns the lock of a function
+func (c *Cache) functionLock(logicalID string) *sync.Mutex {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	lock, ok := c.building[logicalID]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.building[logicalID] = lock
+	}
+	return lock
+}
+
+// key returns the cache key of a function's artifact, which is a hash of everything the
+// artifact depends on: the builder and its configuration, the function, and its source code
+func (c *Cache) key(f *Function, name string, builder Builder) (string, error) {
+
+	h, err := c.configHash(f, name, builder)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.hashSource(h, f.CodeDir); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+
+}
+
+// layerKey returns the cache key of a function's dependency layer, which only depends on the
+// files its dependencies are declared in (and whether they're there), rather than all of its
+// source code
+func (c *Cache) layerKey(f *Function, name string, builder Builder, layers LayerBuilder) (string, error) {
+
+	h, err := c.configHash(f, name, builder)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "layer\n")
+
+	for _, file := range layers.LayerFiles(f) {
+		path := filepath.Join(f.CodeDir, file)
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			fmt.Fprintf(h, "%s missing\n", filepath.ToSlash(file))
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		sum, err := c.hashFile(path, info)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %x\n", filepath.ToSlash(file), sum)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+
+}
+
+// configHash returns a hash of a function's build configuration: the builder and its
+// configuration, and the function
+func (c *Cache) configHash(f *Function, name string, builder Builder) (hash.Hash, error) {
+
+	h := sha256.New()
+	metadata, err := json.Marshal(f.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(h, "builder %s\nconfig %s\nruntime %s\nhandler %s\nmetadata %s\n", name, builder.Config(f), f.Runtime, f.Handler, metadata)
+	return h, nil
+
+}
+
+// hashSource writes the names, modes and contents of the files in a directory to h
+func (c *Cache) hashSource(h hash.Hash, dir string) error {
+
+	cacheDir, _ := filepath.Abs(c.Dir)
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != dir && skippedDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			if abs, _ := filepath.Abs(path); abs == cacheDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s %s ", filepath.ToSlash(rel), info.Mode())
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\n", target)
+		case info.Mode().IsRegular():
+			sum, err := c.hashFile(path, info)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%x\n", sum)
+		default:
+			fmt.Fprintf(h, "\n")
+		}
+
+		return nil
+	})
+
+}
+
+// hashFile returns the hash of a file's contents, which is only worked out again when the
+// file's size or modification time changed since the last time
+func (c *Cache) hashFile(path string, info os.FileInfo) ([]byte, error) {
+
+	c.lock.Lock()
+	known, ok := c.files[path]
+	c.lock.Unlock()
+
+	if ok && known.size == info.Size() && known.modTime.Equal(info.ModTime()) {
+		return known.sum, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	sum := h.Sum(nil)
+
+	c.lock.Lock()
+	c.files[path] = fileHash{size: info.Size(), modTime: info.ModTime(), sum: sum}
+	c.lock.Unlock()
+
+	return sum, nil
+
+}
+
+// prune removes the artifacts of a function other than the one with the given key, unless
+// they're still in use
+func (c *Cache) prune(dir string, key string) {
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, entry := range entries {
+		target := filepath.Join(dir, entry.Name())
+		if entry.Name() != key && !strings.HasPrefix(entry.Name(), "building-") && c.used[target] == 0 {
+			os.RemoveAll(target)
+		}
+	}
+
+}
@@ -0,0 +1,206 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// copyBuilder builds functions with the "copy" runtime by copying their handler file, and
+// counts its builds
+type copyBuilder struct {
+	builds *int
+	config *string
+}
+
+func (b copyBuilder) Detect(f *Function) bool {
+	return f.Runtime == "copy"
+}
+
+func (b copyBuilder) Config(f *Function) string {
+	return *b.config
+}
+
+func (b copyBuilder) Build(ctx context.Context, f *Function, artifact string, log io.Writer) error {
+	*b.builds++
+	data, err := ioutil.ReadFile(filepath.Join(f.CodeDir, f.Handler))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(log, "copying %s\n", f.Handler)
+	return ioutil.WriteFile(filepath.Join(artifact, f.Handler), data, 0644)
+}
+
+func (b copyBuilder) Env(f *Function) map[string]string {
+	return map[string]string{"COPIED_HANDLER": f.Handler}
+}
+
+func (b copyBuilder) LayerFiles(f *Function) []string {
+	return []string{"deps"}
+}
+
+func (b copyBuilder) BuildLayer(ctx context.Context, f *Function, layer string, log io.Writer) error {
+	*b.builds++
+	data, err := ioutil.ReadFile(filepath.Join(f.CodeDir, "deps"))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(layer, "deps"), data, 0644)
+}
+
+var _ = Describe("Cache", func() {
+
+	builds, config := 0, "v1"
+	RegisterBuilder("copy", copyBuilder{builds: &builds, config: &config})
+
+	var dir string
+	var cache *Cache
+
+	function := func(name string) *Function {
+		return &Function{LogicalID: name, Runtime: "copy", Handler: "handler", CodeDir: filepath.Join(dir, "src", name)}
+	}
+
+	write := func(name string, content string) {
+		os.MkdirAll(filepath.Join(dir, "src", name), 0755)
+		Expect(ioutil.WriteFile(filepath.Join(dir, "src", name, "handler"), []byte(content), 0644)).To(BeNil())
+	}
+
+	build := func(f *Function) *Result {
+		result, err := cache.Build(context.Background(), f, ioutil.Discard)
+		Expect(err).To(BeNil())
+		return result
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "build")
+		cache = NewCache(filepath.Join(dir, "cache"))
+		builds, config = 0, "v1"
+		write("One", "one")
+		write("Two", "two")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("builds functions into artifacts", func() {
+		result := build(function("One"))
+		Expect(result.Builder).To(Equal("copy"))
+		Expect(result.Cached).To(BeFalse())
+
+		data, _ := ioutil.ReadFile(filepath.Join(result.Artifact, "handler"))
+		Expect(string(data)).To(Equal("one"))
+	})
+
+	It("only builds functions again when their source changes", func() {
+		first := build(function("One"))
+		build(function("Two"))
+		Expect(builds).To(Equal(2))
+
+		second := build(function("One"))
+		Expect(builds).To(Equal(2))
+		Expect(second.Cached).To(BeTrue())
+		Expect(second.Artifact).To(Equal(first.Artifact))
+		cache.Release(first)
+		cache.Release(second)
+
+		write("One", "edited")
+		os.Chtimes(filepath.Join(dir, "src", "One", "handler"), time.Now(), time.Now().Add(time.Second))
+		third := build(function("One"))
+		build(function("Two"))
+		Expect(builds).To(Equal(3))
+		Expect(third.Artifact).ToNot(Equal(first.Artifact))
+
+		// Only the latest artifact is kept
+		_, err := os.Stat(first.Artifact)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("keeps artifacts that are still in use until they're released", func() {
+		first := build(function("One"))
+
+		write("One", "edited")
+		os.Chtimes(filepath.Join(dir, "src", "One", "handler"), time.Now(), time.Now().Add(time.Second))
+		second := build(function("One"))
+		Expect(first.Artifact).To(BeADirectory())
+
+		cache.Release(first)
+		_, err := os.Stat(first.Artifact)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+
+		// The latest artifact is kept, even once it's released
+		cache.Release(second)
+		Expect(second.Artifact).To(BeADirectory())
+	})
+
+	It("returns the environment that artifacts need, whether they're cached or not", func() {
+		Expect(build(function("One")).Env).To(Equal(map[string]string{"COPIED_HANDLER": "handler"}))
+		Expect(build(function("One")).Env).To(Equal(map[string]string{"COPIED_HANDLER": "handler"}))
+	})
+
+	It("builds dependency layers, which only depend on the dependencies, and runs functions from their source", func() {
+		ioutil.WriteFile(filepath.Join(dir, "src", "One", "deps"), []byte("left-pad"), 0644)
+		f := function("One")
+		f.DependencyLayer = true
+
+		first := build(f)
+		Expect(first.Artifact).To(Equal(f.CodeDir))
+		Expect(filepath.Join(first.Layer, "deps")).To(BeAnExistingFile())
+		Expect(builds).To(Equal(1))
+
+		write("One", "edited")
+		os.Chtimes(filepath.Join(dir, "src", "One", "handler"), time.Now(), time.Now().Add(time.Second))
+		Expect(build(f).Cached).To(BeTrue())
+
+		ioutil.WriteFile(filepath.Join(dir, "src", "One", "deps"), []byte("left-pad is-odd"), 0644)
+		os.Chtimes(filepath.Join(dir, "src", "One", "deps"), time.Now(), time.Now().Add(time.Second))
+		second := build(f)
+		Expect(second.Cached).To(BeFalse())
+		Expect(second.Layer).ToNot(Equal(first.Layer))
+		Expect(builds).To(Equal(2))
+	})
+
+	It("builds functions again when their configuration changes", func() {
+		build(function("One"))
+
+		f := function("One")
+		f.Metadata = map[string]interface{}{"BuildMethod": "copy"}
+		build(f)
+		Expect(builds).To(Equal(2))
+
+		config = "v2"
+		build(f)
+		Expect(builds).To(Equal(3))
+	})
+
+	It("doesn't keep the artifacts of failed builds", func() {
+		f := function("Missing")
+		os.MkdirAll(f.CodeDir, 0755)
+
+		_, err := cache.Build(context.Background(), f, ioutil.Discard)
+		Expect(err).To(BeAssignableToTypeOf(&BuildError{}))
+		Expect(err.Error()).To(ContainSubstring("building Missing with the copy builder failed"))
+
+		entries, _ := ioutil.ReadDir(filepath.Join(cache.Dir, "Missing"))
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("returns ErrNoBuilder for functions that no builder builds", func() {
+		_, err := cache.Build(context.Background(), &Function{LogicalID: "Node", Runtime: "nodejs8.10", CodeDir: dir}, ioutil.Discard)
+		Expect(err).To(Equal(ErrNoBuilder))
+	})
+
+	It("won't register a builder twice", func() {
+		Expect(func() { RegisterBuilder("go", goBuilder{}) }).To(Panic())
+		Expect(Builders()).To(ContainElement("copy"))
+	})
+
+})
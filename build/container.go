@@ -0,0 +1,165 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"golang.org/x/net/context"
+)
+
+// dockerCommand is the Docker CLI that builder containers are run with
+var dockerCommand = "docker"
+
+// ToolCacheDir is where the caches of build tools that are shared between builds are kept,
+// such as pip's wheels, so that dependencies aren't downloaded and compiled for every build.
+// If it's empty, builds start with empty caches.
+var ToolCacheDir string
+
+// containerLabel is set on builder containers, with the value "build", so that 'sam local
+// cleanup' finds the ones that are left behind. It's the label of the invoker's containers.
+const containerLabel = "com.amazonaws.sam-local"
+
+// BuilderImage returns the image that functions with a runtime are built in: lambci's build
+// image for the runtime, which is Amazon Linux with the runtime's build tools and make
+func BuilderImage(runtime string) string {
+	return "lambci/lambda:build-" + runtime
+}
+
+// containerRun is a command to run in a builder container
+type containerRun struct {
+	Image   string
+	Command []string
+
+	// Mounts are the host directories to mount in the container, by their path in it
+	Mounts map[string]string
+
+	// Caches are the tool caches to mount in the container (see ToolCacheDir), by their
+	// path in it
+	Caches map[string]string
+
+	// Env are the environment variables of the command
+	Env map[string]string
+
+	// Dir is the working directory of the command in the container
+	Dir string
+}
+
+// run runs the command in a new container, which is removed once it exits. On Linux, the
+// container runs as the current user, so that the files it writes to the mounted directories
+// can be cleaned up without root.
+func (c containerRun) run(ctx context.Context, log io.Writer) error {
+
+	args := []string{"run", "--rm", "--label", containerLabel + "=build"}
+	if runtime.GOOS == "linux" {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+
+	mounts := map[string]string{}
+	for path, dir := range c.Mounts {
+		mounts[path] = dir
+	}
+	for path, name := range c.Caches {
+		if ToolCacheDir == "" {
+			continue
+		}
+		dir := filepath.Join(ToolCacheDir, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		mounts[path] = dir
+	}
+
+	for _, path := range sortedKeys(mounts) {
+		args = append(args, "-v", mounts[path]+":"+path)
+	}
+	for _, name := range sortedKeys(c.Env) {
+		args = append(args, "-e", name+"="+c.Env[name])
+	}
+	if c.Dir != "" {
+		args = append(args, "-w", c.Dir)
+	}
+
+	args = append(args, c.Image)
+	args = append(args, c.Command...)
+
+	cmd := exec.CommandContext(ctx, dockerCommand, args...)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.Error); ok {
+			return fmt.Errorf("builder containers need Docker: %s", err)
+		}
+		return err
+	}
+	return nil
+
+}
+
+// copySource copies the source code of a function to a scratch directory, which builds can
+// write to without changing the function's source (and so its hash)
+func copySource(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir() && skippedDirs[info.Name()] && path != src:
+			return filepath.SkipDir
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// copyFile copies a file, with the given permissions
+func copyFile(src string, dst string, perm os.FileMode) error {
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+
+}
+
+// sortedKeys returns the keys of a map, sorted
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
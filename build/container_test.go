@@ -0,0 +1,123 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeDocker is a docker command for tests, which records its arguments in args and runs the
+// command on this machine, with the mounted directories in place of their paths in the
+// container
+const fakeDocker = `#!/bin/sh
+echo "$@" > "$(dirname "$0")/args"
+mounts="-e t"
+dir=/
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-v) mounts="$mounts -e s|^${2#*:}|${2%%:*}| -e t"; shift 2 ;;
+	-e) export "$2"; shift 2 ;;
+	-w) dir="$2"; shift 2 ;;
+	--user|--label) shift 2 ;;
+	run|--rm) shift ;;
+	*) shift; break ;;
+	esac
+done
+for name in $(env | grep '^[A-Z_]*=/tmp/' | cut -d= -f1); do
+	export "$name=$(eval echo \$$name | sed $mounts)"
+done
+for arg; do shift; set -- "$@" "$(echo "$arg" | sed $mounts)"; done
+cd "$(echo "$dir" | sed $mounts)" || exit 1
+exec "$@"
+`
+
+// useFakeDocker runs builder containers with fakeDocker, from a directory of tools that are
+// shell scripts with the given bodies
+func useFakeDocker(bin string, tools map[string]string) {
+	os.MkdirAll(bin, 0755)
+	ioutil.WriteFile(filepath.Join(bin, "docker"), []byte(fakeDocker), 0755)
+	for name, body := range tools {
+		ioutil.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+body+"\n"), 0755)
+	}
+	dockerCommand = filepath.Join(bin, "docker")
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// resetFakeDocker runs builder containers with Docker again
+func resetFakeDocker(bin string) {
+	dockerCommand = "docker"
+	os.Setenv("PATH", strings.TrimPrefix(os.Getenv("PATH"), bin+string(os.PathListSeparator)))
+}
+
+// fakeDockerArgs returns the arguments docker was last run with
+func fakeDockerArgs(bin string) string {
+	args, _ := ioutil.ReadFile(filepath.Join(bin, "args"))
+	return strings.TrimSpace(string(args))
+}
+
+var _ = Describe("Builder containers", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "container")
+		useFakeDocker(filepath.Join(dir, "bin"), map[string]string{"tool": "pwd > \"$CACHE/pwd\""})
+	})
+
+	AfterEach(func() {
+		resetFakeDocker(filepath.Join(dir, "bin"))
+		ToolCacheDir = ""
+		os.RemoveAll(dir)
+	})
+
+	It("mounts the tool caches", func() {
+		ToolCacheDir = filepath.Join(dir, "tools")
+		os.MkdirAll(filepath.Join(dir, "work"), 0755)
+
+		err := containerRun{
+			Image:   "image",
+			Command: []string{"tool"},
+			Mounts:  map[string]string{"/tmp/work": filepath.Join(dir, "work")},
+			Caches:  map[string]string{"/tmp/cache": "tool"},
+			Env:     map[string]string{"CACHE": "/tmp/cache"},
+			Dir:     "/tmp/work",
+		}.run(context.Background(), GinkgoWriter)
+		Expect(err).To(BeNil())
+
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).To(HavePrefix("run --rm --label " + containerLabel + "=build "))
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).To(HaveSuffix("-v " + filepath.Join(dir, "tools", "tool") + ":/tmp/cache -v " + filepath.Join(dir, "work") + ":/tmp/work -e CACHE=/tmp/cache -w /tmp/work image tool"))
+		pwd, _ := ioutil.ReadFile(filepath.Join(dir, "tools", "tool", "pwd"))
+		Expect(strings.TrimSpace(string(pwd))).To(Equal(filepath.Join(dir, "work")))
+	})
+
+	It("leaves out the tool caches without a ToolCacheDir", func() {
+		containerRun{Image: "image", Command: []string{"true"}, Caches: map[string]string{"/tmp/cache": "tool"}}.run(context.Background(), GinkgoWriter)
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).ToNot(ContainSubstring("/tmp/cache"))
+	})
+
+	It("copies source code without the directories that aren't part of it", func() {
+		src := filepath.Join(dir, "src")
+		os.MkdirAll(filepath.Join(src, ".git"), 0755)
+		os.MkdirAll(filepath.Join(src, "lib"), 0755)
+		ioutil.WriteFile(filepath.Join(src, ".git", "HEAD"), []byte("ref"), 0644)
+		ioutil.WriteFile(filepath.Join(src, "lib", "run.sh"), []byte("#!/bin/sh"), 0755)
+		os.Symlink("lib/run.sh", filepath.Join(src, "run"))
+
+		dst := filepath.Join(dir, "dst")
+		Expect(copySource(src, dst)).To(BeNil())
+
+		Expect(filepath.Join(dst, ".git")).ToNot(BeAnExistingFile())
+		info, err := os.Stat(filepath.Join(dst, "lib", "run.sh"))
+		Expect(err).To(BeNil())
+		Expect(info.Mode() & 0111).ToNot(BeZero())
+		link, _ := os.Readlink(filepath.Join(dst, "run"))
+		Expect(link).To(Equal("lib/run.sh"))
+	})
+
+})
@@ -0,0 +1,116 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// dotnetBuilder builds .NET Core functions from their project, with 'dotnet publish' in a
+// builder container. The project is the one named after the assembly in the function's
+// handler (Assembly::Namespace.Class::Method), or the only one in its CodeUri. NuGet's
+// packages are kept in the tool cache. ReadyToRun compilation is turned on with the
+// function's BuildProperties:
+//
+//	Metadata:
+//	  BuildProperties:
+//	    ReadyToRun: true
+type dotnetBuilder struct{}
+
+// dotnetProjectExtensions are the extensions of .NET project files
+var dotnetProjectExtensions = []string{".csproj", ".fsproj", ".vbproj"}
+
+// Detect implements Builder
+func (dotnetBuilder) Detect(f *Function) bool {
+	return strings.HasPrefix(f.Runtime, "dotnetcore") && len(dotnetProjects(f)) > 0
+}
+
+// Config implements Builder. Artifacts depend on the builder image.
+func (dotnetBuilder) Config(f *Function) string {
+	return BuilderImage(f.Runtime)
+}
+
+// Build implements Builder. The project is published from a copy of the function's source
+// code, so its obj and bin directories don't count as changes to the source.
+func (dotnetBuilder) Build(ctx context.Context, f *Function, artifact string, log io.Writer) error {
+
+	assembly := strings.SplitN(f.Handler, "::", 2)[0]
+	project, err := dotnetProject(f, assembly)
+	if err != nil {
+		return err
+	}
+
+	scratch, err := ioutil.TempDir("", "sam-build")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratch)
+
+	if err := copySource(f.CodeDir, scratch); err != nil {
+		return err
+	}
+
+	command := []string{"dotnet", "publish", project, "--configuration", "Release", "--output", "/tmp/artifacts"}
+	properties, _ := f.Metadata["BuildProperties"].(map[string]interface{})
+	if readyToRun, _ := properties["ReadyToRun"].(bool); readyToRun {
+		command = append(command, "--runtime", "linux-x64", "--self-contained", "false", "-p:PublishReadyToRun=true")
+	}
+
+	err = containerRun{
+		Image:   BuilderImage(f.Runtime),
+		Command: command,
+		Mounts:  map[string]string{"/tmp/scratch": scratch, "/tmp/artifacts": artifact},
+		Caches:  map[string]string{"/tmp/nuget": "nuget"},
+		Env: map[string]string{
+			"HOME":                        "/tmp",
+			"DOTNET_CLI_HOME":             "/tmp",
+			"DOTNET_CLI_TELEMETRY_OPTOUT": "1",
+			"NUGET_PACKAGES":              "/tmp/nuget",
+		},
+		Dir: "/tmp/scratch",
+	}.run(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(artifact, assembly+".dll")); err != nil {
+		return fmt.Errorf("%s didn't publish %s.dll, which is the assembly of its handler %s", project, assembly, f.Handler)
+	}
+	return nil
+
+}
+
+// dotnetProjects returns the project files in a function's CodeUri
+func dotnetProjects(f *Function) []string {
+	projects := []string{}
+	for _, ext := range dotnetProjectExtensions {
+		matches, _ := filepath.Glob(filepath.Join(f.CodeDir, "*"+ext))
+		for _, match := range matches {
+			projects = append(projects, filepath.Base(match))
+		}
+	}
+	return projects
+}
+
+// dotnetProject returns the project file to publish for an assembly: the one named after it,
+// or the only one there is
+func dotnetProject(f *Function, assembly string) (string, error) {
+
+	projects := dotnetProjects(f)
+	for _, project := range projects {
+		if strings.TrimSuffix(project, filepath.Ext(project)) == assembly {
+			return project, nil
+		}
+	}
+
+	if len(projects) == 1 {
+		return projects[0], nil
+	}
+	return "", fmt.Errorf("none of its projects (%s) are named after the assembly %s of its handler", strings.Join(projects, ", "), assembly)
+
+}
@@ -0,0 +1,75 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("dotnetBuilder", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "dotnet")
+		os.MkdirAll(filepath.Join(dir, "src"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "src", "HelloWorld.csproj"), []byte("<Project/>"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "src", "Tests.csproj"), []byte("<Project/>"), 0644)
+
+		// The fake dotnet publishes an assembly named after the project
+		useFakeDocker(filepath.Join(dir, "bin"), map[string]string{
+			"dotnet": "touch \"$6/$(basename \"$2\" .csproj).dll\"",
+		})
+	})
+
+	AfterEach(func() {
+		resetFakeDocker(filepath.Join(dir, "bin"))
+		os.RemoveAll(dir)
+	})
+
+	function := func(handler string, properties map[string]interface{}) *Function {
+		return &Function{
+			LogicalID: "Hello",
+			Runtime:   "dotnetcore2.0",
+			Handler:   handler,
+			CodeDir:   filepath.Join(dir, "src"),
+			Metadata:  map[string]interface{}{"BuildProperties": properties},
+		}
+	}
+
+	It("builds .NET Core functions with a project", func() {
+		Expect(dotnetBuilder{}.Detect(function("HelloWorld::HelloWorld.Function::Handler", nil))).To(BeTrue())
+		Expect(dotnetBuilder{}.Detect(&Function{Runtime: "dotnetcore2.0", CodeDir: dir})).To(BeFalse())
+	})
+
+	It("publishes the project of the handler's assembly", func() {
+		artifact := filepath.Join(dir, "artifact")
+		os.Mkdir(artifact, 0755)
+		Expect(dotnetBuilder{}.Build(context.Background(), function("HelloWorld::HelloWorld.Function::Handler", nil), artifact, GinkgoWriter)).To(BeNil())
+
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).To(HaveSuffix("lambci/lambda:build-dotnetcore2.0 dotnet publish HelloWorld.csproj --configuration Release --output /tmp/artifacts"))
+		Expect(filepath.Join(artifact, "HelloWorld.dll")).To(BeAnExistingFile())
+	})
+
+	It("publishes ReadyToRun assemblies", func() {
+		Expect(dotnetBuilder{}.Build(context.Background(), function("HelloWorld::HelloWorld.Function::Handler", map[string]interface{}{"ReadyToRun": true}), dir, GinkgoWriter)).To(BeNil())
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).To(HaveSuffix("--runtime linux-x64 --self-contained false -p:PublishReadyToRun=true"))
+	})
+
+	It("fails when no project is named after the handler's assembly", func() {
+		err := dotnetBuilder{}.Build(context.Background(), function("Other::Other.Function::Handler", nil), dir, GinkgoWriter)
+		Expect(err).To(MatchError("none of its projects (HelloWorld.csproj, Tests.csproj) are named after the assembly Other of its handler"))
+	})
+
+	It("fails when the handler's assembly isn't published", func() {
+		os.Remove(filepath.Join(dir, "src", "Tests.csproj"))
+		err := dotnetBuilder{}.Build(context.Background(), function("Other::Other.Function::Handler", nil), dir, GinkgoWriter)
+		Expect(err).To(MatchError(ContainSubstring("didn't publish Other.dll")))
+	})
+
+})
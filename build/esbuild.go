@@ -0,0 +1,130 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// esbuildBuilder bundles Node.js functions whose Metadata has 'BuildMethod: esbuild' into a
+// single file with esbuild, so they're run without their node_modules. The aws-sdk is left
+// out, as Lambda provides it. BuildProperties in the Metadata configure the bundle:
+//
+//	Metadata:
+//	  BuildMethod: esbuild
+//	  BuildProperties:
+//	    Minify: true
+//	    Sourcemap: false
+//	    External: [pg-native]
+type esbuildBuilder struct{}
+
+// esbuildEntryExtensions are the extensions of the files that a handler's entry point can be
+var esbuildEntryExtensions = []string{".ts", ".tsx", ".js", ".mjs", ".cjs"}
+
+// esbuildVersions are the outputs of 'esbuild --version', by esbuild command
+var esbuildVersions = struct {
+	sync.Mutex
+	byCommand map[string]string
+}{byCommand: map[string]string{}}
+
+// Detect implements Builder
+func (esbuildBuilder) Detect(f *Function) bool {
+	return strings.HasPrefix(f.Runtime, "nodejs") && strings.EqualFold(buildMethod(f), "esbuild")
+}
+
+// Config implements Builder. Artifacts depend on the version of esbuild.
+func (esbuildBuilder) Config(f *Function) string {
+
+	command := esbuildCommand(f)
+
+	esbuildVersions.Lock()
+	defer esbuildVersions.Unlock()
+
+	if version, ok := esbuildVersions.byCommand[command]; ok {
+		return version
+	}
+	out, _ := exec.Command(command, "--version").Output()
+	esbuildVersions.byCommand[command] = strings.TrimSpace(string(out))
+	return esbuildVersions.byCommand[command]
+
+}
+
+// Build implements Builder. The bundle has the path of the handler's file, with a .js
+// extension, so the Handler finds it as it would the source.
+func (esbuildBuilder) Build(ctx context.Context, f *Function, artifact string, log io.Writer) error {
+
+	entry, err := esbuildEntryPoint(f)
+	if err != nil {
+		return err
+	}
+
+	rel, _ := filepath.Rel(f.CodeDir, entry)
+	outfile := filepath.Join(artifact, strings.TrimSuffix(rel, filepath.Ext(rel))+".js")
+
+	args := []string{entry, "--bundle", "--platform=node", "--format=cjs", "--outfile=" + outfile, "--external:aws-sdk"}
+	if version := strings.TrimPrefix(f.Runtime, "nodejs"); version != "" {
+		args = append(args, "--target=node"+strings.TrimSuffix(version, ".x"))
+	}
+
+	properties, _ := f.Metadata["BuildProperties"].(map[string]interface{})
+	if minify, _ := properties["Minify"].(bool); minify {
+		args = append(args, "--minify")
+	}
+	if sourcemap, ok := properties["Sourcemap"].(bool); sourcemap || !ok {
+		args = append(args, "--sourcemap")
+	}
+	external, _ := properties["External"].([]interface{})
+	for _, module := range external {
+		if name, ok := module.(string); ok {
+			args = append(args, "--external:"+name)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, esbuildCommand(f), args...)
+	cmd.Dir = f.CodeDir
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.Error); ok {
+			return fmt.Errorf("esbuild isn't installed; add it to the function's devDependencies (npm install --save-dev esbuild): %s", err)
+		}
+		return err
+	}
+	return nil
+
+}
+
+// esbuildCommand returns the esbuild of a function's package, or the one in the PATH
+func esbuildCommand(f *Function) string {
+	local := filepath.Join(f.CodeDir, "node_modules", ".bin", "esbuild")
+	if _, err := os.Stat(local); err == nil {
+		return local
+	}
+	return "esbuild"
+}
+
+// esbuildEntryPoint returns the source file of a function's handler, e.g. src/app.ts for
+// src/app.handler
+func esbuildEntryPoint(f *Function) (string, error) {
+
+	i := strings.LastIndex(f.Handler, ".")
+	if i < 0 {
+		return "", fmt.Errorf("its handler %s isn't in the form file.function", f.Handler)
+	}
+
+	base := filepath.Join(f.CodeDir, filepath.FromSlash(f.Handler[:i]))
+	for _, ext := range esbuildEntryExtensions {
+		if info, err := os.Stat(base + ext); err == nil && !info.IsDir() {
+			return base + ext, nil
+		}
+	}
+
+	return "", fmt.Errorf("none of %s{%s} exist for its handler %s", base, strings.Join(esbuildEntryExtensions, ","), f.Handler)
+
+}
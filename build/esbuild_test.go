@@ -0,0 +1,75 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("esbuildBuilder", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "esbuild")
+		os.MkdirAll(filepath.Join(dir, "src"), 0755)
+		os.MkdirAll(filepath.Join(dir, "node_modules", ".bin"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "src", "app.ts"), []byte("export const handler = async () => ({})\n"), 0644)
+
+		// The fake esbuild records its arguments, and writes the bundle to --outfile
+		ioutil.WriteFile(filepath.Join(dir, "node_modules", ".bin", "esbuild"), []byte(`#!/bin/sh
+[ "$1" = "--version" ] && echo 0.8.0 && exit
+echo "$@" > "$(dirname "$0")/args"
+for arg; do case "$arg" in --outfile=*) mkdir -p "$(dirname "${arg#--outfile=}")"; echo bundle > "${arg#--outfile=}" ;; esac; done
+`), 0755)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	function := func(properties map[string]interface{}) *Function {
+		return &Function{
+			LogicalID: "Hello",
+			Runtime:   "nodejs8.10",
+			Handler:   "src/app.handler",
+			CodeDir:   dir,
+			Metadata:  map[string]interface{}{"BuildMethod": "esbuild", "BuildProperties": properties},
+		}
+	}
+
+	It("bundles Node.js functions whose BuildMethod is esbuild", func() {
+		Expect(esbuildBuilder{}.Detect(function(nil))).To(BeTrue())
+		Expect(esbuildBuilder{}.Detect(&Function{Runtime: "nodejs8.10", CodeDir: dir})).To(BeFalse())
+		Expect(esbuildBuilder{}.Config(function(nil))).To(Equal("0.8.0"))
+
+		artifact := filepath.Join(dir, "artifact")
+		os.Mkdir(artifact, 0755)
+		Expect(esbuildBuilder{}.Build(context.Background(), function(nil), artifact, GinkgoWriter)).To(BeNil())
+		Expect(filepath.Join(artifact, "src", "app.js")).To(BeAnExistingFile())
+
+		args, _ := ioutil.ReadFile(filepath.Join(dir, "node_modules", ".bin", "args"))
+		Expect(string(args)).To(Equal(filepath.Join(dir, "src", "app.ts") + " --bundle --platform=node --format=cjs --outfile=" + filepath.Join(artifact, "src", "app.js") + " --external:aws-sdk --target=node8.10 --sourcemap\n"))
+	})
+
+	It("configures the bundle with BuildProperties", func() {
+		f := function(map[string]interface{}{"Minify": true, "Sourcemap": false, "External": []interface{}{"pg-native"}})
+		Expect(esbuildBuilder{}.Build(context.Background(), f, dir, GinkgoWriter)).To(BeNil())
+
+		args, _ := ioutil.ReadFile(filepath.Join(dir, "node_modules", ".bin", "args"))
+		Expect(string(args)).To(HaveSuffix("--target=node8.10 --minify --external:pg-native\n"))
+	})
+
+	It("fails for handlers without a source file", func() {
+		f := function(nil)
+		f.Handler = "missing.handler"
+		err := esbuildBuilder{}.Build(context.Background(), f, dir, GinkgoWriter)
+		Expect(err).To(MatchError(ContainSubstring("exist for its handler missing.handler")))
+	})
+
+})
@@ -0,0 +1,79 @@
+package build
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// goBuilder builds go1.x functions from source in a golang builder container, so building
+// them doesn't need a Go toolchain that can cross compile on this machine. The handler is
+// compiled for Lambda's Linux, without cgo, so it runs in the lambci image. The container's
+// GOPATH, which has the module cache (GOMODCACHE) and the build cache (GOCACHE), is kept in
+// the tool cache, so rebuilds only compile what changed.
+type goBuilder struct{}
+
+// defaultGoImage is the image that functions are built in when their go.mod doesn't have
+// a Go version
+const defaultGoImage = "golang:1"
+
+// Detect implements Builder. It builds go1.x functions whose CodeUri has Go source files.
+func (goBuilder) Detect(f *Function) bool {
+	if f.Runtime != "go1.x" {
+		return false
+	}
+	matches, _ := filepath.Glob(filepath.Join(f.CodeDir, "*.go"))
+	return len(matches) > 0
+}
+
+// Config implements Builder. Artifacts depend on the golang image.
+func (goBuilder) Config(f *Function) string {
+	return goImage(f)
+}
+
+// Build implements Builder. The binary is named after the handler, as the go1.x runtime
+// expects.
+func (goBuilder) Build(ctx context.Context, f *Function, artifact string, log io.Writer) error {
+	return containerRun{
+		Image:   goImage(f),
+		Command: []string{"go", "build", "-o", "/tmp/artifacts/" + f.Handler, "."},
+		Mounts:  map[string]string{"/tmp/source": f.CodeDir, "/tmp/artifacts": artifact},
+		Caches:  map[string]string{"/tmp/go": "go"},
+		Env: map[string]string{
+			"GOOS":        "linux",
+			"GOARCH":      "amd64",
+			"CGO_ENABLED": "0",
+			"GOPATH":      "/tmp/go",
+			"GOMODCACHE":  "/tmp/go/pkg/mod",
+			"GOCACHE":     "/tmp/go/cache",
+			"HOME":        "/tmp",
+		},
+		Dir: "/tmp/source",
+	}.run(ctx, log)
+}
+
+// goImage returns the golang image that a function is built in, for the Go version in its
+// go.mod (e.g. golang:1.11 for 'go 1.11')
+func goImage(f *Function) string {
+
+	file, err := os.Open(filepath.Join(f.CodeDir, "go.mod"))
+	if err != nil {
+		return defaultGoImage
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "go" {
+			return "golang:" + fields[1]
+		}
+	}
+
+	return defaultGoImage
+
+}
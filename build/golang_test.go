@@ -0,0 +1,73 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("goBuilder", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "gobuild")
+		os.MkdirAll(filepath.Join(dir, "src"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "src", "go.mod"), []byte("module hello\n\ngo 1.11\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+
+		// The fake container runs the Go toolchain on this machine
+		useFakeDocker(filepath.Join(dir, "bin"), nil)
+		ToolCacheDir = filepath.Join(dir, "tools")
+	})
+
+	AfterEach(func() {
+		resetFakeDocker(filepath.Join(dir, "bin"))
+		ToolCacheDir = ""
+		os.RemoveAll(dir)
+	})
+
+	function := func() *Function {
+		return &Function{LogicalID: "Hello", Runtime: "go1.x", Handler: "hello", CodeDir: filepath.Join(dir, "src")}
+	}
+
+	It("builds go1.x functions with Go source files", func() {
+		Expect(goBuilder{}.Detect(function())).To(BeTrue())
+		Expect(goBuilder{}.Detect(&Function{Runtime: "go1.x", CodeDir: filepath.Join(dir, "empty")})).To(BeFalse())
+		Expect(goBuilder{}.Detect(&Function{Runtime: "nodejs8.10", CodeDir: filepath.Join(dir, "src")})).To(BeFalse())
+	})
+
+	It("builds in the golang image of the go.mod's Go version", func() {
+		Expect(goBuilder{}.Config(function())).To(Equal("golang:1.11"))
+
+		os.Remove(filepath.Join(dir, "src", "go.mod"))
+		Expect(goBuilder{}.Config(function())).To(Equal("golang:1"))
+	})
+
+	It("cross compiles the handler in a golang container, with the Go caches kept", func() {
+		if _, err := exec.LookPath("go"); err != nil {
+			Skip("go is not installed")
+		}
+
+		artifact := filepath.Join(dir, "artifact")
+		os.Mkdir(artifact, 0755)
+		Expect(goBuilder{}.Build(context.Background(), function(), artifact, GinkgoWriter)).To(BeNil())
+
+		args := fakeDockerArgs(filepath.Join(dir, "bin"))
+		Expect(args).To(ContainSubstring("-v " + filepath.Join(dir, "tools", "go") + ":/tmp/go"))
+		Expect(args).To(ContainSubstring("-e GOARCH=amd64 -e GOCACHE=/tmp/go/cache -e GOMODCACHE=/tmp/go/pkg/mod -e GOOS=linux -e GOPATH=/tmp/go"))
+		Expect(args).To(HaveSuffix("golang:1.11 go build -o /tmp/artifacts/hello ."))
+
+		info, err := os.Stat(filepath.Join(artifact, "hello"))
+		Expect(err).To(BeNil())
+		Expect(info.Mode() & 0111).ToNot(BeZero())
+		Expect(filepath.Join(dir, "tools", "go", "cache")).To(BeADirectory())
+	})
+
+})
@@ -0,0 +1,181 @@
+package build
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// javaBuilder builds Java functions with a Maven (pom.xml) or Gradle (build.gradle) project
+// in their CodeUri, in a builder container. The project's output, a shaded jar or a zip with
+// a lib directory, is extracted into the artifact, as Lambda does with deployment packages.
+// Maven's repository and Gradle's caches are kept in the tool cache.
+type javaBuilder struct{}
+
+// javaBuildFiles are the build files that javaBuilder detects, in the order it prefers them
+var javaBuildFiles = []string{"pom.xml", "build.gradle", "build.gradle.kts"}
+
+// Detect implements Builder
+func (javaBuilder) Detect(f *Function) bool {
+	return strings.HasPrefix(f.Runtime, "java") && javaBuildFile(f) != ""
+}
+
+// Config implements Builder. Artifacts depend on the builder image.
+func (javaBuilder) Config(f *Function) string {
+	return BuilderImage(f.Runtime)
+}
+
+// Build implements Builder. The project is built in a copy of the function's source code,
+// so its target or build directory doesn't count as a change to the source.
+func (javaBuilder) Build(ctx context.Context, f *Function, artifact string, log io.Writer) error {
+
+	scratch, err := ioutil.TempDir("", "sam-build")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratch)
+
+	if err := copySource(f.CodeDir, scratch); err != nil {
+		return err
+	}
+
+	run := containerRun{
+		Image:  BuilderImage(f.Runtime),
+		Mounts: map[string]string{"/tmp/scratch": scratch},
+		Env:    map[string]string{"HOME": "/tmp"},
+		Dir:    "/tmp/scratch",
+	}
+
+	var outputs []string
+	if javaBuildFile(f) == "pom.xml" {
+		run.Command = []string{"mvn", "--batch-mode", "--quiet", "package", "-DskipTests", "-Dmaven.repo.local=/tmp/maven"}
+		run.Caches = map[string]string{"/tmp/maven": "maven"}
+		outputs = []string{"target/*.jar"}
+	} else {
+		gradle := "gradle"
+		if _, err := os.Stat(filepath.Join(f.CodeDir, "gradlew")); err == nil {
+			gradle = "./gradlew"
+		}
+		run.Command = []string{gradle, "build", "--exclude-task", "test", "--no-daemon", "--quiet"}
+		run.Caches = map[string]string{"/tmp/gradle": "gradle"}
+		run.Env["GRADLE_USER_HOME"] = "/tmp/gradle"
+		outputs = []string{"build/distributions/*.zip", "build/libs/*.jar"}
+	}
+
+	if err := run.run(ctx, log); err != nil {
+		return err
+	}
+
+	output, err := javaOutput(scratch, outputs)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(log, "Extracting %s\n", filepath.Base(output))
+	return extractZip(output, artifact)
+
+}
+
+// javaBuildFile returns the build file of a function's project, or "" if it doesn't have one
+func javaBuildFile(f *Function) string {
+	for _, name := range javaBuildFiles {
+		if _, err := os.Stat(filepath.Join(f.CodeDir, name)); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// javaOutput returns the output of a build: the largest file that matches the first of the
+// patterns that any match, which is the shaded jar when there's also a thin one. Jars of
+// sources, javadoc and tests, and the originals that were shaded, aren't outputs.
+func javaOutput(dir string, patterns []string) (string, error) {
+
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+
+		candidates := []os.FileInfo{}
+		paths := map[os.FileInfo]string{}
+		for _, match := range matches {
+			name := filepath.Base(match)
+			if strings.HasPrefix(name, "original-") || strings.HasSuffix(name, "-sources.jar") || strings.HasSuffix(name, "-javadoc.jar") || strings.HasSuffix(name, "-tests.jar") {
+				continue
+			}
+			if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+				candidates = append(candidates, info)
+				paths[info] = match
+			}
+		}
+
+		if len(candidates) > 0 {
+			sort.Slice(candidates, func(i, j int) bool { return candidates[i].Size() > candidates[j].Size() })
+			return paths[candidates[0]], nil
+		}
+	}
+
+	return "", fmt.Errorf("the build didn't output any of %s", strings.Join(patterns, ", "))
+
+}
+
+// extractZip extracts a zip file (or jar) into a directory
+func extractZip(filename string, dir string) error {
+
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+
+		target := filepath.Join(dir, filepath.FromSlash(file.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("%s contains %s, which is outside of it", filepath.Base(filename), file.Name)
+		}
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := extractZipFile(file, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// extractZipFile extracts a file in a zip file to target
+func extractZipFile(file *zip.File, target string) error {
+
+	in, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode().Perm()|0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+
+}
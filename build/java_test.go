@@ -0,0 +1,104 @@
+package build
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// writeZip writes a zip file with the given files
+func writeZip(filename string, files map[string]string) {
+	os.MkdirAll(filepath.Dir(filename), 0755)
+	out, _ := os.Create(filename)
+	defer out.Close()
+	archive := zip.NewWriter(out)
+	for name, content := range files {
+		w, _ := archive.Create(name)
+		w.Write([]byte(content))
+	}
+	archive.Close()
+}
+
+var _ = Describe("javaBuilder", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "java")
+		os.MkdirAll(filepath.Join(dir, "src"), 0755)
+
+		// The fake build tools copy prebuilt outputs to where the real ones write them
+		writeZip(filepath.Join(dir, "outputs", "app-1.0.jar"), map[string]string{"example/Hello.class": "shaded", "lib/dependency.class": "shaded"})
+		writeZip(filepath.Join(dir, "outputs", "original-app-1.0.jar"), map[string]string{"example/Hello.class": "thin"})
+		writeZip(filepath.Join(dir, "outputs", "app.zip"), map[string]string{"example/Hello.class": "zipped", "lib/dependency.jar": "zipped"})
+		os.Setenv("OUTPUTS", filepath.Join(dir, "outputs"))
+
+		useFakeDocker(filepath.Join(dir, "bin"), map[string]string{
+			"mvn":    "mkdir -p target && cp \"$OUTPUTS\"/*.jar target/",
+			"gradle": "mkdir -p build/distributions build/libs && cp \"$OUTPUTS/app.zip\" build/distributions/ && cp \"$OUTPUTS/app-1.0.jar\" build/libs/",
+		})
+	})
+
+	AfterEach(func() {
+		resetFakeDocker(filepath.Join(dir, "bin"))
+		os.Unsetenv("OUTPUTS")
+		os.RemoveAll(dir)
+	})
+
+	function := func() *Function {
+		return &Function{LogicalID: "Hello", Runtime: "java8", Handler: "example.Hello::handleRequest", CodeDir: filepath.Join(dir, "src")}
+	}
+
+	build := func() string {
+		artifact := filepath.Join(dir, "artifact")
+		os.Mkdir(artifact, 0755)
+		Expect(javaBuilder{}.Build(context.Background(), function(), artifact, GinkgoWriter)).To(BeNil())
+		return artifact
+	}
+
+	It("builds Java functions with a Maven or Gradle project", func() {
+		Expect(javaBuilder{}.Detect(function())).To(BeFalse())
+		ioutil.WriteFile(filepath.Join(dir, "src", "build.gradle"), []byte(""), 0644)
+		Expect(javaBuilder{}.Detect(function())).To(BeTrue())
+		Expect(javaBuilder{}.Detect(&Function{Runtime: "python3.6", CodeDir: filepath.Join(dir, "src")})).To(BeFalse())
+	})
+
+	It("extracts the shaded jar of Maven projects", func() {
+		ioutil.WriteFile(filepath.Join(dir, "src", "pom.xml"), []byte("<project/>"), 0644)
+		artifact := build()
+
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).To(HaveSuffix("lambci/lambda:build-java8 mvn --batch-mode --quiet package -DskipTests -Dmaven.repo.local=/tmp/maven"))
+		class, _ := ioutil.ReadFile(filepath.Join(artifact, "example", "Hello.class"))
+		Expect(string(class)).To(Equal("shaded"))
+		Expect(filepath.Join(dir, "src", "target")).ToNot(BeAnExistingFile())
+	})
+
+	It("extracts the distribution zip of Gradle projects", func() {
+		ioutil.WriteFile(filepath.Join(dir, "src", "build.gradle"), []byte(""), 0644)
+		artifact := build()
+
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).To(ContainSubstring("-e GRADLE_USER_HOME=/tmp/gradle"))
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).To(HaveSuffix("gradle build --exclude-task test --no-daemon --quiet"))
+		Expect(filepath.Join(artifact, "lib", "dependency.jar")).To(BeAnExistingFile())
+	})
+
+	It("fails when the build has no output", func() {
+		ioutil.WriteFile(filepath.Join(dir, "src", "pom.xml"), []byte("<project/>"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "bin", "mvn"), []byte("#!/bin/sh\n"), 0755)
+		err := javaBuilder{}.Build(context.Background(), function(), dir, GinkgoWriter)
+		Expect(err).To(MatchError("the build didn't output any of target/*.jar"))
+	})
+
+	It("won't extract files outside of the artifact", func() {
+		writeZip(filepath.Join(dir, "evil.zip"), map[string]string{"../evil": "evil"})
+		Expect(extractZip(filepath.Join(dir, "evil.zip"), filepath.Join(dir, "artifact"))).ToNot(BeNil())
+		Expect(filepath.Join(dir, "evil")).ToNot(BeAnExistingFile())
+	})
+
+})
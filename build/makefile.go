@@ -0,0 +1,65 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// makefileBuilder builds functions whose Metadata has 'BuildMethod: makefile', by running the
+// build-<LogicalId> target of the Makefile in their CodeUri in a builder container. The target
+// writes the function's code to $ARTIFACTS_DIR, which is what the function runs from, so any
+// build system can be used.
+type makefileBuilder struct{}
+
+// Detect implements Builder
+func (makefileBuilder) Detect(f *Function) bool {
+	return strings.EqualFold(buildMethod(f), "makefile")
+}
+
+// Config implements Builder. Artifacts depend on the builder image.
+func (makefileBuilder) Config(f *Function) string {
+	return BuilderImage(f.Runtime)
+}
+
+// Build implements Builder. The Makefile is run in a copy of the function's source code, so
+// whatever it writes next to it doesn't count as a change to the source.
+func (makefileBuilder) Build(ctx context.Context, f *Function, artifact string, log io.Writer) error {
+
+	if _, err := os.Stat(filepath.Join(f.CodeDir, "Makefile")); err != nil {
+		return fmt.Errorf("its BuildMethod is makefile, but %s has no Makefile", f.CodeDir)
+	}
+
+	scratch, err := ioutil.TempDir("", "sam-build")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratch)
+
+	if err := copySource(f.CodeDir, scratch); err != nil {
+		return err
+	}
+
+	return containerRun{
+		Image:   BuilderImage(f.Runtime),
+		Command: []string{"make", "build-" + f.LogicalID},
+		Mounts: map[string]string{
+			"/tmp/scratch":   scratch,
+			"/tmp/artifacts": artifact,
+		},
+		Env: map[string]string{"ARTIFACTS_DIR": "/tmp/artifacts"},
+		Dir: "/tmp/scratch",
+	}.run(ctx, log)
+
+}
+
+// buildMethod returns the BuildMethod in the Metadata of a function, if it has one
+func buildMethod(f *Function) string {
+	method, _ := f.Metadata["BuildMethod"].(string)
+	return method
+}
@@ -0,0 +1,72 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("makefileBuilder", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "makefile")
+		os.MkdirAll(filepath.Join(dir, "src"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "src", "Makefile"), []byte("build-Hello:\n"), 0644)
+		useFakeDocker(filepath.Join(dir, "bin"), map[string]string{
+			"make": "echo \"$1\" > made\ncp made \"$ARTIFACTS_DIR/\"",
+		})
+	})
+
+	AfterEach(func() {
+		resetFakeDocker(filepath.Join(dir, "bin"))
+		os.RemoveAll(dir)
+	})
+
+	function := func() *Function {
+		return &Function{
+			LogicalID: "Hello",
+			Runtime:   "provided",
+			CodeDir:   filepath.Join(dir, "src"),
+			Metadata:  map[string]interface{}{"BuildMethod": "makefile"},
+		}
+	}
+
+	It("builds functions whose BuildMethod is makefile", func() {
+		Expect(makefileBuilder{}.Detect(function())).To(BeTrue())
+		Expect(makefileBuilder{}.Detect(&Function{Runtime: "go1.x"})).To(BeFalse())
+
+		name, _, err := Find(&Function{Runtime: "go1.x", CodeDir: dir, Metadata: map[string]interface{}{"BuildMethod": "Makefile"}})
+		Expect(err).To(BeNil())
+		Expect(name).To(Equal("makefile"))
+	})
+
+	It("runs the function's make target in a builder container", func() {
+		artifact := filepath.Join(dir, "artifact")
+		os.Mkdir(artifact, 0755)
+		Expect(makefileBuilder{}.Build(context.Background(), function(), artifact, GinkgoWriter)).To(BeNil())
+
+		args, _ := ioutil.ReadFile(filepath.Join(dir, "bin", "args"))
+		Expect(string(args)).To(ContainSubstring("-e ARTIFACTS_DIR=/tmp/artifacts -w /tmp/scratch lambci/lambda:build-provided make build-Hello"))
+		Expect(string(args)).To(ContainSubstring("-v " + artifact + ":/tmp/artifacts"))
+
+		made, _ := ioutil.ReadFile(filepath.Join(artifact, "made"))
+		Expect(string(made)).To(Equal("build-Hello\n"))
+
+		// The build ran in a copy of the source code
+		Expect(filepath.Join(dir, "src", "made")).ToNot(BeAnExistingFile())
+	})
+
+	It("fails for functions without a Makefile", func() {
+		os.Remove(filepath.Join(dir, "src", "Makefile"))
+		err := makefileBuilder{}.Build(context.Background(), function(), dir, GinkgoWriter)
+		Expect(err).To(MatchError(ContainSubstring("has no Makefile")))
+	})
+
+})
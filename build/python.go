@@ -0,0 +1,67 @@
+package build
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// pythonBuilder builds Python functions with a requirements.txt, by installing their
+// requirements next to their code in a builder container. Packages with native code (e.g.
+// numpy or psycopg2) are built for Lambda's Amazon Linux, rather than for this machine, which
+// Lambda can't load ("invalid ELF header"). pip's wheels are kept in the tool cache, so
+// packages are only compiled once.
+type pythonBuilder struct{}
+
+// Detect implements Builder
+func (pythonBuilder) Detect(f *Function) bool {
+	if !strings.HasPrefix(f.Runtime, "python") {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(f.CodeDir, "requirements.txt"))
+	return err == nil
+}
+
+// Config implements Builder. Artifacts depend on the builder image.
+func (pythonBuilder) Config(f *Function) string {
+	return BuilderImage(f.Runtime)
+}
+
+// Build implements Builder
+func (pythonBuilder) Build(ctx context.Context, f *Function, artifact string, log io.Writer) error {
+
+	if err := copySource(f.CodeDir, artifact); err != nil {
+		return err
+	}
+
+	return containerRun{
+		Image:   BuilderImage(f.Runtime),
+		Command: []string{"pip", "install", "--requirement", "requirements.txt", "--target", ".", "--cache-dir", "/tmp/pip-cache"},
+		Mounts:  map[string]string{"/tmp/artifacts": artifact},
+		Caches:  map[string]string{"/tmp/pip-cache": "pip-" + f.Runtime},
+		Env:     map[string]string{"HOME": "/tmp"},
+		Dir:     "/tmp/artifacts",
+	}.run(ctx, log)
+
+}
+
+// LayerFiles implements LayerBuilder
+func (pythonBuilder) LayerFiles(f *Function) []string {
+	return []string{"requirements.txt"}
+}
+
+// BuildLayer implements LayerBuilder. The requirements are installed into python/, which
+// Lambda puts on the sys.path of functions.
+func (pythonBuilder) BuildLayer(ctx context.Context, f *Function, layer string, log io.Writer) error {
+	return containerRun{
+		Image:   BuilderImage(f.Runtime),
+		Command: []string{"pip", "install", "--requirement", "requirements.txt", "--target", "/tmp/layer/python", "--cache-dir", "/tmp/pip-cache"},
+		Mounts:  map[string]string{"/tmp/source": f.CodeDir, "/tmp/layer": layer},
+		Caches:  map[string]string{"/tmp/pip-cache": "pip-" + f.Runtime},
+		Env:     map[string]string{"HOME": "/tmp"},
+		Dir:     "/tmp/source",
+	}.run(ctx, log)
+}
@@ -0,0 +1,66 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pythonBuilder", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "python")
+		os.MkdirAll(filepath.Join(dir, "src"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "src", "app.py"), []byte("def handler(event, context):\n    pass\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "src", "requirements.txt"), []byte("numpy\n"), 0644)
+
+		// The fake pip installs each requirement as a directory
+		useFakeDocker(filepath.Join(dir, "bin"), map[string]string{
+			"pip": "for requirement in $(cat requirements.txt); do mkdir -p \"$5/$requirement\"; done\ntouch \"$7/wheel\"",
+		})
+		ToolCacheDir = filepath.Join(dir, "tools")
+	})
+
+	AfterEach(func() {
+		resetFakeDocker(filepath.Join(dir, "bin"))
+		ToolCacheDir = ""
+		os.RemoveAll(dir)
+	})
+
+	It("builds Python functions with a requirements.txt", func() {
+		Expect(pythonBuilder{}.Detect(&Function{Runtime: "python3.6", CodeDir: filepath.Join(dir, "src")})).To(BeTrue())
+		Expect(pythonBuilder{}.Detect(&Function{Runtime: "python3.6", CodeDir: dir})).To(BeFalse())
+		Expect(pythonBuilder{}.Detect(&Function{Runtime: "nodejs8.10", CodeDir: filepath.Join(dir, "src")})).To(BeFalse())
+	})
+
+	It("installs the requirements next to the code in a builder container", func() {
+		artifact := filepath.Join(dir, "artifact")
+		os.Mkdir(artifact, 0755)
+		f := &Function{LogicalID: "Hello", Runtime: "python3.6", Handler: "app.handler", CodeDir: filepath.Join(dir, "src")}
+		Expect(pythonBuilder{}.Build(context.Background(), f, artifact, GinkgoWriter)).To(BeNil())
+
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).To(HaveSuffix("lambci/lambda:build-python3.6 pip install --requirement requirements.txt --target . --cache-dir /tmp/pip-cache"))
+		Expect(filepath.Join(artifact, "app.py")).To(BeAnExistingFile())
+		Expect(filepath.Join(artifact, "numpy")).To(BeADirectory())
+		Expect(filepath.Join(dir, "tools", "pip-python3.6", "wheel")).To(BeAnExistingFile())
+	})
+
+	It("installs the requirements into a dependency layer", func() {
+		layer := filepath.Join(dir, "layer")
+		os.Mkdir(layer, 0755)
+		f := &Function{LogicalID: "Hello", Runtime: "python3.6", Handler: "app.handler", CodeDir: filepath.Join(dir, "src"), DependencyLayer: true}
+		Expect(pythonBuilder{}.BuildLayer(context.Background(), f, layer, GinkgoWriter)).To(BeNil())
+
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).To(HaveSuffix("pip install --requirement requirements.txt --target /tmp/layer/python --cache-dir /tmp/pip-cache"))
+		Expect(filepath.Join(layer, "python", "numpy")).To(BeADirectory())
+		Expect(pythonBuilder{}.LayerFiles(f)).To(Equal([]string{"requirements.txt"}))
+	})
+
+})
@@ -0,0 +1,111 @@
+package build
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// rubyBuilder builds Ruby functions with a Gemfile, by installing their gems next to their
+// code with 'bundle install --deployment' in a builder container. Gems with native extensions
+// (e.g. nokogiri or pg) are compiled for Lambda's Amazon Linux, rather than for this machine.
+// The container's home, which has Bundler's caches, is kept in the tool cache.
+type rubyBuilder struct{}
+
+// Detect implements Builder
+func (rubyBuilder) Detect(f *Function) bool {
+	if !strings.HasPrefix(f.Runtime, "ruby") {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(f.CodeDir, "Gemfile"))
+	return err == nil
+}
+
+// Config implements Builder. Artifacts depend on the builder image.
+func (rubyBuilder) Config(f *Function) string {
+	return BuilderImage(f.Runtime)
+}
+
+// Build implements Builder. Deployment mode installs the gems into vendor/bundle, exactly as
+// they're locked in the Gemfile.lock.
+func (b rubyBuilder) Build(ctx context.Context, f *Function, artifact string, log io.Writer) error {
+
+	if err := checkGemfileLock(f); err != nil {
+		return err
+	}
+
+	if err := copySource(f.CodeDir, artifact); err != nil {
+		return err
+	}
+
+	return b.install(ctx, f, artifact, log)
+
+}
+
+// LayerFiles implements LayerBuilder
+func (rubyBuilder) LayerFiles(f *Function) []string {
+	return []string{"Gemfile", "Gemfile.lock"}
+}
+
+// BuildLayer implements LayerBuilder. The gems are installed into ruby/vendor/bundle, next
+// to a copy of the Gemfile.
+func (b rubyBuilder) BuildLayer(ctx context.Context, f *Function, layer string, log io.Writer) error {
+
+	if err := checkGemfileLock(f); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(layer, "ruby")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, name := range b.LayerFiles(f) {
+		if err := copyFile(filepath.Join(f.CodeDir, name), filepath.Join(dir, name), 0644); err != nil {
+			return err
+		}
+	}
+
+	return b.install(ctx, f, dir, log)
+
+}
+
+// checkGemfileLock returns an error if a function doesn't have a Gemfile.lock
+func checkGemfileLock(f *Function) error {
+	if _, err := os.Stat(filepath.Join(f.CodeDir, "Gemfile.lock")); err != nil {
+		return errors.New("bundle install --deployment needs a Gemfile.lock, which 'bundle lock' creates")
+	}
+	return nil
+}
+
+// install runs 'bundle install --deployment' in a directory with a Gemfile
+func (rubyBuilder) install(ctx context.Context, f *Function, dir string, log io.Writer) error {
+	return containerRun{
+		Image:   BuilderImage(f.Runtime),
+		Command: []string{"bundle", "install", "--deployment"},
+		Mounts:  map[string]string{"/tmp/artifacts": dir},
+		Caches:  map[string]string{"/tmp/home": "bundler-" + f.Runtime},
+		Env:     map[string]string{"HOME": "/tmp/home"},
+		Dir:     "/tmp/artifacts",
+	}.run(ctx, log)
+}
+
+// Env implements EnvBuilder. Bundler looks for the Gemfile and the gems in the function's
+// code (or its dependency layer), whatever its working directory is, and doesn't try to
+// change the Gemfile.lock, as /var/task and /opt are read-only.
+func (rubyBuilder) Env(f *Function) map[string]string {
+	root := "/var/task"
+	if f.DependencyLayer {
+		root = "/opt/ruby"
+	}
+	return map[string]string{
+		"BUNDLE_GEMFILE":    root + "/Gemfile",
+		"BUNDLE_PATH":       root + "/vendor/bundle",
+		"BUNDLE_DEPLOYMENT": "true",
+		"BUNDLE_FROZEN":     "true",
+	}
+}
@@ -0,0 +1,88 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("rubyBuilder", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "ruby")
+		os.MkdirAll(filepath.Join(dir, "src"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "src", "app.rb"), []byte("def handler(event:, context:)\nend\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "src", "Gemfile"), []byte("gem 'nokogiri'\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "src", "Gemfile.lock"), []byte("GEM\n  specs:\n    nokogiri (1.10.0)\n"), 0644)
+
+		// The fake bundle installs each gem as a directory
+		useFakeDocker(filepath.Join(dir, "bin"), map[string]string{
+			"bundle": "mkdir -p vendor/bundle/ruby/2.5.0/gems/nokogiri-1.10.0\ntouch \"$HOME/index\"",
+		})
+		ToolCacheDir = filepath.Join(dir, "tools")
+	})
+
+	AfterEach(func() {
+		resetFakeDocker(filepath.Join(dir, "bin"))
+		ToolCacheDir = ""
+		os.RemoveAll(dir)
+	})
+
+	function := func() *Function {
+		return &Function{LogicalID: "Hello", Runtime: "ruby2.5", Handler: "app.handler", CodeDir: filepath.Join(dir, "src")}
+	}
+
+	It("builds Ruby functions with a Gemfile", func() {
+		Expect(rubyBuilder{}.Detect(function())).To(BeTrue())
+		Expect(rubyBuilder{}.Detect(&Function{Runtime: "ruby2.5", CodeDir: dir})).To(BeFalse())
+		Expect(rubyBuilder{}.Detect(&Function{Runtime: "python3.6", CodeDir: filepath.Join(dir, "src")})).To(BeFalse())
+	})
+
+	It("installs the gems next to the code in a builder container", func() {
+		artifact := filepath.Join(dir, "artifact")
+		os.Mkdir(artifact, 0755)
+		Expect(rubyBuilder{}.Build(context.Background(), function(), artifact, GinkgoWriter)).To(BeNil())
+
+		Expect(fakeDockerArgs(filepath.Join(dir, "bin"))).To(HaveSuffix("lambci/lambda:build-ruby2.5 bundle install --deployment"))
+		Expect(filepath.Join(artifact, "app.rb")).To(BeAnExistingFile())
+		Expect(filepath.Join(artifact, "vendor", "bundle", "ruby", "2.5.0", "gems", "nokogiri-1.10.0")).To(BeADirectory())
+		Expect(filepath.Join(dir, "tools", "bundler-ruby2.5", "index")).To(BeAnExistingFile())
+	})
+
+	It("needs a Gemfile.lock", func() {
+		os.Remove(filepath.Join(dir, "src", "Gemfile.lock"))
+		err := rubyBuilder{}.Build(context.Background(), function(), filepath.Join(dir, "artifact"), GinkgoWriter)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("needs a Gemfile.lock"))
+	})
+
+	It("points Bundler at the installed gems when functions are invoked", func() {
+		env := rubyBuilder{}.Env(function())
+		Expect(env).To(HaveKeyWithValue("BUNDLE_GEMFILE", "/var/task/Gemfile"))
+		Expect(env).To(HaveKeyWithValue("BUNDLE_PATH", "/var/task/vendor/bundle"))
+	})
+
+	It("installs the gems into a dependency layer, which Bundler is pointed at", func() {
+		layer := filepath.Join(dir, "layer")
+		os.Mkdir(layer, 0755)
+		f := function()
+		f.DependencyLayer = true
+		Expect(rubyBuilder{}.BuildLayer(context.Background(), f, layer, GinkgoWriter)).To(BeNil())
+
+		Expect(filepath.Join(layer, "ruby", "Gemfile.lock")).To(BeAnExistingFile())
+		Expect(filepath.Join(layer, "ruby", "vendor", "bundle", "ruby", "2.5.0", "gems", "nokogiri-1.10.0")).To(BeADirectory())
+		Expect(filepath.Join(layer, "ruby", "app.rb")).ToNot(BeAnExistingFile())
+
+		env := rubyBuilder{}.Env(f)
+		Expect(env).To(HaveKeyWithValue("BUNDLE_GEMFILE", "/opt/ruby/Gemfile"))
+		Expect(env).To(HaveKeyWithValue("BUNDLE_PATH", "/opt/ruby/vendor/bundle"))
+	})
+
+})
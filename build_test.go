@@ -0,0 +1,148 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/awslabs/aws-sam-local/build"
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/aws-sam-local/loader"
+	"github.com/awslabs/goformation/cloudformation"
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// copyBuilder builds functions whose BuildMethod is copy, by copying their main.go to their
+// handler
+type copyBuilder struct{}
+
+func (copyBuilder) Detect(f *build.Function) bool {
+	return f.Metadata["BuildMethod"] == "copy"
+}
+
+func (copyBuilder) Config(f *build.Function) string {
+	return ""
+}
+
+func (copyBuilder) Build(ctx context.Context, f *build.Function, artifact string, log io.Writer) error {
+	data, err := ioutil.ReadFile(filepath.Join(f.CodeDir, "main.go"))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(artifact, f.Handler), data, 0755)
+}
+
+func init() {
+	build.RegisterBuilder("copy", copyBuilder{})
+}
+
+var _ = Describe("Building functions", func() {
+
+	var dir string
+	var template *loader.Template
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "sam-build")
+		os.MkdirAll(filepath.Join(dir, "hello"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "hello", "go.mod"), []byte("module hello\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "hello", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "code.zip"), []byte{}, 0644)
+
+		var err error
+		template, err = loader.Parse([]byte(`
+Resources:
+  Hello:
+    Type: AWS::Serverless::Function
+    Metadata:
+      Owner: me
+    Properties:
+      Runtime: go1.x
+      Handler: hello
+      CodeUri: hello
+  Copied:
+    Type: AWS::Serverless::Function
+    Metadata:
+      BuildMethod: copy
+    Properties:
+      Runtime: provided
+      Handler: hello
+      CodeUri: hello
+  Zipped:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: go1.x
+      Handler: hello
+      CodeUri: code.zip
+  Remote:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: go1.x
+      Handler: hello
+      CodeUri:
+        Bucket: bucket
+        Key: code.zip
+`), loader.Options{})
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("only builds functions whose code is a local directory", func() {
+		functions := template.GetAllAWSServerlessFunctionResources()
+
+		f := newBuildFunction(template, "Hello", functions["Hello"], dir)
+		Expect(f).To(Equal(&build.Function{
+			LogicalID: "Hello",
+			Runtime:   "go1.x",
+			Handler:   "hello",
+			CodeDir:   filepath.Join(dir, "hello"),
+			Metadata:  map[string]interface{}{"Owner": "me"},
+		}))
+
+		Expect(newBuildFunction(template, "Zipped", functions["Zipped"], dir)).To(BeNil())
+		Expect(newBuildFunction(template, "Remote", functions["Remote"], dir)).To(BeNil())
+	})
+
+	It("runs functions from their artifacts", func() {
+		functions := template.GetAllAWSServerlessFunctionResources()
+		builder := &functionBuilder{
+			cache:     build.NewCache(filepath.Join(dir, "builds")),
+			log:       GinkgoWriter,
+			functions: map[string]*build.Function{"Copied": newBuildFunction(template, "Copied", functions["Copied"], dir)},
+		}
+
+		runt := &invoker.Runtime{LogicalID: "Copied", Cwd: dir, Function: functions["Copied"]}
+		invocation := *runt
+		release, err := builder.Setup(context.Background(), &invocation)
+		Expect(err).To(BeNil())
+		defer release()
+
+		Expect(invocation.Cwd).To(HavePrefix(filepath.Join(dir, "builds", "Copied")))
+		Expect(invocation.Function.CodeUri).To(BeNil())
+		Expect(filepath.Join(invocation.Cwd, "hello")).To(BeAnExistingFile())
+
+		// The runtime that every request's copy is made from is left as it was
+		Expect(runt.Cwd).To(Equal(dir))
+		Expect(runt.Function.CodeUri).NotTo(BeNil())
+	})
+
+	It("gives functions the environment their artifacts need, under their own", func() {
+		function := cloudformation.AWSServerlessFunction{
+			CodeUri:     &cloudformation.AWSServerlessFunction_CodeUri{String: &dir},
+			Environment: &cloudformation.AWSServerlessFunction_FunctionEnvironment{Variables: map[string]string{"BUNDLE_PATH": "vendor"}},
+		}
+		variables := function.Environment.Variables
+
+		runFromArtifact(&function, &build.Result{Env: map[string]string{"BUNDLE_PATH": "/var/task/vendor/bundle", "BUNDLE_FROZEN": "true"}})
+		Expect(function.CodeUri).To(BeNil())
+		Expect(function.Environment.Variables).To(Equal(map[string]string{"BUNDLE_PATH": "vendor", "BUNDLE_FROZEN": "true"}))
+		Expect(variables).To(HaveLen(1))
+	})
+
+})
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/build"
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/codegangsta/cli"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"golang.org/x/net/context"
+)
+
+// cleanupItem is something SAM Local created on the machine, that 'sam local cleanup' removes
+type cleanupItem struct {
+	Kind   string
+	Name   string
+	remove func() error
+}
+
+func cleanup(c *cli.Context) {
+
+	dryRun := c.Bool("dry-run")
+
+	// Recordings are found through the cache directory, so they are listed before it
+	items := findLocalState()
+
+	cli, err := invoker.DockerClient()
+	if err == nil {
+		var dockerItems []*cleanupItem
+		dockerItems, err = findDockerState(context.Background(), cli, c.Bool("images"))
+		items = append(dockerItems, items...)
+	}
+	if err != nil {
+		log.Printf("Skipping Docker containers and images, as Docker isn't available: %s\n", err)
+	}
+
+	if len(items) == 0 {
+		fmt.Fprintf(os.Stderr, "Nothing to clean up\n")
+		return
+	}
+
+	failed := 0
+	for _, item := range items {
+
+		if dryRun {
+			fmt.Fprintf(os.Stdout, "Would remove %s %s\n", item.Kind, item.Name)
+			continue
+		}
+
+		if err := item.remove(); err != nil {
+			errMsg.Fprintf(os.Stderr, "Could not remove %s %s: %s\n", item.Kind, item.Name, err)
+			failed++
+			continue
+		}
+
+		fmt.Fprintf(os.Stdout, "Removed %s %s\n", item.Kind, item.Name)
+
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+
+}
+
+// findDockerState finds the containers created by SAM Local, and optionally the runtime
+// and builder images it pulled
+func findDockerState(ctx context.Context, cli *client.Client, images bool) ([]*cleanupItem, error) {
+
+	items := []*cleanupItem{}
+
+	filter := filters.NewArgs()
+	filter.Add("label", samLocalLabel)
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: filter})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, container := range containers {
+		id := container.ID
+		items = append(items, &cleanupItem{
+			Kind: "container",
+			Name: fmt.Sprintf("%s (%s, %s)", id[:12], container.Labels[samLocalLabel], container.Image),
+			remove: func() error {
+				return cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true})
+			},
+		})
+	}
+
+	if !images {
+		return items, nil
+	}
+
+	found := map[string]bool{}
+	for _, image := range cleanupImages() {
+
+		if found[image] {
+			continue
+		}
+		found[image] = true
+
+		imageFilter := filters.NewArgs()
+		imageFilter.Add("reference", image)
+		list, err := cli.ImageList(ctx, types.ImageListOptions{Filters: imageFilter})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(list) > 0 {
+			image := image
+			items = append(items, &cleanupItem{
+				Kind: "image",
+				Name: image,
+				remove: func() error {
+					_, err := cli.ImageRemove(ctx, image, types.ImageRemoveOptions{PruneChildren: true})
+					return err
+				},
+			})
+		}
+
+	}
+
+	return items, nil
+
+}
+
+// cleanupImages returns the images that SAM Local pulls: the runtime images that functions
+// are invoked in, and the lambci build images that they're built in, in order
+func cleanupImages() []string {
+
+	images := []string{}
+	for runtime, image := range invoker.RuntimeImages {
+		images = append(images, image, build.BuilderImage(runtime))
+	}
+	sort.Strings(images)
+	return images
+
+}
+
+// findLocalState finds the files SAM Local created: decompressed ZIP/JAR CodeUris,
+// recorded requests and the cache directory
+func findLocalState() []*cleanupItem {
+
+	items := []*cleanupItem{}
+
+	dirs, _ := filepath.Glob(filepath.Join(invoker.TempDir(), invoker.DecompressedArchivePrefix+"*"))
+	for _, dir := range dirs {
+		// Decompressed archives are named with a timestamp
+		if _, err := strconv.ParseInt(strings.TrimPrefix(filepath.Base(dir), invoker.DecompressedArchivePrefix), 10, 64); err != nil {
+			continue
+		}
+		items = append(items, removeAllItem("decompressed archive", dir))
+	}
+
+	for _, dir := range getRecordingDirs() {
+
+		_, files, err := loadRecordings(dir)
+		if err != nil || len(files) == 0 {
+			continue
+		}
+
+		dir, files := dir, files
+		items = append(items, &cleanupItem{
+			Kind: "recordings",
+			Name: fmt.Sprintf("%s (%d requests)", dir, len(files)),
+			remove: func() error {
+				for _, file := range files {
+					if err := os.Remove(file); err != nil {
+						return err
+					}
+				}
+				// Only remove the directory if nothing else was saved in it
+				if remaining, err := ioutil.ReadDir(dir); err == nil && len(remaining) == 0 {
+					return os.Remove(dir)
+				}
+				return nil
+			},
+		})
+
+	}
+
+	if _, err := os.Stat(getCacheDir()); err == nil {
+		items = append(items, removeAllItem("cache", getCacheDir()))
+	}
+
+	return items
+
+}
+
+// removeAllItem is a cleanupItem for a directory
+func removeAllItem(kind string, dir string) *cleanupItem {
+	return &cleanupItem{
+		Kind: kind,
+		Name: dir,
+		remove: func() error {
+			return os.RemoveAll(dir)
+		},
+	}
+}
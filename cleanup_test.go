@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cleanup", func() {
+
+	var tmp, cache, recordings string
+
+	BeforeEach(func() {
+		root, _ := ioutil.TempDir("", "cleanup")
+		tmp = filepath.Join(root, "tmp")
+		cache = filepath.Join(root, "cache")
+		recordings = filepath.Join(root, "recordings")
+		os.MkdirAll(tmp, 0755)
+
+		os.Setenv("TMPDIR", tmp)
+		os.Setenv("XDG_CACHE_HOME", cache)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(filepath.Dir(tmp))
+		os.Unsetenv("TMPDIR")
+		os.Unsetenv("XDG_CACHE_HOME")
+	})
+
+	It("finds and removes decompressed archives, recordings and the cache", func() {
+		os.MkdirAll(filepath.Join(tmp, "aws-sam-local-1508000000000000000"), 0755)
+		os.MkdirAll(filepath.Join(tmp, "aws-sam-local-something-else"), 0755)
+
+		rec, err := newRecorder(recordings)
+		Expect(err).To(BeNil())
+		Expect(rec.save(&recording{Function: "HelloWorld"})).To(BeNil())
+
+		items := findLocalState()
+		kinds := []string{}
+		for _, item := range items {
+			kinds = append(kinds, item.Kind)
+			Expect(item.remove()).To(BeNil())
+		}
+		Expect(kinds).To(Equal([]string{"decompressed archive", "recordings", "cache"}))
+
+		_, err = os.Stat(filepath.Join(tmp, "aws-sam-local-1508000000000000000"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		_, err = os.Stat(filepath.Join(tmp, "aws-sam-local-something-else"))
+		Expect(err).To(BeNil())
+		_, err = os.Stat(recordings)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		_, err = os.Stat(getCacheDir())
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("keeps other files in recording directories", func() {
+		rec, _ := newRecorder(recordings)
+		rec.save(&recording{Function: "HelloWorld"})
+		ioutil.WriteFile(filepath.Join(recordings, "notes.txt"), []byte("keep me"), 0644)
+
+		for _, item := range findLocalState() {
+			item.remove()
+		}
+
+		files, _ := ioutil.ReadDir(recordings)
+		Expect(files).To(HaveLen(1))
+	})
+
+	It("cleans up the builder images along with the runtime images", func() {
+		images := cleanupImages()
+		Expect(images).To(ContainElement(invoker.RuntimeImages["nodejs8.10"]))
+		Expect(images).To(ContainElement("lambci/lambda:build-nodejs8.10"))
+	})
+
+})
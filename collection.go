@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/codegangsta/cli"
+)
+
+// postmanSchema is the version of the Postman collection format that's generated
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// examplePathParameter is the value example requests use for path parameters
+const examplePathParameter = "example"
+
+func exportRequests(c *cli.Context) {
+
+	filename := getTemplateFilename(c.String("template"))
+	template := openTemplate(c, filename, nil)
+
+	format := c.String("format")
+	if format != "postman" && format != "curl" {
+		fmt.Fprintf(os.Stderr, "ERROR: Invalid --format '%s' (must be one of postman or curl)\n", format)
+		os.Exit(1)
+	}
+
+	listeners, err := parseListeners(c.StringSlice("host"), c.StringSlice("port"))
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	apiListeners, err := parseAPIBindings(c.StringSlice("api-listener"), listeners)
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	endpoints, err := getEndpoints(template, listeners, apiListeners, c.Bool("prefix-routing"))
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	var data []byte
+	mode := os.FileMode(0644)
+	if format == "curl" {
+		data = []byte(getCurlScript(endpoints, getAPITitle(template, filename)))
+		mode = 0755
+	} else {
+		data, _ = json.MarshalIndent(getPostmanCollection(endpoints, getAPITitle(template, filename)), "", "    ")
+		data = append(data, '\n')
+	}
+
+	if output := c.String("output"); output != "" {
+		if err := ioutil.WriteFile(output, data, mode); err != nil {
+			errMsg.Printf("Failed to write the requests: %s\n\n", err)
+			os.Exit(1)
+		}
+		successMsg.Fprintf(os.Stderr, "Wrote %d example requests to %s\n", len(endpoints), output)
+		return
+	}
+
+	os.Stdout.Write(data)
+
+}
+
+// exampleRequest is an example request for one endpoint
+type exampleRequest struct {
+	Name   string
+	Method string
+
+	// BaseURL is the variable that holds the URL of the endpoint's listener, and Path the
+	// path after it, with example values for its parameters
+	BaseURL string
+	Path    string
+
+	Parameters []string
+	Body       string
+}
+
+// getExampleRequests creates an example request for every endpoint. Requests that
+// usually have a body send the body of the sample Api event from 'sam local generate-event
+// api'. Routes for ANY method are requested with GET.
+func getExampleRequests(endpoints []*endpoint) ([]*exampleRequest, map[string]string) {
+
+	variables := baseURLVariables(endpoints)
+	body := sampleAPIEventBody()
+
+	requests := []*exampleRequest{}
+	for _, e := range endpoints {
+
+		request := &exampleRequest{
+			Name:    e.Method + " " + e.Path,
+			Method:  e.Method,
+			BaseURL: variables[strings.TrimSuffix(e.URL, e.Path)],
+			Path:    e.Path,
+		}
+
+		if e.Function != "" {
+			request.Name += " (" + e.Function + ")"
+		}
+
+		if request.Method == "ANY" {
+			request.Method = "GET"
+		}
+
+		for _, match := range pathParameter.FindAllStringSubmatch(e.Path, -1) {
+			request.Parameters = append(request.Parameters, match[1])
+		}
+
+		if request.Method == "POST" || request.Method == "PUT" || request.Method == "PATCH" {
+			request.Body = body
+		}
+
+		requests = append(requests, request)
+
+	}
+
+	urls := map[string]string{}
+	for url, variable := range variables {
+		urls[variable] = url
+	}
+
+	return requests, urls
+
+}
+
+// baseURLVariables names the variable holding the URL of each listener: baseUrl for the
+// first, then baseUrl2, baseUrl3 and so on
+func baseURLVariables(endpoints []*endpoint) map[string]string {
+
+	urls := []string{}
+	for _, e := range endpoints {
+		url := strings.TrimSuffix(e.URL, e.Path)
+		if !containsString(urls, url) {
+			urls = append(urls, url)
+		}
+	}
+	sort.Strings(urls)
+
+	variables := map[string]string{}
+	for i, url := range urls {
+		variables[url] = "baseUrl"
+		if i > 0 {
+			variables[url] = fmt.Sprintf("baseUrl%d", i+1)
+		}
+	}
+
+	return variables
+
+}
+
+// getPostmanCollection creates a Postman collection with an example request for every
+// endpoint. The listeners' URLs are collection variables, so they can be changed in one place.
+func getPostmanCollection(endpoints []*endpoint, title string) map[string]interface{} {
+
+	requests, urls := getExampleRequests(endpoints)
+
+	variables := []interface{}{}
+	for _, name := range sortedKeys(urls) {
+		variables = append(variables, map[string]interface{}{"key": name, "value": urls[name]})
+	}
+
+	items := []interface{}{}
+	for _, r := range requests {
+
+		// Postman writes path parameters as :name
+		path := pathParameter.ReplaceAllString(r.Path, ":$1")
+
+		url := map[string]interface{}{
+			"raw":  "{{" + r.BaseURL + "}}" + path,
+			"host": []string{"{{" + r.BaseURL + "}}"},
+			"path": strings.Split(strings.TrimPrefix(path, "/"), "/"),
+		}
+
+		if len(r.Parameters) > 0 {
+			parameters := []interface{}{}
+			for _, name := range r.Parameters {
+				parameters = append(parameters, map[string]interface{}{"key": name, "value": examplePathParameter})
+			}
+			url["variable"] = parameters
+		}
+
+		request := map[string]interface{}{
+			"method": r.Method,
+			"header": []interface{}{},
+			"url":    url,
+		}
+
+		if r.Body != "" {
+			request["header"] = []interface{}{
+				map[string]interface{}{"key": "Content-Type", "value": "application/json"},
+			}
+			request["body"] = map[string]interface{}{"mode": "raw", "raw": r.Body}
+		}
+
+		items = append(items, map[string]interface{}{
+			"name":    r.Name,
+			"request": request,
+		})
+
+	}
+
+	return map[string]interface{}{
+		"info": map[string]interface{}{
+			"name":   title,
+			"schema": postmanSchema,
+		},
+		"variable": variables,
+		"item":     items,
+	}
+
+}
+
+// getCurlScript creates a shell script with a curl command for every endpoint. The
+// listeners' URLs are variables that can be overridden from the environment.
+func getCurlScript(endpoints []*endpoint, title string) string {
+
+	requests, urls := getExampleRequests(endpoints)
+
+	script := &bytes.Buffer{}
+	fmt.Fprintf(script, "#!/bin/sh\n")
+	fmt.Fprintf(script, "# Example requests for %s, served by 'sam local start-api'\n\n", title)
+
+	for _, name := range sortedKeys(urls) {
+		fmt.Fprintf(script, "%s=${%s:-%s}\n", shellVariable(name), shellVariable(name), urls[name])
+	}
+
+	for _, r := range requests {
+
+		path := pathParameter.ReplaceAllString(r.Path, examplePathParameter)
+
+		fmt.Fprintf(script, "\n# %s\n", r.Name)
+		fmt.Fprintf(script, "curl -i -X %s \"$%s%s\"", r.Method, shellVariable(r.BaseURL), path)
+		if r.Body != "" {
+			fmt.Fprintf(script, " -H 'Content-Type: application/json' -d %s", shellQuote(r.Body))
+		}
+		fmt.Fprintf(script, "\n")
+
+	}
+
+	return script.String()
+
+}
+
+// shellVariable converts a variable name to the shell's style, e.g. baseUrl2 to BASE_URL2
+func shellVariable(name string) string {
+	return strings.ToUpper(strings.Replace(name, "Url", "_Url", 1))
+}
+
+// shellQuote quotes a value in single quotes for the shell
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exporting example requests", func() {
+
+	endpoints := []*endpoint{
+		{Method: "POST", Path: "/orders", URL: "http://127.0.0.1:3000/orders", Function: "Orders"},
+		{Method: "ANY", Path: "/users/{id}/{proxy+}", URL: "http://127.0.0.1:3000/users/{id}/{proxy+}", Function: "Users"},
+		{Method: "GET", Path: "/admin", URL: "http://127.0.0.1:3001/admin"},
+	}
+
+	It("uses the body of the sample Api event", func() {
+		Expect(sampleAPIEventBody()).To(Equal(`{ "test": "body"}`))
+	})
+
+	It("creates a request for every endpoint", func() {
+		requests, urls := getExampleRequests(endpoints)
+		Expect(urls).To(Equal(map[string]string{
+			"baseUrl":  "http://127.0.0.1:3000",
+			"baseUrl2": "http://127.0.0.1:3001",
+		}))
+
+		Expect(requests).To(HaveLen(3))
+		Expect(*requests[0]).To(Equal(exampleRequest{
+			Name:    "POST /orders (Orders)",
+			Method:  "POST",
+			BaseURL: "baseUrl",
+			Path:    "/orders",
+			Body:    `{ "test": "body"}`,
+		}))
+		Expect(*requests[1]).To(Equal(exampleRequest{
+			Name:       "ANY /users/{id}/{proxy+} (Users)",
+			Method:     "GET",
+			BaseURL:    "baseUrl",
+			Path:       "/users/{id}/{proxy+}",
+			Parameters: []string{"id", "proxy"},
+		}))
+		Expect(requests[2].BaseURL).To(Equal("baseUrl2"))
+	})
+
+	It("writes a Postman collection", func() {
+		collection := getPostmanCollection(endpoints, "Shop")
+		Expect(getMap(collection["info"])["name"]).To(Equal("Shop"))
+		Expect(collection["variable"]).To(Equal([]interface{}{
+			map[string]interface{}{"key": "baseUrl", "value": "http://127.0.0.1:3000"},
+			map[string]interface{}{"key": "baseUrl2", "value": "http://127.0.0.1:3001"},
+		}))
+
+		items := collection["item"].([]interface{})
+		Expect(items).To(HaveLen(3))
+
+		request := getMap(getMap(items[1])["request"])
+		url := getMap(request["url"])
+		Expect(url["raw"]).To(Equal("{{baseUrl}}/users/:id/:proxy"))
+		Expect(url["path"]).To(Equal([]string{"users", ":id", ":proxy"}))
+		Expect(url["variable"]).To(HaveLen(2))
+		Expect(request).NotTo(HaveKey("body"))
+
+		request = getMap(getMap(items[0])["request"])
+		Expect(getMap(request["body"])["raw"]).To(Equal(`{ "test": "body"}`))
+	})
+
+	It("writes a curl script", func() {
+		script := getCurlScript(endpoints, "Shop")
+		Expect(script).To(HavePrefix("#!/bin/sh\n"))
+		Expect(script).To(ContainSubstring("BASE_URL=${BASE_URL:-http://127.0.0.1:3000}\n"))
+		Expect(script).To(ContainSubstring("BASE_URL2=${BASE_URL2:-http://127.0.0.1:3001}\n"))
+		Expect(script).To(ContainSubstring(`curl -i -X POST "$BASE_URL/orders" -H 'Content-Type: application/json' -d '{ "test": "body"}'`))
+		Expect(script).To(ContainSubstring(`curl -i -X GET "$BASE_URL/users/example/example"`))
+		Expect(script).To(ContainSubstring(`curl -i -X GET "$BASE_URL2/admin"`))
+	})
+
+	It("quotes values for the shell", func() {
+		Expect(shellQuote(`it's`)).To(Equal(`'it'\''s'`))
+	})
+
+})
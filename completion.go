@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/codegangsta/cli"
+)
+
+// completionScripts are the completion scripts for each supported shell. They all ask
+// sam itself for the candidates (with --generate-bash-completion), so completions always
+// match the installed version and the template in the current directory.
+var completionScripts = map[string]string{
+
+	"bash": `# sam bash completion. Add to ~/.bashrc:
+#   source <(sam completion bash)
+_sam_completion() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local candidates
+    candidates=$(sam "${COMP_WORDS[@]:1:COMP_CWORD-1}" --generate-bash-completion 2>/dev/null)
+    COMPREPLY=($(compgen -W "${candidates}" -- "${cur}"))
+}
+complete -o default -F _sam_completion sam
+`,
+
+	"zsh": `#compdef sam
+# sam zsh completion. Add to ~/.zshrc:
+#   source <(sam completion zsh)
+_sam() {
+    local -a candidates
+    candidates=(${(f)"$(sam ${words[2,CURRENT-1]} --generate-bash-completion 2>/dev/null)"})
+    if (( ${#candidates} )); then
+        compadd -a candidates
+    fi
+    _files
+}
+compdef _sam sam
+`,
+
+	"fish": `# sam fish completion. Save to ~/.config/fish/completions/sam.fish:
+#   sam completion fish > ~/.config/fish/completions/sam.fish
+function __sam_complete
+    set -l words (commandline -opc)
+    set -e words[1]
+    sam $words --generate-bash-completion 2>/dev/null
+end
+complete -c sam -a '(__sam_complete)'
+`,
+}
+
+func completion(c *cli.Context) {
+
+	shell := c.Args().First()
+	script, found := completionScripts[shell]
+	if !found {
+		fmt.Fprintf(os.Stderr, "ERROR: Unsupported shell '%s' (must be one of %s)\n", shell, strings.Join(completionShells(), ", "))
+		os.Exit(1)
+	}
+
+	fmt.Fprint(os.Stdout, script)
+
+}
+
+// completeShells completes the shells 'sam completion' supports
+func completeShells(c *cli.Context) {
+	if c.NArg() == 0 {
+		for _, shell := range completionShells() {
+			fmt.Fprintln(c.App.Writer, shell)
+		}
+	}
+}
+
+func completionShells() []string {
+	shells := []string{}
+	for shell := range completionScripts {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+	return shells
+}
+
+// completeFunctionNames completes the logical ID of a function, read from the template
+func completeFunctionNames(c *cli.Context) {
+	if c.NArg() == 0 {
+		for _, name := range templateFunctionNames(getTemplateFilename(c.String("template"))) {
+			fmt.Fprintln(c.App.Writer, name)
+		}
+	}
+}
+
+// completeFlags returns a completion function that lists the given flags
+func completeFlags(flags []cli.Flag) cli.BashCompleteFunc {
+	return func(c *cli.Context) {
+		for _, name := range flagNames(flags) {
+			fmt.Fprintln(c.App.Writer, name)
+		}
+	}
+}
+
+// addFlagCompletion makes every command without subcommands complete its flags,
+// after any arguments its own completion function lists
+func addFlagCompletion(commands []cli.Command) {
+	for i := range commands {
+
+		if len(commands[i].Subcommands) > 0 {
+			addFlagCompletion(commands[i].Subcommands)
+			continue
+		}
+
+		complete := commands[i].BashComplete
+		flags := completeFlags(commands[i].Flags)
+		commands[i].BashComplete = func(c *cli.Context) {
+			if complete != nil {
+				complete(c)
+			}
+			flags(c)
+		}
+
+	}
+}
+
+// flagNames returns the names of the flags as they're typed on the command line,
+// e.g. --template and -t
+func flagNames(flags []cli.Flag) []string {
+
+	names := []string{}
+	for _, flag := range flags {
+		for _, name := range strings.Split(flag.GetName(), ",") {
+			name = strings.TrimSpace(name)
+			if len(name) == 1 {
+				names = append(names, "-"+name)
+			} else if name != "" {
+				names = append(names, "--"+name)
+			}
+		}
+	}
+
+	return names
+
+}
+
+// templateFunctionNames returns the logical IDs of the functions in a template,
+// or nothing if the template can't be read
+func templateFunctionNames(filename string) []string {
+
+	names := []string{}
+
+	template, err := readRawTemplate(filename)
+	if err != nil {
+		return names
+	}
+
+	for name, resource := range getMap(template["Resources"]) {
+		if getMap(resource)["Type"] == "AWS::Serverless::Function" {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+
+}
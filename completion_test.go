@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/codegangsta/cli"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Completion", func() {
+
+	It("has a script for every shell", func() {
+		Expect(completionShells()).To(Equal([]string{"bash", "fish", "zsh"}))
+		for _, script := range completionScripts {
+			Expect(script).To(ContainSubstring("--generate-bash-completion"))
+		}
+	})
+
+	It("lists flags as they're typed", func() {
+		Expect(flagNames([]cli.Flag{
+			cli.StringFlag{Name: "template, t"},
+			cli.BoolFlag{Name: "dry-run"},
+		})).To(Equal([]string{"--template", "-t", "--dry-run"}))
+	})
+
+	It("completes flags of commands without subcommands", func() {
+		commands := []cli.Command{
+			{Name: "local", Subcommands: []cli.Command{{Name: "invoke", Flags: []cli.Flag{cli.BoolFlag{Name: "dry-run"}}}}},
+			{Name: "validate"},
+		}
+		addFlagCompletion(commands)
+		Expect(commands[0].BashComplete).To(BeNil())
+		Expect(commands[0].Subcommands[0].BashComplete).ToNot(BeNil())
+		Expect(commands[1].BashComplete).ToNot(BeNil())
+	})
+
+	It("lists the functions in the template", func() {
+		dir, _ := ioutil.TempDir("", "completion")
+		defer os.RemoveAll(dir)
+
+		filename := filepath.Join(dir, "template.yaml")
+		ioutil.WriteFile(filename, []byte(`
+Resources:
+  World:
+    Type: AWS::Serverless::Function
+    Properties:
+      Environment:
+        Variables:
+          TABLE: !Ref Table
+  Hello:
+    Type: AWS::Serverless::Function
+  Table:
+    Type: AWS::Serverless::SimpleTable
+`), 0644)
+
+		Expect(templateFunctionNames(filename)).To(Equal([]string{"Hello", "World"}))
+		Expect(templateFunctionNames(filepath.Join(dir, "missing.yaml"))).To(BeEmpty())
+	})
+
+})
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// responseContract holds the responses that the OpenAPI (or Swagger) definitions of the
+// template's APIs declare, to check the local API's responses against with
+// --validate-responses. Problems are logged, or with fail, the response is replaced with
+// a 502 error.
+type responseContract struct {
+	operations []*contractOperation
+	fail       bool
+
+	// apis are the logical IDs of the APIs that own each route, keyed by method and path
+	// template (e.g. GET /orders/{id}). When several APIs declare a route, the first of them
+	// (in order of their logical IDs) owns it.
+	apis map[string]string
+}
+
+// contractOperation is an operation of an API definition
+type contractOperation struct {
+	api    string
+	method string
+	route  string
+
+	// responses are the operation's responses, by status code, status code range (e.g. 2XX)
+	// or default
+	responses map[string]interface{}
+
+	// schema resolves the $refs of the definition, and openAPI3 is whether it's an OpenAPI 3
+	// definition, rather than a Swagger 2 one
+	schema   *eventSchema
+	openAPI3 bool
+}
+
+// contractMethods are the operations of a path item, by their key
+var contractMethods = map[string]string{
+	"get": "GET", "put": "PUT", "post": "POST", "delete": "DELETE", "options": "OPTIONS",
+	"head": "HEAD", "patch": "PATCH", "x-amazon-apigateway-any-method": "ANY",
+}
+
+// newResponseContract returns the responseContract of the template's API definitions for a
+// --validate-responses mode (warn or fail), or nil if the mode is "". APIs without a
+// definition (such as the implicit API) aren't checked.
+func newResponseContract(template *cloudformation.Template, mode string) (*responseContract, error) {
+
+	if mode == "" {
+		return nil, nil
+	}
+	if mode != "warn" && mode != "fail" {
+		return nil, fmt.Errorf("invalid --validate-responses '%s' (must be one of warn or fail)", mode)
+	}
+
+	contract := &responseContract{fail: mode == "fail", apis: map[string]string{}}
+
+	apis := template.GetAllAWSServerlessApiResources()
+	names := []string{}
+	for name := range apis {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		api := apis[name]
+		if api.DefinitionBody == nil && api.DefinitionUri == nil {
+			continue
+		}
+
+		data, err := (&router.AWSServerlessApi{AWSServerlessApi: &api}).Swagger()
+		if err != nil {
+			return nil, fmt.Errorf("could not read the definition of %s: %s", name, err)
+		}
+
+		var definition map[string]interface{}
+		if err := json.Unmarshal(data, &definition); err != nil {
+			return nil, fmt.Errorf("invalid definition of %s: %s", name, err)
+		}
+		contract.add(name, definition)
+	}
+
+	return contract, nil
+
+}
+
+// add adds the operations of an API's definition
+func (c *responseContract) add(api string, definition map[string]interface{}) {
+
+	_, openAPI3 := definition["openapi"]
+	schema := &eventSchema{root: openAPINullable(definition)}
+
+	paths, _ := definition["paths"].(map[string]interface{})
+	for route, item := range paths {
+		item, _ := item.(map[string]interface{})
+		for key, operation := range item {
+			method, ok := contractMethods[strings.ToLower(key)]
+			if !ok {
+				continue
+			}
+			operation, _ := operation.(map[string]interface{})
+			responses, _ := operation["responses"].(map[string]interface{})
+			if len(responses) == 0 {
+				continue
+			}
+			if _, owned := c.apis[method+" "+route]; !owned {
+				c.apis[method+" "+route] = api
+			}
+			c.operations = append(c.operations, &contractOperation{
+				api:       api,
+				method:    method,
+				route:     route,
+				responses: responses,
+				schema:    schema,
+				openAPI3:  openAPI3,
+			})
+		}
+	}
+
+}
+
+// openAPINullable rewrites the 'nullable: true' of OpenAPI 3.0 schemas as a "null" type,
+// which the JSON Schema validator understands
+func openAPINullable(value interface{}) interface{} {
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			value[key] = openAPINullable(child)
+		}
+		if nullable, _ := value["nullable"].(bool); nullable {
+			if t, ok := value["type"].(string); ok {
+				value["type"] = []interface{}{t, "null"}
+			}
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = openAPINullable(child)
+		}
+	}
+
+	return value
+
+}
+
+// Validate checks a response to a request for a route (the path template of the mount
+// that served it, e.g. /orders/{id}) against the definition of its operation, and returns
+// every problem found. Responses on routes that no definition declares aren't checked, and
+// nor are bodies that aren't JSON.
+func (c *responseContract) Validate(method string, route string, status int, contentType string, body []byte) []string {
+
+	operation := c.operation(method, route)
+	if operation == nil {
+		return nil
+	}
+
+	response, declared := operation.response(status)
+	if !declared {
+		return []string{fmt.Sprintf("status %d isn't one of the declared responses (%s)", status, strings.Join(operation.statuses(), ", "))}
+	}
+	if ref, ok := response["$ref"].(string); ok {
+		resolved, err := operation.schema.resolve(ref)
+		if err != nil {
+			return []string{err.Error()}
+		}
+		response, _ = resolved.(map[string]interface{})
+	}
+
+	// Function headers are added to the default Content-Type, so only the first one counts
+	mediaType, _, _ := mime.ParseMediaType(strings.SplitN(contentType, ",", 2)[0])
+	schema := response["schema"]
+	if operation.openAPI3 {
+		content, _ := response["content"].(map[string]interface{})
+		if len(content) == 0 {
+			return nil
+		}
+		declaredType := contractMediaType(content, mediaType)
+		if declaredType == "" {
+			return []string{fmt.Sprintf("Content-Type '%s' isn't one of the declared ones (%s)", contentType, strings.Join(sortedMapKeys(content), ", "))}
+		}
+		mediaTypeObject, _ := content[declaredType].(map[string]interface{})
+		schema = mediaTypeObject["schema"]
+	}
+
+	if schema == nil || (mediaType != "" && !strings.Contains(mediaType, "json")) {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{fmt.Sprintf("$: invalid JSON: %s", err)}
+	}
+	return operation.schema.check(value, schema)
+
+}
+
+// operation returns the operation of a route in the definition of the API that owns it, or
+// nil. Only the owner's operations are matched, so routes that overlap those of other APIs
+// (such as /orders/latest and /orders/{id}) are checked against the right definition.
+func (c *responseContract) operation(method string, route string) *contractOperation {
+
+	api, owned := c.apis[method+" "+route]
+	if !owned {
+		if api, owned = c.apis["ANY "+route]; !owned {
+			return nil
+		}
+	}
+
+	for _, operation := range c.operations {
+		if operation.api == api && operation.route == route && (operation.method == method || operation.method == "ANY") {
+			return operation
+		}
+	}
+	return nil
+
+}
+
+// response returns the declared response for a status code, from the most specific of its
+// code, its range (e.g. 2XX) and the default response
+func (o *contractOperation) response(status int) (map[string]interface{}, bool) {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if response, ok := o.responses[key].(map[string]interface{}); ok {
+			return response, true
+		}
+	}
+	return nil, false
+}
+
+// statuses returns the status codes of the declared responses
+func (o *contractOperation) statuses() []string {
+	statuses := []string{}
+	for status := range o.responses {
+		if !strings.HasPrefix(status, "x-") {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Strings(statuses)
+	return statuses
+}
+
+// contractMediaType returns the declared media type that matches a response's media type:
+// the same one, one with a wildcard, or the only one if the response has none
+func contractMediaType(content map[string]interface{}, mediaType string) string {
+
+	if mediaType == "" && len(content) == 1 {
+		for declared := range content {
+			return declared
+		}
+	}
+
+	candidates := []string{mediaType, strings.SplitN(mediaType, "/", 2)[0] + "/*", "*/*"}
+	for _, candidate := range candidates {
+		for declared := range content {
+			if strings.EqualFold(declared, candidate) {
+				return declared
+			}
+		}
+	}
+	return ""
+
+}
+
+// sortedMapKeys returns the keys of a map, in order
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Wrap returns an event handler that checks the function's responses against the
+// definition. With fail, responses are buffered, so that ones that don't match can be
+// replaced with a 502 error.
+func (c *responseContract) Wrap(function string, handler router.EventHandlerFunc) router.EventHandlerFunc {
+
+	return func(w http.ResponseWriter, event *router.Event) {
+
+		if !c.fail {
+			capture := &responseCapture{ResponseWriter: w}
+			handler(capture, event)
+			c.report(function, event, capture.status, w.Header().Get("Content-Type"), capture.body.Bytes())
+			return
+		}
+
+		buffered := httptest.NewRecorder()
+		handler(buffered, event)
+
+		if errs := c.report(function, event, buffered.Code, buffered.Header().Get("Content-Type"), buffered.Body.Bytes()); len(errs) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": "The response doesn't match the OpenAPI definition",
+				"errors":  errs,
+			})
+			return
+		}
+
+		for name, values := range buffered.Header() {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(buffered.Code)
+		io.Copy(w, buffered.Body)
+
+	}
+
+}
+
+// report validates a response, and logs the problems found
+func (c *responseContract) report(function string, event *router.Event, status int, contentType string, body []byte) []string {
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	errs := c.Validate(event.HTTPMethod, event.Resource, status, contentType, body)
+	if len(errs) > 0 {
+		warnMsg.Printf("The response of %s to %s %s doesn't match the OpenAPI definition:\n", function, event.HTTPMethod, event.Path)
+		for _, err := range errs {
+			warnMsg.Printf("  %s\n", err)
+		}
+	}
+	return errs
+
+}
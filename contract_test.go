@@ -0,0 +1,249 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/awslabs/goformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validating responses", func() {
+
+	template, _ := goformation.ParseJSON([]byte(`{
+		"Resources": {
+			"Shop": {
+				"Type": "AWS::Serverless::Api",
+				"Properties": {
+					"StageName": "prod",
+					"DefinitionBody": {
+						"openapi": "3.0.1",
+						"paths": {
+							"/orders/{id}": {
+								"get": {
+									"responses": {
+										"200": {
+											"content": {
+												"application/json": { "schema": { "$ref": "#/components/schemas/Order" } }
+											}
+										},
+										"404": { "$ref": "#/components/responses/NotFound" }
+									}
+								}
+							},
+							"/orders/latest": {
+								"get": {
+									"responses": {
+										"2XX": {
+											"content": {
+												"text/plain": { "schema": { "type": "string" } }
+											}
+										}
+									}
+								}
+							}
+						},
+						"components": {
+							"schemas": {
+								"Order": {
+									"type": "object",
+									"required": ["id", "total"],
+									"properties": {
+										"id": { "type": "string" },
+										"total": { "type": "number" },
+										"note": { "type": "string", "nullable": true }
+									}
+								}
+							},
+							"responses": {
+								"NotFound": {
+									"content": {
+										"application/json": {
+											"schema": { "type": "object", "required": ["message"] }
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			},
+			"Legacy": {
+				"Type": "AWS::Serverless::Api",
+				"Properties": {
+					"StageName": "prod",
+					"DefinitionBody": {
+						"swagger": "2.0",
+						"paths": {
+							"/users": {
+								"x-amazon-apigateway-any-method": {
+									"responses": {
+										"default": { "schema": { "type": "array", "items": { "type": "string" } } }
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}`))
+
+	Context("with OpenAPI 3 definitions", func() {
+
+		contract, err := newResponseContract(template, "warn")
+
+		It("loads the definitions", func() {
+			Expect(err).To(BeNil())
+			Expect(contract.operations).To(HaveLen(3))
+		})
+
+		It("accepts responses that match their schema", func() {
+			Expect(contract.Validate("GET", "/orders/{id}", 200, "application/json", []byte(`{"id":"1","total":12,"note":null}`))).To(BeEmpty())
+		})
+
+		It("reports responses that don't match their schema", func() {
+			Expect(contract.Validate("GET", "/orders/{id}", 200, "application/json; charset=utf-8", []byte(`{"id":1}`))).To(ConsistOf(
+				"$: missing required property 'total'",
+				"$.id: expected string, got integer",
+			))
+		})
+
+		It("resolves the $refs of responses", func() {
+			Expect(contract.Validate("GET", "/orders/{id}", 404, "application/json", []byte(`{}`))).To(ConsistOf(
+				"$: missing required property 'message'",
+			))
+		})
+
+		It("reports statuses that aren't declared", func() {
+			Expect(contract.Validate("GET", "/orders/{id}", 500, "application/json", []byte(`{}`))).To(ConsistOf(
+				"status 500 isn't one of the declared responses (200, 404)",
+			))
+		})
+
+		It("reports content types that aren't declared", func() {
+			Expect(contract.Validate("GET", "/orders/{id}", 200, "text/html", []byte(`<p>1</p>`))).To(ConsistOf(
+				"Content-Type 'text/html' isn't one of the declared ones (application/json)",
+			))
+		})
+
+		It("matches status code ranges", func() {
+			Expect(contract.Validate("GET", "/orders/latest", 201, "text/plain", []byte(`hello`))).To(BeEmpty())
+		})
+
+		It("doesn't check requests that aren't declared", func() {
+			Expect(contract.Validate("POST", "/orders/{id}", 500, "", nil)).To(BeEmpty())
+			Expect(contract.Validate("GET", "/orders/{orderId}", 500, "", nil)).To(BeEmpty())
+		})
+
+	})
+
+	Context("with Swagger 2 definitions", func() {
+
+		contract, _ := newResponseContract(template, "warn")
+
+		It("checks the default response of any method", func() {
+			Expect(contract.Validate("DELETE", "/users", 200, "application/json", []byte(`["a","b"]`))).To(BeEmpty())
+			Expect(contract.Validate("PUT", "/users", 400, "application/json", []byte(`["a",1]`))).To(ConsistOf(
+				"$[1]: expected string, got integer",
+			))
+		})
+
+	})
+
+	It("only checks responses against the definition of the API that owns their route", func() {
+		template, _ := goformation.ParseJSON([]byte(`{
+			"Resources": {
+				"Admin": {
+					"Type": "AWS::Serverless::Api",
+					"Properties": {
+						"StageName": "prod",
+						"DefinitionBody": {
+							"openapi": "3.0.1",
+							"paths": {
+								"/orders/latest": {
+									"get": { "responses": { "200": { "content": { "text/plain": { "schema": { "type": "string" } } } } } }
+								}
+							}
+						}
+					}
+				},
+				"Shop": {
+					"Type": "AWS::Serverless::Api",
+					"Properties": {
+						"StageName": "prod",
+						"DefinitionBody": {
+							"openapi": "3.0.1",
+							"paths": {
+								"/orders/{id}": {
+									"get": { "responses": { "200": { "content": { "application/json": { "schema": { "type": "object" } } } } } }
+								}
+							}
+						}
+					}
+				}
+			}
+		}`))
+		contract, _ := newResponseContract(template, "warn")
+
+		Expect(contract.Validate("GET", "/orders/{id}", 200, "application/json", []byte(`{"id":"latest"}`))).To(BeEmpty())
+		Expect(contract.Validate("GET", "/orders/latest", 200, "application/json", []byte(`{"id":"latest"}`))).To(ConsistOf(
+			"Content-Type 'application/json' isn't one of the declared ones (text/plain)",
+		))
+	})
+
+	It("isn't enabled without a mode, and rejects invalid ones", func() {
+		contract, err := newResponseContract(template, "")
+		Expect(contract).To(BeNil())
+		Expect(err).To(BeNil())
+
+		_, err = newResponseContract(template, "strict")
+		Expect(err).NotTo(BeNil())
+	})
+
+	Context("wrapping event handlers", func() {
+
+		handler := func(body string) router.EventHandlerFunc {
+			return func(w http.ResponseWriter, event *router.Event) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("X-Order", "1")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(body))
+			}
+		}
+		event := &router.Event{HTTPMethod: "GET", Path: "/orders/1", Resource: "/orders/{id}"}
+
+		It("passes matching responses through", func() {
+			contract, _ := newResponseContract(template, "fail")
+			w := httptest.NewRecorder()
+			contract.Wrap("Orders", handler(`{"id":"1","total":12}`))(w, event)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("X-Order")).To(Equal("1"))
+			Expect(w.Body.String()).To(Equal(`{"id":"1","total":12}`))
+		})
+
+		It("replaces responses that don't match with a 502 error with fail", func() {
+			contract, _ := newResponseContract(template, "fail")
+			w := httptest.NewRecorder()
+			contract.Wrap("Orders", handler(`{"id":"1"}`))(w, event)
+
+			Expect(w.Code).To(Equal(http.StatusBadGateway))
+			Expect(w.Body.String()).To(ContainSubstring("missing required property 'total'"))
+		})
+
+		It("only logs responses that don't match with warn", func() {
+			contract, _ := newResponseContract(template, "warn")
+			w := httptest.NewRecorder()
+			contract.Wrap("Orders", handler(`{"id":"1"}`))(w, event)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(Equal(`{"id":"1"}`))
+		})
+
+	})
+
+})
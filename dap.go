@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/codegangsta/cli"
+)
+
+// dapAttachTimeout is how long a function waits for an editor to connect to the
+// debug adapter and attach, before the developer is left to attach by hand
+const dapAttachTimeout = 60 * time.Second
+
+// debugAdapter is a minimal Debug Adapter Protocol server that editors connect to
+// once (e.g. with "debugServer" in a VS Code launch configuration). Whenever a function
+// starts in debug mode, it asks the connected editors to attach to it with a
+// startDebugging reverse request, so developers don't need to race to attach
+// before the handler runs.
+type debugAdapter struct {
+	listener net.Listener
+
+	mu        sync.Mutex
+	clients   []*dapClient
+	connected chan struct{}
+}
+
+// dapClient is an editor connected to the debug adapter
+type dapClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	mu                     sync.Mutex
+	seq                    int
+	pending                map[int]chan *dapMessage
+	supportsStartDebugging bool
+}
+
+// dapMessage is a Debug Adapter Protocol request, response or event
+type dapMessage struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command,omitempty"`
+	Event      string          `json:"event,omitempty"`
+	RequestSeq int             `json:"request_seq,omitempty"`
+	Success    *bool           `json:"success,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	Body       interface{}     `json:"body,omitempty"`
+}
+
+// startDebugAdapter starts the debug adapter if --debug-adapter-port is set. It's only
+// useful along with --debug-port, as otherwise there is nothing to attach to.
+func startDebugAdapter(c *cli.Context) *debugAdapter {
+
+	port := c.String("debug-adapter-port")
+	if port == "" || c.Bool("dry-run") {
+		return nil
+	}
+
+	if c.String("debug-port") == "" {
+		warnMsg.Fprintf(os.Stderr, "Ignoring --debug-adapter-port, as functions are only started in debug mode with --debug-port\n")
+		return nil
+	}
+
+	adapter, err := newDebugAdapter("127.0.0.1:" + port)
+	if err != nil {
+		log.Fatalf("Could not start the debug adapter: %s\n", err)
+	}
+
+	log.Printf("Debug adapter listening on %s: connect your editor to it to attach to functions automatically\n", adapter.Addr())
+	return adapter
+
+}
+
+// newDebugAdapter starts a debug adapter listening on the given address
+func newDebugAdapter(addr string) (*debugAdapter, error) {
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := &debugAdapter{
+		listener:  listener,
+		connected: make(chan struct{}),
+	}
+
+	go adapter.serve()
+	return adapter, nil
+
+}
+
+// Addr returns the address the debug adapter listens on
+func (a *debugAdapter) Addr() string {
+	return a.listener.Addr().String()
+}
+
+// Close stops the debug adapter, disconnecting every editor
+func (a *debugAdapter) Close() error {
+	a.mu.Lock()
+	for _, client := range a.clients {
+		client.conn.Close()
+	}
+	a.mu.Unlock()
+	return a.listener.Close()
+}
+
+func (a *debugAdapter) serve() {
+
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		client := &dapClient{
+			conn:    conn,
+			reader:  bufio.NewReader(conn),
+			pending: map[int]chan *dapMessage{},
+		}
+
+		go a.handle(client)
+	}
+
+}
+
+// handle answers the requests of an editor until it disconnects
+func (a *debugAdapter) handle(client *dapClient) {
+
+	defer a.remove(client)
+	defer client.conn.Close()
+
+	for {
+
+		msg, err := readDAPMessage(client.reader)
+		if err != nil {
+			return
+		}
+
+		if msg.Type == "response" {
+			client.resolve(msg)
+			continue
+		}
+
+		if msg.Type != "request" {
+			continue
+		}
+
+		switch msg.Command {
+		case "initialize":
+			args := struct {
+				SupportsStartDebuggingRequest bool `json:"supportsStartDebuggingRequest"`
+			}{}
+			json.Unmarshal(msg.Arguments, &args)
+
+			client.mu.Lock()
+			client.supportsStartDebugging = args.SupportsStartDebuggingRequest
+			client.mu.Unlock()
+
+			client.respond(msg, true, "", map[string]interface{}{"supportsConfigurationDoneRequest": true})
+			client.send(&dapMessage{Type: "event", Event: "initialized"})
+
+		case "attach", "launch":
+			client.respond(msg, true, "", nil)
+			client.output("Connected to SAM Local. Functions started with --debug-port will be attached to automatically.\n")
+			a.add(client)
+
+		case "threads":
+			client.respond(msg, true, "", map[string]interface{}{"threads": []interface{}{}})
+
+		case "configurationDone":
+			client.respond(msg, true, "", nil)
+
+		case "disconnect", "terminate":
+			client.respond(msg, true, "", nil)
+			return
+
+		default:
+			client.respond(msg, false, fmt.Sprintf("%s is not supported by SAM Local", msg.Command), nil)
+		}
+
+	}
+
+}
+
+// add registers an editor that is ready to be asked to attach to functions
+func (a *debugAdapter) add(client *dapClient) {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.clients = append(a.clients, client)
+	if len(a.clients) == 1 {
+		close(a.connected)
+	}
+
+}
+
+func (a *debugAdapter) remove(client *dapClient) {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i, c := range a.clients {
+		if c == client {
+			a.clients = append(a.clients[:i], a.clients[i+1:]...)
+			break
+		}
+	}
+
+	if len(a.clients) == 0 {
+		select {
+		case <-a.connected:
+			a.connected = make(chan struct{})
+		default:
+		}
+	}
+
+}
+
+// AttachDebugger implements invoker.Debugger. It asks the editors connected to the debug
+// adapter to attach to the function. The runtime waits for a debugger before running the
+// handler, so there is no rush.
+func (a *debugAdapter) AttachDebugger(r *invoker.Runtime) {
+
+	port, err := strconv.Atoi(r.DebugPort)
+	if err != nil {
+		return
+	}
+
+	code := r.Cwd
+	if r.DecompressedCwd != "" {
+		code = r.DecompressedCwd
+	}
+
+	target := &debugTarget{
+		Name:      debugConfigName(r.LogicalID),
+		Function:  r.LogicalID,
+		Runtime:   r.Name,
+		Port:      port,
+		LocalRoot: code,
+	}
+
+	log.Printf("Waiting for the debugger to attach to %s on port %d...\n", r.LogicalID, port)
+	if a.Attach(target, dapAttachTimeout) {
+		log.Printf("Debugger attached to %s\n", r.LogicalID)
+	} else {
+		log.Printf("No editor attached to %s through the debug adapter. Attach a debugger to port %d to continue.\n", r.LogicalID, port)
+	}
+
+}
+
+// Attach asks the connected editors to attach a debugger to a function that has just
+// started in debug mode, and waits until one of them has. If no editor is connected
+// yet, it waits for one for up to timeout. It returns false if no debugger was attached.
+func (a *debugAdapter) Attach(target *debugTarget, timeout time.Duration) bool {
+
+	a.mu.Lock()
+	connected := a.connected
+	a.mu.Unlock()
+
+	deadline := time.After(timeout)
+
+	select {
+	case <-connected:
+	case <-deadline:
+		return false
+	}
+
+	a.mu.Lock()
+	clients := append([]*dapClient{}, a.clients...)
+	a.mu.Unlock()
+
+	config := vscodeLaunchConfig(target)
+	attached := false
+
+	for _, client := range clients {
+
+		client.mu.Lock()
+		supported := client.supportsStartDebugging
+		client.mu.Unlock()
+
+		if !supported {
+			client.output(fmt.Sprintf("%s is waiting for a debugger on port %d\n", target.Function, target.Port))
+			continue
+		}
+
+		response := client.request("startDebugging", map[string]interface{}{
+			"request":       "attach",
+			"configuration": config,
+		})
+
+		select {
+		case msg, ok := <-response:
+			if ok && msg.Success != nil && *msg.Success {
+				attached = true
+			}
+		case <-deadline:
+		}
+
+	}
+
+	return attached
+
+}
+
+// request sends a reverse request to the editor, and returns a channel the response is sent to
+func (c *dapClient) request(command string, args interface{}) chan *dapMessage {
+
+	data, _ := json.Marshal(args)
+	response := make(chan *dapMessage, 1)
+
+	c.mu.Lock()
+	c.seq++
+	seq := c.seq
+	c.pending[seq] = response
+	c.mu.Unlock()
+
+	if err := c.write(&dapMessage{Seq: seq, Type: "request", Command: command, Arguments: data}); err != nil {
+		c.mu.Lock()
+		delete(c.pending, seq)
+		c.mu.Unlock()
+		close(response)
+	}
+
+	return response
+
+}
+
+// resolve passes the response to a reverse request to whoever is waiting for it
+func (c *dapClient) resolve(msg *dapMessage) {
+
+	c.mu.Lock()
+	response, found := c.pending[msg.RequestSeq]
+	delete(c.pending, msg.RequestSeq)
+	c.mu.Unlock()
+
+	if found {
+		response <- msg
+	}
+
+}
+
+func (c *dapClient) respond(request *dapMessage, success bool, message string, body interface{}) {
+	c.send(&dapMessage{
+		Type:       "response",
+		Command:    request.Command,
+		RequestSeq: request.Seq,
+		Success:    &success,
+		Message:    message,
+		Body:       body,
+	})
+}
+
+// output shows a message in the editor's debug console
+func (c *dapClient) output(text string) {
+	c.send(&dapMessage{Type: "event", Event: "output", Body: map[string]string{"category": "console", "output": text}})
+}
+
+// send numbers a message and writes it to the editor
+func (c *dapClient) send(msg *dapMessage) {
+	c.mu.Lock()
+	c.seq++
+	msg.Seq = c.seq
+	c.mu.Unlock()
+
+	if err := c.write(msg); err != nil {
+		log.Printf("Could not send %s to the debugger: %s\n", msg.Type, err)
+	}
+}
+
+func (c *dapClient) write(msg *dapMessage) error {
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err = fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+
+}
+
+// readDAPMessage reads a message framed with a Content-Length header
+func readDAPMessage(r *bufio.Reader) (*dapMessage, error) {
+
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %s", err)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	msg := &dapMessage{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Debug adapter", func() {
+
+	var adapter *debugAdapter
+
+	BeforeEach(func() {
+		var err error
+		adapter, err = newDebugAdapter("127.0.0.1:0")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		adapter.Close()
+	})
+
+	// connect connects an editor to the debug adapter, and waits until it's attached
+	connect := func(startDebugging bool) (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("tcp", adapter.Addr())
+		Expect(err).To(BeNil())
+		reader := bufio.NewReader(conn)
+
+		send := func(seq int, command string, args string) {
+			data := fmt.Sprintf(`{"seq":%d,"type":"request","command":"%s","arguments":%s}`, seq, command, args)
+			fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(data), data)
+		}
+
+		send(1, "initialize", fmt.Sprintf(`{"adapterID":"sam","supportsStartDebuggingRequest":%t}`, startDebugging))
+		msg, err := readDAPMessage(reader)
+		Expect(err).To(BeNil())
+		Expect(msg.Type).To(Equal("response"))
+		Expect(msg.Command).To(Equal("initialize"))
+		Expect(*msg.Success).To(BeTrue())
+
+		msg, _ = readDAPMessage(reader)
+		Expect(msg.Event).To(Equal("initialized"))
+
+		send(2, "attach", `{}`)
+		msg, _ = readDAPMessage(reader)
+		Expect(msg.Command).To(Equal("attach"))
+		msg, _ = readDAPMessage(reader)
+		Expect(msg.Event).To(Equal("output"))
+
+		return conn, reader
+	}
+
+	target := &debugTarget{Name: "Attach to Hello (SAM Local)", Function: "Hello", Runtime: "nodejs8.10", Port: 5858, LocalRoot: "/code"}
+
+	It("asks connected editors to attach to functions", func() {
+		conn, reader := connect(true)
+		defer conn.Close()
+
+		attached := make(chan bool)
+		go func() {
+			attached <- adapter.Attach(target, 5*time.Second)
+		}()
+
+		msg, err := readDAPMessage(reader)
+		Expect(err).To(BeNil())
+		Expect(msg.Type).To(Equal("request"))
+		Expect(msg.Command).To(Equal("startDebugging"))
+
+		args := struct {
+			Request       string                 `json:"request"`
+			Configuration map[string]interface{} `json:"configuration"`
+		}{}
+		Expect(json.Unmarshal(msg.Arguments, &args)).To(BeNil())
+		Expect(args.Request).To(Equal("attach"))
+		Expect(args.Configuration["name"]).To(Equal("Attach to Hello (SAM Local)"))
+		Expect(args.Configuration["type"]).To(Equal("node"))
+		Expect(args.Configuration["port"]).To(Equal(float64(5858)))
+		Expect(args.Configuration["localRoot"]).To(Equal("/code"))
+
+		data := fmt.Sprintf(`{"seq":3,"type":"response","request_seq":%d,"command":"startDebugging","success":true}`, msg.Seq)
+		fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(data), data)
+
+		Eventually(attached).Should(Receive(BeTrue()))
+	})
+
+	It("tells editors without startDebugging where to attach", func() {
+		conn, reader := connect(false)
+		defer conn.Close()
+
+		Expect(adapter.Attach(target, time.Second)).To(BeFalse())
+
+		msg, _ := readDAPMessage(reader)
+		Expect(msg.Event).To(Equal("output"))
+		Expect(msg.Body).To(HaveKeyWithValue("output", "Hello is waiting for a debugger on port 5858\n"))
+	})
+
+	It("gives up when no editor connects", func() {
+		Expect(adapter.Attach(target, 10*time.Millisecond)).To(BeFalse())
+	})
+
+})
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/term"
+	"golang.org/x/net/context"
+)
+
+const (
+	// dashboardLogLines is how many log lines are kept for each function
+	dashboardLogLines = 1000
+
+	// dashboardRequests is how many recent requests are kept
+	dashboardRequests = 100
+
+	// dashboardSystemLogs is the name of the log view for SAM Local's own logs
+	dashboardSystemLogs = "SAM Local"
+)
+
+// ansiEscape matches the color codes in log lines, which are removed so that lines
+// can be cut to the width of the terminal
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*[a-zA-Z]")
+
+// dashboard is the live terminal view of 'sam local start-api --tui'. It shows the mounted
+// routes, the running containers, the most recent requests and the logs of one function
+// at a time, and is redrawn every refreshInterval.
+type dashboard struct {
+	sync.Mutex
+
+	out             io.Writer
+	refreshInterval time.Duration
+	started         time.Time
+
+	routes     []*routePlan
+	containers map[string]*dashboardContainer
+	requests   []*dashboardRequest
+
+	// invocations counts the finished invocations of each function, to tell
+	// whether a new container is the function's first (cold) start
+	invocations map[string]int
+
+	logs     map[string]*dashboardLog
+	selected string
+	scroll   int
+
+	stop    chan struct{}
+	restore *term.State
+	drawing sync.Mutex
+}
+
+// dashboardRequest is a request shown in the recent requests panel
+type dashboardRequest struct {
+	Time     time.Time
+	Function string
+	Method   string
+	Path     string
+	Status   int
+	Latency  time.Duration
+
+	// Dropped is whether the connection was closed without a response, by fault injection
+	Dropped bool
+}
+
+// dashboardContainer is a running function container
+type dashboardContainer struct {
+	ID       string
+	Function string
+	Started  time.Time
+	Cold     bool
+	memory   *invoker.MemoryMonitor
+}
+
+// dashboardLog keeps the most recent log lines of a function
+type dashboardLog struct {
+	dashboard *dashboard
+	lines     []string
+	partial   string
+}
+
+// newDashboard creates a dashboard that draws to out
+func newDashboard(out io.Writer) *dashboard {
+	d := &dashboard{
+		out:             out,
+		refreshInterval: 500 * time.Millisecond,
+		started:         time.Now(),
+		containers:      map[string]*dashboardContainer{},
+		invocations:     map[string]int{},
+		logs:            map[string]*dashboardLog{},
+		selected:        dashboardSystemLogs,
+		stop:            make(chan struct{}),
+	}
+	d.Logger(dashboardSystemLogs)
+	return d
+}
+
+// SetRoutes sets the routes shown in the routes panel
+func (d *dashboard) SetRoutes(routes []*routePlan) {
+	d.Lock()
+	defer d.Unlock()
+	d.routes = routes
+}
+
+// Logger returns the writer a function's logs are sent to
+func (d *dashboard) Logger(function string) io.Writer {
+	d.Lock()
+	defer d.Unlock()
+
+	if _, found := d.logs[function]; !found {
+		d.logs[function] = &dashboardLog{dashboard: d}
+	}
+	return d.logs[function]
+}
+
+// Write implements io.Writer, splitting the output into lines
+func (l *dashboardLog) Write(p []byte) (int, error) {
+
+	l.dashboard.Lock()
+	defer l.dashboard.Unlock()
+
+	text := l.partial + ansiEscape.ReplaceAllString(string(p), "")
+	lines := strings.Split(text, "\n")
+	l.partial = lines[len(lines)-1]
+
+	for _, line := range lines[:len(lines)-1] {
+		l.lines = append(l.lines, strings.TrimRight(line, "\r"))
+	}
+
+	if len(l.lines) > dashboardLogLines {
+		l.lines = l.lines[len(l.lines)-dashboardLogLines:]
+	}
+
+	return len(p), nil
+
+}
+
+// Wrap wraps a function's handler to show its requests in the recent requests panel. The
+// handler can still flush streamed responses, and drop connections, through it.
+func (d *dashboard) Wrap(function string, handler router.EventHandlerFunc) router.EventHandlerFunc {
+
+	return func(w http.ResponseWriter, event *router.Event) {
+
+		capture := &responseCapture{ResponseWriter: w}
+		started := time.Now()
+
+		handler(capture, event)
+
+		d.Lock()
+		defer d.Unlock()
+
+		d.invocations[function]++
+		d.requests = append(d.requests, &dashboardRequest{
+			Time:     started,
+			Function: function,
+			Method:   event.HTTPMethod,
+			Path:     event.Path,
+			Status:   capture.Recorded().StatusCode,
+			Latency:  time.Since(started),
+			Dropped:  capture.hijacked,
+		})
+
+		if len(d.requests) > dashboardRequests {
+			d.requests = d.requests[len(d.requests)-dashboardRequests:]
+		}
+
+	}
+
+}
+
+// Start switches the terminal to the dashboard and keeps it up to date until Close is called.
+// Keys are read from stdin: q quits, tab and the left/right arrows switch between function
+// logs and the up/down arrows (or page up/down) scroll through them.
+func (d *dashboard) Start() {
+
+	if state, err := term.SetRawTerminal(os.Stdin.Fd()); err == nil {
+		d.restore = state
+	}
+
+	// Use the alternate screen, so the terminal is left as it was on exit
+	fmt.Fprint(d.out, "\x1b[?1049h\x1b[?25l")
+
+	// Without Docker, the dashboard only leaves out the containers
+	cli, err := invoker.DockerClient()
+	if err != nil {
+		log.Printf("Could not connect to Docker to show the function containers: %s\n", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(d.refreshInterval)
+		defer ticker.Stop()
+		for {
+			if cli != nil {
+				d.updateContainers(cli)
+			}
+			d.draw()
+			select {
+			case <-ticker.C:
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+
+	go d.readKeys(os.Stdin)
+
+}
+
+// Close restores the terminal
+func (d *dashboard) Close() {
+
+	select {
+	case <-d.stop:
+		return
+	default:
+		close(d.stop)
+	}
+
+	fmt.Fprint(d.out, "\x1b[?25h\x1b[?1049l")
+	if d.restore != nil {
+		term.RestoreTerminal(os.Stdin.Fd(), d.restore)
+	}
+
+}
+
+// updateContainers finds the running function containers, and starts monitoring the memory
+// usage of new ones
+func (d *dashboard) updateContainers(cli *client.Client) {
+
+	filter := filters.NewArgs()
+	filter.Add("label", samLocalLabel)
+
+	list, err := cli.ContainerList(context.Background(), types.ContainerListOptions{Filters: filter})
+	if err != nil {
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	running := map[string]bool{}
+	for _, c := range list {
+		running[c.ID] = true
+		if _, found := d.containers[c.ID]; found {
+			continue
+		}
+		function := c.Labels[samLocalLabel]
+		d.containers[c.ID] = &dashboardContainer{
+			ID:       c.ID,
+			Function: function,
+			Started:  time.Unix(c.Created, 0),
+			Cold:     d.invocations[function] == 0,
+			memory:   invoker.MonitorMemory(context.Background(), cli, c.ID),
+		}
+	}
+
+	for id := range d.containers {
+		if !running[id] {
+			delete(d.containers, id)
+		}
+	}
+
+}
+
+// readKeys handles key presses until stdin is closed or q is pressed
+func (d *dashboard) readKeys(in io.Reader) {
+
+	buf := make([]byte, 16)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		if !d.handleKey(string(buf[:n])) {
+			// Shut down the same way as when interrupted, so in-flight requests can finish
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(os.Interrupt)
+			}
+			return
+		}
+		d.draw()
+	}
+
+}
+
+// handleKey handles a key press, and returns false if it asks to quit
+func (d *dashboard) handleKey(key string) bool {
+
+	d.Lock()
+	defer d.Unlock()
+
+	views := d.logViews()
+	current := 0
+	for i, name := range views {
+		if name == d.selected {
+			current = i
+		}
+	}
+
+	switch key {
+	case "q", "\x03":
+		return false
+	case "\t", "\x1b[C":
+		d.selected = views[(current+1)%len(views)]
+		d.scroll = 0
+	case "\x1b[Z", "\x1b[D":
+		d.selected = views[(current+len(views)-1)%len(views)]
+		d.scroll = 0
+	case "\x1b[A", "k":
+		d.scroll++
+	case "\x1b[B", "j":
+		d.scroll--
+	case "\x1b[5~":
+		d.scroll += 10
+	case "\x1b[6~":
+		d.scroll -= 10
+	}
+
+	if d.scroll < 0 {
+		d.scroll = 0
+	}
+	if max := len(d.logs[d.selected].lines); d.scroll > max {
+		d.scroll = max
+	}
+
+	return true
+
+}
+
+// logViews returns the names of the log views: SAM Local's own, then each function's
+func (d *dashboard) logViews() []string {
+	functions := []string{}
+	for name := range d.logs {
+		if name != dashboardSystemLogs {
+			functions = append(functions, name)
+		}
+	}
+	sort.Strings(functions)
+	return append([]string{dashboardSystemLogs}, functions...)
+}
+
+// draw redraws the dashboard
+func (d *dashboard) draw() {
+
+	width, height := 120, 40
+	if size, err := term.GetWinsize(os.Stdout.Fd()); err == nil && size.Width > 0 {
+		width, height = int(size.Width), int(size.Height)
+	}
+
+	lines := d.render(width, height)
+
+	d.drawing.Lock()
+	defer d.drawing.Unlock()
+	fmt.Fprint(d.out, "\x1b[H\x1b[2J"+strings.Join(lines, "\r\n"))
+
+}
+
+// render lays out the dashboard for a terminal of the given size
+func (d *dashboard) render(width int, height int) []string {
+
+	d.Lock()
+	defer d.Unlock()
+
+	lines := []string{}
+	add := func(format string, args ...interface{}) {
+		line := fmt.Sprintf(format, args...)
+		if len(line) > width {
+			line = line[:width]
+		}
+		lines = append(lines, line)
+	}
+
+	add("AWS SAM Local - up %s - q: quit, tab/arrows: switch logs, up/down: scroll", time.Since(d.started)/time.Second*time.Second)
+	add("")
+
+	add("ROUTES (%d)", len(d.routes))
+	for i, route := range d.routes {
+		if i == 5 && len(d.routes) > 6 {
+			add("  ... and %d more", len(d.routes)-5)
+			break
+		}
+		add("  %-20s %-50s %s", strings.Join(route.Methods, ","), route.URL, route.Handler)
+	}
+	add("")
+
+	containers := []*dashboardContainer{}
+	for _, c := range d.containers {
+		containers = append(containers, c)
+	}
+	sort.Sort(byContainerStarted(containers))
+
+	add("CONTAINERS (%d)", len(containers))
+	for _, c := range containers {
+		state := "warm"
+		if c.Cold {
+			state = "cold"
+		}
+		add("  %-30s %-12s %-4s %8s %6d MB", c.Function, c.ID[:12], state, time.Since(c.Started)/time.Second*time.Second, c.memory.Max()/1024/1024)
+	}
+	add("")
+
+	add("RECENT REQUESTS")
+	for i := len(d.requests) - 1; i >= 0 && i >= len(d.requests)-5; i-- {
+		r := d.requests[i]
+		status := strconv.Itoa(r.Status)
+		if r.Dropped {
+			status = "---"
+		}
+		add("  %s %-7s %-40s %3s %8s  %s", r.Time.Format("15:04:05"), r.Method, r.Path, status, r.Latency/time.Millisecond*time.Millisecond, r.Function)
+	}
+	add("")
+
+	tabs := []string{}
+	for _, name := range d.logViews() {
+		if name == d.selected {
+			name = "[" + name + "]"
+		}
+		tabs = append(tabs, name)
+	}
+	add("LOGS  %s", strings.Join(tabs, "  "))
+
+	// The logs take up the rest of the screen, showing the latest lines unless scrolled up
+	logs := d.logs[d.selected].lines
+	space := height - len(lines)
+	if space < 1 {
+		space = 1
+	}
+	end := len(logs) - d.scroll
+	start := end - space
+	if start < 0 {
+		start = 0
+	}
+	for _, line := range logs[start:end] {
+		add("  %s", line)
+	}
+
+	return lines
+
+}
+
+// byContainerStarted sorts containers by when they were started
+type byContainerStarted []*dashboardContainer
+
+func (c byContainerStarted) Len() int           { return len(c) }
+func (c byContainerStarted) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byContainerStarted) Less(i, j int) bool { return c[i].Started.Before(c[j].Started) }
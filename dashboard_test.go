@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/router"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dashboard", func() {
+
+	var dash *dashboard
+
+	BeforeEach(func() {
+		dash = newDashboard(&bytes.Buffer{})
+	})
+
+	It("keeps the latest log lines of each function", func() {
+		logs := dash.Logger("Hello")
+		fmt.Fprint(logs, "\x1b[32m[Hello]\x1b[0m first\nsecond")
+		Expect(dash.logs["Hello"].lines).To(Equal([]string{"[Hello] first"}))
+
+		fmt.Fprint(logs, " line\n")
+		Expect(dash.logs["Hello"].lines).To(Equal([]string{"[Hello] first", "second line"}))
+
+		for i := 0; i < dashboardLogLines+10; i++ {
+			fmt.Fprintf(logs, "line %d\n", i)
+		}
+		Expect(dash.logs["Hello"].lines).To(HaveLen(dashboardLogLines))
+		Expect(dash.logs["Hello"].lines[dashboardLogLines-1]).To(Equal(fmt.Sprintf("line %d", dashboardLogLines+9)))
+	})
+
+	It("shows requests with their status", func() {
+		handler := dash.Wrap("Hello", func(w http.ResponseWriter, e *router.Event) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+		handler(httptest.NewRecorder(), &router.Event{HTTPMethod: "GET", Path: "/hello"})
+
+		Expect(dash.requests).To(HaveLen(1))
+		Expect(dash.requests[0].Function).To(Equal("Hello"))
+		Expect(dash.requests[0].Status).To(Equal(http.StatusTeapot))
+		Expect(dash.invocations["Hello"]).To(Equal(1))
+	})
+
+	It("lets injected faults drop connections", func() {
+		faults := &faultConfig{global: &faultRule{DropRate: 1}}
+		handler := dash.Wrap("Hello", faults.Wrap("Hello", func(w http.ResponseWriter, e *router.Event) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		handled := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer close(handled)
+			handler(w, &router.Event{HTTPMethod: "GET", Path: "/hello"})
+		}))
+		defer server.Close()
+
+		_, err := http.Get(server.URL)
+		Expect(err).NotTo(BeNil())
+		<-handled
+
+		dash.Lock()
+		requests := dash.requests
+		dash.Unlock()
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Dropped).To(BeTrue())
+		Expect(strings.Join(dash.render(120, 40), "\n")).To(MatchRegexp(`GET +/hello +---`))
+	})
+
+	It("switches between logs and scrolls them", func() {
+		for _, name := range []string{"World", "Hello"} {
+			logs := dash.Logger(name)
+			for i := 0; i < 20; i++ {
+				fmt.Fprintf(logs, "%s %d\n", name, i)
+			}
+		}
+
+		Expect(dash.handleKey("\t")).To(BeTrue())
+		Expect(dash.selected).To(Equal("Hello"))
+		Expect(dash.handleKey("\x1b[C")).To(BeTrue())
+		Expect(dash.selected).To(Equal("World"))
+		Expect(dash.handleKey("\t")).To(BeTrue())
+		Expect(dash.selected).To(Equal(dashboardSystemLogs))
+		Expect(dash.handleKey("\x1b[D")).To(BeTrue())
+		Expect(dash.selected).To(Equal("World"))
+
+		dash.handleKey("\x1b[A")
+		dash.handleKey("\x1b[A")
+		Expect(dash.scroll).To(Equal(2))
+		dash.handleKey("\x1b[6~")
+		Expect(dash.scroll).To(Equal(0))
+
+		Expect(dash.handleKey("q")).To(BeFalse())
+	})
+
+	It("lays out the panels to fit the terminal", func() {
+		dash.SetRoutes([]*routePlan{{URL: "http://127.0.0.1:3000/hello", Methods: []string{"GET"}, Handler: "index.handler"}})
+		logs := dash.Logger(dashboardSystemLogs)
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(logs, "log %d %s\n", i, strings.Repeat("x", 200))
+		}
+
+		lines := dash.render(80, 30)
+		Expect(lines).To(HaveLen(30))
+		for _, line := range lines {
+			Expect(len(line)).To(BeNumerically("<=", 80))
+		}
+
+		screen := strings.Join(lines, "\n")
+		Expect(screen).To(ContainSubstring("ROUTES (1)"))
+		Expect(screen).To(ContainSubstring("http://127.0.0.1:3000/hello"))
+		Expect(screen).To(ContainSubstring("CONTAINERS (0)"))
+		Expect(screen).To(ContainSubstring("LOGS  [SAM Local]"))
+		Expect(lines[29]).To(HavePrefix("  log 99 "))
+	})
+
+})
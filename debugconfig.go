@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/codegangsta/cli"
+)
+
+// debugTarget is a function that an editor can attach a debugger to, once it's
+// started with 'sam local invoke --debug-port' or 'sam local start-api --debug-port'
+type debugTarget struct {
+	Name     string
+	Function string
+	Runtime  string
+	Port     int
+
+	// LocalRoot is the function's code, relative to the workspace
+	LocalRoot string
+}
+
+// debugConfigName is the name editors show for the configuration that attaches to a function
+func debugConfigName(function string) string {
+	return fmt.Sprintf("Attach to %s (SAM Local)", function)
+}
+
+func generateDebugConfig(c *cli.Context) {
+
+	filename := getTemplateFilename(c.String("template"))
+	template := openTemplate(c, filename, nil)
+
+	editor := c.String("editor")
+	if editor != "vscode" && editor != "intellij" {
+		fmt.Fprintf(os.Stderr, "ERROR: Invalid --editor '%s' (must be one of vscode or intellij)\n", editor)
+		os.Exit(1)
+	}
+
+	port, err := strconv.Atoi(c.String("debug-port"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Invalid --debug-port '%s'\n", c.String("debug-port"))
+		os.Exit(1)
+	}
+
+	// Paths in the configurations are relative to the workspace, which is
+	// where the configurations are written to
+	workspace := c.String("output-dir")
+	if workspace == "" {
+		workspace = filepath.Dir(filename)
+	}
+
+	targets, skipped := getDebugTargets(template, filepath.Dir(filename), workspace, port)
+	for _, name := range skipped {
+		warnMsg.Fprintf(os.Stderr, "Skipping %s: its runtime doesn't support debugging\n", name)
+	}
+
+	if len(targets) == 0 {
+		log.Fatalf("No functions that can be debugged were found in %s\n", filename)
+	}
+
+	if editor == "vscode" {
+		err = writeVSCodeLaunchConfig(targets, c.String("output-dir"))
+	} else {
+		err = writeIntelliJRunConfigs(targets, c.String("output-dir"))
+	}
+
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+
+}
+
+// getDebugTargets returns the functions in the template that can be debugged, and the names of
+// the ones whose runtime doesn't support it
+func getDebugTargets(template *cloudformation.Template, dir string, workspace string, port int) ([]*debugTarget, []string) {
+
+	targets := []*debugTarget{}
+	skipped := []string{}
+
+	for name, function := range template.GetAllAWSServerlessFunctionResources() {
+
+		if !canDebug(function.Runtime) {
+			skipped = append(skipped, name)
+			continue
+		}
+
+		// The code is mounted at /var/task. When CodeUri is an archive,
+		// the sources are assumed to be next to it.
+		code := dir
+		if function.CodeUri != nil && function.CodeUri.String != nil && !strings.HasPrefix(*function.CodeUri.String, "s3://") {
+			code = filepath.Join(dir, *function.CodeUri.String)
+			if info, err := os.Stat(code); err == nil && !info.IsDir() {
+				code = filepath.Dir(code)
+			}
+		}
+
+		root, err := filepath.Rel(workspace, code)
+		if err != nil {
+			root = code
+		}
+
+		targets = append(targets, &debugTarget{
+			Name:      debugConfigName(name),
+			Function:  name,
+			Runtime:   function.Runtime,
+			Port:      port,
+			LocalRoot: filepath.ToSlash(root),
+		})
+
+	}
+
+	sort.Sort(byDebugFunction(targets))
+	sort.Strings(skipped)
+	return targets, skipped
+
+}
+
+// canDebug checks whether 'sam local' can start a runtime in debug mode
+func canDebug(runtime string) bool {
+	switch runtime {
+	case invoker.RuntimeName.Java8, invoker.RuntimeName.NodeJS, invoker.RuntimeName.NodeJS43, invoker.RuntimeName.NodeJS610,
+		invoker.RuntimeName.NodeJS810, invoker.RuntimeName.Python27, invoker.RuntimeName.Python36:
+		return true
+	}
+	return false
+}
+
+// vscodeLaunchConfig returns the VS Code configuration that attaches to a function
+func vscodeLaunchConfig(target *debugTarget) map[string]interface{} {
+
+	root := workspaceRoot("${workspaceFolder}", target.LocalRoot)
+
+	config := map[string]interface{}{
+		"name":    target.Name,
+		"request": "attach",
+		"port":    target.Port,
+	}
+
+	switch target.Runtime {
+	case invoker.RuntimeName.Java8:
+		config["type"] = "java"
+		config["hostName"] = "localhost"
+		config["projectName"] = target.Function
+	case invoker.RuntimeName.Python27, invoker.RuntimeName.Python36:
+		// Python functions start the debugger themselves, e.g. with ptvsd
+		config["type"] = "python"
+		config["host"] = "localhost"
+		config["pathMappings"] = []map[string]string{{"localRoot": root, "remoteRoot": "/var/task"}}
+	default:
+		// Older Node.js runtimes are started with --debug-brk, newer ones with --inspect
+		protocol := "inspector"
+		if target.Runtime == invoker.RuntimeName.NodeJS || target.Runtime == invoker.RuntimeName.NodeJS43 {
+			protocol = "legacy"
+		}
+		config["type"] = "node"
+		config["address"] = "localhost"
+		config["localRoot"] = root
+		config["remoteRoot"] = "/var/task"
+		config["protocol"] = protocol
+	}
+
+	return config
+
+}
+
+// writeVSCodeLaunchConfig writes the configurations to .vscode/launch.json in the output
+// directory, or to stdout if there is none. Configurations already in launch.json are kept,
+// apart from the ones for the same functions, which are replaced.
+func writeVSCodeLaunchConfig(targets []*debugTarget, outputDir string) error {
+
+	launch := map[string]interface{}{
+		"version":        "0.2.0",
+		"configurations": []interface{}{},
+	}
+
+	filename := ""
+	if outputDir != "" {
+		filename = filepath.Join(outputDir, ".vscode", "launch.json")
+		if data, err := ioutil.ReadFile(filename); err == nil {
+			if err := json.Unmarshal(data, &launch); err != nil {
+				return fmt.Errorf("could not update %s (comments aren't supported): %s", filename, err)
+			}
+		}
+	}
+
+	launch["configurations"] = mergeLaunchConfigs(launch["configurations"], targets)
+
+	data, _ := json.MarshalIndent(launch, "", "    ")
+	if filename == "" {
+		fmt.Fprintf(os.Stdout, "%s\n", data)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	successMsg.Fprintf(os.Stderr, "Wrote %d configurations to %s\n", len(targets), filename)
+	return nil
+
+}
+
+// mergeLaunchConfigs replaces the configurations with the same names as the targets,
+// keeping the others in their original order
+func mergeLaunchConfigs(existing interface{}, targets []*debugTarget) []interface{} {
+
+	names := map[string]bool{}
+	for _, target := range targets {
+		names[target.Name] = true
+	}
+
+	configs := []interface{}{}
+	if list, ok := existing.([]interface{}); ok {
+		for _, config := range list {
+			if name, _ := getMap(config)["name"].(string); !names[name] {
+				configs = append(configs, config)
+			}
+		}
+	}
+
+	for _, target := range targets {
+		configs = append(configs, vscodeLaunchConfig(target))
+	}
+
+	return configs
+
+}
+
+// intellijRunConfig returns the IntelliJ run configuration that attaches to a function.
+// IntelliJ can only attach to Java and (inspector protocol) Node.js functions.
+func intellijRunConfig(target *debugTarget) (string, bool) {
+
+	switch target.Runtime {
+	case invoker.RuntimeName.Java8:
+		return fmt.Sprintf(`<component name="ProjectRunConfigurationManager">
+  <configuration default="false" name="%s" type="Remote">
+    <option name="USE_SOCKET_TRANSPORT" value="true" />
+    <option name="SERVER_MODE" value="false" />
+    <option name="SHMEM_ADDRESS" />
+    <option name="HOST" value="localhost" />
+    <option name="PORT" value="%d" />
+    <method v="2" />
+  </configuration>
+</component>
+`, xmlEscape(target.Name), target.Port), true
+	case invoker.RuntimeName.NodeJS610, invoker.RuntimeName.NodeJS810:
+		return fmt.Sprintf(`<component name="ProjectRunConfigurationManager">
+  <configuration default="false" name="%s" type="ChromiumRemoteDebugType" factoryName="Chromium Remote" port="%d">
+    <mapping url="/var/task" local-file="%s" />
+    <method v="2" />
+  </configuration>
+</component>
+`, xmlEscape(target.Name), target.Port, xmlEscape(workspaceRoot("$PROJECT_DIR$", target.LocalRoot))), true
+	}
+
+	return "", false
+
+}
+
+// writeIntelliJRunConfigs writes a run configuration for each function to .idea/runConfigurations
+// in the output directory, or to stdout if there is none
+func writeIntelliJRunConfigs(targets []*debugTarget, outputDir string) error {
+
+	written := 0
+	for _, target := range targets {
+
+		config, ok := intellijRunConfig(target)
+		if !ok {
+			warnMsg.Fprintf(os.Stderr, "Skipping %s: IntelliJ can't attach to %s functions\n", target.Function, target.Runtime)
+			continue
+		}
+
+		if outputDir == "" {
+			fmt.Fprintf(os.Stdout, "%s\n", config)
+			continue
+		}
+
+		dir := filepath.Join(outputDir, ".idea", "runConfigurations")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		filename := filepath.Join(dir, "SAM_Local_"+target.Function+".xml")
+		if err := ioutil.WriteFile(filename, []byte(config), 0644); err != nil {
+			return err
+		}
+		written++
+
+	}
+
+	if outputDir != "" {
+		successMsg.Fprintf(os.Stderr, "Wrote %d configurations to %s\n", written, filepath.Join(outputDir, ".idea", "runConfigurations"))
+	}
+
+	return nil
+
+}
+
+// workspaceRoot returns a path relative to the workspace, using the editor's variable for it
+func workspaceRoot(variable string, path string) string {
+	if path == "." {
+		return variable
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return variable + "/" + path
+}
+
+func xmlEscape(value string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(value)
+}
+
+// byDebugFunction sorts debug targets by function name
+type byDebugFunction []*debugTarget
+
+func (t byDebugFunction) Len() int           { return len(t) }
+func (t byDebugFunction) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t byDebugFunction) Less(i, j int) bool { return t[i].Function < t[j].Function }
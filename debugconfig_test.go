@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/awslabs/goformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Debug configurations", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "debugconfig")
+		os.MkdirAll(filepath.Join(dir, "target"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "target", "app.jar"), []byte(""), 0644)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	template := func() []*debugTarget {
+		template, _ := goformation.ParseJSON([]byte(`{
+			"Resources": {
+				"Node": { "Type": "AWS::Serverless::Function", "Properties": { "Runtime": "nodejs8.10", "Handler": "index.handler", "CodeUri": "src" } },
+				"Legacy": { "Type": "AWS::Serverless::Function", "Properties": { "Runtime": "nodejs4.3", "Handler": "index.handler" } },
+				"Java": { "Type": "AWS::Serverless::Function", "Properties": { "Runtime": "java8", "Handler": "app.Handler", "CodeUri": "target/app.jar" } },
+				"Python": { "Type": "AWS::Serverless::Function", "Properties": { "Runtime": "python3.6", "Handler": "app.handler", "CodeUri": "py" } },
+				"Go": { "Type": "AWS::Serverless::Function", "Properties": { "Runtime": "go1.x", "Handler": "main" } }
+			}
+		}`))
+		targets, skipped := getDebugTargets(template, dir, dir, 5858)
+		Expect(skipped).To(Equal([]string{"Go"}))
+		return targets
+	}
+
+	It("finds the functions that can be debugged", func() {
+		targets := template()
+		Expect(targets).To(HaveLen(4))
+		Expect(targets[0].Function).To(Equal("Java"))
+		Expect(targets[0].LocalRoot).To(Equal("target"))
+		Expect(targets[1].Function).To(Equal("Legacy"))
+		Expect(targets[1].LocalRoot).To(Equal("."))
+		Expect(targets[2].Function).To(Equal("Node"))
+		Expect(targets[2].LocalRoot).To(Equal("src"))
+	})
+
+	It("uses the right debugger for each runtime", func() {
+		targets := template()
+
+		java := vscodeLaunchConfig(targets[0])
+		Expect(java["type"]).To(Equal("java"))
+		Expect(java["port"]).To(Equal(5858))
+
+		legacy := vscodeLaunchConfig(targets[1])
+		Expect(legacy["protocol"]).To(Equal("legacy"))
+		Expect(legacy["localRoot"]).To(Equal("${workspaceFolder}"))
+
+		node := vscodeLaunchConfig(targets[2])
+		Expect(node["type"]).To(Equal("node"))
+		Expect(node["protocol"]).To(Equal("inspector"))
+		Expect(node["localRoot"]).To(Equal("${workspaceFolder}/src"))
+		Expect(node["remoteRoot"]).To(Equal("/var/task"))
+
+		python := vscodeLaunchConfig(targets[3])
+		Expect(python["type"]).To(Equal("python"))
+		Expect(python["pathMappings"]).To(Equal([]map[string]string{{"localRoot": "${workspaceFolder}/py", "remoteRoot": "/var/task"}}))
+
+		_, ok := intellijRunConfig(targets[3])
+		Expect(ok).To(BeFalse())
+		config, ok := intellijRunConfig(targets[2])
+		Expect(ok).To(BeTrue())
+		Expect(config).To(ContainSubstring(`port="5858"`))
+		Expect(config).To(ContainSubstring(`local-file="$PROJECT_DIR$/src"`))
+	})
+
+	It("keeps other configurations in launch.json", func() {
+		os.MkdirAll(filepath.Join(dir, ".vscode"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, ".vscode", "launch.json"), []byte(`{
+			"version": "0.2.0",
+			"configurations": [
+				{ "name": "Run tests", "type": "node", "request": "launch" },
+				{ "name": "Attach to Node (SAM Local)", "type": "node", "request": "attach", "port": 9229 }
+			]
+		}`), 0644)
+
+		Expect(writeVSCodeLaunchConfig(template(), dir)).To(BeNil())
+
+		data, _ := ioutil.ReadFile(filepath.Join(dir, ".vscode", "launch.json"))
+		launch := struct {
+			Configurations []map[string]interface{} `json:"configurations"`
+		}{}
+		Expect(json.Unmarshal(data, &launch)).To(BeNil())
+
+		names := []string{}
+		for _, config := range launch.Configurations {
+			names = append(names, config["name"].(string))
+		}
+		Expect(names).To(Equal([]string{
+			"Run tests",
+			"Attach to Java (SAM Local)",
+			"Attach to Legacy (SAM Local)",
+			"Attach to Node (SAM Local)",
+			"Attach to Python (SAM Local)",
+		}))
+		Expect(launch.Configurations[3]["port"]).To(Equal(float64(5858)))
+	})
+
+})
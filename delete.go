@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/codegangsta/cli"
+)
+
+// s3Object is an artifact that 'sam package' uploaded to S3
+type s3Object struct {
+	Bucket string
+	Key    string
+}
+
+func (o s3Object) String() string {
+	return "s3://" + o.Bucket + "/" + o.Key
+}
+
+// ecrImage is a container image that 'sam package' pushed to ECR
+type ecrImage struct {
+	Repository string
+
+	// ID is either the image's tag or its digest (sha256:...)
+	ID string
+}
+
+func (i ecrImage) String() string {
+	if strings.HasPrefix(i.ID, "sha256:") {
+		return i.Repository + "@" + i.ID
+	}
+	return i.Repository + ":" + i.ID
+}
+
+// stackArtifacts are the packaged artifacts that a deployed stack's template refers to
+type stackArtifacts struct {
+	Objects []s3Object
+	Images  []ecrImage
+}
+
+func deleteStack(c *cli.Context) {
+
+	aws := &awsCLI{Profile: c.String("profile"), Region: c.String("region")}
+
+	stack := c.String("stack-name")
+	if stack == "" {
+		fmt.Fprintf(os.Stderr, "ERROR: --stack-name is required\n")
+		os.Exit(1)
+	}
+
+	bucket := c.String("s3-bucket")
+	prefix := strings.Trim(c.String("s3-prefix"), "/")
+	if prefix != "" && bucket == "" {
+		fmt.Fprintf(os.Stderr, "ERROR: --s3-prefix needs --s3-bucket\n")
+		os.Exit(1)
+	}
+
+	// The original template (as deployed, before transforms) refers to the packaged artifacts
+	out, err := aws.Run("cloudformation", "get-template", "--stack-name", stack, "--template-stage", "Original", "--query", "TemplateBody", "--output", "json")
+	if err != nil {
+		errMsg.Fprintf(os.Stderr, "Failed to get the template of stack %s: %s\n", stack, err)
+		os.Exit(1)
+	}
+
+	template, err := parseTemplateBody([]byte(out))
+	if err != nil {
+		errMsg.Fprintf(os.Stderr, "Failed to parse the template of stack %s: %s\n", stack, err)
+		os.Exit(1)
+	}
+
+	artifacts := getStackArtifacts(template)
+
+	fmt.Fprintf(os.Stderr, "The following will be deleted:\n")
+	fmt.Fprintf(os.Stderr, "  CloudFormation stack %s\n", stack)
+	for _, object := range artifacts.Objects {
+		fmt.Fprintf(os.Stderr, "  %s\n", object)
+	}
+	for _, image := range artifacts.Images {
+		fmt.Fprintf(os.Stderr, "  %s\n", image)
+	}
+	if prefix != "" {
+		fmt.Fprintf(os.Stderr, "  Everything in s3://%s/%s/\n", bucket, prefix)
+	} else if bucket != "" {
+		fmt.Fprintf(os.Stderr, "  Everything in s3://%s\n", bucket)
+	}
+
+	if !c.Bool("no-prompts") && !confirm(os.Stdin, os.Stderr, "Are you sure?") {
+		fmt.Fprintf(os.Stderr, "Nothing was deleted\n")
+		os.Exit(1)
+	}
+
+	log.Printf("Deleting stack %s\n", stack)
+	if _, err := aws.Run("cloudformation", "delete-stack", "--stack-name", stack); err != nil {
+		errMsg.Fprintf(os.Stderr, "Failed to delete stack %s: %s\n", stack, err)
+		os.Exit(1)
+	}
+
+	// Artifacts are only deleted once nothing uses them
+	if _, err := aws.Run("cloudformation", "wait", "stack-delete-complete", "--stack-name", stack); err != nil {
+		errMsg.Fprintf(os.Stderr, "Stack %s wasn't deleted, so its artifacts were kept: %s\n", stack, err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, err := range deleteArtifacts(aws, artifacts, bucket, prefix) {
+		errMsg.Fprintf(os.Stderr, "%s\n", err)
+		failed = true
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+
+	successMsg.Fprintf(os.Stderr, "Deleted stack %s and its artifacts\n", stack)
+
+}
+
+// parseTemplateBody parses the TemplateBody that CloudFormation returns, which is the
+// template itself if it was JSON, or a string if it was YAML
+func parseTemplateBody(data []byte) (map[string]interface{}, error) {
+
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+
+	if text, ok := body.(string); ok {
+		return parseRawTemplate([]byte(text), true)
+	}
+
+	if template, ok := body.(map[string]interface{}); ok {
+		return template, nil
+	}
+
+	return nil, fmt.Errorf("unexpected template body")
+
+}
+
+// getStackArtifacts finds the S3 objects and ECR images that a packaged template's functions,
+// layers and APIs refer to. Only the properties that 'sam package' sets are looked at, so
+// other S3 locations (such as in environment variables) are never touched.
+func getStackArtifacts(template map[string]interface{}) *stackArtifacts {
+
+	artifacts := &stackArtifacts{}
+	seenObjects := map[s3Object]bool{}
+	seenImages := map[ecrImage]bool{}
+
+	addObject := func(location interface{}, bucketKey string, keyKey string) {
+		var object s3Object
+		if uri, ok := location.(string); ok {
+			parts := strings.SplitN(strings.TrimPrefix(uri, "s3://"), "/", 2)
+			if !strings.HasPrefix(uri, "s3://") || len(parts) != 2 {
+				return
+			}
+			object = s3Object{Bucket: parts[0], Key: parts[1]}
+		} else {
+			bucket, _ := getMap(location)[bucketKey].(string)
+			key, _ := getMap(location)[keyKey].(string)
+			object = s3Object{Bucket: bucket, Key: key}
+		}
+		if object.Bucket != "" && object.Key != "" && !seenObjects[object] {
+			seenObjects[object] = true
+			artifacts.Objects = append(artifacts.Objects, object)
+		}
+	}
+
+	addImage := func(uri interface{}) {
+		value, _ := uri.(string)
+		_, repository, _, err := parseECRRepository(value)
+		if err != nil {
+			return
+		}
+		image := ecrImage{}
+		if i := strings.Index(repository, "@"); i >= 0 {
+			image = ecrImage{Repository: repository[:i], ID: repository[i+1:]}
+		} else if i := strings.LastIndex(repository, ":"); i >= 0 {
+			image = ecrImage{Repository: repository[:i], ID: repository[i+1:]}
+		} else {
+			image = ecrImage{Repository: repository, ID: "latest"}
+		}
+		if !seenImages[image] {
+			seenImages[image] = true
+			artifacts.Images = append(artifacts.Images, image)
+		}
+	}
+
+	for _, resource := range getMap(template["Resources"]) {
+		properties := getMap(getMap(resource)["Properties"])
+		switch getMap(resource)["Type"] {
+		case "AWS::Serverless::Function":
+			addObject(properties["CodeUri"], "Bucket", "Key")
+			addImage(properties["ImageUri"])
+		case "AWS::Lambda::Function":
+			addObject(properties["Code"], "S3Bucket", "S3Key")
+			addImage(getMap(properties["Code"])["ImageUri"])
+		case "AWS::Serverless::LayerVersion":
+			addObject(properties["ContentUri"], "Bucket", "Key")
+		case "AWS::Lambda::LayerVersion":
+			addObject(properties["Content"], "S3Bucket", "S3Key")
+		case "AWS::Serverless::Api":
+			addObject(properties["DefinitionUri"], "Bucket", "Key")
+		case "AWS::ApiGateway::RestApi":
+			addObject(properties["BodyS3Location"], "Bucket", "Key")
+		}
+	}
+
+	sort.Sort(byS3Object(artifacts.Objects))
+	sort.Sort(byECRImage(artifacts.Images))
+	return artifacts
+
+}
+
+// deleteArtifacts deletes a stack's artifacts, and optionally everything under a prefix of
+// the deployment bucket. It carries on if an artifact can't be deleted (e.g. if it's already
+// gone), and returns the errors.
+func deleteArtifacts(aws *awsCLI, artifacts *stackArtifacts, bucket string, prefix string) []error {
+
+	errs := []error{}
+
+	for _, object := range artifacts.Objects {
+		log.Printf("Deleting %s\n", object)
+		if _, err := aws.Run("s3", "rm", object.String()); err != nil {
+			errs = append(errs, fmt.Errorf("Failed to delete %s: %s", object, err))
+		}
+	}
+
+	// Images are deleted a repository at a time
+	repositories := map[string][]string{}
+	names := []string{}
+	for _, image := range artifacts.Images {
+		if _, found := repositories[image.Repository]; !found {
+			names = append(names, image.Repository)
+		}
+		id := "imageTag=" + image.ID
+		if strings.HasPrefix(image.ID, "sha256:") {
+			id = "imageDigest=" + image.ID
+		}
+		repositories[image.Repository] = append(repositories[image.Repository], id)
+	}
+
+	for _, repository := range names {
+		log.Printf("Deleting images from ECR repository %s\n", repository)
+		args := append([]string{"ecr", "batch-delete-image", "--repository-name", repository, "--image-ids"}, repositories[repository]...)
+		if _, err := aws.Run(args...); err != nil {
+			errs = append(errs, fmt.Errorf("Failed to delete images from %s: %s", repository, err))
+		}
+	}
+
+	if bucket != "" {
+		location := "s3://" + bucket
+		if prefix != "" {
+			location += "/" + prefix + "/"
+		}
+		log.Printf("Deleting everything in %s\n", location)
+		if _, err := aws.Run("s3", "rm", location, "--recursive"); err != nil {
+			errs = append(errs, fmt.Errorf("Failed to empty %s: %s", location, err))
+		}
+	}
+
+	return errs
+
+}
+
+// confirm asks a yes/no question, and returns whether the answer was yes
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// byS3Object sorts S3 objects by bucket, then key
+type byS3Object []s3Object
+
+func (o byS3Object) Len() int           { return len(o) }
+func (o byS3Object) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
+func (o byS3Object) Less(i, j int) bool { return o[i].String() < o[j].String() }
+
+// byECRImage sorts ECR images by repository, then tag or digest
+type byECRImage []ecrImage
+
+func (i byECRImage) Len() int           { return len(i) }
+func (i byECRImage) Swap(a, b int)      { i[a], i[b] = i[b], i[a] }
+func (i byECRImage) Less(a, b int) bool { return i[a].String() < i[b].String() }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Deleting stacks", func() {
+
+	It("parses JSON and YAML template bodies", func() {
+		template, err := parseTemplateBody([]byte(`{"Resources": {"Hello": {"Type": "AWS::Serverless::Function"}}}`))
+		Expect(err).To(BeNil())
+		Expect(getMap(getMap(template["Resources"])["Hello"])["Type"]).To(Equal("AWS::Serverless::Function"))
+
+		template, err = parseTemplateBody([]byte(`"Resources:\n  Hello:\n    Type: AWS::Serverless::Function\n    Properties:\n      Role: !GetAtt Role.Arn\n"`))
+		Expect(err).To(BeNil())
+		properties := getMap(getMap(getMap(template["Resources"])["Hello"])["Properties"])
+		Expect(properties["Role"]).To(Equal(map[string]interface{}{"Fn::GetAtt": "Role.Arn"}))
+	})
+
+	It("finds the packaged artifacts", func() {
+		template, _ := parseTemplateBody([]byte(`{
+			"Resources": {
+				"Hello": {
+					"Type": "AWS::Serverless::Function",
+					"Properties": {
+						"CodeUri": "s3://deploys/app/0123abcd",
+						"Environment": { "Variables": { "INPUT": "s3://data/input.csv" } }
+					}
+				},
+				"World": {
+					"Type": "AWS::Serverless::Function",
+					"Properties": { "CodeUri": "s3://deploys/app/0123abcd" }
+				},
+				"Image": {
+					"Type": "AWS::Serverless::Function",
+					"Properties": { "ImageUri": "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:image-latest" }
+				},
+				"Raw": {
+					"Type": "AWS::Lambda::Function",
+					"Properties": {
+						"Code": { "ImageUri": "123456789012.dkr.ecr.us-east-1.amazonaws.com/app@sha256:abcdef" }
+					}
+				},
+				"Libs": {
+					"Type": "AWS::Lambda::LayerVersion",
+					"Properties": { "Content": { "S3Bucket": "deploys", "S3Key": "app/layer" } }
+				},
+				"Api": {
+					"Type": "AWS::Serverless::Api",
+					"Properties": { "DefinitionUri": { "Bucket": "deploys", "Key": "app/swagger" } }
+				},
+				"Data": {
+					"Type": "AWS::S3::Bucket",
+					"Properties": { "BucketName": "data" }
+				}
+			}
+		}`))
+
+		artifacts := getStackArtifacts(template)
+		Expect(artifacts.Objects).To(Equal([]s3Object{
+			{Bucket: "deploys", Key: "app/0123abcd"},
+			{Bucket: "deploys", Key: "app/layer"},
+			{Bucket: "deploys", Key: "app/swagger"},
+		}))
+		Expect(artifacts.Images).To(Equal([]ecrImage{
+			{Repository: "app", ID: "image-latest"},
+			{Repository: "app", ID: "sha256:abcdef"},
+		}))
+		Expect(artifacts.Images[1].String()).To(Equal("app@sha256:abcdef"))
+	})
+
+	It("only goes ahead when confirmed", func() {
+		out := &bytes.Buffer{}
+		Expect(confirm(strings.NewReader("y\n"), out, "Are you sure?")).To(BeTrue())
+		Expect(out.String()).To(Equal("Are you sure? [y/N]: "))
+		Expect(confirm(strings.NewReader("Yes\n"), out, "Are you sure?")).To(BeTrue())
+		Expect(confirm(strings.NewReader("\n"), out, "Are you sure?")).To(BeFalse())
+		Expect(confirm(strings.NewReader(""), out, "Are you sure?")).To(BeFalse())
+	})
+
+})
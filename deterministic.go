@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// deterministicEpoch is the time of the first event of a --deterministic run
+var deterministicEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// deterministicSource generates the request IDs, trace IDs and times of events, and the
+// randomness of injected faults, from a seed, so that --deterministic runs give the same
+// events and logs for the same requests. Each event is a second after the previous one.
+type deterministicSource struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+	now   time.Time
+}
+
+// newDeterministicSource returns a deterministicSource for a seed, and makes injected faults
+// use randomness from the seed too
+func newDeterministicSource(seed int64) *deterministicSource {
+
+	faultRandMutex.Lock()
+	faultRand = rand.New(rand.NewSource(seed))
+	faultRandMutex.Unlock()
+
+	return &deterministicSource{
+		rand: rand.New(rand.NewSource(seed)),
+		now:  deterministicEpoch.Add(-time.Second),
+	}
+
+}
+
+// Now returns the time of the next event
+func (d *deterministicSource) Now() time.Time {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.now = d.now.Add(time.Second)
+	return d.now
+}
+
+// RequestID generates a version 4 UUID, in the format of API Gateway's request IDs
+func (d *deterministicSource) RequestID() string {
+
+	b := d.bytes(16)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+
+}
+
+// TraceID generates an X-Ray trace header, for the time of the latest event
+func (d *deterministicSource) TraceID() string {
+	d.mutex.Lock()
+	epoch := d.now.Unix()
+	d.mutex.Unlock()
+	return fmt.Sprintf("Root=1-%08x-%s", epoch, hex.EncodeToString(d.bytes(12)))
+}
+
+// bytes returns n random bytes
+func (d *deterministicSource) bytes(n int) []byte {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	b := make([]byte, n)
+	d.rand.Read(b)
+	return b
+}
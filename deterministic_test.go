@@ -0,0 +1,50 @@
+package main
+
+import (
+	"regexp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Deterministic mode", func() {
+
+	It("generates the same IDs and times for the same seed", func() {
+		a, b := newDeterministicSource(42), newDeterministicSource(42)
+		for i := 0; i < 3; i++ {
+			Expect(a.RequestID()).To(Equal(b.RequestID()))
+			Expect(a.Now()).To(Equal(b.Now()))
+			Expect(a.TraceID()).To(Equal(b.TraceID()))
+		}
+	})
+
+	It("generates other IDs for other seeds", func() {
+		Expect(newDeterministicSource(1).RequestID()).NotTo(Equal(newDeterministicSource(2).RequestID()))
+	})
+
+	It("generates request IDs in the format of API Gateway's", func() {
+		id := newDeterministicSource(0).RequestID()
+		Expect(id).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+	})
+
+	It("starts events at a fixed time, a second apart", func() {
+		d := newDeterministicSource(0)
+		first := d.Now()
+		Expect(first).To(Equal(deterministicEpoch))
+		Expect(d.Now().Sub(first).Seconds()).To(Equal(1.0))
+	})
+
+	It("generates trace IDs for the time of the latest event", func() {
+		d := newDeterministicSource(0)
+		d.Now()
+		Expect(regexp.MustCompile(`^Root=1-5e0be100-[0-9a-f]{24}$`).MatchString(d.TraceID())).To(BeTrue())
+	})
+
+	It("seeds injected faults", func() {
+		newDeterministicSource(7)
+		first := faultRand.Float64()
+		newDeterministicSource(7)
+		Expect(faultRand.Float64()).To(Equal(first))
+	})
+
+})
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+)
+
+// maskedEnvironmentVariables contain credentials, and are not printed by --dry-run
+var maskedEnvironmentVariables = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"}
+
+// containerPlan describes the container that would be started to invoke a function
+type containerPlan struct {
+	Function    string            `json:"Function"`
+	Runtime     string            `json:"Runtime"`
+	Handler     string            `json:"Handler"`
+	Image       string            `json:"Image"`
+	Mounts      []string          `json:"Mounts"`
+	Environment map[string]string `json:"Environment"`
+	Entrypoint  []string          `json:"Entrypoint,omitempty"`
+	Ports       []string          `json:"Ports,omitempty"`
+	MemorySize  int               `json:"MemorySize"`
+	Timeout     int               `json:"Timeout"`
+	Network     string            `json:"Network,omitempty"`
+}
+
+// routePlan describes an API Gateway route that would be served by start-api
+type routePlan struct {
+	URL     string   `json:"URL"`
+	Methods []string `json:"Methods"`
+	Handler string   `json:"Handler,omitempty"`
+}
+
+// planContainer works out the container configuration used to invoke the function, without
+// connecting to Docker. Archives are not decompressed, and credentials are masked.
+func planContainer(r *invoker.Runtime, profile string) (*containerPlan, error) {
+
+	config, host, err := r.ContainerConfig("", profile)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &containerPlan{
+		Function:    r.LogicalID,
+		Runtime:     r.Name,
+		Handler:     r.Function.Handler,
+		Image:       config.Image,
+		Mounts:      host.Binds,
+		Environment: map[string]string{},
+		Entrypoint:  config.Entrypoint,
+		MemorySize:  r.Function.MemorySize,
+		Timeout:     r.Function.Timeout,
+		Network:     r.DockerNetwork,
+	}
+
+	for _, variable := range config.Env {
+		parts := strings.SplitN(variable, "=", 2)
+		plan.Environment[parts[0]] = parts[1]
+	}
+
+	for _, name := range maskedEnvironmentVariables {
+		if plan.Environment[name] != "" {
+			plan.Environment[name] = "********"
+		}
+	}
+
+	for port := range config.ExposedPorts {
+		plan.Ports = append(plan.Ports, string(port))
+	}
+	sort.Strings(plan.Ports)
+
+	return plan, nil
+
+}
+
+// planRoutes lists the routes mounted on every listener
+func planRoutes(listeners []*listener) []*routePlan {
+
+	routes := []*routePlan{}
+	for _, l := range listeners {
+		for _, mount := range l.Router.Mounts() {
+			route := &routePlan{
+				URL:     fmt.Sprintf("http://%s%s", l.Addr(), mount.Path),
+				Methods: mount.Methods(),
+			}
+			if mount.Function != nil {
+				route.Handler = mount.Function.Handler
+			}
+			routes = append(routes, route)
+		}
+	}
+
+	return routes
+
+}
+
+// writeDryRun writes the result of a dry run as indented JSON
+func writeDryRun(w io.Writer, v interface{}) {
+	data, _ := json.MarshalIndent(v, "", "    ")
+	fmt.Fprintf(w, "%s\n", data)
+}
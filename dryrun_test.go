@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/goformation/cloudformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dry run", func() {
+
+	It("works out the container without Docker, and masks credentials", func() {
+		os.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+		os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+		defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+		defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+		runt, err := invoker.New(invoker.NewRuntimeOpt{
+			Cwd:       os.TempDir(),
+			LogicalID: "HelloWorld",
+			Function: cloudformation.AWSServerlessFunction{
+				Runtime: "nodejs6.10",
+				Handler: "index.handler",
+				Environment: &cloudformation.AWSServerlessFunction_FunctionEnvironment{
+					Variables: map[string]string{"TABLE": "users"},
+				},
+			},
+		})
+		Expect(err).To(BeNil())
+
+		plan, err := planContainer(runt, "")
+		Expect(err).To(BeNil())
+		Expect(plan.Image).To(Equal("lambci/lambda:nodejs6.10"))
+		Expect(plan.Timeout).To(Equal(3))
+		Expect(plan.MemorySize).To(Equal(128))
+		Expect(plan.Environment["TABLE"]).To(Equal("users"))
+		Expect(plan.Environment["AWS_ACCESS_KEY_ID"]).To(Equal("********"))
+		Expect(plan.Environment["AWS_SECRET_ACCESS_KEY"]).To(Equal("********"))
+	})
+
+	It("rejects unsupported runtimes", func() {
+		_, err := invoker.New(invoker.NewRuntimeOpt{Function: cloudformation.AWSServerlessFunction{Runtime: "cobol"}})
+		Expect(err).To(Equal(invoker.ErrRuntimeNotSupported))
+	})
+
+})
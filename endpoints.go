@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/codegangsta/cli"
+)
+
+// endpoint is a single route that 'sam local start-api' would serve
+type endpoint struct {
+	Method           string   `json:"Method"`
+	Path             string   `json:"Path"`
+	URL              string   `json:"URL"`
+	Function         string   `json:"Function,omitempty"`
+	Authorizer       string   `json:"Authorizer,omitempty"`
+	BinaryMediaTypes []string `json:"BinaryMediaTypes,omitempty"`
+}
+
+func listEndpoints(c *cli.Context) {
+
+	filename := getTemplateFilename(c.String("template"))
+	template := openTemplate(c, filename, nil)
+
+	format := c.String("format")
+	if format != "table" && format != "json" {
+		fmt.Fprintf(os.Stderr, "ERROR: Invalid --format '%s' (must be one of table or json)\n", format)
+		os.Exit(1)
+	}
+
+	listeners, err := parseListeners(c.StringSlice("host"), c.StringSlice("port"))
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	apiListeners, err := parseAPIBindings(c.StringSlice("api-listener"), listeners)
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	endpoints, err := getEndpoints(template, listeners, apiListeners, c.Bool("prefix-routing"))
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	if format == "json" {
+		data, _ := json.MarshalIndent(endpoints, "", "    ")
+		fmt.Fprintf(os.Stdout, "%s\n", data)
+		return
+	}
+
+	writeEndpointsTable(os.Stdout, endpoints)
+
+}
+
+// getEndpoints works out every route that 'sam local start-api' would mount for the
+// template, in the same way as start-api does, but without creating any runtimes.
+func getEndpoints(template *cloudformation.Template, listeners []*listener, apiListeners map[string]*listener, prefixRouting bool) ([]*endpoint, error) {
+
+	for _, l := range listeners {
+		l.Router = router.NewServerlessRouter(router.NewServerlessRouterOpt{UsePrefix: prefixRouting})
+	}
+
+	apis := template.GetAllAWSServerlessApiResources()
+	if err := mountAPIs(apis, listeners, apiListeners); err != nil {
+		return nil, err
+	}
+	routeApis := getRouteApis(apis)
+
+	// Keep track of which function each mount belongs to. Mounting a function
+	// creates new mounts (or takes over existing ones) pointing to a new
+	// router.AWSServerlessFunction, so any function not seen yet is the one just mounted.
+	names := map[*router.AWSServerlessFunction]string{}
+	noop := func(http.ResponseWriter, *router.Event) {}
+
+	functions := template.GetAllAWSServerlessFunctionResources()
+
+	// Mount functions in order, so that when two functions define the same route
+	// the result is the same every time
+	sorted := []string{}
+	for name := range functions {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		mountFunction(functions[name], listeners, apiListeners, noop)
+		for _, l := range listeners {
+			for _, mount := range l.Router.Mounts() {
+				if mount.Function == nil {
+					continue
+				}
+				if _, seen := names[mount.Function]; !seen {
+					names[mount.Function] = name
+				}
+			}
+		}
+	}
+
+	endpoints := []*endpoint{}
+	for _, l := range listeners {
+		for _, mount := range l.Router.Mounts() {
+
+			e := &endpoint{
+				Method:           strings.ToUpper(mount.Method),
+				Path:             mount.Path,
+				URL:              fmt.Sprintf("http://%s%s", l.Addr(), mount.Path),
+				BinaryMediaTypes: mount.BinaryMediaTypes,
+			}
+
+			if mount.Function != nil {
+				e.Function = names[mount.Function]
+				e.Authorizer = getEventAuthorizer(template, e.Function, routeApis[e.Method+" "+e.Path], mount.Path, mount.Method)
+			} else if mount.IntegrationArn != nil {
+				// Api mounts that no function in the template handles
+				e.Function, _ = mount.IntegrationArn.GetFunctionName()
+			}
+
+			endpoints = append(endpoints, e)
+		}
+	}
+
+	sort.Sort(byURLAndMethod(endpoints))
+	return endpoints, nil
+
+}
+
+// getEventAuthorizer returns the authorizer that protects the Api event of a function with
+// the given path and method: either the Authorizer set on the event, or the DefaultAuthorizer
+// of the Api that defines the route. GoFormation doesn't support Auth yet, so it's read from
+// the raw template.
+func getEventAuthorizer(template *cloudformation.Template, function string, api string, path string, method string) string {
+
+	authorizer := ""
+
+	events, _ := getResourceProperty(template, function, "Events")
+	for _, event := range getMap(events) {
+		properties := getMap(getMap(event)["Properties"])
+		if properties["Path"] == path && strings.EqualFold(fmt.Sprintf("%v", properties["Method"]), method) {
+			authorizer, _ = getMap(properties["Auth"])["Authorizer"].(string)
+			break
+		}
+	}
+
+	if authorizer == "" && api != "" {
+		auth, _ := getResourceProperty(template, api, "Auth")
+		authorizer, _ = getMap(auth)["DefaultAuthorizer"].(string)
+	}
+
+	if authorizer == "NONE" {
+		return ""
+	}
+
+	return authorizer
+
+}
+
+// getRouteApis returns the logical ID of the Api that defines each route, keyed by
+// method and path. The definitions also tell which Api the events that don't reference
+// one with RestApiId belong to.
+func getRouteApis(apis map[string]cloudformation.AWSServerlessApi) map[string]string {
+
+	routes := map[string]string{}
+	for name, api := range apis {
+		api := api
+		mounts, err := (&router.AWSServerlessApi{AWSServerlessApi: &api}).Mounts()
+		if err != nil {
+			continue
+		}
+		for _, mount := range mounts {
+			routes[strings.ToUpper(mount.Method)+" "+mount.Path] = name
+		}
+	}
+
+	return routes
+
+}
+
+// getMap returns a value from a raw template as a map, or an empty map if it isn't one
+func getMap(value interface{}) map[string]interface{} {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m
+	}
+	return map[string]interface{}{}
+}
+
+// writeEndpointsTable writes the endpoints as a table
+func writeEndpointsTable(w io.Writer, endpoints []*endpoint) {
+
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(table, "METHOD\tURL\tFUNCTION\tAUTHORIZER\tBINARY TYPES\n")
+
+	for _, e := range endpoints {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n",
+			e.Method,
+			e.URL,
+			orDash(e.Function),
+			orDash(e.Authorizer),
+			orDash(strings.Join(e.BinaryMediaTypes, ",")),
+		)
+	}
+
+	table.Flush()
+
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// byURLAndMethod sorts endpoints by URL, then method
+type byURLAndMethod []*endpoint
+
+func (e byURLAndMethod) Len() int      { return len(e) }
+func (e byURLAndMethod) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e byURLAndMethod) Less(i, j int) bool {
+	if e[i].URL != e[j].URL {
+		return e[i].URL < e[j].URL
+	}
+	return e[i].Method < e[j].Method
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"github.com/awslabs/goformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Listing endpoints", func() {
+
+	template, _ := goformation.ParseJSON([]byte(`{
+		"Resources": {
+			"Users": {
+				"Type": "AWS::Serverless::Function",
+				"Properties": {
+					"Runtime": "nodejs6.10",
+					"Handler": "users.handler",
+					"Events": {
+						"GetUser": {
+							"Type": "Api",
+							"Properties": { "Path": "/users/{id}", "Method": "get", "Auth": { "Authorizer": "JwtAuth" } }
+						}
+					}
+				}
+			},
+			"Orders": {
+				"Type": "AWS::Serverless::Function",
+				"Properties": {
+					"Runtime": "nodejs6.10",
+					"Handler": "orders.handler",
+					"Events": {
+						"ListOrders": {
+							"Type": "Api",
+							"Properties": { "Path": "/orders", "Method": "get", "RestApiId": { "Ref": "Shop" } }
+						}
+					}
+				}
+			},
+			"Shop": {
+				"Type": "AWS::Serverless::Api",
+				"Properties": {
+					"StageName": "prod",
+					"Auth": { "DefaultAuthorizer": "ShopAuth" },
+					"DefinitionBody": {
+						"swagger": "2.0",
+						"x-amazon-apigateway-binary-media-types": ["image/png"],
+						"paths": {
+							"/orders": {
+								"get": {
+									"x-amazon-apigateway-integration": {
+										"type": "aws_proxy",
+										"uri": "arn:aws:apigateway:us-east-1:lambda:path/2015-03-31/functions/arn:aws:lambda:us-east-1:123456789012:function:Orders/invocations"
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}`))
+
+	It("lists every route with its function, authorizer and binary types", func() {
+		listeners, _ := parseListeners(nil, nil)
+		endpoints, err := getEndpoints(template, listeners, map[string]*listener{}, false)
+		Expect(err).To(BeNil())
+		Expect(endpoints).To(HaveLen(2))
+
+		Expect(*endpoints[0]).To(Equal(endpoint{
+			Method:           "GET",
+			Path:             "/orders",
+			URL:              "http://127.0.0.1:3000/orders",
+			Function:         "Orders",
+			Authorizer:       "ShopAuth",
+			BinaryMediaTypes: []string{"image/png"},
+		}))
+
+		Expect(*endpoints[1]).To(Equal(endpoint{
+			Method:     "GET",
+			Path:       "/users/{id}",
+			URL:        "http://127.0.0.1:3000/users/{id}",
+			Function:   "Users",
+			Authorizer: "JwtAuth",
+		}))
+	})
+
+})
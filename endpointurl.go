@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// parseEndpointURLs parses --aws-endpoint-url values into endpoint URLs by service. A value
+// is either the URL of every service (e.g. LocalStack's http://localhost:4566), or a
+// service's own, e.g. 's3=http://localhost:9000' for minio.
+func parseEndpointURLs(values []string) (map[string]string, error) {
+
+	endpoints := map[string]string{}
+	for _, value := range values {
+
+		service, endpoint := "", value
+		if i := strings.Index(value, "="); i >= 0 && !strings.Contains(value[:i], "://") {
+			service, endpoint = strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+1:])
+			if service == "" {
+				return nil, fmt.Errorf("invalid --aws-endpoint-url '%s' (the service is missing, e.g. 's3=http://localhost:9000')", value)
+			}
+		}
+
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid --aws-endpoint-url '%s' (must be a URL like 'http://localhost:4566', or a service and a URL like 's3=http://localhost:9000')", value)
+		}
+
+		service = strings.ToLower(service)
+		if _, ok := endpoints[service]; ok {
+			if service == "" {
+				return nil, fmt.Errorf("--aws-endpoint-url is given more than once for every service")
+			}
+			return nil, fmt.Errorf("--aws-endpoint-url is given more than once for %s", service)
+		}
+		endpoints[service] = endpoint
+	}
+
+	return endpoints, nil
+
+}
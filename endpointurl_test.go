@@ -0,0 +1,38 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AWS endpoint URLs", func() {
+
+	It("parses the endpoint of every service and of single services", func() {
+		endpoints, err := parseEndpointURLs([]string{"http://localhost:4566", "S3=http://localhost:9000", "dynamodb = http://localhost:8000"})
+		Expect(err).To(BeNil())
+		Expect(endpoints).To(Equal(map[string]string{
+			"":         "http://localhost:4566",
+			"s3":       "http://localhost:9000",
+			"dynamodb": "http://localhost:8000",
+		}))
+	})
+
+	It("keeps URLs with query strings whole", func() {
+		endpoints, err := parseEndpointURLs([]string{"http://localhost:4566/?a=b"})
+		Expect(err).To(BeNil())
+		Expect(endpoints).To(HaveKeyWithValue("", "http://localhost:4566/?a=b"))
+	})
+
+	It("rejects values that aren't URLs", func() {
+		for _, value := range []string{"localhost:4566", "s3=", "=http://localhost:9000", "s3=minio"} {
+			_, err := parseEndpointURLs([]string{value})
+			Expect(err).ToNot(BeNil(), value)
+		}
+	})
+
+	It("rejects services that are given twice", func() {
+		_, err := parseEndpointURLs([]string{"s3=http://localhost:9000", "s3=http://localhost:9001"})
+		Expect(err).To(MatchError("--aws-endpoint-url is given more than once for s3"))
+	})
+
+})
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/router"
+)
+
+// faultRule describes the latency and failures to inject into requests
+type faultRule struct {
+	// Latency is a fixed delay added before invoking the function (e.g. "250ms")
+	Latency string `json:"Latency"`
+	// Jitter is the upper bound of a random delay added on top of Latency
+	Jitter string `json:"Jitter"`
+	// ErrorRate is the probability (0-1) of returning an error instead of invoking the function
+	ErrorRate float64 `json:"ErrorRate"`
+	// ErrorStatus is the HTTP status code returned for injected errors (default 503)
+	ErrorStatus int `json:"ErrorStatus"`
+	// DropRate is the probability (0-1) of closing the connection without a response
+	DropRate float64 `json:"DropRate"`
+
+	latency time.Duration
+	jitter  time.Duration
+}
+
+// faultConfig is the structure of the --fault-config file. Rules can target functions
+// (by logical ID) or routes ("METHOD /path" or "/path", using the path from the template).
+// A route rule takes precedence over a function rule, which takes precedence over the
+// rule built from the --inject-* flags. When route rules overlap, the most specific one
+// applies, as with API Gateway's routes (see faultRouteBefore).
+type faultConfig struct {
+	Functions map[string]*faultRule `json:"Functions"`
+	Routes    map[string]*faultRule `json:"Routes"`
+
+	global *faultRule
+	routes []*faultRoute
+}
+
+// faultRoute is a route rule with its path compiled to a regular expression
+type faultRoute struct {
+	method   string
+	template string
+	path     *regexp.Regexp
+	rule     *faultRule
+}
+
+var (
+	// faultRand is the source of randomness for injected faults
+	faultRand      = rand.New(rand.NewSource(time.Now().UnixNano()))
+	faultRandMutex sync.Mutex
+
+	// pathParamRe matches {param} and {proxy+} path parameters
+	pathParamRe = regexp.MustCompile(`\\\{[^/]+?(\\\+)?\\\}`)
+)
+
+// newFaultConfig loads the fault config file (if provided) and adds the global
+// rule from the --inject-* flags. It returns nil if no faults are configured.
+func newFaultConfig(filename string, global *faultRule) (*faultConfig, error) {
+
+	config := &faultConfig{}
+
+	if filename != "" {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("could not read fault config %s: %s", filename, err)
+		}
+
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("invalid fault config %s: %s", filename, err)
+		}
+	}
+
+	if global != nil && (global.Latency != "" || global.Jitter != "" || global.ErrorRate > 0 || global.DropRate > 0) {
+		config.global = global
+	}
+
+	if config.global == nil && len(config.Functions) == 0 && len(config.Routes) == 0 {
+		return nil, nil
+	}
+
+	rules := []*faultRule{}
+	if config.global != nil {
+		rules = append(rules, config.global)
+	}
+	for _, rule := range config.Functions {
+		rules = append(rules, rule)
+	}
+
+	for route, rule := range config.Routes {
+		method, path := "", route
+		if parts := strings.Fields(route); len(parts) == 2 {
+			method, path = strings.ToUpper(parts[0]), parts[1]
+		}
+
+		config.routes = append(config.routes, &faultRoute{
+			method:   method,
+			template: path,
+			path:     compileRoutePath(path),
+			rule:     rule,
+		})
+		rules = append(rules, rule)
+	}
+
+	// The first route that matches a request applies, so the order can't be the map's
+	sort.Slice(config.routes, func(i, j int) bool {
+		return faultRouteBefore(config.routes[i], config.routes[j])
+	})
+
+	for _, rule := range rules {
+		if err := rule.parse(); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+
+}
+
+// compileRoutePath converts a template path (e.g. /users/{id} or /{proxy+}) to a regular expression
+func compileRoutePath(path string) *regexp.Regexp {
+	pattern := pathParamRe.ReplaceAllStringFunc(regexp.QuoteMeta(path), func(param string) string {
+		if strings.Contains(param, "+") {
+			return ".+"
+		}
+		return "[^/]+"
+	})
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+// faultRouteBefore returns whether route a is more specific than route b. Paths are compared
+// segment by segment, where literal segments (/users/me) come before path parameters
+// (/users/{id}), which come before greedy ones (/users/{proxy+}). Otherwise, routes for a
+// method come before routes for any method, and the rest are in the order of their paths.
+func faultRouteBefore(a *faultRoute, b *faultRoute) bool {
+
+	segmentsA, segmentsB := strings.Split(a.template, "/"), strings.Split(b.template, "/")
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		if rankA, rankB := faultSegmentRank(segmentsA[i]), faultSegmentRank(segmentsB[i]); rankA != rankB {
+			return rankA < rankB
+		}
+	}
+
+	if len(segmentsA) != len(segmentsB) {
+		return len(segmentsA) > len(segmentsB)
+	}
+	if (a.method == "") != (b.method == "") {
+		return a.method != ""
+	}
+	if a.template != b.template {
+		return a.template < b.template
+	}
+	return a.method < b.method
+
+}
+
+// faultSegmentRank ranks a segment of a route's path: literal, path parameter, then greedy
+func faultSegmentRank(segment string) int {
+	switch {
+	case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "+}"):
+		return 2
+	case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (rule *faultRule) parse() error {
+
+	var err error
+
+	if rule.Latency != "" {
+		if rule.latency, err = time.ParseDuration(rule.Latency); err != nil {
+			return fmt.Errorf("invalid fault latency '%s': %s", rule.Latency, err)
+		}
+	}
+
+	if rule.Jitter != "" {
+		if rule.jitter, err = time.ParseDuration(rule.Jitter); err != nil {
+			return fmt.Errorf("invalid fault jitter '%s': %s", rule.Jitter, err)
+		}
+	}
+
+	if rule.ErrorRate < 0 || rule.ErrorRate > 1 || rule.DropRate < 0 || rule.DropRate > 1 {
+		return fmt.Errorf("fault error and drop rates must be between 0 and 1")
+	}
+
+	if rule.ErrorStatus == 0 {
+		rule.ErrorStatus = http.StatusServiceUnavailable
+	}
+
+	return nil
+
+}
+
+// ruleFor finds the rule that applies to a request for a function
+func (config *faultConfig) ruleFor(function string, event *router.Event) *faultRule {
+
+	for _, route := range config.routes {
+		if (route.method == "" || route.method == event.HTTPMethod) && route.path.MatchString(event.Path) {
+			return route.rule
+		}
+	}
+
+	if rule, ok := config.Functions[function]; ok {
+		return rule
+	}
+
+	return config.global
+
+}
+
+// Wrap returns an event handler that injects the configured faults before invoking the function
+func (config *faultConfig) Wrap(function string, handler router.EventHandlerFunc) router.EventHandlerFunc {
+
+	return func(w http.ResponseWriter, event *router.Event) {
+
+		rule := config.ruleFor(function, event)
+		if rule == nil {
+			handler(w, event)
+			return
+		}
+
+		faultRandMutex.Lock()
+		delay := rule.latency
+		if rule.jitter > 0 {
+			delay += time.Duration(faultRand.Int63n(int64(rule.jitter)))
+		}
+		drop := faultRand.Float64() < rule.DropRate
+		fail := faultRand.Float64() < rule.ErrorRate
+		faultRandMutex.Unlock()
+
+		if delay > 0 {
+			log.Printf("Injecting %s latency into %s %s\n", delay, event.HTTPMethod, event.Path)
+			time.Sleep(delay)
+		}
+
+		if drop {
+			if hijacker, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hijacker.Hijack(); err == nil {
+					log.Printf("Injecting dropped connection into %s %s\n", event.HTTPMethod, event.Path)
+					conn.Close()
+					return
+				}
+			}
+		}
+
+		if fail {
+			log.Printf("Injecting HTTP %d error into %s %s\n", rule.ErrorStatus, event.HTTPMethod, event.Path)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(rule.ErrorStatus)
+			w.Write([]byte(`{ "message": "Injected fault" }`))
+			return
+		}
+
+		handler(w, event)
+
+	}
+
+}
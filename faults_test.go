@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/awslabs/aws-sam-local/router"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fault injection", func() {
+
+	Context("compiling route paths", func() {
+
+		It("matches path parameters", func() {
+			re := compileRoutePath("/users/{id}")
+			Expect(re.MatchString("/users/42")).To(BeTrue())
+			Expect(re.MatchString("/users/42/orders")).To(BeFalse())
+		})
+
+		It("matches greedy proxy parameters", func() {
+			re := compileRoutePath("/files/{proxy+}")
+			Expect(re.MatchString("/files/a/b/c")).To(BeTrue())
+			Expect(re.MatchString("/other")).To(BeFalse())
+		})
+
+	})
+
+	Context("with a fault config file", func() {
+
+		var config *faultConfig
+
+		BeforeEach(func() {
+			file, _ := ioutil.TempFile("", "faults")
+			file.WriteString(`{
+				"Functions": { "Payments": { "ErrorRate": 1, "ErrorStatus": 502 } },
+				"Routes": { "GET /users/{id}": { "Latency": "1ms" } }
+			}`)
+			file.Close()
+			defer os.Remove(file.Name())
+
+			var err error
+			config, err = newFaultConfig(file.Name(), &faultRule{})
+			Expect(err).To(BeNil())
+		})
+
+		It("prefers route rules over function rules", func() {
+			rule := config.ruleFor("Payments", &router.Event{HTTPMethod: "GET", Path: "/users/1"})
+			Expect(rule.Latency).To(Equal("1ms"))
+		})
+
+		It("falls back to function rules", func() {
+			rule := config.ruleFor("Payments", &router.Event{HTTPMethod: "POST", Path: "/users/1"})
+			Expect(rule.ErrorStatus).To(Equal(502))
+		})
+
+		It("doesn't apply to other functions", func() {
+			rule := config.ruleFor("Users", &router.Event{HTTPMethod: "POST", Path: "/users/1"})
+			Expect(rule).To(BeNil())
+		})
+
+		It("injects errors instead of invoking the function", func() {
+			invoked := false
+			handler := config.Wrap("Payments", func(w http.ResponseWriter, e *router.Event) {
+				invoked = true
+			})
+
+			rr := httptest.NewRecorder()
+			handler(rr, &router.Event{HTTPMethod: "POST", Path: "/pay"})
+			Expect(invoked).To(BeFalse())
+			Expect(rr.Code).To(Equal(502))
+		})
+
+	})
+
+	It("applies the most specific of overlapping route rules", func() {
+		file, _ := ioutil.TempFile("", "faults")
+		file.WriteString(`{
+			"Routes": {
+				"/users/{proxy+}": { "ErrorStatus": 503 },
+				"/users/{id}": { "ErrorStatus": 502 },
+				"GET /users/{id}": { "ErrorStatus": 501 },
+				"/users/me": { "ErrorStatus": 500 }
+			}
+		}`)
+		file.Close()
+		defer os.Remove(file.Name())
+
+		config, err := newFaultConfig(file.Name(), &faultRule{})
+		Expect(err).To(BeNil())
+
+		status := func(method string, path string) int {
+			return config.ruleFor("Users", &router.Event{HTTPMethod: method, Path: path}).ErrorStatus
+		}
+		Expect(status("GET", "/users/me")).To(Equal(500))
+		Expect(status("GET", "/users/42")).To(Equal(501))
+		Expect(status("POST", "/users/42")).To(Equal(502))
+		Expect(status("GET", "/users/42/orders")).To(Equal(503))
+	})
+
+	It("returns nil when nothing is configured", func() {
+		config, err := newFaultConfig("", &faultRule{})
+		Expect(err).To(BeNil())
+		Expect(config).To(BeNil())
+	})
+
+	It("rejects invalid rates", func() {
+		_, err := newFaultConfig("", &faultRule{ErrorRate: 2})
+		Expect(err).ToNot(BeNil())
+	})
+
+})
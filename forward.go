@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/awslabs/aws-sam-local/invoker"
+)
+
+// awsForwarder sends the AWS API calls that functions make to SAM Local's own endpoints on
+// to AWS, or to the service's --aws-endpoint-url, signed again with SAM Local's credentials
+type awsForwarder struct {
+	// upstreams are the --aws-endpoint-url endpoints, by service ("" for every service)
+	upstreams map[string]string
+
+	signer *v4.Signer
+	client *http.Client
+}
+
+// awsForwardHopHeaders are the headers of a call that aren't sent on, as the call is signed again
+var awsForwardHopHeaders = map[string]bool{
+	"Authorization":        true,
+	"X-Amz-Date":           true,
+	"X-Amz-Security-Token": true,
+	"X-Amz-Content-Sha256": true,
+	"Content-Length":       true,
+	"Connection":           true,
+}
+
+// newAWSForwarder returns an awsForwarder that signs calls with a profile's credentials
+func newAWSForwarder(upstreams map[string]string, profile string) *awsForwarder {
+	return &awsForwarder{
+		upstreams: upstreams,
+		signer:    v4.NewSigner(awsForwardCredentials(profile)),
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// awsForwardCredentials returns the credentials that calls are signed with again: the
+// profile's, or the ones functions get when there aren't any, which endpoints like
+// LocalStack's accept
+func awsForwardCredentials(profile string) *credentials.Credentials {
+	sess, err := session.NewSessionWithOptions(session.Options{Profile: profile})
+	if err == nil {
+		if _, err = sess.Config.Credentials.Get(); err == nil {
+			return sess.Config.Credentials
+		}
+	}
+	return credentials.NewStaticCredentials("defaultkey", "defaultsecret", "")
+}
+
+// forward signs a call again and sends it on to an endpoint, or to the service's endpoint in
+// AWS if it's ""
+func (f *awsForwarder) forward(w http.ResponseWriter, r *http.Request, service string, region string, endpoint string, body []byte) {
+
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", service, region)
+	}
+	target := strings.TrimSuffix(endpoint, "/") + r.URL.RequestURI()
+
+	out, err := http.NewRequest(r.Method, target, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	for name, values := range r.Header {
+		if !awsForwardHopHeaders[http.CanonicalHeaderKey(name)] {
+			out.Header[name] = values
+		}
+	}
+
+	if _, err := f.signer.Sign(out, bytes.NewReader(body), service, region, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	resp, err := f.client.Do(out)
+	if err != nil {
+		log.Printf("Could not call %s: %s\n", target, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+
+}
+
+// upstream returns the --aws-endpoint-url of a service, or of every service, or ""
+func (f *awsForwarder) upstream(service string) string {
+
+	names := []string{}
+	for name := range f.upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name != "" && invoker.EndpointVariable(name) == invoker.EndpointVariable(service) {
+			return f.upstreams[name]
+		}
+	}
+	return f.upstreams[""]
+
+}
+
+// listenForFunctions listens on a free port on the addresses that functions reach SAM
+// Local's own AWS endpoints by: the loopback interface, and the Docker host's address when
+// they run in containers. Never every interface, as the endpoints call AWS with your
+// credentials.
+func listenForFunctions(backend string) ([]net.Listener, int, error) {
+
+	hosts := []string{"127.0.0.1"}
+	if backend == "docker" {
+		address, err := invoker.DockerHostAddress()
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not find the Docker host's address: %s", err)
+		}
+		if address != "" {
+			hosts = append(hosts, address)
+		}
+	}
+
+	// Every address needs the same port, which another process may have taken on one of
+	// them by chance
+	var err error
+	for attempt := 0; attempt < 10; attempt++ {
+		listeners := []net.Listener{}
+		port := 0
+		for _, host := range hosts {
+			var listener net.Listener
+			if listener, err = net.Listen("tcp", net.JoinHostPort(host, fmt.Sprint(port))); err != nil {
+				break
+			}
+			listeners = append(listeners, listener)
+			port = listener.Addr().(*net.TCPAddr).Port
+		}
+		if err == nil {
+			return listeners, port, nil
+		}
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}
+
+	return nil, 0, err
+
+}
+
+// serveForFunctions serves a handler on the listeners of listenForFunctions
+func serveForFunctions(listeners []net.Listener, handler http.Handler) {
+	for _, listener := range listeners {
+		go http.Serve(listener, handler)
+	}
+}
+
+// awsCallers are the credentials that SAM Local's own AWS endpoints accept calls signed
+// with, as secret access keys by access key ID
+type awsCallers map[string]string
+
+// errUnknownCaller is returned for calls that weren't signed by one of the awsCallers
+var errUnknownCaller = errors.New("the call wasn't signed with credentials that SAM Local gave a function")
+
+// verify checks the Signature Version 4 signature of a call (in its Authorization header,
+// or its query for presigned URLs) against the callers' secret access keys
+func (c awsCallers) verify(r *http.Request, body []byte) error {
+
+	query := r.URL.Query()
+	var credential, signedHeaders, signature, date, payload string
+
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+			return errUnknownCaller
+		}
+		for _, field := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ",") {
+			field = strings.TrimSpace(field)
+			switch {
+			case strings.HasPrefix(field, "Credential="):
+				credential = strings.TrimPrefix(field, "Credential=")
+			case strings.HasPrefix(field, "SignedHeaders="):
+				signedHeaders = strings.TrimPrefix(field, "SignedHeaders=")
+			case strings.HasPrefix(field, "Signature="):
+				signature = strings.TrimPrefix(field, "Signature=")
+			}
+		}
+		date = r.Header.Get("X-Amz-Date")
+		payload = r.Header.Get("X-Amz-Content-Sha256")
+	} else {
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		date = query.Get("X-Amz-Date")
+		payload = query.Get("X-Amz-Content-Sha256")
+		if payload == "" {
+			payload = "UNSIGNED-PAYLOAD"
+		}
+		query.Del("X-Amz-Signature")
+	}
+
+	// The credential is <key>/<date>/<region>/<service>/aws4_request
+	scope := strings.SplitN(credential, "/", 2)
+	secret, ok := c[scope[0]]
+	if !ok || len(scope) < 2 || len(date) < 8 || signedHeaders == "" {
+		return errUnknownCaller
+	}
+	parts := strings.Split(scope[1], "/")
+	if len(parts) != 4 || parts[0] != date[:8] {
+		return errUnknownCaller
+	}
+
+	if payload == "" {
+		sum := sha256.Sum256(body)
+		payload = hex.EncodeToString(sum[:])
+	}
+
+	headers := []string{}
+	for _, name := range strings.Split(signedHeaders, ";") {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = fmt.Sprint(r.ContentLength)
+			if v := r.Header.Get("Content-Length"); v != "" {
+				value = v
+			}
+		default:
+			value = strings.Join(r.Header[http.CanonicalHeaderKey(name)], ",")
+		}
+		headers = append(headers, name+":"+strings.Join(strings.Fields(value), " "))
+	}
+
+	key := []byte("AWS4" + secret)
+	for _, part := range parts {
+		key = hmacSHA256(key, part)
+	}
+
+	// S3's paths are signed as they are, and other services' are escaped again
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	paths := []string{path}
+	if parts[2] != "s3" {
+		paths = []string{sigV4Escape(path), path}
+	}
+
+	for _, path := range paths {
+		canonical := strings.Join([]string{
+			r.Method,
+			path,
+			strings.Replace(query.Encode(), "+", "%20", -1),
+			strings.Join(headers, "\n") + "\n",
+			signedHeaders,
+			payload,
+		}, "\n")
+		sum := sha256.Sum256([]byte(canonical))
+		toSign := strings.Join([]string{"AWS4-HMAC-SHA256", date, scope[1], hex.EncodeToString(sum[:])}, "\n")
+
+		if hmac.Equal([]byte(hex.EncodeToString(hmacSHA256(key, toSign))), []byte(signature)) {
+			return nil
+		}
+	}
+
+	return errUnknownCaller
+
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with a key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sigV4Escape escapes a path the way Signature Version 4 does, which leaves only unreserved
+// characters and slashes as they are
+func sigV4Escape(path string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
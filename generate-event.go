@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
 	"text/template"
@@ -112,6 +114,34 @@ func generate(eventType string, c *cli.Context) {
 
 }
 
+// apiEventBody is the default body of sample Api events, escaped to go in the event's JSON
+const apiEventBody = `{ \"test\": \"body\"}`
+
+// sampleAPIEventBody returns the body of a sample Api event, as a client would send it
+func sampleAPIEventBody() string {
+
+	buf := &bytes.Buffer{}
+	template.Must(template.New("event").Parse(apiEvent)).Execute(buf, struct {
+		Method   string
+		Body     string
+		Resource string
+		Path     string
+	}{
+		Method:   "POST",
+		Body:     apiEventBody,
+		Resource: "/{proxy+}",
+		Path:     "/examplepath",
+	})
+
+	var event struct {
+		Body string `json:"body"`
+	}
+	json.Unmarshal(buf.Bytes(), &event)
+
+	return event.Body
+
+}
+
 var s3Event = `{
   "Records": [
     {
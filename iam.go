@@ -0,0 +1,601 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/loader"
+)
+
+// iamStatement is a statement of an IAM policy. Its actions and resources are patterns,
+// which can have * and ? wildcards.
+type iamStatement struct {
+	Effect       string
+	Actions      []string
+	NotActions   []string
+	Resources    []string
+	NotResources []string
+}
+
+// iamPolicy is what a function's execution role would be allowed to do once it's deployed,
+// as far as its template tells
+type iamPolicy struct {
+	statements []iamStatement
+
+	// unknown are the policies whose statements SAM Local doesn't know, such as customer
+	// managed policies, which could allow anything
+	unknown []string
+}
+
+// iamAllowAll is the statement of policies that allow everything
+var iamAllowAll = []iamStatement{{Effect: "Allow", Actions: []string{"*"}, Resources: []string{"*"}}}
+
+// iamManagedPolicies are the statements of common AWS managed policies, by name
+var iamManagedPolicies = map[string][]iamStatement{
+	"AdministratorAccess":         iamAllowAll,
+	"PowerUserAccess":             {{Effect: "Allow", NotActions: []string{"iam:*", "organizations:*", "account:*"}, Resources: []string{"*"}}},
+	"AWSLambdaBasicExecutionRole": iamAllow("logs:CreateLogGroup", "logs:CreateLogStream", "logs:PutLogEvents"),
+	"AWSLambdaExecute":            iamAllow("logs:*", "s3:GetObject", "s3:PutObject"),
+	"AWSLambdaVPCAccessExecutionRole": iamAllow("logs:CreateLogGroup", "logs:CreateLogStream", "logs:PutLogEvents",
+		"ec2:CreateNetworkInterface", "ec2:DescribeNetworkInterfaces", "ec2:DeleteNetworkInterface"),
+	"AWSLambdaFullAccess":          iamAllow("lambda:*", "cloudwatch:*", "dynamodb:*", "events:*", "iam:ListRoles", "kinesis:*", "logs:*", "s3:*", "sns:*", "sqs:*", "xray:*"),
+	"AWSLambda_FullAccess":         iamAllow("lambda:*", "cloudwatch:*", "events:*", "logs:*", "xray:*"),
+	"AWSLambdaRole":                iamAllow("lambda:InvokeFunction"),
+	"AWSXrayWriteOnlyAccess":       iamAllow("xray:PutTraceSegments", "xray:PutTelemetryRecords", "xray:GetSamplingRules", "xray:GetSamplingTargets", "xray:GetSamplingStatisticSummaries"),
+	"AmazonS3FullAccess":           iamAllow("s3:*"),
+	"AmazonS3ReadOnlyAccess":       iamAllow("s3:Get*", "s3:List*"),
+	"AmazonDynamoDBFullAccess":     iamAllow("dynamodb:*"),
+	"AmazonDynamoDBReadOnlyAccess": iamAllow("dynamodb:BatchGetItem", "dynamodb:Describe*", "dynamodb:List*", "dynamodb:GetItem", "dynamodb:Query", "dynamodb:Scan"),
+	"AmazonSQSFullAccess":          iamAllow("sqs:*"),
+	"AmazonSQSReadOnlyAccess":      iamAllow("sqs:GetQueueAttributes", "sqs:GetQueueUrl", "sqs:ListDeadLetterSourceQueues", "sqs:ListQueues", "sqs:ListQueueTags"),
+	"AmazonSNSFullAccess":          iamAllow("sns:*"),
+	"AmazonSNSReadOnlyAccess":      iamAllow("sns:GetTopicAttributes", "sns:List*"),
+	"AmazonKinesisFullAccess":      iamAllow("kinesis:*"),
+	"AmazonKinesisReadOnlyAccess":  iamAllow("kinesis:Get*", "kinesis:List*", "kinesis:Describe*"),
+	"SecretsManagerReadWrite":      iamAllow("secretsmanager:*"),
+	"AmazonSSMReadOnlyAccess":      iamAllow("ssm:Describe*", "ssm:Get*", "ssm:List*"),
+	"CloudWatchFullAccess":         iamAllow("cloudwatch:*", "logs:*"),
+}
+
+// iamPolicyTemplates are the actions that common SAM policy templates allow, by name. Their
+// resources aren't checked, as they're usually references to other resources in the
+// template, which don't exist locally.
+var iamPolicyTemplates = map[string][]iamStatement{
+	"DynamoDBCrudPolicy": iamAllow("dynamodb:GetItem", "dynamodb:DeleteItem", "dynamodb:PutItem", "dynamodb:Scan", "dynamodb:Query",
+		"dynamodb:UpdateItem", "dynamodb:BatchWriteItem", "dynamodb:BatchGetItem", "dynamodb:DescribeTable", "dynamodb:ConditionCheckItem"),
+	"DynamoDBReadPolicy":       iamAllow("dynamodb:GetItem", "dynamodb:Scan", "dynamodb:Query", "dynamodb:BatchGetItem", "dynamodb:DescribeTable"),
+	"DynamoDBWritePolicy":      iamAllow("dynamodb:PutItem", "dynamodb:UpdateItem", "dynamodb:BatchWriteItem"),
+	"DynamoDBStreamReadPolicy": iamAllow("dynamodb:DescribeStream", "dynamodb:GetRecords", "dynamodb:GetShardIterator", "dynamodb:ListStreams"),
+	"S3ReadPolicy":             iamAllow("s3:GetObject", "s3:ListBucket", "s3:GetBucketLocation", "s3:GetObjectVersion", "s3:GetLifecycleConfiguration"),
+	"S3WritePolicy":            iamAllow("s3:PutObject", "s3:PutObjectAcl", "s3:PutLifecycleConfiguration"),
+	"S3CrudPolicy": iamAllow("s3:GetObject", "s3:ListBucket", "s3:GetBucketLocation", "s3:GetObjectVersion", "s3:PutObject", "s3:PutObjectAcl",
+		"s3:GetLifecycleConfiguration", "s3:PutLifecycleConfiguration", "s3:DeleteObject"),
+	"SQSSendMessagePolicy": iamAllow("sqs:SendMessage*"),
+	"SQSPollerPolicy": iamAllow("sqs:ChangeMessageVisibility", "sqs:ChangeMessageVisibilityBatch", "sqs:DeleteMessage", "sqs:DeleteMessageBatch",
+		"sqs:GetQueueAttributes", "sqs:ReceiveMessage"),
+	"SNSPublishMessagePolicy": iamAllow("sns:Publish"),
+	"SNSCrudPolicy":           iamAllow("sns:ListSubscriptionsByTopic", "sns:CreateTopic", "sns:SetTopicAttributes", "sns:Subscribe", "sns:Publish"),
+	"LambdaInvokePolicy":      iamAllow("lambda:InvokeFunction"),
+	"KinesisCrudPolicy": iamAllow("kinesis:AddTagsToStream", "kinesis:CreateStream", "kinesis:DecreaseStreamRetentionPeriod", "kinesis:DeleteStream",
+		"kinesis:DescribeStream", "kinesis:DescribeStreamSummary", "kinesis:GetShardIterator", "kinesis:IncreaseStreamRetentionPeriod",
+		"kinesis:ListTagsForStream", "kinesis:MergeShards", "kinesis:PutRecord", "kinesis:PutRecords", "kinesis:SplitShard", "kinesis:RemoveTagsFromStream"),
+	"KinesisStreamReadPolicy":               iamAllow("kinesis:ListStreams", "kinesis:DescribeLimits", "kinesis:DescribeStream", "kinesis:DescribeStreamSummary", "kinesis:GetRecords", "kinesis:GetShardIterator"),
+	"SSMParameterReadPolicy":                iamAllow("ssm:DescribeParameters", "ssm:GetParameters", "ssm:GetParameter", "ssm:GetParametersByPath"),
+	"AWSSecretsManagerGetSecretValuePolicy": iamAllow("secretsmanager:GetSecretValue"),
+	"StepFunctionsExecutionPolicy":          iamAllow("states:StartExecution"),
+	"EventBridgePutEventsPolicy":            iamAllow("events:PutEvents"),
+	"KMSDecryptPolicy":                      iamAllow("kms:Decrypt"),
+	"KMSEncryptPolicy":                      iamAllow("kms:Encrypt"),
+	"CloudWatchPutMetricPolicy":             iamAllow("cloudwatch:PutMetricData"),
+	"SESSendBouncePolicy":                   iamAllow("ses:SendBounce"),
+	"SESCrudPolicy":                         iamAllow("ses:GetIdentityVerificationAttributes", "ses:SendEmail", "ses:SendRawEmail", "ses:VerifyEmailIdentity"),
+}
+
+// iamAllow returns the statements of a policy that allows actions on any resource
+func iamAllow(actions ...string) []iamStatement {
+	return []iamStatement{{Effect: "Allow", Actions: actions, Resources: []string{"*"}}}
+}
+
+// functionPolicy returns the policy of a function's execution role: the role in its Role
+// property, if it's one of the template's AWS::IAM::Role resources, or else the role that
+// SAM creates from its Policies
+func functionPolicy(template *loader.Template, name string) *iamPolicy {
+
+	policy := &iamPolicy{}
+	properties, _ := template.Resource(name)["Properties"].(map[string]interface{})
+
+	if role, ok := properties["Role"]; ok && role != nil {
+		roleName := referencedResource(role)
+		if resource, _ := template.Resource(roleName)["Type"].(string); roleName == "" || resource != "AWS::IAM::Role" {
+			policy.unknown = append(policy.unknown, fmt.Sprintf("its Role %s", describeValue(role)))
+			return policy
+		}
+		policy.addRole(template, roleName)
+		return policy
+	}
+
+	// SAM gives every role it creates permission to write logs
+	policy.addManaged("AWSLambdaBasicExecutionRole")
+
+	policies := properties["Policies"]
+	if _, ok := policies.([]interface{}); !ok && policies != nil {
+		policies = []interface{}{policies}
+	}
+	list, _ := policies.([]interface{})
+
+	for _, p := range list {
+		switch p := p.(type) {
+		case string:
+			policy.addManaged(p)
+		case map[string]interface{}:
+			if _, ok := p["Statement"]; ok {
+				policy.statements = append(policy.statements, parseIAMStatements(p)...)
+				continue
+			}
+			for templateName := range p {
+				if statements, ok := iamPolicyTemplates[templateName]; ok {
+					policy.statements = append(policy.statements, statements...)
+				} else {
+					policy.unknown = append(policy.unknown, "the policy template "+templateName)
+				}
+			}
+		default:
+			policy.unknown = append(policy.unknown, "the policy "+describeValue(p))
+		}
+	}
+
+	return policy
+
+}
+
+// addRole adds the policies of one of the template's AWS::IAM::Role resources: its own, its
+// managed policies, and the AWS::IAM::Policy and AWS::IAM::ManagedPolicy resources that are
+// attached to it
+func (p *iamPolicy) addRole(template *loader.Template, roleName string) {
+
+	properties, _ := template.Resource(roleName)["Properties"].(map[string]interface{})
+
+	inline, _ := properties["Policies"].([]interface{})
+	for _, policy := range inline {
+		policy, _ := policy.(map[string]interface{})
+		p.statements = append(p.statements, parseIAMStatements(policy["PolicyDocument"])...)
+	}
+
+	managed, _ := properties["ManagedPolicyArns"].([]interface{})
+	for _, arn := range managed {
+		if name := referencedResource(arn); name != "" {
+			p.addPolicyResource(template, name)
+		} else if arn, ok := arn.(string); ok {
+			p.addManaged(arn)
+		} else {
+			p.unknown = append(p.unknown, "the managed policy "+describeValue(arn))
+		}
+	}
+
+	// Policies can also name the roles they're attached to
+	names := []string{}
+	for name := range template.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		resource := template.Resource(name)
+		if t, _ := resource["Type"].(string); t != "AWS::IAM::Policy" && t != "AWS::IAM::ManagedPolicy" {
+			continue
+		}
+		properties, _ := resource["Properties"].(map[string]interface{})
+		roles, _ := properties["Roles"].([]interface{})
+		for _, role := range roles {
+			if referencedResource(role) == roleName {
+				p.statements = append(p.statements, parseIAMStatements(properties["PolicyDocument"])...)
+			}
+		}
+	}
+
+}
+
+// addPolicyResource adds the statements of an AWS::IAM::ManagedPolicy resource
+func (p *iamPolicy) addPolicyResource(template *loader.Template, name string) {
+	properties, _ := template.Resource(name)["Properties"].(map[string]interface{})
+	if properties == nil {
+		p.unknown = append(p.unknown, "the managed policy "+name)
+		return
+	}
+	p.statements = append(p.statements, parseIAMStatements(properties["PolicyDocument"])...)
+}
+
+// addManaged adds the statements of an AWS managed policy, by name or ARN
+func (p *iamPolicy) addManaged(name string) {
+	short := name[strings.LastIndex(name, "/")+1:]
+	if statements, ok := iamManagedPolicies[short]; ok {
+		p.statements = append(p.statements, statements...)
+		return
+	}
+	p.unknown = append(p.unknown, "the managed policy "+name)
+}
+
+// parseIAMStatements returns the statements of a policy document. Resources that are
+// references to other resources in the template match any resource, as do the parts of
+// ARNs that are pseudo parameters (e.g. ${AWS::AccountId}).
+func parseIAMStatements(document interface{}) []iamStatement {
+
+	doc, _ := document.(map[string]interface{})
+	list, ok := doc["Statement"].([]interface{})
+	if !ok && doc["Statement"] != nil {
+		list = []interface{}{doc["Statement"]}
+	}
+
+	statements := []iamStatement{}
+	for _, s := range list {
+		s, _ := s.(map[string]interface{})
+		if s == nil {
+			continue
+		}
+
+		statement := iamStatement{
+			Effect:       fmt.Sprint(s["Effect"]),
+			Actions:      iamPatterns(s["Action"]),
+			NotActions:   iamPatterns(s["NotAction"]),
+			Resources:    iamPatterns(s["Resource"]),
+			NotResources: iamPatterns(s["NotResource"]),
+		}
+		if len(statement.Resources) == 0 && len(statement.NotResources) == 0 {
+			statement.Resources = []string{"*"}
+		}
+		statements = append(statements, statement)
+	}
+
+	return statements
+
+}
+
+// pseudoParameterEx matches the variables that are left in Fn::Sub strings
+var pseudoParameterEx = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// iamPatterns returns the patterns of an Action or Resource, which can be one or a list
+func iamPatterns(value interface{}) []string {
+
+	list, ok := value.([]interface{})
+	if !ok {
+		if value == nil {
+			return nil
+		}
+		list = []interface{}{value}
+	}
+
+	patterns := []string{}
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			patterns = append(patterns, pseudoParameterEx.ReplaceAllString(s, "*"))
+		} else {
+			patterns = append(patterns, "*")
+		}
+	}
+	return patterns
+
+}
+
+// referencedResource returns the resource that a Ref or Fn::GetAtt refers to, or ""
+func referencedResource(value interface{}) string {
+	m, _ := value.(map[string]interface{})
+	if ref, ok := m["Ref"].(string); ok {
+		return ref
+	}
+	if att, ok := m["Fn::GetAtt"].([]interface{}); ok && len(att) > 0 {
+		name, _ := att[0].(string)
+		return name
+	}
+	return ""
+}
+
+// describeValue returns a value of a template as it'd be written in JSON
+func describeValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// Allows returns whether the policy allows an action on a resource ("" if it isn't known):
+// none of its statements deny it, and one of them allows it, or there are policies that
+// SAM Local doesn't know, which might. Conditions aren't checked.
+func (p *iamPolicy) Allows(action string, resource string) bool {
+
+	allowed := len(p.unknown) > 0
+	for _, s := range p.statements {
+		if !s.matches(action, resource) {
+			continue
+		}
+		if s.Effect == "Deny" {
+			return false
+		}
+		if s.Effect == "Allow" {
+			allowed = true
+		}
+	}
+
+	return allowed
+
+}
+
+// matches returns whether a statement applies to an action on a resource
+func (s iamStatement) matches(action string, resource string) bool {
+
+	if len(s.NotActions) > 0 {
+		if matchAny(s.NotActions, action, true) {
+			return false
+		}
+	} else if !matchAny(s.Actions, action, true) {
+		return false
+	}
+
+	if resource == "" {
+		return true
+	}
+	if len(s.NotResources) > 0 {
+		return !matchAny(s.NotResources, resource, false)
+	}
+	return matchAny(s.Resources, resource, false)
+
+}
+
+// matchAny returns whether any of the patterns match a value. Actions are matched without
+// regard to case, as IAM does. Resources are matched as ARNs, but in any account, as SAM
+// Local doesn't know which one the function will be deployed to.
+func matchAny(patterns []string, value string, action bool) bool {
+	for _, pattern := range patterns {
+		if action && wildcardMatch(strings.ToLower(pattern), strings.ToLower(value)) {
+			return true
+		}
+		if !action && arnMatch(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// arnMatch returns whether an ARN pattern matches an ARN, in any account
+func arnMatch(pattern string, arn string) bool {
+
+	p, a := strings.SplitN(pattern, ":", 6), strings.SplitN(arn, ":", 6)
+	if len(p) != 6 || len(a) != 6 {
+		return wildcardMatch(pattern, arn)
+	}
+
+	for i := range p {
+		if i != 4 && !wildcardMatch(p[i], a[i]) {
+			return false
+		}
+	}
+	return true
+
+}
+
+// wildcardMatch returns whether a pattern with * (any characters) and ? (one character)
+// wildcards matches a value
+func wildcardMatch(pattern string, value string) bool {
+
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(value); i >= 0; i-- {
+				if wildcardMatch(pattern[1:], value[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(value) == 0 {
+				return false
+			}
+		default:
+			if len(value) == 0 || pattern[0] != value[0] {
+				return false
+			}
+		}
+		pattern, value = pattern[1:], value[1:]
+	}
+
+	return len(value) == 0
+
+}
+
+// awsRequest is an AWS API call that a function made
+type awsRequest struct {
+	// Service is the service's IAM prefix (e.g. dynamodb), and Region is where it was made,
+	// as signed by the SDK
+	Service string
+	Region  string
+
+	// Action is the name of the API operation (e.g. PutItem), or "" if it isn't known
+	Action string
+
+	// Resource is the ARN of the resource that the call acts on, or "" if it isn't known
+	Resource string
+
+	// Protocol is how the service's errors are written: json, query, rest-xml or rest-json
+	Protocol string
+}
+
+// iamServicePrefixes are the IAM prefixes of the services whose actions aren't prefixed with
+// the name they're signed with
+var iamServicePrefixes = map[string]string{
+	"monitoring": "cloudwatch",
+	"email":      "ses",
+}
+
+// IAMAction returns the IAM action of the call, e.g. dynamodb:PutItem
+func (req awsRequest) IAMAction() string {
+	prefix := req.Service
+	if p, ok := iamServicePrefixes[prefix]; ok {
+		prefix = p
+	}
+	return prefix + ":" + req.Action
+}
+
+// credentialScopeEx matches the credential scope of a Signature Version 4 signature
+var credentialScopeEx = regexp.MustCompile(`Credential=[^/,]+/[0-9]+/([^/]+)/([^/]+)/aws4_request`)
+
+// lambdaInvokeEx matches the path of Lambda's Invoke API
+var lambdaInvokeEx = regexp.MustCompile(`^/[0-9-]+/functions/([^/]+)/invocations$`)
+
+// parseAWSRequest works out which API call a request to an AWS endpoint is, from its
+// signature, and the protocol of its service
+func parseAWSRequest(r *http.Request, body []byte) awsRequest {
+
+	req := awsRequest{}
+
+	scope := r.Header.Get("Authorization")
+	if scope == "" {
+		scope = "Credential=" + r.URL.Query().Get("X-Amz-Credential")
+	}
+	if m := credentialScopeEx.FindStringSubmatch(scope); m != nil {
+		req.Region, req.Service = m[1], m[2]
+	}
+
+	params := map[string]interface{}{}
+	switch {
+	case r.Header.Get("X-Amz-Target") != "":
+		target := r.Header.Get("X-Amz-Target")
+		req.Action = target[strings.LastIndex(target, ".")+1:]
+		req.Protocol = "json"
+		json.Unmarshal(body, &params)
+
+	case req.Service == "s3":
+		req.Action, req.Resource = s3Action(r)
+		req.Protocol = "rest-xml"
+
+	case req.Service == "lambda":
+		req.Protocol = "rest-json"
+		if m := lambdaInvokeEx.FindStringSubmatch(r.URL.EscapedPath()); m != nil && r.Method == http.MethodPost {
+			req.Action = "InvokeFunction"
+			name, _ := url.PathUnescape(m[1])
+			params["FunctionName"] = name
+		}
+
+	default:
+		values := r.URL.Query()
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			values, _ = url.ParseQuery(string(body))
+		}
+		if action := values.Get("Action"); action != "" {
+			req.Action = action
+			req.Protocol = "query"
+			for name := range values {
+				params[name] = values.Get(name)
+			}
+		} else {
+			req.Protocol = "rest-json"
+		}
+	}
+
+	if req.Resource == "" {
+		req.Resource = resourceARN(req, params)
+	}
+	return req
+
+}
+
+// resourceARN works out the ARN of the resource that a call acts on from its parameters,
+// for the services whose resources are named in them, or returns ""
+func resourceARN(req awsRequest, params map[string]interface{}) string {
+
+	param := func(name string) string {
+		s, _ := params[name].(string)
+		return s
+	}
+
+	switch req.Service {
+	case "dynamodb":
+		if table := param("TableName"); table != "" {
+			return fmt.Sprintf("arn:aws:dynamodb:%s::table/%s", req.Region, table)
+		}
+	case "sqs":
+		if queue := param("QueueUrl"); queue != "" {
+			return fmt.Sprintf("arn:aws:sqs:%s::%s", req.Region, queue[strings.LastIndex(queue, "/")+1:])
+		}
+	case "sns":
+		if topic := param("TopicArn"); topic != "" {
+			return topic
+		}
+	case "lambda":
+		name := param("FunctionName")
+		if strings.HasPrefix(name, "arn:") {
+			return name
+		}
+		if name != "" {
+			return fmt.Sprintf("arn:aws:lambda:%s::function:%s", req.Region, name)
+		}
+	}
+
+	return ""
+
+}
+
+// s3Action returns the action and resource of a path-style call to S3's REST API
+func s3Action(r *http.Request) (string, string) {
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path == "" {
+		return "ListAllMyBuckets", ""
+	}
+
+	parts := strings.SplitN(path, "/", 2)
+	resource := "arn:aws:s3:::" + path
+	query := r.URL.Query()
+
+	// Calls on buckets
+	if len(parts) == 1 || parts[1] == "" {
+		resource = "arn:aws:s3:::" + parts[0]
+		switch {
+		case r.Method == http.MethodPost && hasQuery(query, "delete"):
+			return "DeleteObject", resource + "/*"
+		case r.Method == http.MethodGet && hasQuery(query, "location"):
+			return "GetBucketLocation", resource
+		case r.Method == http.MethodGet || r.Method == http.MethodHead:
+			return "ListBucket", resource
+		case r.Method == http.MethodPut:
+			return "CreateBucket", resource
+		case r.Method == http.MethodDelete:
+			return "DeleteBucket", resource
+		}
+		return "", resource
+	}
+
+	// Calls on objects
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if hasQuery(query, "acl") {
+			return "GetObjectAcl", resource
+		}
+		return "GetObject", resource
+	case http.MethodPut:
+		if hasQuery(query, "acl") {
+			return "PutObjectAcl", resource
+		}
+		return "PutObject", resource
+	case http.MethodPost:
+		// multipart uploads
+		return "PutObject", resource
+	case http.MethodDelete:
+		if hasQuery(query, "uploadId") {
+			return "AbortMultipartUpload", resource
+		}
+		return "DeleteObject", resource
+	}
+	return "", resource
+
+}
+
+// hasQuery returns whether a query string has a parameter, with or without a value
+func hasQuery(query url.Values, name string) bool {
+	_, ok := query[name]
+	return ok
+}
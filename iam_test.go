@@ -0,0 +1,198 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/loader"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// signedRequest returns a request signed (in name only) for a service in us-east-1
+func signedRequest(method string, target string, service string, body string) *http.Request {
+	r, _ := http.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKID/20240101/us-east-1/"+service+"/aws4_request, SignedHeaders=host, Signature=abc")
+	return r
+}
+
+var _ = Describe("IAM simulation", func() {
+
+	var template *loader.Template
+
+	BeforeEach(func() {
+		var err error
+		template, err = loader.Parse([]byte(`
+Resources:
+  Orders:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: nodejs6.10
+      Handler: index.handler
+      Policies:
+        - AmazonSQSReadOnlyAccess
+        - DynamoDBCrudPolicy:
+            TableName: !Ref Table
+        - Statement:
+            - Effect: Allow
+              Action: s3:GetObject
+              Resource: !Sub "arn:aws:s3:::${Bucket}/orders/*"
+            - Effect: Allow
+              Action: sns:Publish
+              Resource: arn:aws:sns:us-east-1:123456789012:orders
+  Custom:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: nodejs6.10
+      Handler: index.handler
+      Policies:
+        - MyCompanyPolicy
+  Admin:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: nodejs6.10
+      Handler: index.handler
+      Role: !GetAtt AdminRole.Arn
+  External:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: nodejs6.10
+      Handler: index.handler
+      Role: arn:aws:iam::123456789012:role/external
+  AdminRole:
+    Type: AWS::IAM::Role
+    Properties:
+      ManagedPolicyArns:
+        - arn:aws:iam::aws:policy/PowerUserAccess
+  AdminPolicy:
+    Type: AWS::IAM::Policy
+    Properties:
+      Roles:
+        - !Ref AdminRole
+      PolicyDocument:
+        Statement:
+          - Effect: Deny
+            Action: s3:DeleteObject
+            Resource: "*"
+  Table:
+    Type: AWS::DynamoDB::Table
+  Bucket:
+    Type: AWS::S3::Bucket
+`), loader.Options{})
+		Expect(err).To(BeNil())
+	})
+
+	Context("working out the policy of a function", func() {
+
+		It("adds its managed policies, policy templates and policy documents", func() {
+			policy := functionPolicy(template, "Orders")
+			Expect(policy.unknown).To(BeEmpty())
+
+			Expect(policy.Allows("logs:PutLogEvents", "")).To(BeTrue())
+			Expect(policy.Allows("sqs:GetQueueAttributes", "arn:aws:sqs:us-east-1::orders")).To(BeTrue())
+			Expect(policy.Allows("sqs:SendMessage", "arn:aws:sqs:us-east-1::orders")).To(BeFalse())
+			Expect(policy.Allows("dynamodb:PutItem", "arn:aws:dynamodb:us-east-1::table/anything")).To(BeTrue())
+			Expect(policy.Allows("dynamodb:DeleteTable", "arn:aws:dynamodb:us-east-1::table/anything")).To(BeFalse())
+		})
+
+		It("matches references to other resources with any resource", func() {
+			policy := functionPolicy(template, "Orders")
+			Expect(policy.Allows("s3:GetObject", "arn:aws:s3:::my-bucket/orders/1.json")).To(BeTrue())
+			Expect(policy.Allows("s3:GetObject", "arn:aws:s3:::my-bucket/invoices/1.json")).To(BeFalse())
+		})
+
+		It("matches ARNs in any account", func() {
+			policy := functionPolicy(template, "Orders")
+			Expect(policy.Allows("sns:Publish", "arn:aws:sns:us-east-1::orders")).To(BeTrue())
+			Expect(policy.Allows("sns:Publish", "arn:aws:sns:us-east-1::invoices")).To(BeFalse())
+		})
+
+		It("allows everything when there are policies it doesn't know", func() {
+			policy := functionPolicy(template, "Custom")
+			Expect(policy.unknown).To(ConsistOf("the managed policy MyCompanyPolicy"))
+			Expect(policy.Allows("dynamodb:DeleteTable", "")).To(BeTrue())
+		})
+
+		It("uses the policies of a role in the template, and those attached to it", func() {
+			policy := functionPolicy(template, "Admin")
+			Expect(policy.unknown).To(BeEmpty())
+			Expect(policy.Allows("dynamodb:DeleteTable", "")).To(BeTrue())
+			Expect(policy.Allows("iam:CreateUser", "")).To(BeFalse())
+			Expect(policy.Allows("s3:DeleteObject", "arn:aws:s3:::my-bucket/1.json")).To(BeFalse())
+			Expect(policy.Allows("logs:PutLogEvents", "")).To(BeTrue())
+		})
+
+		It("doesn't know the policies of roles outside the template", func() {
+			policy := functionPolicy(template, "External")
+			Expect(policy.unknown).To(HaveLen(1))
+			Expect(policy.Allows("iam:CreateUser", "")).To(BeTrue())
+		})
+
+	})
+
+	Context("matching wildcards", func() {
+
+		It("matches * and ?", func() {
+			Expect(wildcardMatch("s3:Get*", "s3:GetObject")).To(BeTrue())
+			Expect(wildcardMatch("s3:Get*", "s3:PutObject")).To(BeFalse())
+			Expect(wildcardMatch("table/?", "table/a")).To(BeTrue())
+			Expect(wildcardMatch("table/?", "table/ab")).To(BeFalse())
+		})
+
+		It("matches ARNs in any account", func() {
+			Expect(arnMatch("arn:aws:sqs:*:123456789012:orders", "arn:aws:sqs:us-east-1::orders")).To(BeTrue())
+			Expect(arnMatch("arn:aws:sqs:*:123456789012:orders", "arn:aws:sqs:us-east-1::invoices")).To(BeFalse())
+		})
+
+	})
+
+	Context("parsing AWS API calls", func() {
+
+		It("parses calls to JSON services", func() {
+			r := signedRequest("POST", "http://localhost/", "dynamodb", `{"TableName":"orders"}`)
+			r.Header.Set("X-Amz-Target", "DynamoDB_20120810.PutItem")
+			req := parseAWSRequest(r, []byte(`{"TableName":"orders"}`))
+			Expect(req.IAMAction()).To(Equal("dynamodb:PutItem"))
+			Expect(req.Resource).To(Equal("arn:aws:dynamodb:us-east-1::table/orders"))
+			Expect(req.Protocol).To(Equal("json"))
+		})
+
+		It("parses calls to query services", func() {
+			body := "Action=SendMessage&QueueUrl=https%3A%2F%2Fsqs.us-east-1.amazonaws.com%2F123456789012%2Forders&MessageBody=hi"
+			r := signedRequest("POST", "http://localhost/", "sqs", body)
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+			req := parseAWSRequest(r, []byte(body))
+			Expect(req.IAMAction()).To(Equal("sqs:SendMessage"))
+			Expect(req.Resource).To(Equal("arn:aws:sqs:us-east-1::orders"))
+			Expect(req.Protocol).To(Equal("query"))
+		})
+
+		It("parses calls to S3", func() {
+			req := parseAWSRequest(signedRequest("GET", "http://localhost/my-bucket/orders/1.json", "s3", ""), nil)
+			Expect(req.IAMAction()).To(Equal("s3:GetObject"))
+			Expect(req.Resource).To(Equal("arn:aws:s3:::my-bucket/orders/1.json"))
+			Expect(req.Protocol).To(Equal("rest-xml"))
+		})
+
+		It("parses invocations of Lambda functions", func() {
+			req := parseAWSRequest(signedRequest("POST", "http://localhost/2015-03-31/functions/Orders/invocations", "lambda", "{}"), []byte("{}"))
+			Expect(req.IAMAction()).To(Equal("lambda:InvokeFunction"))
+			Expect(req.Resource).To(Equal("arn:aws:lambda:us-east-1::function:Orders"))
+		})
+
+		It("uses the IAM prefix of services signed with another name", func() {
+			body := "Action=PutMetricData&Namespace=orders"
+			r := signedRequest("POST", "http://localhost/", "monitoring", body)
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			Expect(parseAWSRequest(r, []byte(body)).IAMAction()).To(Equal("cloudwatch:PutMetricData"))
+		})
+
+		It("doesn't parse unsigned requests", func() {
+			r, _ := http.NewRequest("GET", "http://localhost/", nil)
+			Expect(parseAWSRequest(r, nil).Service).To(Equal(""))
+		})
+
+	})
+
+})
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/awslabs/aws-sam-local/loader"
+)
+
+// iamProxy is the AWS endpoint of a function with --simulate-iam. It checks the function's
+// AWS API calls against the policies of its execution role in the template, and answers the
+// ones the deployed function wouldn't be allowed to make with AccessDenied, as AWS would.
+// The others are signed again, with SAM Local's credentials, and sent on to AWS, or to the
+// service's --aws-endpoint-url. It only accepts calls signed with the dummy credentials
+// that the function is given, as anything else could make calls with yours through it.
+type iamProxy struct {
+	*awsForwarder
+
+	function string
+	policy   *iamPolicy
+
+	// endpoints and credentials are what the function is given, to call AWS through the proxy
+	endpoints   map[string]string
+	credentials credentials.Value
+}
+
+// startIAMProxies starts an iamProxy for each function, by function. Proxies only listen
+// on the addresses that functions reach them by (see listenForFunctions).
+func startIAMProxies(template *loader.Template, names []string, upstreams map[string]string, profile string, backend string) (map[string]*iamProxy, error) {
+
+	forwarder := newAWSForwarder(upstreams, profile)
+
+	proxies := map[string]*iamProxy{}
+	for _, name := range names {
+
+		creds, err := newDummyCredentials()
+		if err != nil {
+			return nil, fmt.Errorf("could not start the IAM simulation of %s: %s", name, err)
+		}
+
+		proxy := &iamProxy{
+			awsForwarder: forwarder,
+			function:     name,
+			policy:       functionPolicy(template, name),
+			credentials:  creds,
+		}
+
+		for _, unknown := range proxy.policy.unknown {
+			warnMsg.Printf("Simulating IAM for %s: SAM Local doesn't know what %s allows, so it allows everything\n", name, unknown)
+		}
+
+		listeners, port, err := listenForFunctions(backend)
+		if err != nil {
+			return nil, fmt.Errorf("could not start the IAM simulation of %s: %s", name, err)
+		}
+		serveForFunctions(listeners, proxy)
+
+		proxy.endpoints = map[string]string{"": fmt.Sprintf("http://localhost:%d", port)}
+		proxies[name] = proxy
+	}
+
+	return proxies, nil
+
+}
+
+// newDummyCredentials returns random credentials, which only SAM Local's own endpoints accept
+func newDummyCredentials() (credentials.Value, error) {
+
+	key := make([]byte, 8)
+	secret := make([]byte, 30)
+	if _, err := rand.Read(key); err != nil {
+		return credentials.Value{}, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return credentials.Value{}, err
+	}
+
+	return credentials.Value{
+		AccessKeyID:     "SAMLOCAL" + strings.ToUpper(hex.EncodeToString(key)),
+		SecretAccessKey: base64.StdEncoding.EncodeToString(secret),
+	}, nil
+
+}
+
+// ServeHTTP implements http.Handler
+func (p *iamProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := parseAWSRequest(r, body)
+	switch {
+	case req.Service == "":
+		log.Printf("Simulating IAM for %s: %s %s isn't a signed AWS API call\n", p.function, r.Method, r.URL.Path)
+		http.Error(w, "SAM Local's IAM simulation only accepts signed AWS API calls", http.StatusBadRequest)
+		return
+	case p.callers().verify(r, body) != nil:
+		warnMsg.Printf("Simulating IAM for %s: rejected %s %s, as it wasn't signed with the function's credentials\n", p.function, r.Method, r.URL.Path)
+		http.Error(w, "SAM Local's IAM simulation only accepts calls signed with the credentials it gave the function", http.StatusForbidden)
+		return
+	case req.Action == "":
+		log.Printf("Simulating IAM for %s: not checking %s %s, as SAM Local doesn't know which %s action it is\n", p.function, r.Method, r.URL.Path, req.Service)
+	case req.IAMAction() == "sts:GetCallerIdentity":
+		// needs no permissions
+	case !p.policy.Allows(req.IAMAction(), req.Resource):
+		resource := req.Resource
+		if resource == "" {
+			resource = "*"
+		}
+		warnMsg.Printf("Simulating IAM for %s: denied %s on %s, as none of its policies allow it\n", p.function, req.IAMAction(), resource)
+		writeAccessDenied(w, req, fmt.Sprintf("User: arn:aws:sts::123456789012:assumed-role/%sRole/%s is not authorized to perform: %s on resource: %s (denied by SAM Local's IAM simulation)", p.function, p.function, req.IAMAction(), resource))
+		return
+	}
+
+	p.forward(w, r, req.Service, req.Region, p.upstream(req.Service), body)
+
+}
+
+// callers are the credentials that the proxy accepts calls signed with: the function's
+func (p *iamProxy) callers() awsCallers {
+	return awsCallers{p.credentials.AccessKeyID: p.credentials.SecretAccessKey}
+}
+
+// writeAccessDenied writes the AccessDenied error of a call, the way its service's protocol
+// writes errors, so the SDK raises it as it would for AWS
+func writeAccessDenied(w http.ResponseWriter, req awsRequest, message string) {
+
+	switch req.Protocol {
+	case "json":
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Header().Set("X-Amzn-ErrorType", "AccessDeniedException")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"__type": "AccessDeniedException", "message": message})
+
+	case "query":
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code><Message>%s</Message></Error></ErrorResponse>", xmlEscape(message))
+
+	case "rest-xml":
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "<Error><Code>AccessDenied</Code><Message>%s</Message></Error>", xmlEscape(message))
+
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-ErrorType", "AccessDeniedException")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"Type": "User", "message": message})
+	}
+
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IAM simulation proxy", func() {
+
+	var upstream *httptest.Server
+	var received *http.Request
+	var proxy *iamProxy
+
+	BeforeEach(func() {
+		received = nil
+		upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			w.Write([]byte(`{}`))
+		}))
+
+		proxy = &iamProxy{
+			awsForwarder: &awsForwarder{
+				upstreams: map[string]string{"": upstream.URL},
+				signer:    v4.NewSigner(credentials.NewStaticCredentials("localkey", "localsecret", "")),
+				client:    http.DefaultClient,
+			},
+			function:    "Orders",
+			policy:      &iamPolicy{statements: iamAllow("dynamodb:GetItem")},
+			credentials: credentials.Value{AccessKeyID: "SAMLOCALKEY", SecretAccessKey: "functionsecret"},
+		}
+	})
+
+	AfterEach(func() {
+		upstream.Close()
+	})
+
+	// functionRequest returns a request signed with the function's credentials, as its SDK would
+	functionRequest := func(method string, target string, service string, body string, header http.Header) *http.Request {
+		r, _ := http.NewRequest(method, target, strings.NewReader(body))
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		signer := v4.NewSigner(credentials.NewStaticCredentials("SAMLOCALKEY", "functionsecret", ""))
+		signer.DisableURIPathEscaping = service == "s3"
+		signer.Sign(r, strings.NewReader(body), service, "us-east-1", time.Now())
+		return r
+	}
+
+	call := func(action string) *httptest.ResponseRecorder {
+		r := functionRequest("POST", "http://localhost/", "dynamodb", `{"TableName":"orders"}`, http.Header{"X-Amz-Target": {"DynamoDB_20120810." + action}})
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, r)
+		return w
+	}
+
+	It("sends allowed calls on, signed with its own credentials", func() {
+		w := call("GetItem")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(received).NotTo(BeNil())
+		Expect(received.Header.Get("X-Amz-Target")).To(Equal("DynamoDB_20120810.GetItem"))
+		Expect(received.Header.Get("Authorization")).To(ContainSubstring("Credential=localkey/"))
+		Expect(received.Header.Get("Authorization")).To(ContainSubstring("/us-east-1/dynamodb/aws4_request"))
+	})
+
+	It("denies calls the function isn't allowed to make", func() {
+		w := call("DeleteTable")
+		Expect(received).To(BeNil())
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+		response := map[string]string{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		Expect(response["__type"]).To(Equal("AccessDeniedException"))
+		Expect(response["message"]).To(ContainSubstring("not authorized to perform: dynamodb:DeleteTable on resource: arn:aws:dynamodb:us-east-1::table/orders"))
+	})
+
+	It("denies calls to S3 with an XML error", func() {
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, functionRequest("DELETE", "http://localhost/my-bucket/1.json", "s3", "", nil))
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		body, _ := ioutil.ReadAll(w.Body)
+		Expect(string(body)).To(HavePrefix("<Error><Code>AccessDenied</Code>"))
+	})
+
+	It("rejects calls that weren't signed with the function's credentials", func() {
+		r := signedRequest("POST", "http://localhost/", "dynamodb", `{"TableName":"orders"}`)
+		r.Header.Set("X-Amz-Target", "DynamoDB_20120810.GetItem")
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(received).To(BeNil())
+
+		r = functionRequest("POST", "http://localhost/", "dynamodb", `{"TableName":"orders"}`, http.Header{"X-Amz-Target": {"DynamoDB_20120810.GetItem"}})
+		r.Header.Set("X-Amz-Target", "DynamoDB_20120810.DeleteTable")
+		w = httptest.NewRecorder()
+		proxy.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(received).To(BeNil())
+	})
+
+	It("accepts calls to escaped paths", func() {
+		proxy.policy = &iamPolicy{statements: iamAllow("lambda:InvokeFunction")}
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, functionRequest("POST", "http://localhost/2015-03-31/functions/arn%3Aaws%3Alambda%3Aus-east-1%3A123456789012%3Afunction%3AOrders/invocations", "lambda", "{}", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(received).NotTo(BeNil())
+	})
+
+	It("only listens on the loopback interface for functions that don't run in containers", func() {
+		listeners, port, err := listenForFunctions("native")
+		Expect(err).To(BeNil())
+		defer listeners[0].Close()
+		Expect(listeners).To(HaveLen(1))
+		Expect(listeners[0].Addr().String()).To(Equal(fmt.Sprintf("127.0.0.1:%d", port)))
+	})
+
+	It("rejects requests that aren't signed", func() {
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/", strings.NewReader("")))
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+		Expect(received).To(BeNil())
+	})
+
+})
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/goformation/intrinsics"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/term"
+	yamlwrapper "github.com/sanathkr/yaml"
+	"golang.org/x/net/context"
+)
+
+// imageFunction is a function packaged as a container image, built from a Dockerfile
+// described in its Metadata:
+//
+//	Metadata:
+//	  Dockerfile: Dockerfile
+//	  DockerContext: ./hello
+//	  DockerTag: v1
+type imageFunction struct {
+	LogicalID  string
+	Dockerfile string
+	Context    string
+	Tag        string
+	BuildArgs  map[string]*string
+
+	// properties is the Properties (AWS::Serverless::Function) or Code (AWS::Lambda::Function)
+	// object in the raw template that the ImageUri is written to
+	properties map[string]interface{}
+}
+
+// imagePackageOptions are the options of 'sam package' for container image functions.
+// They are removed from the arguments passed on to 'aws cloudformation package'.
+type imagePackageOptions struct {
+	Repository       string
+	CreateRepository bool
+}
+
+// parseImagePackageArgs extracts the image options, the template file and the AWS CLI
+// profile and region from the arguments of 'sam package'. The returned arguments are the
+// ones to pass on to 'aws cloudformation package'.
+func parseImagePackageArgs(args []string) (rest []string, opt *imagePackageOptions, template string, aws *awsCLI) {
+
+	opt = &imagePackageOptions{}
+	aws = &awsCLI{}
+
+	for i := 0; i < len(args); i++ {
+
+		name, value, hasValue := args[i], "", false
+		if parts := strings.SplitN(args[i], "=", 2); len(parts) == 2 {
+			name, value, hasValue = parts[0], parts[1], true
+		} else if i+1 < len(args) {
+			value = args[i+1]
+		}
+
+		// Consumes the value of the current option, when it was passed as the next argument
+		next := func() string {
+			if !hasValue {
+				i++
+			}
+			return value
+		}
+
+		switch name {
+		case "--image-repository":
+			opt.Repository = next()
+			continue
+		case "--create-image-repository":
+			opt.CreateRepository = true
+			continue
+		case "--template-file":
+			template = value
+		case "--profile":
+			aws.Profile = value
+		}
+
+		rest = append(rest, args[i])
+
+	}
+
+	return rest, opt, template, aws
+
+}
+
+// packageImages builds the container image functions in the template, pushes them to ECR
+// and writes a copy of the template with their ImageUris set. It returns the arguments
+// for 'aws cloudformation package', pointing to the new template, and a function to remove
+// it once packaging is done. Templates without image functions are left alone.
+func packageImages(args []string) ([]string, func(), error) {
+
+	done := func() {}
+
+	args, opt, filename, aws := parseImagePackageArgs(args)
+	if filename == "" {
+		return args, done, nil
+	}
+
+	template, err := readRawTemplate(filename)
+	if err != nil {
+		return nil, done, err
+	}
+
+	functions, err := findImageFunctions(template, filepath.Dir(filename))
+	if err != nil {
+		return nil, done, err
+	}
+
+	if len(functions) == 0 {
+		return args, done, nil
+	}
+
+	if opt.Repository == "" {
+		return nil, done, fmt.Errorf("the template contains container image functions, so an ECR repository must be provided with --image-repository")
+	}
+
+	registry, repository, region, err := parseECRRepository(opt.Repository)
+	if err != nil {
+		return nil, done, err
+	}
+	aws.Region = region
+
+	if opt.CreateRepository {
+		if err := ensureECRRepository(aws, repository); err != nil {
+			return nil, done, err
+		}
+	}
+
+	password, err := aws.Run("ecr", "get-login-password")
+	if err != nil {
+		return nil, done, fmt.Errorf("could not log in to ECR: %s", err)
+	}
+
+	auth, _ := json.Marshal(types.AuthConfig{
+		Username:      "AWS",
+		Password:      strings.TrimSpace(password),
+		ServerAddress: registry,
+	})
+
+	cli, err := invoker.DockerClient()
+	if err != nil {
+		return nil, done, err
+	}
+
+	for _, function := range functions {
+
+		image := fmt.Sprintf("%s:%s-%s", opt.Repository, strings.ToLower(function.LogicalID), function.Tag)
+
+		log.Printf("Building %s from %s\n", image, filepath.Join(function.Context, function.Dockerfile))
+		if err := buildImage(cli, function, image); err != nil {
+			return nil, done, fmt.Errorf("could not build %s: %s", function.LogicalID, err)
+		}
+
+		log.Printf("Pushing %s\n", image)
+		if err := pushImage(cli, image, base64.URLEncoding.EncodeToString(auth)); err != nil {
+			return nil, done, fmt.Errorf("could not push %s: %s", function.LogicalID, err)
+		}
+
+		function.properties["ImageUri"] = image
+
+	}
+
+	// Write the template next to the original, so relative CodeUris still resolve
+	data, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return nil, done, err
+	}
+
+	packaged, err := ioutil.TempFile(filepath.Dir(filename), ".aws-sam-local-package-")
+	if err != nil {
+		return nil, done, err
+	}
+	packaged.Write(data)
+	packaged.Close()
+
+	done = func() {
+		os.Remove(packaged.Name())
+	}
+
+	for i, arg := range args {
+		if arg == "--template-file" && i+1 < len(args) {
+			args[i+1] = packaged.Name()
+		} else if strings.HasPrefix(arg, "--template-file=") {
+			args[i] = "--template-file=" + packaged.Name()
+		}
+	}
+
+	return args, done, nil
+
+}
+
+// readRawTemplate reads a JSON or YAML template without resolving any intrinsic functions.
+// Short form intrinsic functions in YAML (e.g. !Ref) are converted to their long form.
+func readRawTemplate(filename string) (map[string]interface{}, error) {
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := parseRawTemplate(data, strings.HasSuffix(filename, ".yaml") || strings.HasSuffix(filename, ".yml"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %s", filename, err)
+	}
+
+	return template, nil
+
+}
+
+// parseRawTemplate parses a JSON or YAML template without GoFormation
+func parseRawTemplate(data []byte, isYAML bool) (map[string]interface{}, error) {
+
+	if isYAML {
+		// Processing the template registers the YAML tags for short form intrinsic functions
+		if _, err := intrinsics.ProcessYAML(data, nil); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = yamlwrapper.YAMLToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	template := map[string]interface{}{}
+	if err := json.Unmarshal(data, &template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+
+}
+
+// findImageFunctions finds the functions in a raw template that are packaged as container
+// images and have a Dockerfile to build them from
+func findImageFunctions(template map[string]interface{}, dir string) ([]*imageFunction, error) {
+
+	functions := []*imageFunction{}
+
+	for name, resource := range getMap(template["Resources"]) {
+
+		resource := getMap(resource)
+		properties := getMap(resource["Properties"])
+		metadata := getMap(resource["Metadata"])
+
+		if properties["PackageType"] != "Image" || metadata["DockerContext"] == nil {
+			continue
+		}
+
+		// AWS::Lambda::Function keeps its ImageUri in Code
+		target := properties
+		if resource["Type"] == "AWS::Lambda::Function" {
+			if _, ok := properties["Code"].(map[string]interface{}); !ok {
+				properties["Code"] = map[string]interface{}{}
+			}
+			target = getMap(properties["Code"])
+		} else if resource["Type"] != "AWS::Serverless::Function" {
+			continue
+		}
+
+		context, _ := metadata["DockerContext"].(string)
+		function := &imageFunction{
+			LogicalID:  name,
+			Dockerfile: "Dockerfile",
+			Context:    filepath.Join(dir, context),
+			Tag:        "latest",
+			BuildArgs:  map[string]*string{},
+			properties: target,
+		}
+
+		if dockerfile, ok := metadata["Dockerfile"].(string); ok && dockerfile != "" {
+			function.Dockerfile = dockerfile
+		}
+
+		if tag, ok := metadata["DockerTag"].(string); ok && tag != "" {
+			function.Tag = tag
+		}
+
+		for arg, value := range getMap(metadata["DockerBuildArgs"]) {
+			value := fmt.Sprintf("%v", value)
+			function.BuildArgs[arg] = &value
+		}
+
+		if _, err := os.Stat(filepath.Join(function.Context, function.Dockerfile)); err != nil {
+			return nil, fmt.Errorf("could not find the Dockerfile of %s: %s", name, err)
+		}
+
+		functions = append(functions, function)
+
+	}
+
+	sort.Sort(byImageLogicalID(functions))
+	return functions, nil
+
+}
+
+// parseECRRepository splits an ECR repository URI (e.g.
+// 123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app) into its registry,
+// repository name and region
+func parseECRRepository(uri string) (registry string, repository string, region string, err error) {
+
+	parts := strings.SplitN(uri, "/", 2)
+	hostParts := strings.Split(parts[0], ".")
+
+	if len(parts) != 2 || len(hostParts) < 6 || hostParts[1] != "dkr" || hostParts[2] != "ecr" {
+		return "", "", "", fmt.Errorf("invalid ECR repository '%s' (expected e.g. 123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app)", uri)
+	}
+
+	return parts[0], parts[1], hostParts[3], nil
+
+}
+
+// ensureECRRepository creates an ECR repository if it doesn't exist
+func ensureECRRepository(aws *awsCLI, repository string) error {
+
+	if _, err := aws.Run("ecr", "describe-repositories", "--repository-names", repository); err == nil {
+		return nil
+	}
+
+	log.Printf("Creating ECR repository %s\n", repository)
+	if _, err := aws.Run("ecr", "create-repository", "--repository-name", repository); err != nil {
+		return fmt.Errorf("could not create ECR repository %s: %s", repository, err)
+	}
+
+	return nil
+
+}
+
+// buildImage builds the image of a function, showing Docker's build output
+func buildImage(cli *client.Client, function *imageFunction, image string) error {
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeBuildContext(writer, function.Context))
+	}()
+
+	resp, err := cli.ImageBuild(context.Background(), reader, types.ImageBuildOptions{
+		Tags:       []string{image},
+		Dockerfile: function.Dockerfile,
+		BuildArgs:  function.BuildArgs,
+		Remove:     true,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return jsonmessage.DisplayJSONMessagesStream(resp.Body, os.Stderr, os.Stderr.Fd(), term.IsTerminal(os.Stderr.Fd()), nil)
+
+}
+
+// pushImage pushes an image to ECR, showing Docker's push progress
+func pushImage(cli *client.Client, image string, auth string) error {
+
+	progress, err := cli.ImagePush(context.Background(), image, types.ImagePushOptions{RegistryAuth: auth})
+	if err != nil {
+		return err
+	}
+	defer progress.Close()
+
+	return jsonmessage.DisplayJSONMessagesStream(progress, os.Stderr, os.Stderr.Fd(), term.IsTerminal(os.Stderr.Fd()), nil)
+
+}
+
+// writeBuildContext writes a directory as a tar archive for 'docker build'.
+// Files matching the patterns in the directory's .dockerignore are left out.
+func writeBuildContext(w io.Writer, dir string) error {
+
+	ignored := readDockerignore(filepath.Join(dir, ".dockerignore"))
+	archive := tar.NewWriter(w)
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(dir, file)
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if isDockerignored(ignored, rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(archive, f)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+
+}
+
+// readDockerignore reads the patterns in a .dockerignore file
+func readDockerignore(filename string) []string {
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return []string{}
+	}
+	defer f.Close()
+
+	patterns := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, strings.TrimPrefix(filepath.ToSlash(filepath.Clean(line)), "/"))
+	}
+
+	return patterns
+
+}
+
+// isDockerignored checks whether a path (relative to the build context) matches any of the
+// .dockerignore patterns. Patterns starting with ! re-include paths excluded by earlier ones.
+func isDockerignored(patterns []string, path string) bool {
+
+	ignored := false
+	for _, pattern := range patterns {
+
+		exclude := !strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		if matched, _ := filepath.Match(pattern, path); matched {
+			ignored = exclude
+		}
+
+	}
+
+	return ignored
+
+}
+
+// byImageLogicalID sorts image functions by their logical ID
+type byImageLogicalID []*imageFunction
+
+func (f byImageLogicalID) Len() int           { return len(f) }
+func (f byImageLogicalID) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+func (f byImageLogicalID) Less(i, j int) bool { return f[i].LogicalID < f[j].LogicalID }
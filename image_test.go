@@ -0,0 +1,149 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Image packaging", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "image")
+		os.MkdirAll(filepath.Join(dir, "hello", "node_modules"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "hello", "Dockerfile"), []byte("FROM scratch"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "hello", "app.js"), []byte("exports.handler = () => {}"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "hello", "debug.log"), []byte("log"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "hello", "keep.log"), []byte("log"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "hello", "node_modules", "dep.js"), []byte(""), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "hello", ".dockerignore"), []byte("# comment\nnode_modules\n*.log\n!keep.log\n"), 0644)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("removes the image options from the package arguments", func() {
+		args, opt, template, aws := parseImagePackageArgs([]string{
+			"--template-file", "sam.yaml",
+			"--image-repository=123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app",
+			"--create-image-repository",
+			"--profile", "dev",
+			"--s3-bucket", "bucket",
+		})
+		Expect(args).To(Equal([]string{"--template-file", "sam.yaml", "--profile", "dev", "--s3-bucket", "bucket"}))
+		Expect(opt.Repository).To(Equal("123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app"))
+		Expect(opt.CreateRepository).To(BeTrue())
+		Expect(template).To(Equal("sam.yaml"))
+		Expect(aws.Profile).To(Equal("dev"))
+	})
+
+	It("parses ECR repository URIs", func() {
+		registry, repository, region, err := parseECRRepository("123456789012.dkr.ecr.eu-west-1.amazonaws.com/team/my-app")
+		Expect(err).To(BeNil())
+		Expect(registry).To(Equal("123456789012.dkr.ecr.eu-west-1.amazonaws.com"))
+		Expect(repository).To(Equal("team/my-app"))
+		Expect(region).To(Equal("eu-west-1"))
+
+		_, _, _, err = parseECRRepository("docker.io/my-app")
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("finds image functions in YAML templates and sets their ImageUri", func() {
+		filename := filepath.Join(dir, "template.yaml")
+		ioutil.WriteFile(filename, []byte(`
+Resources:
+  Hello:
+    Type: AWS::Serverless::Function
+    Properties:
+      PackageType: Image
+      Environment:
+        Variables:
+          TABLE: !Ref Table
+    Metadata:
+      DockerContext: ./hello
+      DockerTag: v1
+      DockerBuildArgs:
+        NODE_ENV: production
+  Zip:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: nodejs6.10
+      Handler: index.handler
+  Raw:
+    Type: AWS::Lambda::Function
+    Properties:
+      PackageType: Image
+    Metadata:
+      DockerContext: hello
+  Table:
+    Type: AWS::Serverless::SimpleTable
+`), 0644)
+
+		template, err := readRawTemplate(filename)
+		Expect(err).To(BeNil())
+
+		functions, err := findImageFunctions(template, dir)
+		Expect(err).To(BeNil())
+		Expect(functions).To(HaveLen(2))
+
+		Expect(functions[0].LogicalID).To(Equal("Hello"))
+		Expect(functions[0].Context).To(Equal(filepath.Join(dir, "hello")))
+		Expect(functions[0].Dockerfile).To(Equal("Dockerfile"))
+		Expect(functions[0].Tag).To(Equal("v1"))
+		Expect(*functions[0].BuildArgs["NODE_ENV"]).To(Equal("production"))
+		Expect(functions[1].LogicalID).To(Equal("Raw"))
+		Expect(functions[1].Tag).To(Equal("latest"))
+
+		functions[0].properties["ImageUri"] = "repo:hello-v1"
+		functions[1].properties["ImageUri"] = "repo:raw-latest"
+
+		resources := getMap(template["Resources"])
+		hello := getMap(getMap(resources["Hello"])["Properties"])
+		Expect(hello["ImageUri"]).To(Equal("repo:hello-v1"))
+		Expect(getMap(getMap(hello["Environment"])["Variables"])["TABLE"]).To(Equal(map[string]interface{}{"Ref": "Table"}))
+		raw := getMap(getMap(resources["Raw"])["Properties"])
+		Expect(getMap(raw["Code"])["ImageUri"]).To(Equal("repo:raw-latest"))
+	})
+
+	It("fails when a Dockerfile is missing", func() {
+		template := map[string]interface{}{
+			"Resources": map[string]interface{}{
+				"Hello": map[string]interface{}{
+					"Type":       "AWS::Serverless::Function",
+					"Properties": map[string]interface{}{"PackageType": "Image"},
+					"Metadata":   map[string]interface{}{"DockerContext": "hello", "Dockerfile": "Missing.Dockerfile"},
+				},
+			},
+		}
+		_, err := findImageFunctions(template, dir)
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("leaves files in .dockerignore out of the build context", func() {
+		var buf bytes.Buffer
+		Expect(writeBuildContext(&buf, filepath.Join(dir, "hello"))).To(BeNil())
+
+		names := []string{}
+		archive := tar.NewReader(&buf)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).To(BeNil())
+			names = append(names, header.Name)
+		}
+
+		Expect(names).To(ConsistOf(".dockerignore", "Dockerfile", "app.js", "keep.log"))
+	})
+
+})
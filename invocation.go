@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// Invocation types supported by the Lambda Invoke API
+const (
+	invocationTypeRequestResponse = "RequestResponse"
+	invocationTypeEvent           = "Event"
+	invocationTypeDryRun          = "DryRun"
+)
+
+// Log types supported by the Lambda Invoke API
+const (
+	logTypeNone = "None"
+	logTypeTail = "Tail"
+)
+
+// Exit codes of 'sam local invoke', so scripts can tell why an invocation failed.
+// An exit code of 1 is used when SAM Local itself fails.
+const (
+	exitCodeHandledError = 2
+	exitCodeCrash        = 3
+	exitCodeTimeout      = 4
+	exitCodeCancelled    = 130
+)
+
+// exitCode returns the exit code of 'sam local invoke' for the outcome of an invocation
+func exitCode(outcome invoker.Outcome) int {
+	switch outcome {
+	case invoker.OutcomeHandledError:
+		return exitCodeHandledError
+	case invoker.OutcomeCrash:
+		return exitCodeCrash
+	case invoker.OutcomeTimeout:
+		return exitCodeTimeout
+	case invoker.OutcomeCancelled:
+		return exitCodeCancelled
+	}
+	return 0
+}
+
+// logTailSize is the amount of logs returned by the Lambda Invoke API with --log-type Tail
+const logTailSize = 4096
+
+// invokeMetadata matches the output of 'aws lambda invoke', so that scripts
+// written against the Lambda API can be run against SAM Local unchanged
+type invokeMetadata struct {
+	StatusCode      int    `json:"StatusCode"`
+	FunctionError   string `json:"FunctionError,omitempty"`
+	LogResult       string `json:"LogResult,omitempty"`
+	ExecutedVersion string `json:"ExecutedVersion,omitempty"`
+}
+
+// writeInvokeMetadata writes the invocation metadata as indented JSON
+func writeInvokeMetadata(w io.Writer, meta *invokeMetadata) {
+	data, _ := json.MarshalIndent(meta, "", "    ")
+	fmt.Fprintf(w, "%s\n", data)
+}
+
+// logTail is an io.Writer that keeps only the last max bytes written to it
+type logTail struct {
+	max  int
+	data []byte
+}
+
+// Write implements io.Writer
+func (t *logTail) Write(p []byte) (int, error) {
+	t.data = append(t.data, p...)
+	if len(t.data) > t.max {
+		t.data = t.data[len(t.data)-t.max:]
+	}
+	return len(p), nil
+}
+
+// Base64 returns the kept logs, base64 encoded
+func (t *logTail) Base64() string {
+	return base64.StdEncoding.EncodeToString(t.data)
+}
+
+// checkQualifier checks that a function version or alias passed with --qualifier
+// exists locally. Only $LATEST and the function's AutoPublishAlias are available.
+func checkQualifier(template *cloudformation.Template, name string, qualifier string) error {
+
+	if qualifier == "" || qualifier == "$LATEST" {
+		return nil
+	}
+
+	if alias, ok := getResourceProperty(template, name, "AutoPublishAlias"); ok && alias == qualifier {
+		return nil
+	}
+
+	return fmt.Errorf("function %s has no version or alias '%s' (only $LATEST and the AutoPublishAlias are available locally)", name, qualifier)
+
+}
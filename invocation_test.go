@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/goformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Invocation options", func() {
+
+	Context("with a log tail", func() {
+
+		It("keeps only the end of the logs", func() {
+			t := &logTail{max: 4}
+			t.Write([]byte("abc"))
+			t.Write([]byte("defg"))
+			Expect(t.Base64()).To(Equal(base64.StdEncoding.EncodeToString([]byte("defg"))))
+		})
+
+		It("keeps everything when the logs are short", func() {
+			t := &logTail{max: logTailSize}
+			t.Write([]byte(strings.Repeat("x", 10)))
+			Expect(t.Base64()).To(Equal(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 10)))))
+		})
+
+	})
+
+	Context("with a failed invocation", func() {
+
+		It("uses a distinct exit code for each kind of failure", func() {
+			Expect(exitCode(invoker.OutcomeSuccess)).To(Equal(0))
+			Expect(exitCode(invoker.OutcomeHandledError)).To(Equal(2))
+			Expect(exitCode(invoker.OutcomeCrash)).To(Equal(3))
+			Expect(exitCode(invoker.OutcomeTimeout)).To(Equal(4))
+			Expect(exitCode(invoker.OutcomeCancelled)).To(Equal(130))
+		})
+
+	})
+
+	Context("with a qualifier", func() {
+
+		template, _ := goformation.ParseJSON([]byte(`{
+			"Resources": {
+				"Aliased": {
+					"Type": "AWS::Serverless::Function",
+					"Properties": { "Runtime": "nodejs6.10", "Handler": "index.handler", "AutoPublishAlias": "live" }
+				}
+			}
+		}`))
+
+		It("accepts $LATEST", func() {
+			Expect(checkQualifier(template, "Aliased", "$LATEST")).To(BeNil())
+		})
+
+		It("accepts the AutoPublishAlias", func() {
+			Expect(checkQualifier(template, "Aliased", "live")).To(BeNil())
+		})
+
+		It("rejects unknown versions and aliases", func() {
+			Expect(checkQualifier(template, "Aliased", "staging")).ToNot(BeNil())
+			Expect(checkQualifier(template, "Aliased", "3")).ToNot(BeNil())
+		})
+
+	})
+
+})
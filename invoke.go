@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"os"
@@ -11,9 +12,12 @@ import (
 	"io"
 	"sync"
 
-	"github.com/awslabs/goformation"
-	"github.com/awslabs/goformation/intrinsics"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/aws-sam-local/tracing"
 	"github.com/codegangsta/cli"
+	"golang.org/x/net/context"
 )
 
 func invoke(c *cli.Context) {
@@ -33,15 +37,21 @@ func invoke(c *cli.Context) {
 		}
 	}
 
+	logger := newLogger(c)
+
 	filename := getTemplateFilename(c.String("template"))
-	template, err := goformation.OpenWithOptions(filename, &intrinsics.ProcessorOptions{
-		ParameterOverrides: parseParameters(c.String("parameter-values")),
-	})
+	loaded := loadTemplate(c, filename, logger)
+	template := loaded.Template
+
+	hooks, err := loadPlugins(c.StringSlice("plugin"))
 	if err != nil {
-		log.Fatalf("Failed to parse template: %s\n", err)
+		log.Fatalf("Failed to load plugins: %s\n", err)
+	}
+	if template, err = hooks.Template(template); err != nil {
+		log.Fatalf("Failed to process template: %s\n", err)
 	}
 
-	log.Printf("Successfully parsed %s\n", filename)
+	logging.For(logger, logging.Template).Infof("Successfully parsed %s", filename)
 
 	name := c.Args().First()
 
@@ -55,7 +65,8 @@ func invoke(c *cli.Context) {
 	function, found := functions[name]
 	if !found {
 		if len(functions) == 1 && name == "" {
-			for _, f := range functions {
+			for n, f := range functions {
+				name = n
 				function = f
 			}
 		} else {
@@ -75,22 +86,83 @@ func invoke(c *cli.Context) {
 		}
 	}
 
-	// Check connectivity to docker
-	dockerVersion, err := getDockerVersion()
-	if err != nil {
-		log.Printf("Running AWS SAM projects locally requires Docker. Have you got it installed?\n")
-		log.Printf("%s\n", err)
+	invocationType := c.String("invocation-type")
+	if invocationType != invocationTypeRequestResponse && invocationType != invocationTypeEvent && invocationType != invocationTypeDryRun {
+		fmt.Fprintf(os.Stderr, "ERROR: Invalid --invocation-type '%s' (must be one of RequestResponse, Event or DryRun)\n", invocationType)
+		os.Exit(1)
+	}
+
+	logType := c.String("log-type")
+	if logType != logTypeNone && logType != logTypeTail {
+		fmt.Fprintf(os.Stderr, "ERROR: Invalid --log-type '%s' (must be one of None or Tail)\n", logType)
+		os.Exit(1)
+	}
+
+	if err := checkQualifier(template, name, c.String("qualifier")); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
 		os.Exit(1)
 	}
 
-	log.Printf("Connected to Docker %s", dockerVersion)
+	// Optionally validate events against a JSON Schema before invoking the function
+	var schema *eventSchema
+	schemaFile := getEventSchemaFilename(template, name, c.String("event-schema"), filepath.Dir(filename))
+	if schemaFile != "" {
+		if schema, err = loadEventSchema(schemaFile); err != nil {
+			log.Fatalf("Could not load event schema: %s\n", err)
+		}
+	}
+
+	// Where the function result, and the invocation metadata should be written
+	payload := stdout
+	metadata := stderr
+	if outfile := c.String("outfile"); outfile != "" {
+		f, err := os.Create(outfile)
+		if err != nil {
+			log.Fatalf("Could not create output file: %s\n", err)
+		}
+		defer f.Close()
+		payload = f
+		metadata = stdout
+	}
+
+	// A dry run only checks that the function could be invoked
+	if invocationType == invocationTypeDryRun {
+		if _, found := invoker.RuntimeImages[function.Runtime]; !found {
+			log.Fatalf("Could not initiate %s runtime: %s\n", function.Runtime, invoker.ErrRuntimeNotSupported)
+		}
+		log.Printf("Dry run: %s (%s) would be invoked\n", name, function.Runtime)
+		writeInvokeMetadata(metadata, &invokeMetadata{StatusCode: 204})
+		return
+	}
 
 	cwd := filepath.Dir(filename)
 	if c.String("docker-volume-basedir") != "" {
 		cwd = c.String("docker-volume-basedir")
 	}
 
-	runt, err := NewRuntime(NewRuntimeOpt{
+	endpoints, err := parseEndpointURLs(c.StringSlice("aws-endpoint-url"))
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+
+	// Encrypted variables are decrypted through SAM Local's KMS endpoint
+	if endpoints, err = startKMSEmulator(template, []string{name}, endpoints, c.String("kms-keyring"), c.String("profile"), runtimeBackendName(c)); err != nil {
+		log.Fatalf("%s\n", err)
+	}
+
+	// With --simulate-iam, the function's AWS calls are checked against its policies first
+	var creds *credentials.Value
+	if c.Bool("simulate-iam") {
+		proxies, err := startIAMProxies(loaded, []string{name}, endpoints, c.String("profile"), runtimeBackendName(c))
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		endpoints, creds = proxies[name].endpoints, &proxies[name].credentials
+	}
+
+	adapter := startDebugAdapter(c)
+
+	opt := invoker.NewRuntimeOpt{
 		Cwd:             cwd,
 		LogicalID:       name,
 		Function:        function,
@@ -99,10 +171,96 @@ func invoke(c *cli.Context) {
 		DebugPort:       c.String("debug-port"),
 		SkipPullImage:   c.Bool("skip-pull-image"),
 		DockerNetwork:   c.String("docker-network"),
-	})
+		EstimateCost:    c.Bool("estimate-cost"),
+		Backend:         runtimeBackendName(c),
+		Endpoints:       endpoints,
+		Credentials:     creds,
+		Log:             logger,
+	}
+	if adapter != nil {
+		opt.Debugger = adapter
+	}
+
+	// Print the container that would be used to invoke the function, without using Docker
+	if c.Bool("dry-run") {
+		runt, err := invoker.New(opt)
+		if err != nil {
+			log.Fatalf("Could not initiate %s runtime: %s\n", function.Runtime, err)
+		}
+		plan, err := planContainer(runt, c.String("profile"))
+		if err != nil {
+			log.Fatalf("Could not work out the container configuration: %s\n", err)
+		}
+		writeDryRun(stdout, plan)
+		return
+	}
+
+	// Check connectivity to docker
+	if opt.Backend == "native" {
+		warnMsg.Fprintf(os.Stderr, "Running %s on this machine without Docker: its environment won't match Lambda's\n", name)
+	} else if opt.Backend == "docker" {
+		dockerVersion, err := invoker.DockerVersion()
+		if err != nil {
+			log.Printf("Running AWS SAM projects locally requires Docker. Have you got it installed?\n")
+			log.Printf("%s\n", err)
+			os.Exit(1)
+		}
+
+		log.Printf("Connected to Docker %s", dockerVersion)
+	}
+
+	// Interrupting SAM Local cancels the invocations, which stops their containers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt.Context = ctx
+
+	signals := interrupted()
+	go func() {
+		<-signals
+		log.Printf("Execution of function %q was interrupted", name)
+		cancel()
+	}()
+
+	// Optionally export an OpenTelemetry trace of the invocation, which the invoker's spans
+	// are children of
+	tracer, shutdownTracer := newTracer(c, logger)
+	ctx, span := tracer.Start(ctx, "sam local invoke", tracing.Internal, tracing.Attributes{"faas.invoked_name": name})
+	opt.Context = ctx
+
+	// With --build, run the function from its artifact, building it if its code has changed
+	if c.Bool("build") {
+		result, err := newFunctionBuilder(c, loaded, filename, cwd, stderr).build(ctx, name)
+		if err != nil {
+			log.Fatalf("Could not build %s: %s\n", name, err)
+		}
+		if result != nil {
+			opt.Cwd = result.Artifact
+			opt.Layer = result.Layer
+			runFromArtifact(&opt.Function, result)
+		}
+	}
+
+	// Invoke the function once for every event in --event-dir
+	if eventDir := c.String("event-dir"); eventDir != "" {
+
+		files, err := listEventFiles(eventDir)
+		if err != nil {
+			log.Fatalf("Could not read events: %s\n", err)
+		}
+
+		log.Printf("Invoking %s with %d events from %s\n", name, len(files), eventDir)
+		results := invokeBatch(opt, files, c.Int("parallel"), c.String("profile"), schema, hooks)
+		writeBatchResults(payload, results)
+		span.End()
+		shutdownTracer()
+
+		for _, result := range results {
+			if result.Failed() {
+				os.Exit(1)
+			}
+		}
+		return
 
-	if err != nil {
-		log.Fatalf("Could not initiate %s runtime: %s\n", function.Runtime, err)
 	}
 
 	eventFile := c.String("event")
@@ -124,26 +282,104 @@ func invoke(c *cli.Context) {
 		event = string(pb)
 	}
 
+	// Fail fast if the event doesn't match the function's schema, before starting a container
+	if schema != nil {
+		if errs := schema.Validate([]byte(event)); len(errs) > 0 {
+			fmt.Fprintf(os.Stderr, "ERROR: The event doesn't match the schema %s:\n", schemaFile)
+			for _, e := range errs {
+				fmt.Fprintf(os.Stderr, " * %s\n", e)
+			}
+			os.Exit(1)
+		}
+	}
+
+	event, opt.Environment, err = hooks.PreInvoke(name, event)
+	if err != nil {
+		log.Fatalf("Could not invoke function: %s\n", err)
+	}
+
+	runt, err := invoker.NewRuntime(opt)
+	if err != nil {
+		log.Fatalf("Could not initiate %s runtime: %s\n", function.Runtime, err)
+	}
+
 	stdoutTxt, stderrTxt, err := runt.Invoke(event, c.String("profile"))
 	if err != nil {
+		invoker.ActiveContainers.CleanUp()
 		log.Fatalf("Could not invoke function: %s\n", err)
 	}
 
+	// When requested, keep the end of the logs to return them like the Lambda API does
+	logs := &logTail{max: logTailSize}
+	logWriter := io.Writer(stderr)
+	if logType == logTypeTail {
+		logWriter = io.MultiWriter(stderr, logs)
+	}
+
+	// The result of asynchronous invocations is discarded
+	result := payload
+	if invocationType == invocationTypeEvent {
+		result = ioutil.Discard
+	}
+
+	// Keep a copy of the result to work out whether the function failed
+	output := &bytes.Buffer{}
+
 	var wg sync.WaitGroup
 	wg.Add(2)
 
 	go func() {
-		io.Copy(stderr, stderrTxt)
+		io.Copy(logWriter, stderrTxt)
 		wg.Done()
 	}()
 
 	go func() {
-		io.Copy(stdout, stdoutTxt)
+		io.Copy(io.MultiWriter(result, output), stdoutTxt)
 		wg.Done()
 	}()
 
 	wg.Wait()
 
 	fmt.Fprintf(stderr, "\n")
+
+	report := runt.Report("")
+	fmt.Fprintf(stderr, "%s\n", report)
+	if c.Bool("estimate-cost") {
+		fmt.Fprintf(stderr, "Estimated cost: $%.9f (excluding free tier)\n", report.EstimatedCost())
+	}
+
+	outcome := runt.Outcome(output.Bytes())
 	runt.CleanUp()
+	hooks.PostInvoke(name, event, output.Bytes(), outcome, report.Duration)
+	span.End()
+	shutdownTracer()
+
+	meta := &invokeMetadata{StatusCode: 200, FunctionError: outcome.FunctionError(), ExecutedVersion: "$LATEST"}
+	if invocationType == invocationTypeEvent {
+		meta = &invokeMetadata{StatusCode: 202}
+	} else if logType == logTypeTail {
+		meta.LogResult = logs.Base64()
+	}
+
+	// Only print the invocation metadata when it was asked for, so the default output is unchanged
+	if c.String("outfile") != "" || logType == logTypeTail || invocationType != invocationTypeRequestResponse {
+		writeInvokeMetadata(metadata, meta)
+	}
+
+	// Exit with a distinct code when the function failed, so scripts can check the result
+	if code := exitCode(outcome); code != 0 {
+		log.Printf("Invocation failed: %s\n", outcome)
+		os.Exit(code)
+	}
+}
+
+// runtimeBackendName returns the name of the runtime backend that a command's flags ask for
+func runtimeBackendName(c *cli.Context) string {
+	if name := c.String("runtime-backend"); name != "" {
+		return name
+	}
+	if c.Bool("no-docker") {
+		return "native"
+	}
+	return "docker"
 }
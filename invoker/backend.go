@@ -0,0 +1,76 @@
+package invoker
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// RuntimeBackend runs a Runtime's function invocations somewhere, such as in Docker containers
+// or directly on the host. A backend keeps whatever it needs for an invocation on the Runtime,
+// so the same backend can be used by any number of runtimes.
+type RuntimeBackend interface {
+
+	// Start gets the backend ready to run the runtime's functions (e.g. by pulling an image).
+	// It's called once, when the runtime is created. Start and Invoke should give up when
+	// r.Context is cancelled.
+	Start(r *Runtime) error
+
+	// Invoke starts an invocation of the function with the event, and returns its stdout,
+	// which the result is written to
+	Invoke(r *Runtime, event string, profile string) (io.ReadCloser, error)
+
+	// Logs returns the stderr of the invocation that was started last, which the runtime and
+	// the function log to
+	Logs(r *Runtime) io.ReadCloser
+
+	// Wait waits for the invocation to finish, and returns its exit status
+	Wait(r *Runtime) (int, error)
+
+	// Stop stops the invocation, if it's still running, and removes anything it used
+	Stop(r *Runtime)
+}
+
+// StreamingBackend is implemented by runtime backends that can read the event of an
+// invocation from a stream, so that large events don't need to be held in memory. Runtimes
+// read the event into memory for backends that don't implement it.
+type StreamingBackend interface {
+	RuntimeBackend
+
+	// InvokeStream is like Invoke, but reads the event from event
+	InvokeStream(r *Runtime, event io.Reader, profile string) (io.ReadCloser, error)
+}
+
+// runtimeBackends are the backends functions can be run with, by name
+var runtimeBackends = map[string]RuntimeBackend{
+	"docker": dockerBackend{},
+	"native": nativeBackend{},
+}
+
+// RegisterRuntimeBackend makes a runtime backend available under the given name, so that
+// functions can be run with it (NewRuntimeOpt.Backend, or --runtime-backend in the CLI). It's
+// meant to be called from init().
+func RegisterRuntimeBackend(name string, backend RuntimeBackend) {
+	if _, found := runtimeBackends[name]; found {
+		panic("runtime backend " + name + " is already registered")
+	}
+	runtimeBackends[name] = backend
+}
+
+// newRuntimeBackend returns the runtime backend with the given name
+func newRuntimeBackend(name string) (RuntimeBackend, error) {
+
+	backend, found := runtimeBackends[name]
+	if !found {
+		names := []string{}
+		for name := range runtimeBackends {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unsupported runtime backend '%s' (must be one of %s)", name, strings.Join(names, ", "))
+	}
+
+	return backend, nil
+
+}
@@ -0,0 +1,160 @@
+package invoker
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/tracing"
+	"github.com/awslabs/goformation/cloudformation"
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// echoBackend is a runtime backend that returns the event as the result
+type echoBackend struct {
+	started *int
+	stopped *int
+}
+
+func (b echoBackend) Start(r *Runtime) error {
+	*b.started++
+	return nil
+}
+
+func (b echoBackend) Invoke(r *Runtime, event string, profile string) (io.ReadCloser, error) {
+	r.logs = ioutil.NopCloser(strings.NewReader("invoked " + r.LogicalID + "\n"))
+	return ioutil.NopCloser(strings.NewReader(event)), nil
+}
+
+func (b echoBackend) Logs(r *Runtime) io.ReadCloser {
+	return r.logs
+}
+
+func (b echoBackend) Wait(r *Runtime) (int, error) {
+	return 0, nil
+}
+
+func (b echoBackend) Stop(r *Runtime) {
+	*b.stopped++
+}
+
+var _ = Describe("Runtime backends", func() {
+
+	started, stopped := 0, 0
+	RegisterRuntimeBackend("echo", echoBackend{started: &started, stopped: &stopped})
+
+	It("runs functions with a registered backend", func() {
+		runt, err := NewRuntime(NewRuntimeOpt{
+			LogicalID: "Echo",
+			Function:  cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10", Handler: "index.handler", Timeout: 3},
+			Backend:   "echo",
+		})
+		Expect(err).To(BeNil())
+		Expect(started).To(Equal(1))
+
+		stdout, stderr, err := runt.Invoke(`{"value": 42}`, "")
+		Expect(err).To(BeNil())
+
+		output, _ := ioutil.ReadAll(stdout)
+		logs, _ := ioutil.ReadAll(stderr)
+		Expect(string(output)).To(Equal(`{"value": 42}`))
+		Expect(string(logs)).To(Equal("invoked Echo\n"))
+		Expect(runt.Outcome(output)).To(Equal(OutcomeSuccess))
+
+		runt.CleanUp()
+		Expect(stopped).To(Equal(1))
+	})
+
+	It("starts lazy runtimes when they're first invoked", func() {
+		before := started
+		runt, err := NewRuntime(NewRuntimeOpt{
+			LogicalID: "Lazy",
+			Function:  cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10", Handler: "index.handler", Timeout: 3},
+			Backend:   "echo",
+			Lazy:      true,
+		})
+		Expect(err).To(BeNil())
+		Expect(started).To(Equal(before))
+
+		// Copies of the runtime, which start-api invokes each request with, share its start
+		for i := 0; i < 2; i++ {
+			invocation := *runt
+			stdout, _, err := invocation.Invoke(`{}`, "")
+			Expect(err).To(BeNil())
+			ioutil.ReadAll(stdout)
+			invocation.CleanUp()
+		}
+		Expect(started).To(Equal(before + 1))
+	})
+
+	It("reads streamed events for backends that can't stream them", func() {
+		runt, err := NewRuntime(NewRuntimeOpt{
+			LogicalID: "Echo",
+			Function:  cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10", Handler: "index.handler", Timeout: 3},
+			Backend:   "echo",
+		})
+		Expect(err).To(BeNil())
+		defer runt.CleanUp()
+
+		stdout, _, err := runt.InvokeReader(context.Background(), strings.NewReader(`{"value": 42}`), "")
+		Expect(err).To(BeNil())
+
+		output, _ := ioutil.ReadAll(stdout)
+		Expect(string(output)).To(Equal(`{"value": 42}`))
+	})
+
+	It("records spans of invocations that are being traced", func() {
+		spans := []*tracing.Span{}
+		tracer := tracing.NewTracer(tracing.ExporterFunc(func(span *tracing.Span) {
+			spans = append(spans, span)
+		}))
+		ctx, root := tracer.Start(context.Background(), "request", tracing.Server, nil)
+
+		runt, err := NewRuntime(NewRuntimeOpt{
+			LogicalID: "Traced",
+			Function:  cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10", Handler: "index.handler", Timeout: 3},
+			Backend:   "echo",
+			Lazy:      true,
+		})
+		Expect(err).To(BeNil())
+
+		stdout, _, err := runt.InvokeContext(ctx, `{}`, "")
+		Expect(err).To(BeNil())
+		ioutil.ReadAll(stdout)
+		runt.CleanUp()
+
+		Expect(spans).To(HaveLen(2))
+		start, invoke := spans[0], spans[1]
+		Expect(invoke.Name).To(Equal("invoke Traced"))
+		Expect(invoke.Kind).To(Equal(tracing.Client))
+		Expect(invoke.ParentID).To(Equal(root.SpanID))
+		Expect(invoke.Attributes).To(HaveKeyWithValue("faas.coldstart", true))
+		Expect(invoke.Attributes).To(HaveKeyWithValue("aws.lambda.runtime", "nodejs8.10"))
+		Expect(start.Name).To(Equal("start runtime"))
+		Expect(start.ParentID).To(Equal(invoke.SpanID))
+	})
+
+	It("defaults to Docker, or the host with NoDocker", func() {
+		runt, err := New(NewRuntimeOpt{Function: cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10"}})
+		Expect(err).To(BeNil())
+		Expect(runt.Backend).To(Equal(dockerBackend{}))
+
+		runt, err = New(NewRuntimeOpt{Function: cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10"}, NoDocker: true})
+		Expect(err).To(BeNil())
+		Expect(runt.Backend).To(Equal(nativeBackend{}))
+	})
+
+	It("rejects unknown backends", func() {
+		_, err := New(NewRuntimeOpt{Function: cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10"}, Backend: "teleport"})
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("must be one of docker, echo, native"))
+	})
+
+	It("won't register a backend twice", func() {
+		Expect(func() { RegisterRuntimeBackend("docker", dockerBackend{}) }).To(Panic())
+	})
+
+})
@@ -0,0 +1,83 @@
+package invoker
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/sockets"
+	"github.com/docker/go-connections/tlsconfig"
+)
+
+// dockerIdleConnections is how many idle connections to the Docker daemon are kept open to
+// be reused. Each invocation makes several API calls (to create, attach to, start, wait for
+// and remove its container, and to monitor its memory), and concurrent invocations make
+// them at the same time.
+const dockerIdleConnections = 64
+
+// sharedDockerClient is the client that DockerClient returns
+var sharedDockerClient struct {
+	once   sync.Once
+	client *client.Client
+	err    error
+}
+
+// DockerClient returns the Docker client that runtimes (and the rest of SAM Local) share. Its connections to the Docker
+// daemon are kept alive and pooled, so the API calls of each invocation reuse them instead
+// of dialling the daemon (and shaking hands over TLS, for remote ones) every time. It's
+// configured from the environment like client.NewEnvClient.
+func DockerClient() (*client.Client, error) {
+	sharedDockerClient.once.Do(func() {
+		sharedDockerClient.client, sharedDockerClient.err = newDockerClient()
+	})
+	return sharedDockerClient.client, sharedDockerClient.err
+}
+
+// newDockerClient creates a Docker client with a pooled transport, from the DOCKER_HOST,
+// DOCKER_API_VERSION, DOCKER_CERT_PATH and DOCKER_TLS_VERIFY environment variables
+func newDockerClient() (*client.Client, error) {
+
+	transport := &http.Transport{
+		MaxIdleConns:        dockerIdleConnections,
+		MaxIdleConnsPerHost: dockerIdleConnections,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	if certs := os.Getenv("DOCKER_CERT_PATH"); certs != "" {
+		tlsc, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:             filepath.Join(certs, "ca.pem"),
+			CertFile:           filepath.Join(certs, "cert.pem"),
+			KeyFile:            filepath.Join(certs, "key.pem"),
+			InsecureSkipVerify: os.Getenv("DOCKER_TLS_VERIFY") == "",
+		})
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsc
+	}
+
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = client.DefaultDockerHost
+	}
+
+	proto, addr, _, err := client.ParseHost(host)
+	if err != nil {
+		return nil, err
+	}
+	if err := sockets.ConfigureTransport(transport, proto, addr); err != nil {
+		return nil, err
+	}
+
+	version := os.Getenv("DOCKER_API_VERSION")
+	if version == "" {
+		version = api.DefaultVersion
+	}
+
+	return client.NewClient(host, version, &http.Client{Transport: transport}, nil)
+
+}
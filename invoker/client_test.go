@@ -0,0 +1,31 @@
+package invoker
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Docker client", func() {
+
+	It("is shared by runtimes", func() {
+		first, err := DockerClient()
+		Expect(err).To(BeNil())
+		second, _ := DockerClient()
+		Expect(second).To(BeIdenticalTo(first))
+	})
+
+	It("is configured from the environment", func() {
+		defer os.Setenv("DOCKER_HOST", os.Getenv("DOCKER_HOST"))
+
+		os.Setenv("DOCKER_HOST", "tcp://127.0.0.1:2375")
+		_, err := newDockerClient()
+		Expect(err).To(BeNil())
+
+		os.Setenv("DOCKER_HOST", "nonsense")
+		_, err = newDockerClient()
+		Expect(err).ToNot(BeNil())
+	})
+
+})
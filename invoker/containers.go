@@ -0,0 +1,52 @@
+package invoker
+
+import (
+	"sync"
+
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"golang.org/x/net/context"
+)
+
+// ActiveContainers tracks the runtime containers that have been created and not
+// yet removed, so that they can be cleaned up when SAM Local is shut down.
+var ActiveContainers = &ContainerSet{
+	containers: map[string]*client.Client{},
+}
+
+// ContainerSet is a concurrency safe set of Docker container IDs
+type ContainerSet struct {
+	sync.Mutex
+	containers map[string]*client.Client
+
+	// Log receives the entries of CleanUp. If nil, the log package's standard logger is used.
+	Log logging.Logger
+}
+
+// Add starts tracking a container
+func (s *ContainerSet) Add(id string, cli *client.Client) {
+	s.Lock()
+	defer s.Unlock()
+	s.containers[id] = cli
+}
+
+// Remove stops tracking a container
+func (s *ContainerSet) Remove(id string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.containers, id)
+}
+
+// CleanUp stops and removes all of the tracked containers
+func (s *ContainerSet) CleanUp() {
+	s.Lock()
+	defer s.Unlock()
+
+	for id, cli := range s.containers {
+		logging.For(s.Log, logging.Docker).With(logging.Fields{"container": id}).Infof("Removing container %s", id)
+		cli.ContainerKill(context.Background(), id, "SIGKILL")
+		cli.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{})
+		delete(s.containers, id)
+	}
+}
@@ -0,0 +1,18 @@
+// Package invoker runs AWS Lambda functions from SAM templates locally, the same way
+// "sam local invoke" does. Given a parsed template, the logical ID of a function and an
+// event, Invoke runs the function (in a lambci/lambda container by default) and returns
+// what it returned, what it logged, how long it took and how it ended:
+//
+//	template, _ := goformation.Open("template.yaml")
+//	result, err := invoker.Invoke(template, "HelloWorld", []byte(`{"name": "sam"}`), invoker.Options{})
+//	if err != nil {
+//		// the function couldn't be run at all
+//	}
+//	if result.Outcome != invoker.OutcomeSuccess {
+//		// the function returned an error, crashed or timed out
+//	}
+//
+// ContainerConfig returns the Docker configuration Invoke would use, without running
+// anything. For more control (e.g. to invoke the same function several times, or to attach
+// a Debugger), use NewRuntime directly.
+package invoker
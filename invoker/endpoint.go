@@ -0,0 +1,106 @@
+package invoker
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	goruntime "runtime"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// endpointVariable is the environment variable that the AWS SDKs (and CLI) read the endpoint
+// URL of every service from. AWS_ENDPOINT_URL_<SERVICE> overrides it for one service.
+const endpointVariable = "AWS_ENDPOINT_URL"
+
+// hostAlias is the name that containers reach the Docker host by
+const hostAlias = "host.docker.internal"
+
+// EndpointVariable returns the environment variable that the AWS SDKs read the endpoint URL
+// of a service from, e.g. AWS_ENDPOINT_URL_SECRETS_MANAGER for secrets-manager, or
+// AWS_ENDPOINT_URL for "" (every service)
+func EndpointVariable(service string) string {
+	if service == "" {
+		return endpointVariable
+	}
+	service = strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToUpper(service))
+	return endpointVariable + "_" + service
+}
+
+// endpointEnv returns the environment variables that point the AWS SDKs at endpoints, by
+// service ("" for every service)
+func endpointEnv(endpoints map[string]string) map[string]string {
+	env := map[string]string{}
+	for service, endpoint := range endpoints {
+		env[EndpointVariable(service)] = endpoint
+	}
+	return env
+}
+
+// rewriteLocalEndpoints points the endpoint variables in env that are on this machine (e.g.
+// http://localhost:4566 for LocalStack) at the Docker host, as localhost is the container
+// itself inside one. It returns whether any were rewritten.
+func rewriteLocalEndpoints(env map[string]string) bool {
+
+	rewritten := false
+	for name, value := range env {
+		if !strings.HasPrefix(name, endpointVariable) {
+			continue
+		}
+
+		endpoint, err := url.Parse(value)
+		if err != nil || !isLoopback(endpoint.Hostname()) {
+			continue
+		}
+
+		if port := endpoint.Port(); port != "" {
+			endpoint.Host = net.JoinHostPort(hostAlias, port)
+		} else {
+			endpoint.Host = hostAlias
+		}
+		env[name] = endpoint.String()
+		rewritten = true
+	}
+
+	return rewritten
+
+}
+
+// isLoopback returns whether a host name is this machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// DockerHostAddress returns the address that containers reach the Docker host by, as
+// host.docker.internal, so that servers for them can listen on it rather than on every
+// interface. That's the gateway of Docker's default bridge network on Linux, and "" with
+// Docker for Mac and Windows, which reach the host's loopback interface themselves.
+func DockerHostAddress() (string, error) {
+
+	if goruntime.GOOS != "linux" {
+		return "", nil
+	}
+
+	cli, err := DockerClient()
+	if err != nil {
+		return "", err
+	}
+
+	bridge, err := cli.NetworkInspect(context.Background(), "bridge", false)
+	if err != nil {
+		return "", err
+	}
+	for _, config := range bridge.IPAM.Config {
+		if ip := net.ParseIP(config.Gateway); ip != nil && ip.To4() != nil {
+			return config.Gateway, nil
+		}
+	}
+
+	return "", errors.New("the Docker bridge network has no gateway")
+
+}
@@ -0,0 +1,53 @@
+package invoker
+
+import (
+	goruntime "runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Endpoints", func() {
+
+	It("names the endpoint variables of services as the AWS SDKs do", func() {
+		Expect(EndpointVariable("")).To(Equal("AWS_ENDPOINT_URL"))
+		Expect(EndpointVariable("s3")).To(Equal("AWS_ENDPOINT_URL_S3"))
+		Expect(EndpointVariable("secrets-manager")).To(Equal("AWS_ENDPOINT_URL_SECRETS_MANAGER"))
+	})
+
+	It("points endpoints on this machine at the Docker host", func() {
+		env := map[string]string{
+			"AWS_ENDPOINT_URL":          "http://localhost:4566",
+			"AWS_ENDPOINT_URL_S3":       "http://127.0.0.1:9000/",
+			"AWS_ENDPOINT_URL_DYNAMODB": "http://dynamodb:8000",
+			"OTHER":                     "http://localhost",
+		}
+		Expect(rewriteLocalEndpoints(env)).To(BeTrue())
+		Expect(env).To(Equal(map[string]string{
+			"AWS_ENDPOINT_URL":          "http://host.docker.internal:4566",
+			"AWS_ENDPOINT_URL_S3":       "http://host.docker.internal:9000/",
+			"AWS_ENDPOINT_URL_DYNAMODB": "http://dynamodb:8000",
+			"OTHER":                     "http://localhost",
+		}))
+
+		Expect(rewriteLocalEndpoints(map[string]string{"AWS_ENDPOINT_URL": "https://s3.amazonaws.com"})).To(BeFalse())
+	})
+
+	It("passes the endpoints to the function's container, over its own variables", func() {
+		r := &Runtime{
+			LogicalID: "Hello",
+			Image:     "lambci/lambda:nodejs8.10",
+			Endpoints: map[string]string{"": "http://localhost:4566", "sqs": "http://elasticmq:9324"},
+		}
+		r.Environment = map[string]string{"AWS_ENDPOINT_URL_SQS": "http://plugin:9324"}
+
+		config, host, err := r.containerConfig("{}", "")
+		Expect(err).To(BeNil())
+		Expect(config.Env).To(ContainElement("AWS_ENDPOINT_URL=http://host.docker.internal:4566"))
+		Expect(config.Env).To(ContainElement("AWS_ENDPOINT_URL_SQS=http://plugin:9324"))
+		if goruntime.GOOS == "linux" {
+			Expect(host.ExtraHosts).To(ContainElement("host.docker.internal:host-gateway"))
+		}
+	})
+
+})
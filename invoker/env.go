@@ -0,0 +1,297 @@
+package invoker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+/**
+ The Environment Variable Saga..
+
+ There are two types of environment variables
+ 	- Lambda runtime variables starting with "AWS_" and passed to every function
+ 	- Custom environment variables defined in SAM template for each function
+
+ Custom environment variables defined in the SAM template can contain hard-coded
+ values or fetch from a stack parameter or use Intrinsics to generate a value at
+ at stack creation time like ARNs. It can get complicated to support all cases
+
+ Instead we will parse only hard-coded values from the template. For the rest,
+ users can supply values through the Shell's environment or the env-var override
+ CLI argument. If a value is provided through more than one method, the method
+ with higher priority will win.
+
+ Priority (Highest to lowest)
+	Runtime.Environment (e.g. from plugins)
+	Runtime.Credentials (AWS credentials, e.g. from --simulate-iam)
+	Runtime.Endpoints (AWS_ENDPOINT_URL variables, from --aws-endpoint-url)
+	Env-Var CLI argument
+	Shell's Environment
+	Hard-coded values from template
+
+ This priority also applies to AWS_* system variables
+*/
+
+// environment returns the environment variables of the runtime's function
+func (r *Runtime) environment(profile string) map[string]string {
+	env := getEnvironmentVariables(r.log(), r.LogicalID, &r.Function, r.EnvOverrideFile, profile)
+	for name, value := range endpointEnv(r.Endpoints) {
+		env[name] = value
+	}
+	if r.Credentials != nil {
+		env["AWS_ACCESS_KEY_ID"] = r.Credentials.AccessKeyID
+		env["AWS_SECRET_ACCESS_KEY"] = r.Credentials.SecretAccessKey
+		delete(env, "AWS_SESSION_TOKEN")
+		if r.Credentials.SessionToken != "" {
+			env["AWS_SESSION_TOKEN"] = r.Credentials.SessionToken
+		}
+	}
+	for name, value := range r.Environment {
+		env[name] = value
+	}
+	return env
+}
+
+func getEnvironmentVariables(log *logging.Entry, logicalID string, function *cloudformation.AWSServerlessFunction, overrideFile string, profile string) map[string]string {
+
+	env := getEnvDefaults(log, function, profile)
+	overrides := getEnvOverrides(log, logicalID, overrideFile)
+
+	if function.Environment != nil {
+		for name, value := range function.Environment.Variables {
+
+			// hard-coded values, lowest priority
+			if stringedValue, ok := toStringMaybe(value); ok {
+				// Get only hard-coded values from the template
+				env[name] = stringedValue
+			}
+
+			// Shell's environment, second priority
+			if value, ok := os.LookupEnv(name); ok {
+				env[name] = value
+			}
+
+			// EnvVars overrides provided by customer, highest priority
+			if len(overrides) > 0 {
+				if value, ok := overrides[name]; ok {
+					env[name] = value
+				}
+			}
+		}
+	}
+
+	return env
+
+}
+
+func getEnvDefaults(log *logging.Entry, function *cloudformation.AWSServerlessFunction, profile string) map[string]string {
+
+	creds := getSessionOrDefaultCreds(log, profile)
+
+	// Variables available in Lambda execution environment for all functions (AWS_* variables)
+	env := map[string]string{
+		"AWS_SAM_LOCAL":                   "true",
+		"AWS_REGION":                      creds["region"],
+		"AWS_DEFAULT_REGION":              creds["region"],
+		"AWS_ACCESS_KEY_ID":               creds["key"],
+		"AWS_SECRET_ACCESS_KEY":           creds["secret"],
+		"AWS_LAMBDA_FUNCTION_MEMORY_SIZE": strconv.Itoa(int(function.MemorySize)),
+		"AWS_LAMBDA_FUNCTION_TIMEOUT":     strconv.Itoa(int(function.Timeout)),
+		"AWS_LAMBDA_FUNCTION_HANDLER":     function.Handler,
+		// "AWS_ACCOUNT_ID=",
+		// "AWS_LAMBDA_EVENT_BODY=",
+		// "AWS_REGION=",
+		// "AWS_LAMBDA_FUNCTION_NAME=",
+		// "AWS_LAMBDA_FUNCTION_VERSION=",
+	}
+
+	if token, ok := creds["sessiontoken"]; ok && token != "" {
+		env["AWS_SESSION_TOKEN"] = token
+	}
+
+	return env
+
+}
+
+// overrideFiles are the environment override files that were read, by filename, so that each
+// invocation only decodes them again when they've changed
+var overrideFiles = struct {
+	sync.Mutex
+	byName map[string]overrideFile
+}{byName: map[string]overrideFile{}}
+
+// overrideFile is a decoded environment override file, and the size and modification time it
+// had when it was read
+type overrideFile struct {
+	size      int64
+	modTime   time.Time
+	overrides map[string]map[string]string
+}
+
+func getEnvOverrides(log *logging.Entry, logicalID string, filename string) map[string]string {
+
+	if len(filename) > 0 {
+
+		overrides, err := readEnvOverrides(filename)
+		if err != nil {
+			log.Warnf("%s", err)
+			return map[string]string{}
+		}
+
+		result := map[string]string{}
+		for k, v := range overrides[logicalID] {
+			result[k] = v
+		}
+		// In case we have a cloudformation parameters json, structure {Parameters: {key:value}}
+		for k, v := range overrides["Parameters"] {
+			result[k] = v
+		}
+		return result
+
+	}
+
+	return map[string]string{}
+
+}
+
+// readEnvOverrides returns the overrides in an environment override file, which are shared
+// between invocations and mustn't be modified
+func readEnvOverrides(filename string) (map[string]map[string]string, error) {
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read environment overrides from %s: %s", filename, err)
+	}
+
+	overrideFiles.Lock()
+	defer overrideFiles.Unlock()
+
+	if cached, ok := overrideFiles.byName[filename]; ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.overrides, nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read environment overrides from %s: %s", filename, err)
+	}
+
+	// This is a JSON of structure {FunctionName: {key:value}, FunctionName: {key:value}}
+	overrides := map[string]map[string]string{}
+	if err = json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("Invalid environment override file %s: %s", filename, err)
+	}
+
+	overrideFiles.byName[filename] = overrideFile{size: info.Size(), modTime: info.ModTime(), overrides: overrides}
+	return overrides, nil
+
+}
+
+// sessionTTL is how long the session of a profile is reused for, before the credential chain
+// is searched again (e.g. for credentials that were rotated in ~/.aws/credentials)
+var sessionTTL = time.Minute
+
+// sessions are the AWS sessions of each profile. They're shared by every function, so the
+// credential chain is searched once per profile rather than once per function, which took
+// minutes for templates with thousands of them.
+var sessions = struct {
+	sync.Mutex
+	byProfile map[string]cachedSession
+}{byProfile: map[string]cachedSession{}}
+
+// cachedSession is the session of a profile, which is nil if no credentials were found
+type cachedSession struct {
+	sess    *session.Session
+	checked time.Time
+}
+
+// getSession returns the session of a profile, or nil if no credentials were found for it
+func getSession(log *logging.Entry, profile string) *session.Session {
+
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	if cached, ok := sessions.byProfile[profile]; ok && time.Since(cached.checked) < sessionTTL {
+		return cached.sess
+	}
+
+	opts := session.Options{}
+	opts.Profile = profile
+
+	sess, err := session.NewSessionWithOptions(opts)
+	if err == nil {
+		if _, err = sess.Config.Credentials.Get(); err != nil {
+			log.Warnf("WARNING: No AWS credentials found. Missing credentials may lead to slow startup times as detailed in https://github.com/awslabs/aws-sam-local/issues/134")
+		}
+	}
+	if err != nil {
+		sess = nil
+	}
+
+	sessions.byProfile[profile] = cachedSession{sess: sess, checked: time.Now()}
+	return sess
+
+}
+
+func getSessionOrDefaultCreds(log *logging.Entry, profile string) map[string]string {
+
+	region := "us-east-1"
+	key := "defaultkey"
+	secret := "defaultsecret"
+
+	result := map[string]string{
+		"region": region,
+		"key":    key,
+		"secret": secret,
+	}
+
+	// Obtain AWS credentials and pass them through to the container runtime via env variables
+	sess := getSession(log, profile)
+	if sess == nil {
+		return result
+	}
+
+	creds, err := sess.Config.Credentials.Get()
+	if err != nil {
+		log.Warnf("WARNING: Could not refresh AWS credentials: %s", err)
+		return result
+	}
+
+	if *sess.Config.Region != "" {
+		result["region"] = *sess.Config.Region
+	}
+
+	result["key"] = creds.AccessKeyID
+	result["secret"] = creds.SecretAccessKey
+	if creds.SessionToken != "" {
+		result["sessiontoken"] = creds.SessionToken
+	}
+
+	return result
+}
+
+// Converts the input to string if it is a primitive type, Otherwise returns nil
+func toStringMaybe(value interface{}) (string, bool) {
+
+	switch value.(type) {
+	case string:
+		return value.(string), true
+	case int:
+		return strconv.Itoa(value.(int)), true
+	case float32, float64:
+		return strconv.FormatFloat(value.(float64), 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value.(bool)), true
+	default:
+		return "", false
+	}
+
+}
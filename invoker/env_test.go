@@ -1,8 +1,12 @@
-package main
+package invoker
 
 import (
+	"io/ioutil"
 	"os"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/awslabs/aws-sam-local/logging"
 	"github.com/awslabs/goformation"
 	"github.com/awslabs/goformation/cloudformation"
 
@@ -12,18 +16,23 @@ import (
 
 var _ = Describe("Environment Variables", func() {
 
+	BeforeEach(func() {
+		// The credentials in the environment change between tests
+		sessions.byProfile = map[string]cachedSession{}
+	})
+
 	Context("with a template that has environment variables defined", func() {
 
 		var functions map[string]cloudformation.AWSServerlessFunction
 		BeforeEach(func() {
-			template, _ := goformation.Open("test/templates/sam-official-samples/iot_backend/template.yaml")
+			template, _ := goformation.Open("../test/templates/sam-official-samples/iot_backend/template.yaml")
 			functions = template.GetAllAWSServerlessFunctionResources()
 		})
 
 		It("return defaults with those defined in the template", func() {
 
 			for name, function := range functions {
-				variables := getEnvironmentVariables(name, &function, "", "")
+				variables := getEnvironmentVariables(logging.For(nil, logging.Docker), name, &function, "", "")
 				Expect(variables).To(HaveLen(9))
 				Expect(variables).To(HaveKey("AWS_SAM_LOCAL"))
 				Expect(variables).To(HaveKey("AWS_REGION"))
@@ -46,7 +55,7 @@ var _ = Describe("Environment Variables", func() {
 				os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
 				os.Unsetenv("AWS_SESSION_TOKEN")
 
-				variables := getEnvironmentVariables(name, &function, "", "")
+				variables := getEnvironmentVariables(logging.For(nil, logging.Docker), name, &function, "", "")
 				Expect(variables).To(HaveLen(9))
 				Expect(variables).To(HaveKey("AWS_SAM_LOCAL"))
 				Expect(variables).To(HaveKey("AWS_REGION"))
@@ -72,7 +81,7 @@ var _ = Describe("Environment Variables", func() {
 				os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
 				os.Setenv("AWS_SESSION_TOKEN", "token")
 
-				variables := getEnvironmentVariables(name, &function, "", "")
+				variables := getEnvironmentVariables(logging.For(nil, logging.Docker), name, &function, "", "")
 				Expect(variables).To(HaveLen(10))
 				Expect(variables).To(HaveKey("AWS_SAM_LOCAL"))
 				Expect(variables).To(HaveKey("AWS_REGION"))
@@ -94,13 +103,27 @@ var _ = Describe("Environment Variables", func() {
 			}
 		})
 
+		It("only looks up the credentials of a profile once", func() {
+			os.Setenv("AWS_ACCESS_KEY_ID", "id")
+			os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+			first := getSession(logging.For(nil, logging.Docker), "")
+			os.Unsetenv("AWS_ACCESS_KEY_ID")
+			os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+			Expect(first).ToNot(BeNil())
+			Expect(getSession(logging.For(nil, logging.Docker), "")).To(BeIdenticalTo(first))
+
+			sessions.byProfile[""] = cachedSession{sess: first, checked: time.Now().Add(-sessionTTL)}
+			Expect(getSession(logging.For(nil, logging.Docker), "")).ToNot(BeIdenticalTo(first))
+		})
+
 		It("overides template with environment variables", func() {
 			for name, function := range functions {
-				variables := getEnvironmentVariables(name, &function, "", "")
+				variables := getEnvironmentVariables(logging.For(nil, logging.Docker), name, &function, "", "")
 				Expect(variables["TABLE_NAME"]).To(Equal(""))
 
 				os.Setenv("TABLE_NAME", "ENV_TABLE")
-				variables = getEnvironmentVariables(name, &function, "", "")
+				variables = getEnvironmentVariables(logging.For(nil, logging.Docker), name, &function, "", "")
 				Expect(variables["TABLE_NAME"]).To(Equal("ENV_TABLE"))
 				os.Unsetenv("TABLE_NAME")
 			}
@@ -108,11 +131,44 @@ var _ = Describe("Environment Variables", func() {
 
 		It("overrides template and environment with customer overrides", func() {
 			for name, function := range functions {
-				variables := getEnvironmentVariables(name, &function, "test/environment-overrides.json", "")
+				variables := getEnvironmentVariables(logging.For(nil, logging.Docker), name, &function, "../test/environment-overrides.json", "")
 				Expect(variables["TABLE_NAME"]).To(Equal("OVERRIDE_TABLE"))
 			}
 			os.Unsetenv("TABLE_NAME")
 		})
 
 	})
+
+	It("reads environment override files again when they change", func() {
+		file, _ := ioutil.TempFile("", "env")
+		defer os.Remove(file.Name())
+		file.WriteString(`{"Hello": {"TABLE": "one"}, "Parameters": {"STAGE": "dev"}}`)
+		file.Close()
+
+		log := logging.For(nil, logging.Docker)
+		Expect(getEnvOverrides(log, "Hello", file.Name())).To(Equal(map[string]string{"TABLE": "one", "STAGE": "dev"}))
+		Expect(getEnvOverrides(log, "Other", file.Name())).To(Equal(map[string]string{"STAGE": "dev"}))
+
+		ioutil.WriteFile(file.Name(), []byte(`{"Hello": {"TABLE": "two"}}`), 0644)
+		os.Chtimes(file.Name(), time.Now(), time.Now().Add(time.Second))
+		Expect(getEnvOverrides(log, "Hello", file.Name())).To(Equal(map[string]string{"TABLE": "two"}))
+	})
+
+	It("gives functions their own credentials instead of the profile's", func() {
+		os.Setenv("AWS_ACCESS_KEY_ID", "id")
+		os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+		os.Setenv("AWS_SESSION_TOKEN", "token")
+		defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+		defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+		defer os.Unsetenv("AWS_SESSION_TOKEN")
+
+		r := &Runtime{
+			LogicalID:   "Hello",
+			Credentials: &credentials.Value{AccessKeyID: "SAMLOCALKEY", SecretAccessKey: "localsecret"},
+		}
+		env := r.environment("")
+		Expect(env["AWS_ACCESS_KEY_ID"]).To(Equal("SAMLOCALKEY"))
+		Expect(env["AWS_SECRET_ACCESS_KEY"]).To(Equal("localsecret"))
+		Expect(env).NotTo(HaveKey("AWS_SESSION_TOKEN"))
+	})
 })
@@ -0,0 +1,67 @@
+package invoker
+
+import (
+	"sync"
+	"time"
+)
+
+// ImageCacheTTL is how long the Docker backend remembers that a runtime image is present (or
+// was pulled) for. Until then, runtimes that use the image don't ask Docker about it again,
+// so commands that create a runtime for each invocation (or start many functions that share
+// an image) don't make the same API calls, or pull the same image, over and over.
+var ImageCacheTTL = 5 * time.Minute
+
+// imageState is what's known about a runtime image
+type imageState struct {
+
+	// present is whether the image is present
+	present bool
+
+	// pulled is whether the image was pulled (rather than only found)
+	pulled bool
+
+	checked time.Time
+}
+
+// imageCache remembers the state of runtime images, until ImageCacheTTL has passed
+type imageCache struct {
+	lock   sync.Mutex
+	images map[string]imageState
+	now    func() time.Time
+}
+
+// dockerImages is the state of the images that runtimes have used during this run
+var dockerImages = newImageCache()
+
+func newImageCache() *imageCache {
+	return &imageCache{images: map[string]imageState{}, now: time.Now}
+}
+
+// get returns the state of an image, if it was checked less than ImageCacheTTL ago
+func (c *imageCache) get(image string) (imageState, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state, ok := c.images[image]
+	if !ok || c.now().Sub(state.checked) >= ImageCacheTTL {
+		return imageState{}, false
+	}
+	return state, true
+}
+
+// set remembers the state of an image
+func (c *imageCache) set(image string, state imageState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state.checked = c.now()
+	c.images[image] = state
+}
+
+// forget forgets the state of an image, e.g. when it turns out to have been removed
+func (c *imageCache) forget(image string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.images, image)
+}
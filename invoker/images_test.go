@@ -0,0 +1,40 @@
+package invoker
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("imageCache", func() {
+
+	It("remembers images until the TTL has passed", func() {
+		now := time.Now()
+		cache := newImageCache()
+		cache.now = func() time.Time { return now }
+
+		_, ok := cache.get("lambci/lambda:nodejs8.10")
+		Expect(ok).To(BeFalse())
+
+		cache.set("lambci/lambda:nodejs8.10", imageState{present: true, pulled: true})
+		state, ok := cache.get("lambci/lambda:nodejs8.10")
+		Expect(ok).To(BeTrue())
+		Expect(state.present).To(BeTrue())
+		Expect(state.pulled).To(BeTrue())
+
+		now = now.Add(ImageCacheTTL)
+		_, ok = cache.get("lambci/lambda:nodejs8.10")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("forgets images", func() {
+		cache := newImageCache()
+		cache.set("lambci/lambda:python3.6", imageState{present: true})
+		cache.forget("lambci/lambda:python3.6")
+
+		_, ok := cache.get("lambci/lambda:python3.6")
+		Expect(ok).To(BeFalse())
+	})
+
+})
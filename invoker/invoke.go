@@ -0,0 +1,156 @@
+package invoker
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// ErrFunctionNotFound is returned when the template has no function with the given logical ID
+var ErrFunctionNotFound = errors.New("function not found in the template")
+
+// Options configures how Invoke runs a function
+type Options struct {
+	// Cwd is the directory that the function's CodeUri is relative to. It defaults to the
+	// current directory.
+	Cwd string
+	// EnvOverrideFile is a JSON file of environment variable values, like sam local invoke's --env-vars
+	EnvOverrideFile string
+	// Profile is the AWS credentials profile whose credentials are passed to the function
+	Profile string
+	// DockerNetwork is the Docker network that the function's container joins
+	DockerNetwork string
+	// SkipPullImage uses the local copy of the runtime's image, without checking for a newer one
+	SkipPullImage bool
+	// Backend is the name of the runtime backend that runs the function (docker by default)
+	Backend string
+	// Endpoints are the endpoint URLs of AWS services that the function calls, by service
+	// ("" for every service), e.g. LocalStack's
+	Endpoints map[string]string
+	// Logs, if set, also receives the function's logs as they're written
+	Logs io.Writer
+	// Log receives SAM Local's own log entries. If nil, the log package's standard logger is used.
+	Log logging.Logger
+}
+
+// Result is the result of an invocation
+type Result struct {
+	// Payload is what the function returned (or the error it raised), as JSON
+	Payload []byte
+	// Logs is everything the function logged
+	Logs          []byte
+	Duration      time.Duration
+	MaxMemoryUsed uint64
+	// Outcome is how the invocation ended. A handled error or a crash isn't an error of
+	// Invoke itself: it's only returned here.
+	Outcome Outcome
+}
+
+// Invoke runs the function with the given logical ID from a parsed template, passing it
+// the event, and waits for it to finish
+func Invoke(template *cloudformation.Template, logicalID string, event []byte, opt Options) (*Result, error) {
+	return InvokeContext(context.Background(), template, logicalID, event, opt)
+}
+
+// InvokeContext is like Invoke, but gives up when ctx is cancelled. An invocation that's
+// cancelled once it has started is stopped, and its Outcome is OutcomeCancelled.
+func InvokeContext(ctx context.Context, template *cloudformation.Template, logicalID string, event []byte, opt Options) (*Result, error) {
+
+	runtOpt, err := runtimeOpt(template, logicalID, opt)
+	if err != nil {
+		return nil, err
+	}
+	runtOpt.Context = ctx
+
+	runt, err := NewRuntime(runtOpt)
+	if err != nil {
+		return nil, err
+	}
+	defer runt.CleanUp()
+
+	stdout, stderr, err := runt.Invoke(string(event), opt.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs bytes.Buffer
+	logsDone := make(chan struct{})
+	go func() {
+		w := io.Writer(&logs)
+		if opt.Logs != nil {
+			w = io.MultiWriter(&logs, opt.Logs)
+		}
+		io.Copy(w, stderr)
+		close(logsDone)
+	}()
+
+	output, err := ioutil.ReadAll(stdout)
+	<-logsDone
+	if err != nil && ctx.Err() == nil {
+		// reads fail when cancelling closes the streams, which the outcome reports
+		return nil, err
+	}
+
+	report := runt.Report("")
+	return &Result{
+		Payload:       bytes.TrimSpace(output),
+		Logs:          logs.Bytes(),
+		Duration:      report.Duration,
+		MaxMemoryUsed: report.MaxMemoryUsed,
+		Outcome:       runt.Outcome(output),
+	}, nil
+
+}
+
+// ContainerConfig returns the Docker container configuration that Invoke would use to run
+// the function with the given event, without running anything
+func ContainerConfig(template *cloudformation.Template, logicalID string, event []byte, opt Options) (*container.Config, *container.HostConfig, error) {
+
+	runtOpt, err := runtimeOpt(template, logicalID, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	runt, err := New(runtOpt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return runt.ContainerConfig(string(event), opt.Profile)
+
+}
+
+// runtimeOpt returns the options of the runtime for a function of the template
+func runtimeOpt(template *cloudformation.Template, logicalID string, opt Options) (NewRuntimeOpt, error) {
+
+	function, found := template.GetAllAWSServerlessFunctionResources()[logicalID]
+	if !found {
+		return NewRuntimeOpt{}, ErrFunctionNotFound
+	}
+
+	logger := opt.Logs
+	if logger == nil {
+		logger = ioutil.Discard
+	}
+
+	return NewRuntimeOpt{
+		Cwd:             opt.Cwd,
+		LogicalID:       logicalID,
+		Function:        function,
+		EnvOverrideFile: opt.EnvOverrideFile,
+		Logger:          logger,
+		Log:             opt.Log,
+		SkipPullImage:   opt.SkipPullImage,
+		DockerNetwork:   opt.DockerNetwork,
+		Backend:         opt.Backend,
+		Endpoints:       opt.Endpoints,
+	}, nil
+
+}
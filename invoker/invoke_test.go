@@ -0,0 +1,115 @@
+package invoker
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/goformation"
+	"github.com/awslabs/goformation/cloudformation"
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Invoke", func() {
+
+	var dir string
+	var template *cloudformation.Template
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "invoke")
+		ioutil.WriteFile(filepath.Join(dir, "index.js"), []byte(`
+exports.handler = (event, context, callback) => {
+	console.log('hello from', context.functionName);
+	callback(null, { value: event.value });
+};
+exports.fail = async () => { throw new Error('boom'); };
+exports.slow = (event, context, callback) => { setTimeout(() => callback(null, {}), 10000); };
+`), 0644)
+
+		template, _ = goformation.ParseJSON([]byte(`{
+			"Resources": {
+				"Hello": {
+					"Type": "AWS::Serverless::Function",
+					"Properties": { "Runtime": "nodejs8.10", "Handler": "index.handler" }
+				},
+				"Fail": {
+					"Type": "AWS::Serverless::Function",
+					"Properties": { "Runtime": "nodejs8.10", "Handler": "index.fail" }
+				},
+				"Slow": {
+					"Type": "AWS::Serverless::Function",
+					"Properties": { "Runtime": "nodejs8.10", "Handler": "index.slow", "Timeout": 30 }
+				}
+			}
+		}`))
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("fails for functions that aren't in the template", func() {
+		_, err := Invoke(template, "Missing", []byte(`{}`), Options{Cwd: dir, Backend: "native"})
+		Expect(err).To(Equal(ErrFunctionNotFound))
+	})
+
+	It("builds the container config without running anything", func() {
+		config, host, err := ContainerConfig(template, "Hello", []byte(`{}`), Options{Cwd: dir})
+		Expect(err).To(BeNil())
+		Expect(config.Image).To(Equal("lambci/lambda:nodejs8.10"))
+		Expect(config.Cmd).To(ContainElement("index.handler"))
+		Expect(host.Binds).To(ContainElement(ContainSubstring(getWorkingDir(logging.For(nil, logging.Docker), dir))))
+	})
+
+	Context("with the native backend", func() {
+
+		BeforeEach(func() {
+			if _, err := exec.LookPath("node"); err != nil {
+				Skip("node is not installed")
+			}
+		})
+
+		It("returns the payload and logs", func() {
+			result, err := Invoke(template, "Hello", []byte(`{"value": 42}`), Options{Cwd: dir, Backend: "native"})
+			Expect(err).To(BeNil())
+			Expect(result.Payload).To(MatchJSON(`{"value": 42}`))
+			Expect(string(result.Logs)).To(ContainSubstring("hello from Hello"))
+			Expect(result.Outcome).To(Equal(OutcomeSuccess))
+			Expect(result.Duration).To(BeNumerically(">", 0))
+		})
+
+		It("returns handled errors as the outcome", func() {
+			result, err := Invoke(template, "Fail", []byte(`{}`), Options{Cwd: dir, Backend: "native"})
+			Expect(err).To(BeNil())
+			Expect(string(result.Payload)).To(ContainSubstring(`"errorMessage":"boom"`))
+			Expect(result.Outcome).To(Equal(OutcomeHandledError))
+		})
+
+		It("stops the invocation when the context is cancelled", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			result, err := InvokeContext(ctx, template, "Slow", []byte(`{}`), Options{Cwd: dir, Backend: "native"})
+			Expect(err).To(BeNil())
+			Expect(result.Outcome).To(Equal(OutcomeCancelled))
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		})
+
+		It("doesn't start invocations when the context is already cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := InvokeContext(ctx, template, "Hello", []byte(`{}`), Options{Cwd: dir, Backend: "native"})
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+	})
+
+})
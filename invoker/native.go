@@ -0,0 +1,395 @@
+package invoker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// nativeProcess is a function running directly on the host with --no-docker
+type nativeProcess struct {
+	cmd *exec.Cmd
+
+	// dir is the temporary directory the bootstrap script is written to
+	dir string
+
+	done chan struct{}
+	err  error
+}
+
+// nativeInterpreters are the interpreters each runtime can run with on the host, in order
+// of preference. Only interpreted runtimes can run without Docker.
+var nativeInterpreters = map[string][]string{
+	RuntimeName.NodeJS:    {"node", "nodejs"},
+	RuntimeName.NodeJS43:  {"node", "nodejs"},
+	RuntimeName.NodeJS610: {"node", "nodejs"},
+	RuntimeName.NodeJS810: {"node", "nodejs"},
+	RuntimeName.Python27:  {"python2.7", "python2", "python"},
+	RuntimeName.Python36:  {"python3.6", "python3", "python"},
+}
+
+// findInterpreter finds the interpreter to run a runtime's functions with on the host
+func findInterpreter(runtime string) (string, error) {
+
+	candidates, found := nativeInterpreters[runtime]
+	if !found {
+		return "", fmt.Errorf("%s functions can't run without Docker (only Node.js and Python functions can)", runtime)
+	}
+
+	for _, candidate := range candidates {
+		if path, err := exec.LookPath(candidate); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("could not find an interpreter for %s functions (tried %s)", runtime, strings.Join(candidates, ", "))
+
+}
+
+// nativeBackend runs functions directly on the host with --no-docker
+type nativeBackend struct{}
+
+// Start implements RuntimeBackend. Functions that run on the host only need an interpreter.
+func (nativeBackend) Start(r *Runtime) error {
+	_, err := findInterpreter(r.Name)
+	return err
+}
+
+// Invoke implements RuntimeBackend. It runs the function with a bootstrap script that emulates
+// the Lambda runtime: it loads the handler, passes it the event and a context, and writes
+// the result to stdout. Anything else the function logs goes to stderr.
+func (nativeBackend) Invoke(r *Runtime, event string, profile string) (io.ReadCloser, error) {
+	return r.invokeNative(strings.NewReader(event), profile)
+}
+
+// InvokeStream implements StreamingBackend. The bootstrap reads the event from stdin, so
+// it's streamed to the process as it reads it.
+func (nativeBackend) InvokeStream(r *Runtime, event io.Reader, profile string) (io.ReadCloser, error) {
+	return r.invokeNative(event, profile)
+}
+
+// invokeNative runs the function on the host, with the event read from stdin
+func (r *Runtime) invokeNative(stdin io.Reader, profile string) (io.ReadCloser, error) {
+
+	r.log().Infof("Invoking %s (%s) without Docker", r.Function.Handler, r.Name)
+
+	if err := r.resolveCodeUri(true); err != nil {
+		return nil, err
+	}
+
+	r.setFunctionDefaults()
+
+	interpreter, err := findInterpreter(r.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "aws-sam-local-native")
+	if err != nil {
+		return nil, err
+	}
+
+	script := nativeNodeBootstrap
+	bootstrap := filepath.Join(dir, "bootstrap.js")
+	if strings.HasPrefix(r.Name, "python") {
+		script = nativePythonBootstrap
+		bootstrap = filepath.Join(dir, "bootstrap.py")
+	}
+
+	if err := ioutil.WriteFile(bootstrap, []byte(script), 0644); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	code := r.Cwd
+	if r.DecompressedCwd != "" {
+		code = r.DecompressedCwd
+	}
+
+	args := []string{}
+	if len(r.DebugPort) > 0 && !strings.HasPrefix(r.Name, "python") {
+		args = append(args, "--inspect-brk=127.0.0.1:"+r.DebugPort)
+	}
+	args = append(args, bootstrap, r.Function.Handler)
+
+	cmd := exec.Command(interpreter, args...)
+	cmd.Dir = code
+	cmd.Env = r.nativeEnv(code, profile)
+	cmd.Stdin = stdin
+
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	r.started = time.Now()
+	r.process = &nativeProcess{cmd: cmd, dir: dir, done: make(chan struct{})}
+
+	process := r.process
+	go func() {
+		process.err = cmd.Wait()
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		close(process.done)
+	}()
+
+	r.logs = stderrReader
+
+	return stdoutReader, nil
+
+}
+
+// Logs implements RuntimeBackend
+func (nativeBackend) Logs(r *Runtime) io.ReadCloser {
+	return r.logs
+}
+
+// nativeEnv returns the environment of a function running on the host: the host's own
+// environment (so that the interpreter and its packages are found), with the function's
+// Lambda environment variables on top
+func (r *Runtime) nativeEnv(code string, profile string) []string {
+
+	env := map[string]string{}
+	for _, variable := range os.Environ() {
+		if parts := strings.SplitN(variable, "=", 2); len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	for name, value := range r.environment(profile) {
+		env[name] = value
+	}
+
+	env["LAMBDA_TASK_ROOT"] = code
+	env["AWS_LAMBDA_FUNCTION_NAME"] = r.LogicalID
+
+	result := []string{}
+	for name, value := range env {
+		result = append(result, name+"="+value)
+	}
+
+	return result
+
+}
+
+// Wait implements RuntimeBackend. It waits for the interpreter to exit.
+func (nativeBackend) Wait(r *Runtime) (int, error) {
+
+	if r.process == nil {
+		return 0, errors.New("the function isn't running")
+	}
+
+	select {
+	case <-r.process.done:
+	case <-time.After(5 * time.Second):
+		return 0, errors.New("timed out waiting for the function to exit")
+	}
+
+	if r.process.err != nil {
+		return 1, nil
+	}
+
+	return 0, nil
+
+}
+
+// Stop implements RuntimeBackend. It stops the interpreter, if it's still running, and
+// removes the bootstrap script.
+func (nativeBackend) Stop(r *Runtime) {
+
+	if r.process == nil {
+		return
+	}
+
+	select {
+	case <-r.process.done:
+	default:
+		r.process.cmd.Process.Kill()
+	}
+
+	os.RemoveAll(r.process.dir)
+
+}
+
+// nativeNodeBootstrap runs a Node.js handler the way the Lambda runtime does
+const nativeNodeBootstrap = `'use strict';
+const crypto = require('crypto');
+const path = require('path');
+const { Writable } = require('stream');
+const util = require('util');
+
+const spec = process.argv[2];
+const dot = spec.lastIndexOf('.');
+const modulePath = spec.slice(0, dot);
+const handlerName = spec.slice(dot + 1);
+
+// stdout is reserved for the result, so logs go to stderr
+const log = function () {
+    process.stderr.write(util.format.apply(util, arguments) + '\n');
+};
+console.log = console.info = console.warn = console.error = log;
+
+const env = process.env;
+const deadline = Date.now() + Number(env.AWS_LAMBDA_FUNCTION_TIMEOUT || 3) * 1000;
+let finished = false;
+
+const finish = (err, result) => {
+    if (finished) {
+        return;
+    }
+    finished = true;
+    let output;
+    if (err) {
+        const e = err instanceof Error ? err : new Error(String(err));
+        output = JSON.stringify({
+            errorMessage: e.message,
+            errorType: e.name,
+            stackTrace: (e.stack || '').split('\n').slice(1).map(line => line.trim()),
+        });
+    } else {
+        output = result === undefined ? 'null' : JSON.stringify(result);
+    }
+    process.stdout.write(output, () => process.exit(0));
+};
+
+// awslambda.streamifyResponse marks handlers that stream their response, which is written
+// to stdout as they write it, with HttpResponseStream.from's prelude first
+const streaming = Symbol('streaming');
+global.awslambda = {
+    streamifyResponse: handler => {
+        handler[streaming] = true;
+        return handler;
+    },
+    HttpResponseStream: {
+        from: (stream, prelude) => {
+            stream.write(JSON.stringify(prelude || {}));
+            stream.write(Buffer.alloc(8));
+            return stream;
+        },
+    },
+};
+
+const stream = (handler, event, context) => {
+    const responseStream = new Writable({
+        write: (chunk, encoding, callback) => process.stdout.write(chunk, callback),
+        final: callback => {
+            finished = true;
+            process.stdout.write('', () => process.exit(0));
+            callback();
+        },
+    });
+    const result = handler(event, responseStream, context);
+    if (result && typeof result.then === 'function') {
+        result.then(() => responseStream.end(), finish);
+    }
+};
+
+let input = '';
+process.stdin.setEncoding('utf8');
+process.stdin.on('data', chunk => { input += chunk; });
+process.stdin.on('end', () => {
+    const event = input ? JSON.parse(input) : {};
+    const context = {
+        functionName: env.AWS_LAMBDA_FUNCTION_NAME,
+        functionVersion: '$LATEST',
+        invokedFunctionArn: 'arn:aws:lambda:' + env.AWS_REGION + ':123456789012:function:' + env.AWS_LAMBDA_FUNCTION_NAME,
+        memoryLimitInMB: env.AWS_LAMBDA_FUNCTION_MEMORY_SIZE,
+        awsRequestId: crypto.randomBytes(16).toString('hex'),
+        logGroupName: '/aws/lambda/' + env.AWS_LAMBDA_FUNCTION_NAME,
+        logStreamName: 'sam-local',
+        callbackWaitsForEmptyEventLoop: true,
+        getRemainingTimeInMillis: () => Math.max(0, deadline - Date.now()),
+        succeed: result => finish(null, result),
+        fail: err => finish(err),
+        done: finish,
+    };
+
+    let handler;
+    try {
+        handler = require(path.resolve(env.LAMBDA_TASK_ROOT, modulePath))[handlerName];
+    } catch (e) {
+        return finish(e);
+    }
+    if (typeof handler !== 'function') {
+        return finish(new Error('Handler \'' + handlerName + '\' missing on module \'' + modulePath + '\''));
+    }
+
+    try {
+        if (handler[streaming]) {
+            return stream(handler, event, context);
+        }
+        const result = handler(event, context, finish);
+        if (result && typeof result.then === 'function') {
+            result.then(value => finish(null, value), finish);
+        }
+    } catch (e) {
+        finish(e);
+    }
+});
+`
+
+// nativePythonBootstrap runs a Python handler the way the Lambda runtime does
+const nativePythonBootstrap = `import importlib
+import json
+import os
+import sys
+import time
+import traceback
+import uuid
+
+spec = sys.argv[1]
+module_name, _, handler_name = spec.rpartition('.')
+sys.path.insert(0, os.environ['LAMBDA_TASK_ROOT'])
+
+# stdout is reserved for the result, so anything printed goes to stderr
+result_output = sys.stdout
+sys.stdout = sys.stderr
+
+deadline = time.time() + float(os.environ.get('AWS_LAMBDA_FUNCTION_TIMEOUT', '3'))
+
+
+class Context(object):
+    function_name = os.environ.get('AWS_LAMBDA_FUNCTION_NAME')
+    function_version = '$LATEST'
+    invoked_function_arn = 'arn:aws:lambda:%s:123456789012:function:%s' % (
+        os.environ.get('AWS_REGION'), os.environ.get('AWS_LAMBDA_FUNCTION_NAME'))
+    memory_limit_in_mb = os.environ.get('AWS_LAMBDA_FUNCTION_MEMORY_SIZE')
+    aws_request_id = str(uuid.uuid4())
+    log_group_name = '/aws/lambda/%s' % os.environ.get('AWS_LAMBDA_FUNCTION_NAME')
+    log_stream_name = 'sam-local'
+    identity = None
+    client_context = None
+
+    def get_remaining_time_in_millis(self):
+        return max(0, int((deadline - time.time()) * 1000))
+
+
+def main():
+    data = sys.stdin.read()
+    event = json.loads(data) if data else {}
+    try:
+        handler = getattr(importlib.import_module(module_name.replace('/', '.')), handler_name)
+        output = json.dumps(handler(event, Context()))
+    except Exception as e:
+        output = json.dumps({
+            'errorMessage': str(e),
+            'errorType': type(e).__name__,
+            'stackTrace': traceback.format_tb(sys.exc_info()[2]),
+        })
+    result_output.write(output)
+    result_output.flush()
+
+
+main()
+`
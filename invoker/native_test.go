@@ -0,0 +1,116 @@
+package invoker
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/goformation/cloudformation"
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Native runtimes", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		if _, err := exec.LookPath("node"); err != nil {
+			Skip("node is not installed")
+		}
+		dir, _ = ioutil.TempDir("", "native")
+		ioutil.WriteFile(filepath.Join(dir, "index.js"), []byte(`
+exports.handler = (event, context, callback) => {
+	console.log('logged');
+	callback(null, { name: context.functionName, value: event.value, task: process.env.LAMBDA_TASK_ROOT });
+};
+exports.fail = async () => { throw new Error('boom'); };
+exports.crash = () => process.exit(1);
+exports.stream = awslambda.streamifyResponse(async (event, responseStream) => {
+	responseStream = awslambda.HttpResponseStream.from(responseStream, { statusCode: 201 });
+	responseStream.write('hello ');
+	responseStream.write('world');
+});
+`), 0644)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	invoke := func(handler string) (string, string, Outcome) {
+		runt, err := New(NewRuntimeOpt{
+			Cwd:       dir,
+			LogicalID: "Hello",
+			Function:  cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10", Handler: handler},
+			NoDocker:  true,
+		})
+		Expect(err).To(BeNil())
+		defer runt.CleanUp()
+
+		stdout, stderr, err := runt.Invoke(`{"value": 42}`, "")
+		Expect(err).To(BeNil())
+
+		logs := make(chan []byte)
+		go func() {
+			data, _ := ioutil.ReadAll(stderr)
+			logs <- data
+		}()
+
+		output, _ := ioutil.ReadAll(stdout)
+		return string(output), string(<-logs), runt.Outcome(output)
+	}
+
+	It("only runs interpreted runtimes", func() {
+		_, err := findInterpreter("java8")
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("runs handlers on the host", func() {
+		output, logs, outcome := invoke("index.handler")
+		Expect(output).To(MatchJSON(`{"name": "Hello", "value": 42, "task": "` + getWorkingDir(logging.For(nil, logging.Docker), dir) + `"}`))
+		Expect(logs).To(ContainSubstring("logged"))
+		Expect(outcome).To(Equal(OutcomeSuccess))
+	})
+
+	It("reports errors and crashes", func() {
+		output, _, outcome := invoke("index.fail")
+		Expect(output).To(ContainSubstring(`"errorMessage":"boom"`))
+		Expect(outcome).To(Equal(OutcomeHandledError))
+
+		_, _, outcome = invoke("index.crash")
+		Expect(outcome).To(Equal(OutcomeCrash))
+	})
+
+	It("streams the responses of streaming handlers", func() {
+		output, _, outcome := invoke("index.stream")
+		Expect(output).To(Equal(`{"statusCode":201}` + "\x00\x00\x00\x00\x00\x00\x00\x00hello world"))
+		Expect(outcome).To(Equal(OutcomeSuccess))
+	})
+
+	It("streams events to the function", func() {
+		runt, err := New(NewRuntimeOpt{
+			Cwd:       dir,
+			LogicalID: "Hello",
+			Function:  cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10", Handler: "index.handler"},
+			NoDocker:  true,
+		})
+		Expect(err).To(BeNil())
+		defer runt.CleanUp()
+
+		event := io.MultiReader(strings.NewReader(`{"value": "`), strings.NewReader(strings.Repeat("x", 1<<20)), strings.NewReader(`"}`))
+		stdout, stderr, err := runt.InvokeReader(context.Background(), event, "")
+		Expect(err).To(BeNil())
+		go ioutil.ReadAll(stderr)
+
+		output, _ := ioutil.ReadAll(stdout)
+		Expect(string(output)).To(ContainSubstring(`"value":"` + strings.Repeat("x", 1<<20) + `"`))
+	})
+
+})
@@ -0,0 +1,56 @@
+package invoker
+
+import (
+	"encoding/json"
+)
+
+// Outcome is how an invocation of a function ended
+type Outcome int
+
+const (
+	OutcomeSuccess Outcome = iota
+	OutcomeHandledError
+	OutcomeCrash
+	OutcomeTimeout
+
+	// OutcomeCancelled means the invocation was stopped because its context was cancelled,
+	// e.g. because the client disconnected or SAM Local was shut down
+	OutcomeCancelled
+)
+
+// FunctionError returns the FunctionError reported by the Lambda Invoke API for the outcome
+func (o Outcome) FunctionError() string {
+	switch o {
+	case OutcomeHandledError:
+		return "Handled"
+	case OutcomeCrash, OutcomeTimeout, OutcomeCancelled:
+		return "Unhandled"
+	}
+	return ""
+}
+
+// String implements fmt.Stringer
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeHandledError:
+		return "function returned an error"
+	case OutcomeCrash:
+		return "function crashed without returning a result"
+	case OutcomeTimeout:
+		return "function timed out"
+	case OutcomeCancelled:
+		return "invocation was cancelled"
+	}
+	return "success"
+}
+
+// isFunctionError returns true if the output of a function is an error
+// returned by the Lambda runtime (e.g. {"errorMessage": "..."})
+func isFunctionError(output []byte) bool {
+	var result map[string]interface{}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return false
+	}
+	_, found := result["errorMessage"]
+	return found
+}
@@ -0,0 +1,23 @@
+package invoker
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Outcome", func() {
+
+	It("detects errors returned by functions", func() {
+		Expect(isFunctionError([]byte(`{"errorMessage": "boom", "errorType": "Error"}`))).To(BeTrue())
+		Expect(isFunctionError([]byte(`{"statusCode": 200}`))).To(BeFalse())
+		Expect(isFunctionError([]byte(`"errorMessage"`))).To(BeFalse())
+	})
+
+	It("reports the FunctionError like the Lambda API", func() {
+		Expect(OutcomeSuccess.FunctionError()).To(Equal(""))
+		Expect(OutcomeHandledError.FunctionError()).To(Equal("Handled"))
+		Expect(OutcomeTimeout.FunctionError()).To(Equal("Unhandled"))
+		Expect(OutcomeCancelled.FunctionError()).To(Equal("Unhandled"))
+	})
+
+})
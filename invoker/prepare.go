@@ -0,0 +1,66 @@
+package invoker
+
+import (
+	"sync"
+)
+
+// NewRuntimes instantiates the runtimes of several functions like NewRuntime, but gets up
+// to parallel of their backends ready at the same time, so that templates with many
+// functions start quickly. runtimes[i] and errs[i] are the results for opts[i].
+//
+// Each runtime image is only pulled once: the first function of each image is started
+// first, and the others then skip pulling it. Pull progress bars are only shown when
+// parallel is 1, as concurrent ones would garble each other.
+func NewRuntimes(opts []NewRuntimeOpt, parallel int) ([]*Runtime, []error) {
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	runtimes := make([]*Runtime, len(opts))
+	errs := make([]error, len(opts))
+
+	// Start the first function of each image, which pulls it, then the rest
+	first, rest := []int{}, []int{}
+	images := map[string]bool{}
+	for i, opt := range opts {
+		image := RuntimeImages[opt.Function.Runtime]
+		if images[image] {
+			rest = append(rest, i)
+			continue
+		}
+		images[image] = true
+		first = append(first, i)
+	}
+
+	for phase, indexes := range [][]int{first, rest} {
+
+		slots := make(chan struct{}, parallel)
+
+		var wg sync.WaitGroup
+		for _, i := range indexes {
+
+			wg.Add(1)
+			slots <- struct{}{}
+
+			opt := opts[i]
+			if phase > 0 {
+				opt.SkipPullImage = true
+			}
+
+			go func(i int, opt NewRuntimeOpt) {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				runtimes[i], errs[i] = newRuntime(opt, parallel > 1)
+			}(i, opt)
+
+		}
+
+		wg.Wait()
+	}
+
+	return runtimes, errs
+
+}
@@ -0,0 +1,74 @@
+package invoker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/awslabs/goformation/cloudformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// preparingBackend is an echo backend whose Start takes a while, and records how many
+// runtimes were starting at the same time, and which were asked to pull their image
+type preparingBackend struct {
+	echoBackend
+	lock     *sync.Mutex
+	starting *int
+	maximum  *int
+	pulled   map[string]bool
+}
+
+func (b preparingBackend) Start(r *Runtime) error {
+	b.lock.Lock()
+	*b.starting++
+	if *b.starting > *b.maximum {
+		*b.maximum = *b.starting
+	}
+	b.pulled[r.LogicalID] = !r.SkipPullImage
+	b.lock.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	b.lock.Lock()
+	*b.starting--
+	b.lock.Unlock()
+	return nil
+}
+
+var _ = Describe("NewRuntimes", func() {
+
+	starting, maximum := 0, 0
+	backend := preparingBackend{lock: &sync.Mutex{}, starting: &starting, maximum: &maximum, pulled: map[string]bool{}}
+	RegisterRuntimeBackend("preparing", backend)
+
+	opt := func(name string, runtime string) NewRuntimeOpt {
+		return NewRuntimeOpt{
+			LogicalID: name,
+			Function:  cloudformation.AWSServerlessFunction{Runtime: runtime, Handler: "index.handler"},
+			Backend:   "preparing",
+		}
+	}
+
+	It("starts up to parallel runtimes at the same time, and pulls each image once", func() {
+		opts := []NewRuntimeOpt{
+			opt("Node1", "nodejs8.10"),
+			opt("Python", "python3.6"),
+			opt("Node2", "nodejs8.10"),
+			opt("Node3", "nodejs8.10"),
+			opt("Unsupported", "cobol"),
+		}
+
+		runtimes, errs := NewRuntimes(opts, 2)
+		Expect(runtimes).To(HaveLen(5))
+		Expect(errs).To(Equal([]error{nil, nil, nil, nil, ErrRuntimeNotSupported}))
+		for i, name := range []string{"Node1", "Python", "Node2", "Node3"} {
+			Expect(runtimes[i].LogicalID).To(Equal(name))
+		}
+
+		Expect(maximum).To(Equal(2))
+		Expect(backend.pulled).To(Equal(map[string]bool{"Node1": true, "Python": true, "Node2": false, "Node3": false}))
+	})
+
+})
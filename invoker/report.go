@@ -0,0 +1,110 @@
+package invoker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"golang.org/x/net/context"
+)
+
+// Lambda bills duration in increments of 100ms
+const billingIncrement = 100 * time.Millisecond
+
+// Lambda pricing (us-east-1), used for the estimated cost of an invocation
+const (
+	pricePerGBSecond = 0.00001667
+	pricePerRequest  = 0.0000002
+)
+
+// Report contains the same information as the REPORT line that
+// Lambda logs at the end of every invocation
+type Report struct {
+	RequestID     string
+	Duration      time.Duration
+	MemorySize    int
+	MaxMemoryUsed uint64
+}
+
+// BilledDuration returns the duration rounded up to the next 100ms, as Lambda bills it
+func (r *Report) BilledDuration() time.Duration {
+	increments := (r.Duration + billingIncrement - 1) / billingIncrement
+	if increments < 1 {
+		increments = 1
+	}
+	return increments * billingIncrement
+}
+
+// EstimatedCost returns the cost of the invocation in USD, excluding the free tier
+func (r *Report) EstimatedCost() float64 {
+	gbSeconds := float64(r.MemorySize) / 1024 * r.BilledDuration().Seconds()
+	return gbSeconds*pricePerGBSecond + pricePerRequest
+}
+
+// String formats the report like Lambda's REPORT log line
+func (r *Report) String() string {
+
+	line := "REPORT"
+	if r.RequestID != "" {
+		line += " RequestId: " + r.RequestID + "\t"
+	}
+
+	return fmt.Sprintf("%s Duration: %.2f ms\tBilled Duration: %d ms\tMemory Size: %d MB\tMax Memory Used: %d MB",
+		line,
+		float64(r.Duration)/float64(time.Millisecond),
+		r.BilledDuration()/time.Millisecond,
+		r.MemorySize,
+		r.MaxMemoryUsed/(1024*1024),
+	)
+
+}
+
+// MemoryMonitor follows the stats of a container, and keeps its highest memory usage
+type MemoryMonitor struct {
+	sync.Mutex
+	max uint64
+}
+
+// MonitorMemory starts following the stats of a container until it stops
+func MonitorMemory(ctx context.Context, cli *client.Client, id string) *MemoryMonitor {
+
+	m := &MemoryMonitor{}
+
+	go func() {
+		stats, err := cli.ContainerStats(ctx, id, true)
+		if err != nil {
+			return
+		}
+		defer stats.Body.Close()
+
+		decoder := json.NewDecoder(stats.Body)
+		for {
+			var s types.StatsJSON
+			if err := decoder.Decode(&s); err != nil {
+				return
+			}
+
+			m.Lock()
+			if s.MemoryStats.MaxUsage > m.max {
+				m.max = s.MemoryStats.MaxUsage
+			}
+			if s.MemoryStats.Usage > m.max {
+				m.max = s.MemoryStats.Usage
+			}
+			m.Unlock()
+		}
+	}()
+
+	return m
+
+}
+
+// Max returns the highest memory usage seen so far, in bytes
+func (m *MemoryMonitor) Max() uint64 {
+	m.Lock()
+	defer m.Unlock()
+	return m.max
+}
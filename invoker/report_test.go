@@ -0,0 +1,28 @@
+package invoker
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Invocation report", func() {
+
+	It("rounds the billed duration up to the next 100ms", func() {
+		Expect((&Report{Duration: 101 * time.Millisecond}).BilledDuration()).To(Equal(200 * time.Millisecond))
+		Expect((&Report{Duration: 200 * time.Millisecond}).BilledDuration()).To(Equal(200 * time.Millisecond))
+		Expect((&Report{Duration: 0}).BilledDuration()).To(Equal(100 * time.Millisecond))
+	})
+
+	It("estimates the cost of an invocation", func() {
+		report := &Report{Duration: time.Second, MemorySize: 1024}
+		Expect(report.EstimatedCost()).To(BeNumerically("~", pricePerGBSecond+pricePerRequest, 1e-12))
+	})
+
+	It("formats the report like Lambda", func() {
+		report := &Report{RequestID: "abc", Duration: 12340 * time.Microsecond, MemorySize: 128, MaxMemoryUsed: 20 * 1024 * 1024}
+		Expect(report.String()).To(Equal("REPORT RequestId: abc\t Duration: 12.34 ms\tBilled Duration: 100 ms\tMemory Size: 128 MB\tMax Memory Used: 20 MB"))
+	})
+
+})
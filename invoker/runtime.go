@@ -0,0 +1,1206 @@
+package invoker
+
+import (
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	goruntime "runtime"
+	"strconv"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/aws-sam-local/tracing"
+	"golang.org/x/net/context"
+
+	"strings"
+	"sync"
+
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/docker/pkg/term"
+	"github.com/docker/go-connections/nat"
+	"github.com/fatih/color"
+	"github.com/imdario/mergo"
+	"github.com/mattn/go-colorable"
+	"github.com/pkg/errors"
+)
+
+// Debugger attaches a debugger to functions that are invoked in debug mode (with a DebugPort)
+type Debugger interface {
+
+	// AttachDebugger is called once the function has started, and is waiting for a debugger
+	AttachDebugger(r *Runtime)
+}
+
+// Runtime contains a reference to a single container for a specific runtime. It is used to invoke functions multiple times against a single container.
+type Runtime struct {
+	LogicalID       string
+	ID              string
+	Name            string
+	Image           string
+	Cwd             string
+	DecompressedCwd string
+	Layer           string
+	Endpoints       map[string]string
+	Credentials     *credentials.Value
+	Function        cloudformation.AWSServerlessFunction
+	EnvOverrideFile string
+	Environment     map[string]string
+	DebugPort       string
+	Debugger        Debugger
+	Context         context.Context
+	Client          *client.Client
+	TimeoutTimer    *time.Timer
+	Logger          io.Writer
+	Log             logging.Logger
+	DockerNetwork   string
+	EstimateCost    bool
+	SkipPullImage   bool
+	Backend         RuntimeBackend
+	started         time.Time
+	memory          *MemoryMonitor
+	timedOut        bool
+	cancelled       bool
+	cancel          context.CancelFunc
+	logs            io.ReadCloser
+	process         *nativeProcess
+	quietPull       bool
+
+	// lazy is the state of the backend of lazy runtimes, which copies of the runtime share,
+	// so that only one of them starts it
+	lazy *lazyStart
+
+	// span is the span of the invocation, when its Context has one, which ends when it's
+	// cleaned up
+	span *tracing.Span
+}
+
+var (
+	// ErrRuntimeNotDownloaded is thrown when NewRuntime() is called, but the requested
+	// runtime has not been downloaded
+	ErrRuntimeNotDownloaded = errors.New("requested runtime has not been downloaded")
+
+	// ErrRuntimeNotSupported is thrown with the requested runtime is not yet supported
+	ErrRuntimeNotSupported = errors.New("unsupported runtime")
+)
+
+// RuntimeName contains the names of the supported Lambda runtimes
+var RuntimeName = struct {
+	NodeJS       string
+	NodeJS43     string
+	NodeJS610    string
+	NodeJS810    string
+	Python27     string
+	Python36     string
+	Java8        string
+	Go1x         string
+	DotNetCore20 string
+}{
+	NodeJS:       "nodejs",
+	NodeJS43:     "nodejs4.3",
+	NodeJS610:    "nodejs6.10",
+	NodeJS810:    "nodejs8.10",
+	Python27:     "python2.7",
+	Python36:     "python3.6",
+	Java8:        "java8",
+	Go1x:         "go1.x",
+	DotNetCore20: "dotnetcore2.0",
+}
+
+// RuntimeImages are the Docker images that functions are invoked in, by runtime
+var RuntimeImages = map[string]string{
+	RuntimeName.NodeJS:       "lambci/lambda:nodejs",
+	RuntimeName.NodeJS43:     "lambci/lambda:nodejs4.3",
+	RuntimeName.NodeJS610:    "lambci/lambda:nodejs6.10",
+	RuntimeName.NodeJS810:    "lambci/lambda:nodejs8.10",
+	RuntimeName.Python27:     "lambci/lambda:python2.7",
+	RuntimeName.Python36:     "lambci/lambda:python3.6",
+	RuntimeName.Java8:        "lambci/lambda:java8",
+	RuntimeName.Go1x:         "lambci/lambda:go1.x",
+	RuntimeName.DotNetCore20: "lambci/lambda:dotnetcore2.0",
+}
+
+// NewRuntimeOpt contains parameters that are passed to the NewRuntime method
+type NewRuntimeOpt struct {
+	Cwd             string
+	LogicalID       string
+	Function        cloudformation.AWSServerlessFunction
+	EnvOverrideFile string
+	DebugPort       string
+	Debugger        Debugger
+	Logger          io.Writer
+	SkipPullImage   bool
+	DockerNetwork   string
+	EstimateCost    bool
+	NoDocker        bool
+
+	// Context is the context of the runtime's Docker operations (such as pulling its image)
+	// and invocations. Cancelling it stops the invocation in progress. If nil,
+	// context.Background() is used.
+	Context context.Context
+
+	// Log receives the runtime's log entries, as the Docker subsystem. Logger is where the
+	// function's own logs are written. If nil, the log package's standard logger is used.
+	Log logging.Logger
+
+	// Environment holds environment variables that override all others, such as the
+	// secrets that a plugin injects
+	Environment map[string]string
+
+	// Endpoints are the endpoint URLs that the function's AWS SDK calls go to, by service
+	// (e.g. s3 or dynamodb), or "" for every service, such as LocalStack's. They're passed
+	// to the function as AWS_ENDPOINT_URL variables, which override the function's own.
+	Endpoints map[string]string
+
+	// Credentials, if set, are the AWS credentials that the function is given instead of
+	// the profile's, such as the ones that SAM Local's IAM simulation checks its calls by
+	Credentials *credentials.Value
+
+	// Layer is a directory to mount at /opt, as Lambda does with a function's layers, such
+	// as the dependencies that were built into a layer of their own
+	Layer string
+
+	// Backend is the name of the runtime backend that runs the function. It defaults to
+	// "docker", or "native" with NoDocker.
+	Backend string
+
+	// Lazy defers getting the backend ready (e.g. pulling the image) until the function is
+	// first invoked, so that runtimes of functions that are never invoked cost nothing
+	Lazy bool
+}
+
+// NewRuntime instantiates a Lambda runtime, and gets its backend ready to invoke the function
+func NewRuntime(opt NewRuntimeOpt) (*Runtime, error) {
+	return newRuntime(opt, false)
+}
+
+// newRuntime instantiates a Lambda runtime and starts its backend. If quietPull is set,
+// images are pulled without showing progress bars.
+func newRuntime(opt NewRuntimeOpt, quietPull bool) (*Runtime, error) {
+
+	r, err := New(opt)
+	if err != nil {
+		return nil, err
+	}
+	r.quietPull = quietPull
+
+	if opt.Lazy {
+		// The image is pulled while requests are being served, so without progress bars
+		r.quietPull = true
+		r.lazy = &lazyStart{pending: true}
+		return r, nil
+	}
+
+	if err := r.Backend.Start(r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+
+}
+
+// lazyStart is whether the backend of a lazy runtime is yet to be started, and the Docker
+// client that starting it connected, for the copies of the runtime that didn't start it
+type lazyStart struct {
+	sync.Mutex
+	pending bool
+	client  *client.Client
+}
+
+// start starts the backend of a lazy runtime, if it hasn't been yet. If it fails, it's
+// tried again on the next invocation.
+func (r *Runtime) start() error {
+
+	if r.lazy == nil {
+		return nil
+	}
+
+	r.lazy.Lock()
+	defer r.lazy.Unlock()
+
+	if !r.lazy.pending {
+		if r.Client == nil {
+			r.Client = r.lazy.client
+		}
+		return nil
+	}
+
+	r.log().Infof("Preparing %s (%s) for its first invocation", r.LogicalID, r.Name)
+	r.span.SetAttributes(tracing.Attributes{"faas.coldstart": true})
+	_, span := tracing.Start(tracing.ContextWithSpan(r.Context, r.span), "start runtime", tracing.Internal, nil)
+	defer span.End()
+	if err := r.Backend.Start(r); err != nil {
+		span.SetError(err)
+		return err
+	}
+	r.lazy.pending = false
+	r.lazy.client = r.Client
+
+	return nil
+
+}
+
+// log returns the entry that the runtime logs to
+func (r *Runtime) log() *logging.Entry {
+	return logging.For(r.Log, logging.Docker).With(logging.Fields{"function": r.LogicalID})
+}
+
+// dockerBackend runs each invocation in a new container of the lambci/lambda image for
+// the function's runtime
+type dockerBackend struct{}
+
+// Start implements RuntimeBackend. It connects to Docker, and pulls the runtime's image.
+func (dockerBackend) Start(r *Runtime) error {
+
+	cli, err := DockerClient()
+	if err != nil {
+		return err
+	}
+	r.Client = cli
+
+	// Check if we have the required Docker image for this runtime, unless it was checked
+	// (or pulled) recently
+	state, cached := dockerImages.get(r.Image)
+	if !cached {
+		filter := filters.NewArgs()
+		filter.Add("reference", r.Image)
+		images, err := cli.ImageList(r.Context, types.ImageListOptions{
+			Filters: filter,
+		})
+		if err != nil {
+			return err
+		}
+		state.present = len(images) > 0
+	}
+
+	// By default, pull images unless we are told not to, or already have
+	pullImage := !state.pulled
+
+	if r.SkipPullImage {
+		r.log().Infof("Requested to skip pulling images ...")
+		pullImage = false
+	}
+
+	// However, if we don't have the image we will need it...
+	if !state.present {
+		r.log().Infof("Runtime image missing, will pull....")
+		pullImage = true
+	}
+
+	if pullImage {
+		r.log().Infof("Fetching %s image for %s runtime...", r.Image, r.Function.Runtime)
+		progress, err := cli.ImagePull(r.Context, r.Image, types.ImagePullOptions{})
+		if !state.present && err != nil {
+			r.log().Errorf("Could not fetch %s Docker image: %s", r.Image, err)
+			return err
+		}
+
+		if err != nil {
+			r.log().Warnf("Could not fetch %s Docker image: %s", r.Image, err)
+		} else if r.quietPull {
+			defer progress.Close()
+			if _, err := io.Copy(ioutil.Discard, progress); err != nil {
+				r.log().Warnf("Could not fetch %s Docker image: %s", r.Image, err)
+			} else {
+				r.log().Infof("Fetched %s image", r.Image)
+				state.pulled = true
+			}
+		} else {
+
+			// Use Docker's standard progressbar to show image pull progress.
+			// However there is a bug that we are working around. We'll do the same
+			// as Docker does, and temporarily set the TERM to a non-existant
+			// terminal
+			// More info here:
+			// https://github.com/Nvveen/Gotty/pull/1
+
+			origTerm := os.Getenv("TERM")
+			os.Setenv("TERM", "eifjccgifcdekgnbtlvrgrinjjvfjggrcudfrriivjht")
+			defer os.Setenv("TERM", origTerm)
+
+			// Show the Docker pull messages in green
+			color.Output = colorable.NewColorableStderr()
+			color.Set(color.FgGreen)
+			defer color.Unset()
+
+			if err := jsonmessage.DisplayJSONMessagesStream(progress, os.Stderr, os.Stderr.Fd(), term.IsTerminal(os.Stderr.Fd()), nil); err == nil {
+				state.pulled = true
+			}
+		}
+
+		state.present = state.present || state.pulled
+	}
+
+	if state.present {
+		dockerImages.set(r.Image, state)
+	}
+
+	return nil
+
+}
+
+// New creates a Runtime without starting its backend, so that the container configuration
+// can be worked out (e.g. for --dry-run) without Docker being available.
+func New(opt NewRuntimeOpt) (*Runtime, error) {
+
+	// Determine which docker image to use for the provided runtime
+	image, found := RuntimeImages[opt.Function.Runtime]
+	if !found {
+		return nil, ErrRuntimeNotSupported
+	}
+
+	name := opt.Backend
+	if name == "" && opt.NoDocker {
+		name = "native"
+	} else if name == "" {
+		name = "docker"
+	}
+
+	backend, err := newRuntimeBackend(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := opt.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return &Runtime{
+		LogicalID:       opt.LogicalID,
+		Name:            opt.Function.Runtime,
+		Cwd:             getWorkingDir(logging.For(opt.Log, logging.Docker), opt.Cwd),
+		Image:           image,
+		Function:        opt.Function,
+		EnvOverrideFile: opt.EnvOverrideFile,
+		Environment:     opt.Environment,
+		Layer:           opt.Layer,
+		Endpoints:       opt.Endpoints,
+		Credentials:     opt.Credentials,
+		DebugPort:       opt.DebugPort,
+		Debugger:        opt.Debugger,
+		Context:         ctx,
+		Logger:          opt.Logger,
+		Log:             opt.Log,
+		DockerNetwork:   opt.DockerNetwork,
+		EstimateCost:    opt.EstimateCost,
+		SkipPullImage:   opt.SkipPullImage,
+		Backend:         backend,
+	}, nil
+
+}
+
+func overrideHostConfig(cfg *container.HostConfig) error {
+
+	const dotfile = ".config/aws-sam-local/container-config.json"
+	const eMsg = "unable to use container host config override file from '$HOME/%s'"
+
+	homeDir := os.Getenv("HOME")
+	if len(homeDir) == 0 {
+		return errors.Wrapf(errors.New("HOME env variable is not set"), eMsg, dotfile)
+	}
+
+	reader, err := os.Open(path.Join(homeDir, dotfile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, eMsg, dotfile)
+	}
+	defer reader.Close()
+
+	override := new(container.HostConfig)
+	if err := json.NewDecoder(reader).Decode(override); err != nil {
+		return errors.Wrapf(err, eMsg, dotfile)
+	}
+
+	return errors.Wrapf(mergo.MergeWithOverwrite(cfg, override), eMsg, dotfile)
+}
+
+func (r *Runtime) getHostConfig() (*container.HostConfig, error) {
+
+	// Check if there is a decompressed archive directory we should
+	// be using instead of the normal working directory (e.g. if a
+	// ZIP/JAR archive was specified as the CodeUri)
+	mount := r.Cwd
+	if r.DecompressedCwd != "" {
+		mount = r.DecompressedCwd
+	}
+
+	// If the path is a Windows style one, convert it to the format that Docker Toolbox requires.
+	mount = convertWindowsPath(mount)
+
+	r.log().Infof("Mounting %s as /var/task:ro inside runtime container", mount)
+	host := &container.HostConfig{
+		Resources: container.Resources{
+			Memory: int64(r.Function.MemorySize * 1024 * 1024),
+		},
+		Binds: []string{
+			fmt.Sprintf("%s:/var/task:ro", mount),
+		},
+		PortBindings: r.getDebugPortBindings(),
+	}
+
+	if r.Layer != "" {
+		layer := convertWindowsPath(r.Layer)
+		r.log().Infof("Mounting %s as /opt:ro inside runtime container", layer)
+		host.Binds = append(host.Binds, fmt.Sprintf("%s:/opt:ro", layer))
+	}
+
+	if err := overrideHostConfig(host); err != nil {
+		r.log().Warnf("%s", err)
+	}
+
+	return host, nil
+}
+
+// resolveCodeUri works out the directory to mount for the function's CodeUri. If the
+// CodeUri is a ZIP/JAR archive it is decompressed, unless decompress is false.
+func (r *Runtime) resolveCodeUri(decompress bool) error {
+
+	// If the CodeUri has been specified as a .jar or .zip file, unzip it on the fly
+	if r.Function.CodeUri != nil && r.Function.CodeUri.String != nil {
+		codeuri := filepath.Join(r.Cwd, *r.Function.CodeUri.String)
+
+		// Check if the CodeUri exists on the local filesystem
+		if _, err := os.Stat(codeuri); err == nil {
+			// It does exist - maybe it's a ZIP/JAR that we need to decompress on the fly
+			if strings.HasSuffix(codeuri, ".jar") || strings.HasSuffix(codeuri, ".zip") {
+				if !decompress {
+					r.DecompressedCwd = codeuri
+					return nil
+				}
+				r.log().Infof("Decompressing %s", codeuri)
+				decompressedDir, err := decompressArchive(codeuri)
+				if err != nil {
+					r.log().Errorf("ERROR: Failed to decompress archive: %s", err)
+					return fmt.Errorf("failed to decompress archive: %s", err)
+				}
+				r.DecompressedCwd = decompressedDir
+			} else {
+				// We have a CodeUri that exists locally, but isn't a ZIP/JAR.
+				// Just append it to the working directory
+				r.Cwd = codeuri
+			}
+		} else {
+			// The CodeUri specified doesn't exist locally. It could be
+			// an S3 location (s3://.....), so just ignore it.
+		}
+	}
+
+	return nil
+
+}
+
+// setFunctionDefaults sets the timeout and memory size of the function, if they aren't set
+// in the SAM template. This needs to be done before environment variables are generated for
+// the Lambda runtime, so that the correct AWS_LAMBDA_FUNCTION_TIMEOUT and
+// AWS_LAMBDA_FUNCTION_MEMORY_SIZE are used.
+func (r *Runtime) setFunctionDefaults() {
+
+	// Default to 3 seconds (as per SAM specification)
+	if r.Function.Timeout <= 0 {
+		r.Function.Timeout = 3
+	}
+
+	// Default to 128MB (as per SAM specification)
+	if r.Function.MemorySize <= 0 {
+		r.Function.MemorySize = 128
+	}
+
+}
+
+// ContainerConfig works out the configuration of the container used to invoke the function
+// with the provided event payload, without connecting to Docker. ZIP/JAR archives are mounted
+// as they are, rather than decompressed.
+func (r *Runtime) ContainerConfig(event string, profile string) (*container.Config, *container.HostConfig, error) {
+
+	if err := r.resolveCodeUri(false); err != nil {
+		return nil, nil, err
+	}
+
+	return r.containerConfig(event, profile)
+
+}
+
+// containerConfig returns the configuration of the container used to invoke the
+// function with the provided event payload.
+func (r *Runtime) containerConfig(event string, profile string) (*container.Config, *container.HostConfig, error) {
+
+	r.setFunctionDefaults()
+
+	// Define the container options
+	config := &container.Config{
+		WorkingDir:   "/var/task",
+		Image:        r.Image,
+		Tty:          false,
+		ExposedPorts: r.getDebugExposedPorts(),
+		Entrypoint:   r.getDebugEntrypoint(),
+		Cmd:          []string{r.Function.Handler, event},
+		Labels:       map[string]string{ContainerLabel: r.LogicalID},
+	}
+
+	// Endpoints on this machine are reached through the Docker host from the container
+	env := r.environment(profile)
+	local := rewriteLocalEndpoints(env)
+	for k, v := range env {
+		config.Env = append(config.Env, k+"="+v)
+	}
+
+	host, err := r.getHostConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Docker for Mac and Windows resolve host.docker.internal themselves
+	if local && goruntime.GOOS == "linux" {
+		host.ExtraHosts = append(host.ExtraHosts, hostAlias+":host-gateway")
+	}
+
+	return config, host, nil
+
+}
+
+// Invoke runs a Lambda function within the runtime with the provided event
+// payload and returns a pair of io.Readers for it's stdout (callback results)
+// and stderr (runtime logs). If the runtime's Context is cancelled before the
+// invocation finishes, the invocation is stopped and both readers are closed.
+func (r *Runtime) Invoke(event string, profile string) (io.Reader, io.Reader, error) {
+
+	return r.invoke(func() (io.ReadCloser, error) {
+		return r.Backend.Invoke(r, event, profile)
+	})
+
+}
+
+// InvokeReader is like InvokeContext, but reads the event from a stream, which lets large
+// events (e.g. with the bodies of uploads) be passed to the function without holding them
+// in memory. Backends that can't stream events get the whole event read into memory.
+func (r *Runtime) InvokeReader(ctx context.Context, event io.Reader, profile string) (io.Reader, io.Reader, error) {
+
+	r.Context = ctx
+
+	backend, ok := r.Backend.(StreamingBackend)
+	if !ok {
+		data, err := ioutil.ReadAll(event)
+		if err != nil {
+			return nil, nil, err
+		}
+		return r.Invoke(string(data), profile)
+	}
+
+	return r.invoke(func() (io.ReadCloser, error) {
+		return backend.InvokeStream(r, event, profile)
+	})
+
+}
+
+// invoke starts an invocation with start, and stops it when it times out or the runtime's
+// Context is cancelled
+func (r *Runtime) invoke(start func() (io.ReadCloser, error)) (io.Reader, io.Reader, error) {
+
+	if err := r.Context.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	_, r.span = tracing.Start(r.Context, "invoke "+r.LogicalID, tracing.Client, tracing.Attributes{
+		"faas.invoked_name":     r.LogicalID,
+		"faas.invoked_provider": "aws",
+		"aws.lambda.runtime":    r.Function.Runtime,
+		"aws.lambda.handler":    r.Function.Handler,
+	})
+
+	if err := r.start(); err != nil {
+		r.span.SetError(err)
+		r.span.End()
+		return nil, nil, err
+	}
+
+	stdout, err := start()
+	if err != nil {
+		// Remove whatever was started before the failure (e.g. if it was cancelled)
+		r.Backend.Stop(r)
+		r.span.SetError(err)
+		r.span.End()
+		return nil, nil, err
+	}
+	stderr := r.Backend.Logs(r)
+
+	// When debugging, the function may be paused for as long as the developer needs,
+	// so it's only stopped when SAM Local is interrupted
+	if len(r.DebugPort) == 0 {
+		r.setupTimeoutTimer(stdout, stderr)
+	}
+	r.stopOnCancel(stdout, stderr)
+
+	return stdout, stderr, nil
+
+}
+
+// InvokeContext is like Invoke, but the invocation is stopped when ctx is cancelled (e.g.
+// when the client of an HTTP request disconnects). ctx replaces the runtime's Context.
+func (r *Runtime) InvokeContext(ctx context.Context, event string, profile string) (io.Reader, io.Reader, error) {
+	r.Context = ctx
+	return r.Invoke(event, profile)
+}
+
+// Invoke implements RuntimeBackend. It creates and starts a container for the invocation,
+// and attaches to it.
+func (dockerBackend) Invoke(r *Runtime, event string, profile string) (io.ReadCloser, error) {
+	return r.invokeContainer(event, nil, profile)
+}
+
+// InvokeStream implements StreamingBackend. The event is written to the container's stdin,
+// which the docker-lambda images read it from when DOCKER_LAMBDA_USE_STDIN is set, rather
+// than passed as an argument, which is limited in size.
+func (dockerBackend) InvokeStream(r *Runtime, event io.Reader, profile string) (io.ReadCloser, error) {
+	return r.invokeContainer("", event, profile)
+}
+
+// invokeContainer creates and starts a container for an invocation with the event, or with
+// the event read from stdin if it's not nil
+func (r *Runtime) invokeContainer(event string, stdin io.Reader, profile string) (io.ReadCloser, error) {
+
+	r.log().Infof("Invoking %s (%s)", r.Function.Handler, r.Name)
+
+	if err := r.resolveCodeUri(true); err != nil {
+		return nil, err
+	}
+
+	config, host, err := r.containerConfig(event, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if stdin != nil {
+		config.Cmd = []string{r.Function.Handler}
+		config.Env = append(config.Env, "DOCKER_LAMBDA_USE_STDIN=1")
+		config.AttachStdin = true
+		config.OpenStdin = true
+		config.StdinOnce = true
+	}
+
+	r.ID = ""
+	resp, err := r.Client.ContainerCreate(r.Context, config, host, nil, "")
+	if err != nil {
+		if client.IsErrImageNotFound(err) {
+			// It was removed since it was checked, so check for it again next time
+			dockerImages.forget(r.Image)
+		}
+		return nil, err
+	}
+
+	r.ID = resp.ID
+	ActiveContainers.Add(resp.ID, r.Client)
+
+	if r.DockerNetwork != "" {
+		if err := r.Client.NetworkConnect(r.Context, r.DockerNetwork, resp.ID, nil); err != nil {
+			return nil, err
+		}
+		r.log().With(logging.Fields{"container": resp.ID}).Infof("Connecting container %s to network %s", resp.ID, r.DockerNetwork)
+	}
+
+	// Attach to the container to read the stdout/stderr stream (and write the event to
+	// stdin). It's attached before it's started, so that stdin is open when it starts.
+	attach, err := r.Client.ContainerAttach(r.Context, resp.ID, types.ContainerAttachOptions{
+		Stream: true,
+		Stdin:  stdin != nil,
+		Stdout: true,
+		Stderr: true,
+		Logs:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Invoke the container
+	if err := r.Client.ContainerStart(r.Context, resp.ID, types.ContainerStartOptions{}); err != nil {
+		attach.Close()
+		return nil, err
+	}
+
+	// Keep track of the duration and memory usage for the invocation report
+	r.started = time.Now()
+	r.memory = MonitorMemory(r.Context, r.Client, resp.ID)
+
+	if stdin != nil {
+		go func() {
+			if _, err := io.Copy(attach.Conn, stdin); err != nil {
+				r.log().Errorf("Error writing the event to the container: %s", err)
+			}
+			attach.CloseWrite()
+		}()
+	}
+
+	// As per the Docker SDK documentation, when attaching to a container
+	// the resulting io.Reader stream is has stdin, stdout and stderr muxed
+	// into a single stream, with a 8 byte header defining the type/size.
+	// Demux the stream into separate io.Readers for stdout and stderr
+	// src: https://docs.docker.com/engine/api/v1.28/#operation/ContainerAttach
+	stdout, stderr, err := demuxDockerStream(r.log(), attach.Reader)
+	if err != nil {
+		return nil, err
+	}
+	r.logs = stderr
+
+	if len(r.DebugPort) > 0 && r.Debugger != nil {
+		r.Debugger.AttachDebugger(r)
+	}
+
+	return stdout, nil
+
+}
+
+// Logs implements RuntimeBackend
+func (dockerBackend) Logs(r *Runtime) io.ReadCloser {
+	return r.logs
+}
+
+// Wait implements RuntimeBackend
+func (dockerBackend) Wait(r *Runtime) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := r.Client.ContainerWait(ctx, r.ID)
+	return int(status), err
+}
+
+// Stop implements RuntimeBackend. It removes the invocation's container, even if the
+// runtime's Context has been cancelled.
+func (dockerBackend) Stop(r *Runtime) {
+	if r.ID == "" {
+		return
+	}
+	r.Client.ContainerKill(context.Background(), r.ID, "SIGKILL")
+	r.Client.ContainerRemove(context.Background(), r.ID, types.ContainerRemoveOptions{})
+	ActiveContainers.Remove(r.ID)
+}
+
+func (r *Runtime) setupTimeoutTimer(stdout, stderr io.ReadCloser) {
+
+	// Start a timer, we'll use this to abort the function if it runs beyond the specified timeout
+	timeout := time.Duration(r.Function.Timeout) * time.Second
+
+	timer := time.NewTimer(timeout)
+	r.TimeoutTimer = timer
+	go func() {
+		<-timer.C
+		r.log().Warnf("Function %s timed out after %d seconds", r.Function.Handler, timeout/time.Second)
+		r.timedOut = true
+		stderr.Close()
+		stdout.Close()
+		r.CleanUp()
+	}()
+}
+
+// stopOnCancel stops the invocation if the runtime's Context is cancelled before the
+// invocation is cleaned up
+func (r *Runtime) stopOnCancel(stdout, stderr io.ReadCloser) {
+
+	parent := r.Context
+	ctx, cancel := context.WithCancel(parent)
+	r.cancel = cancel
+
+	go func() {
+		<-ctx.Done()
+		if parent.Err() == nil {
+			// The invocation was cleaned up
+			return
+		}
+		r.log().Warnf("Invocation of %s was cancelled: %s", r.Function.Handler, parent.Err())
+		r.cancelled = true
+		stderr.Close()
+		stdout.Close()
+		r.CleanUp()
+	}()
+
+}
+
+func (r *Runtime) getDebugPortBindings() nat.PortMap {
+	if len(r.DebugPort) == 0 {
+		return nil
+	}
+	return nat.PortMap{
+		nat.Port(r.DebugPort): []nat.PortBinding{{HostPort: r.DebugPort}},
+	}
+}
+
+func (r *Runtime) getDebugExposedPorts() nat.PortSet {
+	if len(r.DebugPort) == 0 {
+		return nil
+	}
+	return nat.PortSet{nat.Port(r.DebugPort): {}}
+}
+
+func (r *Runtime) getDebugEntrypoint() (overrides []string) {
+	if len(r.DebugPort) == 0 {
+		return
+	}
+
+	debuggerArgs := os.Getenv("DEBUGGER_ARGS")
+	debuggerArgsArray := []string{}
+	if len(debuggerArgs) > 0 {
+		debuggerArgsArray = strings.Split(debuggerArgs, " ")
+	}
+
+	switch r.Name {
+	// configs from: https://github.com/lambci/docker-lambda
+	// to which we add the extra debug mode options
+	case RuntimeName.Java8:
+		overrides = []string{
+			"/usr/bin/java",
+		}
+		overrides = append(overrides, debuggerArgsArray...)
+		overrides = append(overrides,
+			"-agentlib:jdwp=transport=dt_socket,server=y,suspend=y,quiet=y,address="+r.DebugPort,
+			"-XX:MaxHeapSize=1336935k",
+			"-XX:MaxMetaspaceSize=157286k",
+			"-XX:ReservedCodeCacheSize=78643k",
+			"-XX:+UseSerialGC",
+			//"-Xshare:on", doesn't work in conjunction with the debug options
+			"-XX:-TieredCompilation",
+			"-Djava.net.preferIPv4Stack=true",
+			"-jar",
+			"/var/runtime/lib/LambdaJavaRTEntry-1.0.jar",
+		)
+	case RuntimeName.NodeJS:
+		overrides = []string{
+			"/usr/bin/node",
+		}
+		overrides = append(overrides, debuggerArgsArray...)
+		overrides = append(overrides,
+			"--debug-brk="+r.DebugPort,
+			"--nolazy",
+			"--max-old-space-size=1229",
+			"--max-new-space-size=153",
+			"--max-executable-size=153",
+			"--expose-gc",
+			"/var/runtime/node_modules/awslambda/bin/awslambda",
+		)
+	case RuntimeName.NodeJS43:
+		overrides = []string{
+			"/usr/local/lib64/node-v4.3.x/bin/node",
+		}
+		overrides = append(overrides, debuggerArgsArray...)
+		overrides = append(overrides,
+			"--debug-brk="+r.DebugPort,
+			"--nolazy",
+			"--max-old-space-size=2547",
+			"--max-semi-space-size=150",
+			"--max-executable-size=160",
+			"--expose-gc",
+			"/var/runtime/node_modules/awslambda/index.js",
+		)
+	case RuntimeName.NodeJS610:
+		overrides = []string{
+			"/var/lang/bin/node",
+		}
+		overrides = append(overrides, debuggerArgsArray...)
+		overrides = append(overrides,
+			"--inspect="+r.DebugPort,
+			"--debug-brk",
+			"--nolazy",
+			"--max-old-space-size=2547",
+			"--max-semi-space-size=150",
+			"--max-executable-size=160",
+			"--expose-gc",
+			"/var/runtime/node_modules/awslambda/index.js",
+		)
+	case RuntimeName.NodeJS810:
+		overrides = []string{
+			"/var/lang/bin/node",
+		}
+		overrides = append(overrides, debuggerArgsArray...)
+		overrides = append(overrides,
+			"--inspect="+r.DebugPort,
+			"--debug-brk",
+			"--nolazy",
+			"--expose-gc",
+			"--max-semi-space-size=150",
+			"--max-old-space-size=2707",
+			"/var/runtime/node_modules/awslambda/index.js",
+		)
+	case RuntimeName.Python27:
+		overrides = []string{
+			"/usr/bin/python2.7",
+		}
+		overrides = append(overrides, debuggerArgsArray...)
+		overrides = append(overrides, "/var/runtime/awslambda/bootstrap.py")
+	case RuntimeName.Python36:
+		overrides = []string{
+			"/var/lang/bin/python3.6",
+		}
+		overrides = append(overrides, debuggerArgsArray...)
+		overrides = append(overrides, "/var/runtime/awslambda/bootstrap.py")
+	}
+	return
+}
+
+// Report returns the duration and memory usage of the last invocation, like the
+// REPORT line Lambda logs. It should be called once the output has been read.
+func (r *Runtime) Report(requestID string) *Report {
+
+	report := &Report{
+		RequestID:  requestID,
+		Duration:   time.Since(r.started),
+		MemorySize: int(r.Function.MemorySize),
+	}
+
+	if r.memory != nil {
+		report.MaxMemoryUsed = r.memory.Max()
+	}
+
+	return report
+
+}
+
+// Outcome works out how the last invocation ended, from the function's output and
+// the exit status of its container. It should be called once the output has been read.
+func (r *Runtime) Outcome(output []byte) Outcome {
+
+	if r.timedOut {
+		return OutcomeTimeout
+	}
+
+	if r.cancelled {
+		return OutcomeCancelled
+	}
+
+	if isFunctionError(output) {
+		return OutcomeHandledError
+	}
+
+	// The runtime exiting with an error, without returning a result, means the function crashed
+	if status, err := r.Backend.Wait(r); err == nil && status != 0 {
+		return OutcomeCrash
+	}
+
+	return OutcomeSuccess
+
+}
+
+// CleanUp stops the last invocation, and removes whatever the backend used for it (such as
+// its Docker container)
+func (r *Runtime) CleanUp() {
+
+	// Stop the Lambda timeout timer, and stop watching for cancellation
+	if r.TimeoutTimer != nil {
+		r.TimeoutTimer.Stop()
+	}
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	r.Backend.Stop(r)
+
+	if r.timedOut {
+		r.span.SetError(fmt.Errorf("timed out after %d seconds", r.Function.Timeout))
+	} else if r.cancelled {
+		r.span.SetError(errors.New("cancelled"))
+	}
+	r.span.End()
+
+	// Remove any decompressed archive if there was one (e.g. ZIP/JAR)
+	if r.DecompressedCwd != "" {
+		os.RemoveAll(r.DecompressedCwd)
+	}
+
+}
+
+// demuxDockerStream takes a Docker attach stream, and parses out stdout/stderr
+// into separate streams, based on the Docker engine documentation here:
+// https://docs.docker.com/engine/api/v1.28/#operation/ContainerAttach
+// Due to the use of io.Pipe, you should take care to read from the streams
+// in a separate Go routine to avoid deadlocks.
+func demuxDockerStream(log *logging.Entry, input io.Reader) (io.ReadCloser, io.ReadCloser, error) {
+
+	stdoutreader, stdoutwriter := io.Pipe()
+	stderrreader, stderrwriter := io.Pipe()
+
+	// Return early and continue to copy i/o in another go routine
+	go func() {
+
+		_, err := stdcopy.StdCopy(stdoutwriter, stderrwriter, input)
+		if err != nil {
+			log.Errorf("Error reading I/O from runtime container: %s", err)
+		}
+
+		stdoutwriter.Write([]byte("\n"))
+
+		stdoutwriter.Close()
+		stderrwriter.Close()
+
+	}()
+
+	return stdoutreader, stderrreader, nil
+
+}
+
+func DockerVersion() (string, error) {
+
+	cli, err := DockerClient()
+	if err != nil {
+		return "", err
+	}
+
+	response, err := cli.Ping(context.Background())
+	return response.APIVersion, err
+
+}
+
+func getWorkingDir(log *logging.Entry, dir string) string {
+
+	// If the template filepath isn't set, just use the cwd
+	if dir == "" || dir == "." {
+		cwd, err := os.Getwd()
+		if err != nil {
+			// A directory wasn't specified on the command line
+			// and we can't determin the current working directory.
+			log.Errorf("Could not find working directory for template: %s", err)
+			return ""
+		}
+		dir = cwd
+	}
+
+	// Docker volumes require an absolute path.
+	// If the path exists, use the absolute version.
+	if _, err := os.Stat(dir); err == nil {
+		absolute, err := filepath.Abs(dir)
+		if err == nil {
+			dir = absolute
+		}
+	}
+
+	// Windows uses \ as the path delimiter, but Docker requires / as the path delimiter.
+	// Hence the use of filepath.ToSlash for return values.
+	return filepath.ToSlash(dir)
+
+}
+
+// ContainerLabel is set on every Docker container created by SAM Local, with the logical ID
+// of the function it runs
+const ContainerLabel = "com.amazonaws.sam-local"
+
+// DecompressedArchivePrefix is the prefix of the temporary directories that ZIP/JAR
+// CodeUris are decompressed to
+const DecompressedArchivePrefix = "aws-sam-local-"
+
+// TempDir returns the directory temporary files are created in. It is shared with
+// the Docker containers, so on macOS the /private path is used (see decompressArchive).
+func TempDir() string {
+
+	tmpdir := os.TempDir()
+
+	// By default on macOS, os.TempDir() returns a directory in /var/folders/.
+	// This sits outside the default Docker Shared Files directories, however
+	// /var/folders is just a symlink to /private/var/folders/, so use that instead
+	if strings.HasPrefix(tmpdir, "/var/folders") {
+		tmpdir = "/private" + tmpdir
+	}
+
+	return tmpdir
+
+}
+
+// decompressArchive unzips a ZIP archive to a temporary directory and returns
+// the temporary directory name, or an error
+func decompressArchive(src string) (string, error) {
+
+	// Create a temporary directory just for this decompression (dirname: OS tmp directory + unix timestamp))
+	dest := filepath.Join(TempDir(), DecompressedArchivePrefix+strconv.FormatInt(time.Now().UnixNano(), 10))
+
+	var filenames []string
+
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return dest, err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+
+		err := func() error {
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			defer rc.Close()
+
+			// Store filename/path for returning and using later on
+			fpath := filepath.Join(dest, f.Name)
+			filenames = append(filenames, fpath)
+
+			if f.FileInfo().IsDir() {
+
+				// Make Folder
+				os.MkdirAll(fpath, os.ModePerm)
+
+			} else {
+
+				// Make File
+				var fdir string
+				if lastIndex := strings.LastIndex(fpath, string(os.PathSeparator)); lastIndex > -1 {
+					fdir = fpath[:lastIndex]
+				}
+
+				err = os.MkdirAll(fdir, os.ModePerm)
+				if err != nil {
+					return err
+				}
+				f, err := os.OpenFile(
+					fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+
+				_, err = io.Copy(f, rc)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}()
+
+		if err != nil {
+			return dest, err
+		}
+	}
+
+	return dest, nil
+
+}
+
+func convertWindowsPath(input string) string {
+
+	rg := regexp.MustCompile(`^([A-Za-z]+):`)
+	drive := rg.FindAllStringSubmatch(input, 1)
+	if drive != nil {
+
+		// The path starts with a drive letter.
+		// Docker toolbox mounts C:\ as /c/ so we need to extract, convert to lowercase
+		// and replace into the correct format
+		letter := drive[0][1]
+
+		input = rg.ReplaceAllString(input, "/"+strings.ToLower(letter))
+
+	}
+
+	// Convert all OS seperators to '/'
+	input = filepath.ToSlash(input)
+	return input
+
+}
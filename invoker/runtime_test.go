@@ -0,0 +1,73 @@
+package invoker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/awslabs/aws-sam-local/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sam", func() {
+
+	Describe("runtime", func() {
+
+		Context("mount directory", func() {
+			Context("with a windows style path", func() {
+				input := `C:/Users/username/path`
+				It("should replace it with the docker-toolbox format", func() {
+					result := convertWindowsPath(input)
+					Expect(result).To(Equal("/c/Users/username/path"))
+				})
+			})
+		})
+
+		Context("working directory", func() {
+
+			cwd, err := os.Getwd()
+			It("should determin the current working directory", func() {
+				Expect(cwd).ToNot(BeNil())
+				Expect(err).To(BeNil())
+			})
+
+			inputs := [][]string{
+				// input path, output path
+				[]string{"", cwd},
+				[]string{".", cwd},
+				[]string{"/test/directory", "/test/directory"},
+				[]string{"test/non-existant-directory", "test/non-existant-directory"},
+				[]string{"../test", filepath.Dir(cwd) + "/test"},
+			}
+
+			// func getWorkingDir(logging.For(nil, logging.Docker), basedir string, codeuri string, checkWorkingDirExist bool) (string, error) {
+			for _, input := range inputs {
+
+				in := input[0]
+				expected := input[1]
+
+				context := fmt.Sprintf("with input %s", in)
+				Context(context, func() {
+					It("should have the correct directory", func() {
+						dir := getWorkingDir(logging.For(nil, logging.Docker), in)
+						Expect(dir).To(Equal(expected))
+					})
+				})
+
+			}
+
+		})
+
+		Context("with a layer", func() {
+			It("mounts it at /opt", func() {
+				r := &Runtime{LogicalID: "Hello", Cwd: "/code", Layer: "/layer"}
+				host, err := r.getHostConfig()
+				Expect(err).To(BeNil())
+				Expect(host.Binds).To(Equal([]string{"/code:/var/task:ro", "/layer:/opt:ro"}))
+			})
+		})
+
+	})
+})
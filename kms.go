@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// kmsEmulator is SAM Local's KMS endpoint, which functions with encrypted environment
+// variables decrypt them with, as they do with KMS once deployed. Ciphertexts in the
+// keyring are decrypted locally, and the others by KMS (or KMS's --aws-endpoint-url), with
+// SAM Local's credentials. Other KMS calls are sent on to --aws-endpoint-url, or to KMS.
+// It only accepts calls signed with the credentials that functions call KMS with.
+type kmsEmulator struct {
+	*awsForwarder
+
+	callers awsCallers
+
+	// keyring are the plaintexts of ciphertexts, by the ciphertexts' (decoded) bytes
+	keyring map[string]string
+}
+
+// kmsKeyringKeyID is the ID of the key that ciphertexts in the keyring were decrypted with
+const kmsKeyringKeyID = "arn:aws:kms:%s:123456789012:key/sam-local-keyring"
+
+// loadKMSKeyring reads a keyring file, which maps base64 KMS ciphertexts (as they're set
+// in the template) to their plaintexts:
+//
+//	{
+//	  "AQICAHh...": "my database password"
+//	}
+func loadKMSKeyring(filename string) (map[string]string, error) {
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the KMS keyring %s: %s", filename, err)
+	}
+
+	entries := map[string]string{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid KMS keyring %s: %s", filename, err)
+	}
+
+	keyring := map[string]string{}
+	for ciphertext, plaintext := range entries {
+		blob, err := base64.StdEncoding.DecodeString(ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KMS keyring %s: '%s' isn't a base64 ciphertext", filename, ciphertext)
+		}
+		keyring[string(blob)] = plaintext
+	}
+
+	return keyring, nil
+
+}
+
+// encryptedVariables returns the names of a function's environment variables whose values
+// are KMS ciphertexts, from its Metadata:
+//
+//	Metadata:
+//	  SamLocal:
+//	    EncryptedVariables:
+//	      - DB_PASSWORD
+func encryptedVariables(template *cloudformation.Template, name string) []string {
+
+	names := []string{}
+	if samLocal, ok := getResourceMetadata(template, name)["SamLocal"].(map[string]interface{}); ok {
+		list, _ := samLocal["EncryptedVariables"].([]interface{})
+		for _, variable := range list {
+			if variable, ok := variable.(string); ok && variable != "" {
+				names = append(names, variable)
+			}
+		}
+	}
+
+	return names
+
+}
+
+// startKMSEmulator starts a kmsEmulator if any of the functions have encrypted variables,
+// or there's a keyring, and returns the endpoints that functions are given, with it as
+// KMS's. It only listens on the addresses that functions reach it by (see
+// listenForFunctions).
+func startKMSEmulator(template *cloudformation.Template, names []string, endpoints map[string]string, keyringFile string, profile string, backend string) (map[string]string, error) {
+
+	encrypted := map[string][]string{}
+	for _, name := range names {
+		if variables := encryptedVariables(template, name); len(variables) > 0 {
+			encrypted[name] = variables
+		}
+	}
+	if len(encrypted) == 0 && keyringFile == "" {
+		return endpoints, nil
+	}
+
+	keyring := map[string]string{}
+	if keyringFile != "" {
+		var err error
+		if keyring, err = loadKMSKeyring(keyringFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check the ciphertexts in the template up front, as mistakes only show up as a
+	// function failing to decrypt them otherwise
+	for _, name := range names {
+		environment, _ := getResourceProperty(template, name, "Environment")
+		environmentMap, _ := environment.(map[string]interface{})
+		values, _ := environmentMap["Variables"].(map[string]interface{})
+
+		for _, variable := range encrypted[name] {
+			value, ok := values[variable].(string)
+			if !ok {
+				continue
+			}
+			blob, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				warnMsg.Printf("%s of %s is an encrypted variable, but its value isn't a base64 KMS ciphertext\n", variable, name)
+				continue
+			}
+			if _, ok := keyring[string(blob)]; !ok {
+				log.Printf("%s of %s isn't in the KMS keyring, so it will be decrypted by KMS with your credentials\n", variable, name)
+			}
+		}
+	}
+
+	listeners, port, err := listenForFunctions(backend)
+	if err != nil {
+		return nil, fmt.Errorf("could not start the KMS endpoint for encrypted variables: %s", err)
+	}
+
+	// Functions call KMS with the profile's credentials, as SAM Local does, including when
+	// their calls come through the IAM simulation
+	forwarder := newAWSForwarder(endpoints, profile)
+	creds, err := forwarder.signer.Credentials.Get()
+	if err != nil {
+		return nil, fmt.Errorf("could not start the KMS endpoint for encrypted variables: %s", err)
+	}
+	serveForFunctions(listeners, &kmsEmulator{
+		awsForwarder: forwarder,
+		callers:      awsCallers{creds.AccessKeyID: creds.SecretAccessKey},
+		keyring:      keyring,
+	})
+
+	result := map[string]string{}
+	for service, endpoint := range endpoints {
+		result[service] = endpoint
+	}
+	result["kms"] = fmt.Sprintf("http://localhost:%d", port)
+
+	return result, nil
+
+}
+
+// ServeHTTP implements http.Handler
+func (e *kmsEmulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := e.callers.verify(r, body); err != nil {
+		warnMsg.Printf("KMS endpoint for encrypted variables: rejected %s %s, as %s\n", r.Method, r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	req := parseAWSRequest(r, body)
+	if req.Action != "Decrypt" {
+		e.forward(w, r, "kms", req.Region, e.upstream("kms"), body)
+		return
+	}
+
+	input := struct {
+		CiphertextBlob []byte
+		KeyId          string
+	}{}
+	json.Unmarshal(body, &input)
+
+	plaintext, ok := e.keyring[string(input.CiphertextBlob)]
+	if !ok {
+		// Ciphertexts made with real keys are decrypted by KMS, even when every other
+		// service is local, unless KMS itself has an --aws-endpoint-url
+		e.forward(w, r, "kms", req.Region, e.upstreams["kms"], body)
+		return
+	}
+
+	keyID := input.KeyId
+	if keyID == "" {
+		region := req.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		keyID = fmt.Sprintf(kmsKeyringKeyID, region)
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"KeyId":               keyID,
+		"Plaintext":           []byte(plaintext),
+		"EncryptionAlgorithm": "SYMMETRIC_DEFAULT",
+	})
+
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/awslabs/aws-sam-local/loader"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KMS encrypted variables", func() {
+
+	ciphertext := base64.StdEncoding.EncodeToString([]byte("encrypted password"))
+
+	Context("reading a keyring", func() {
+
+		It("decodes the ciphertexts", func() {
+			file, _ := ioutil.TempFile("", "keyring")
+			defer os.Remove(file.Name())
+			file.WriteString(`{"` + ciphertext + `": "hunter2"}`)
+			file.Close()
+
+			keyring, err := loadKMSKeyring(file.Name())
+			Expect(err).To(BeNil())
+			Expect(keyring).To(Equal(map[string]string{"encrypted password": "hunter2"}))
+		})
+
+		It("rejects ciphertexts that aren't base64", func() {
+			file, _ := ioutil.TempFile("", "keyring")
+			defer os.Remove(file.Name())
+			file.WriteString(`{"not base64!": "hunter2"}`)
+			file.Close()
+
+			_, err := loadKMSKeyring(file.Name())
+			Expect(err).To(MatchError(ContainSubstring("isn't a base64 ciphertext")))
+		})
+
+	})
+
+	It("reads the encrypted variables of functions from their Metadata", func() {
+		loaded, err := loader.Parse([]byte(`
+Resources:
+  Orders:
+    Type: AWS::Serverless::Function
+    Metadata:
+      SamLocal:
+        EncryptedVariables:
+          - DB_PASSWORD
+    Properties:
+      Runtime: nodejs6.10
+      Handler: index.handler
+  Plain:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: nodejs6.10
+      Handler: index.handler
+`), loader.Options{})
+		Expect(err).To(BeNil())
+		Expect(encryptedVariables(loaded.Template, "Orders")).To(Equal([]string{"DB_PASSWORD"}))
+		Expect(encryptedVariables(loaded.Template, "Plain")).To(BeEmpty())
+	})
+
+	Context("decrypting", func() {
+
+		var upstream *httptest.Server
+		var received *http.Request
+		var emulator *kmsEmulator
+
+		BeforeEach(func() {
+			received = nil
+			upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				w.Write([]byte(`{"Plaintext":"ZnJvbSBrbXM="}`))
+			}))
+
+			emulator = &kmsEmulator{
+				awsForwarder: &awsForwarder{
+					upstreams: map[string]string{"kms": upstream.URL},
+					signer:    v4.NewSigner(credentials.NewStaticCredentials("localkey", "localsecret", "")),
+					client:    http.DefaultClient,
+				},
+				callers: awsCallers{"localkey": "localsecret"},
+				keyring: map[string]string{"encrypted password": "hunter2"},
+			}
+		})
+
+		AfterEach(func() {
+			upstream.Close()
+		})
+
+		// request returns a call signed as a function's SDK would sign it, with its credentials
+		request := func(action string, body string, key string) *http.Request {
+			r, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader(body))
+			r.Header.Set("X-Amz-Target", "TrentService."+action)
+			v4.NewSigner(credentials.NewStaticCredentials(key, "localsecret", "")).Sign(r, strings.NewReader(body), "kms", "us-east-1", time.Now())
+			return r
+		}
+
+		call := func(action string, body string) map[string]string {
+			r := request(action, body, "localkey")
+			w := httptest.NewRecorder()
+			emulator.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			response := map[string]string{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			return response
+		}
+
+		It("decrypts ciphertexts in the keyring locally", func() {
+			response := call("Decrypt", `{"CiphertextBlob":"`+ciphertext+`","EncryptionContext":{"LambdaFunctionName":"Orders"}}`)
+			Expect(received).To(BeNil())
+			Expect(response["Plaintext"]).To(Equal(base64.StdEncoding.EncodeToString([]byte("hunter2"))))
+			Expect(response["KeyId"]).To(Equal("arn:aws:kms:us-east-1:123456789012:key/sam-local-keyring"))
+		})
+
+		It("has KMS decrypt the other ciphertexts", func() {
+			other := base64.StdEncoding.EncodeToString([]byte("other"))
+			response := call("Decrypt", `{"CiphertextBlob":"`+other+`"}`)
+			Expect(received).NotTo(BeNil())
+			Expect(received.Header.Get("Authorization")).To(ContainSubstring("Credential=localkey/"))
+			Expect(response["Plaintext"]).To(Equal("ZnJvbSBrbXM="))
+		})
+
+		It("rejects calls that weren't signed with the functions' credentials", func() {
+			for _, r := range []*http.Request{
+				request("Decrypt", `{"CiphertextBlob":"`+ciphertext+`"}`, "otherkey"),
+				signedRequest("POST", "http://localhost/", "kms", `{"CiphertextBlob":"`+ciphertext+`"}`),
+			} {
+				r.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+				w := httptest.NewRecorder()
+				emulator.ServeHTTP(w, r)
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+				Expect(w.Body.String()).NotTo(ContainSubstring(base64.StdEncoding.EncodeToString([]byte("hunter2"))))
+			}
+		})
+
+		It("sends other calls on", func() {
+			call("Encrypt", `{"KeyId":"alias/app","Plaintext":"aGk="}`)
+			Expect(received).NotTo(BeNil())
+			Expect(received.Header.Get("X-Amz-Target")).To(Equal("TrentService.Encrypt"))
+		})
+
+	})
+
+})
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/loader"
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+const defaultHost = "127.0.0.1"
+const defaultPort = "3000"
+
+// listener is a single local address that 'sam local start-api' serves
+// a router on. Each listener has its own router, so APIs bound to different
+// listeners don't share a port or path namespace.
+type listener struct {
+	Host   string
+	Port   string
+	Router *router.ServerlessRouter
+}
+
+// Addr returns the host:port address of the listener
+func (l *listener) Addr() string {
+	return net.JoinHostPort(l.Host, l.Port)
+}
+
+// hasMount checks whether a path and method has already been mounted on the listener's router
+func (l *listener) hasMount(path string, method string) bool {
+	return l.Router.HasMount(path, method)
+}
+
+// parseListeners pairs up the (repeatable) --host and --port flags into listeners.
+// If one of the lists is shorter than the other, its last value is reused, so
+// '--port 3000 --port 3001' listens on 127.0.0.1 for both ports.
+func parseListeners(hosts []string, ports []string) ([]*listener, error) {
+
+	if len(hosts) == 0 {
+		hosts = []string{defaultHost}
+	}
+
+	if len(ports) == 0 {
+		ports = []string{defaultPort}
+	}
+
+	count := len(hosts)
+	if len(ports) > count {
+		count = len(ports)
+	}
+
+	listeners := []*listener{}
+	seen := map[string]bool{}
+
+	for i := 0; i < count; i++ {
+		l := &listener{
+			Host: hosts[len(hosts)-1],
+			Port: ports[len(ports)-1],
+		}
+
+		if i < len(hosts) {
+			l.Host = hosts[i]
+		}
+
+		if i < len(ports) {
+			l.Port = ports[i]
+		}
+
+		if seen[l.Addr()] {
+			return nil, fmt.Errorf("address %s is specified more than once", l.Addr())
+		}
+		seen[l.Addr()] = true
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+
+}
+
+// parseAPIBindings parses the --api-listener flags, which are structured as
+// ApiLogicalID=host:port (or ApiLogicalID=port), and returns the listener
+// each AWS::Serverless::Api resource should be mounted on.
+func parseAPIBindings(bindings []string, listeners []*listener) (map[string]*listener, error) {
+
+	result := map[string]*listener{}
+
+	for _, binding := range bindings {
+
+		parts := strings.SplitN(binding, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid Api binding '%s' (expected ApiLogicalID=host:port)", binding)
+		}
+
+		name, address := parts[0], parts[1]
+
+		var found *listener
+		for _, l := range listeners {
+			if address == l.Addr() || address == l.Port {
+				found = l
+				break
+			}
+		}
+
+		if found == nil {
+			return nil, fmt.Errorf("Api %s is bound to %s, which is not one of the --host/--port listeners", name, address)
+		}
+
+		result[name] = found
+
+	}
+
+	return result, nil
+
+}
+
+// resolveRestApiIds sets the RestApiId of the Api event sources that reference their Api
+// with a Ref (e.g. RestApiId: !Ref UsersApi) to the Api's logical ID. References to
+// resources resolve to nothing when the template is parsed, so otherwise only the events
+// that name their Api literally would be served on its listener.
+func resolveRestApiIds(template *loader.Template) {
+
+	if len(template.Apis) == 0 {
+		return
+	}
+
+	for name, resource := range template.Resources {
+
+		resource, _ := resource.(map[string]interface{})
+		if resource["Type"] != "AWS::Serverless::Function" {
+			continue
+		}
+
+		properties, _ := template.Resource(name)["Properties"].(map[string]interface{})
+		events, _ := properties["Events"].(map[string]interface{})
+		for event, source := range events {
+
+			source, _ := source.(map[string]interface{})
+			sourceProperties, _ := source["Properties"].(map[string]interface{})
+			api := referencedResource(sourceProperties["RestApiId"])
+			if _, ok := template.Apis[api]; !ok {
+				continue
+			}
+
+			processed := getMap(getMap(getMap(getMap(resource["Properties"])["Events"])[event])["Properties"])
+			if len(processed) > 0 {
+				processed["RestApiId"] = api
+			}
+			if source := template.Functions[name].Events[event]; source.Properties != nil && source.Properties.ApiEvent != nil {
+				source.Properties.ApiEvent.RestApiId = api
+			}
+
+		}
+
+	}
+
+}
+
+// assignFunctionEvents splits the Api event sources of a function between the listeners.
+// An event goes to the listener of the Api it references with RestApiId, or to the
+// listener of a bound Api whose definition already contains the same path and method.
+// Everything else is served on the first (default) listener.
+func assignFunctionEvents(function cloudformation.AWSServerlessFunction, listeners []*listener, apis map[string]*listener) map[*listener]map[string]cloudformation.AWSServerlessFunction_EventSource {
+
+	result := map[*listener]map[string]cloudformation.AWSServerlessFunction_EventSource{}
+
+	for name, event := range function.Events {
+
+		if event.Type != "Api" || event.Properties == nil || event.Properties.ApiEvent == nil {
+			continue
+		}
+
+		target := listeners[0]
+
+		if l, ok := apis[event.Properties.ApiEvent.RestApiId]; ok {
+			target = l
+		} else {
+			for _, l := range apis {
+				if l.hasMount(event.Properties.ApiEvent.Path, event.Properties.ApiEvent.Method) {
+					target = l
+					break
+				}
+			}
+		}
+
+		if _, ok := result[target]; !ok {
+			result[target] = map[string]cloudformation.AWSServerlessFunction_EventSource{}
+		}
+		result[target][name] = event
+
+	}
+
+	return result
+
+}
+
+// mountAPIs adds every AWS::Serverless::Api to the router of the listener it's bound to,
+// or to the first listener if it isn't bound to one
+func mountAPIs(apis map[string]cloudformation.AWSServerlessApi, listeners []*listener, bindings map[string]*listener) error {
+
+	for name, api := range apis {
+		l := listeners[0]
+		if bound, ok := bindings[name]; ok {
+			l = bound
+		}
+
+		api := api
+		if err := l.Router.AddAPI(&api); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// mountFunction adds the Api event sources of a function to the routers of the listeners
+// they're assigned to. It returns false if the function has no Api event sources.
+func mountFunction(function cloudformation.AWSServerlessFunction, listeners []*listener, bindings map[string]*listener, handler router.EventHandlerFunc) bool {
+
+	assigned := assignFunctionEvents(function, listeners, bindings)
+	if len(assigned) == 0 {
+		return false
+	}
+
+	for l, events := range assigned {
+
+		// Add this AWS::Serverless::Function to the HTTP router, with only
+		// the events that should be served by this listener
+		f := function
+		f.Events = events
+
+		if err := l.Router.AddFunction(&f, handler); err != nil {
+			return false
+		}
+	}
+
+	return true
+
+}
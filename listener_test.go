@@ -0,0 +1,146 @@
+package main
+
+import (
+	"github.com/awslabs/aws-sam-local/loader"
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/awslabs/goformation/cloudformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Listeners", func() {
+
+	Context("parsing --host and --port flags", func() {
+
+		It("defaults to 127.0.0.1:3000", func() {
+			listeners, err := parseListeners(nil, nil)
+			Expect(err).To(BeNil())
+			Expect(listeners).To(HaveLen(1))
+			Expect(listeners[0].Addr()).To(Equal("127.0.0.1:3000"))
+		})
+
+		It("pairs up hosts and ports", func() {
+			listeners, err := parseListeners([]string{"127.0.0.1", "0.0.0.0"}, []string{"3000", "4000"})
+			Expect(err).To(BeNil())
+			Expect(listeners).To(HaveLen(2))
+			Expect(listeners[0].Addr()).To(Equal("127.0.0.1:3000"))
+			Expect(listeners[1].Addr()).To(Equal("0.0.0.0:4000"))
+		})
+
+		It("reuses the last host when there are more ports", func() {
+			listeners, err := parseListeners(nil, []string{"3000", "3001"})
+			Expect(err).To(BeNil())
+			Expect(listeners).To(HaveLen(2))
+			Expect(listeners[1].Addr()).To(Equal("127.0.0.1:3001"))
+		})
+
+		It("fails on duplicate addresses", func() {
+			_, err := parseListeners(nil, []string{"3000", "3000"})
+			Expect(err).ToNot(BeNil())
+		})
+
+	})
+
+	Context("parsing --api-listener flags", func() {
+
+		listeners, _ := parseListeners(nil, []string{"3000", "3001"})
+
+		It("binds an Api by host:port or port", func() {
+			apis, err := parseAPIBindings([]string{"Users=127.0.0.1:3001", "Orders=3000"}, listeners)
+			Expect(err).To(BeNil())
+			Expect(apis).To(HaveKeyWithValue("Users", listeners[1]))
+			Expect(apis).To(HaveKeyWithValue("Orders", listeners[0]))
+		})
+
+		It("fails when the address isn't a listener", func() {
+			_, err := parseAPIBindings([]string{"Users=4000"}, listeners)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("fails on malformed bindings", func() {
+			_, err := parseAPIBindings([]string{"Users"}, listeners)
+			Expect(err).ToNot(BeNil())
+		})
+
+	})
+
+	Context("assigning function events to listeners", func() {
+
+		listeners, _ := parseListeners(nil, []string{"3000", "3001"})
+		for _, l := range listeners {
+			l.Router = router.NewServerlessRouter(router.NewServerlessRouterOpt{})
+		}
+		apis := map[string]*listener{"Users": listeners[1]}
+
+		function := cloudformation.AWSServerlessFunction{
+			Events: map[string]cloudformation.AWSServerlessFunction_EventSource{
+				"Bound": {
+					Type: "Api",
+					Properties: &cloudformation.AWSServerlessFunction_Properties{
+						ApiEvent: &cloudformation.AWSServerlessFunction_ApiEvent{
+							Path:      "/users",
+							Method:    "get",
+							RestApiId: "Users",
+						},
+					},
+				},
+				"Implicit": {
+					Type: "Api",
+					Properties: &cloudformation.AWSServerlessFunction_Properties{
+						ApiEvent: &cloudformation.AWSServerlessFunction_ApiEvent{
+							Path:   "/health",
+							Method: "get",
+						},
+					},
+				},
+				"Schedule": {
+					Type: "Schedule",
+				},
+			},
+		}
+
+		assigned := assignFunctionEvents(function, listeners, apis)
+
+		It("serves events referencing a bound Api on its listener", func() {
+			Expect(assigned[listeners[1]]).To(HaveLen(1))
+			Expect(assigned[listeners[1]]).To(HaveKey("Bound"))
+		})
+
+		It("serves other Api events on the first listener", func() {
+			Expect(assigned[listeners[0]]).To(HaveLen(1))
+			Expect(assigned[listeners[0]]).To(HaveKey("Implicit"))
+		})
+
+		It("serves events referencing a bound Api with a Ref on its listener", func() {
+			template, err := loader.Parse([]byte(`
+Resources:
+  UsersApi:
+    Type: AWS::Serverless::Api
+    Properties:
+      StageName: prod
+  Users:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: nodejs8.10
+      Handler: index.handler
+      Events:
+        List:
+          Type: Api
+          Properties:
+            Path: /users
+            Method: get
+            RestApiId: !Ref UsersApi
+`), loader.Options{})
+			Expect(err).To(BeNil())
+			resolveRestApiIds(template)
+
+			function := template.GetAllAWSServerlessFunctionResources()["Users"]
+			assigned := assignFunctionEvents(function, listeners, map[string]*listener{"UsersApi": listeners[1]})
+			Expect(assigned[listeners[1]]).To(HaveKey("List"))
+			Expect(template.Functions["Users"].Events["List"].Properties.ApiEvent.RestApiId).To(Equal("UsersApi"))
+		})
+
+	})
+
+})
@@ -0,0 +1,145 @@
+package loader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var errNotAnObject = errors.New("the template must be an object")
+
+// Position is a place in a template. Lines and columns start at 1, and are 0 when unknown.
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+// String returns the position as file:line:column, leaving out the parts that are unknown
+func (p Position) String() string {
+	s := p.Filename
+	if p.Line > 0 {
+		if s != "" {
+			s += ":"
+		}
+		s += strconv.Itoa(p.Line)
+		if p.Column > 0 {
+			s += ":" + strconv.Itoa(p.Column)
+		}
+	}
+	return s
+}
+
+// prefix returns the position as the prefix of an error message
+func (p Position) prefix() string {
+	if s := p.String(); s != "" {
+		return s + ": "
+	}
+	return ""
+}
+
+// SyntaxError is returned for templates that aren't valid JSON or YAML
+type SyntaxError struct {
+	Position
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%sinvalid template: %s", e.prefix(), e.Err)
+}
+
+// yamlLineEx extracts the line of a YAML parser error
+var yamlLineEx = regexp.MustCompile(`line (\d+)`)
+
+// newSyntaxError returns the SyntaxError for an error parsing data
+func newSyntaxError(data []byte, err error) *SyntaxError {
+
+	serr := &SyntaxError{Err: err}
+
+	if jerr, ok := err.(*json.SyntaxError); ok {
+		// the offset is after the character that the error is about
+		serr.Line, serr.Column = lineColumn(data, int(jerr.Offset)-1)
+	} else if match := yamlLineEx.FindStringSubmatch(err.Error()); match != nil {
+		serr.Line, _ = strconv.Atoi(match[1])
+	}
+
+	return serr
+
+}
+
+// UnsupportedIntrinsicError is reported for intrinsic functions that SAM Local can't
+// resolve, and which are therefore left out of (or left unresolved in) the template
+type UnsupportedIntrinsicError struct {
+	Position
+
+	// Name is the intrinsic function as it was written, e.g. Fn::Cidr or !If
+	Name string
+}
+
+func (e *UnsupportedIntrinsicError) Error() string {
+	return fmt.Sprintf("%sunsupported intrinsic function %s", e.prefix(), e.Name)
+}
+
+// MissingCodeUriError is reported for functions without a CodeUri
+type MissingCodeUriError struct {
+	Position
+	Function string
+}
+
+func (e *MissingCodeUriError) Error() string {
+	return fmt.Sprintf("%sfunction '%s' has no CodeUri", e.prefix(), e.Function)
+}
+
+// InvalidEventError is reported for function event sources that are missing properties or
+// have invalid ones
+type InvalidEventError struct {
+	Position
+	Function string
+	Event    string
+	Reason   string
+}
+
+func (e *InvalidEventError) Error() string {
+	return fmt.Sprintf("%sinvalid event '%s' of function '%s': %s", e.prefix(), e.Event, e.Function, e.Reason)
+}
+
+// Errors is a list of problems with a template, in the order they appear in it
+type Errors []error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// position returns the position of one of the errors in this package
+func position(err error) Position {
+	switch e := err.(type) {
+	case *SyntaxError:
+		return e.Position
+	case *UnsupportedIntrinsicError:
+		return e.Position
+	case *MissingCodeUriError:
+		return e.Position
+	case *InvalidEventError:
+		return e.Position
+	}
+	return Position{}
+}
+
+// sortErrors sorts errors in the order they appear in the template
+func sortErrors(errs Errors) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		a, b := position(errs[i]), position(errs[j])
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
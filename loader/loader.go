@@ -0,0 +1,399 @@
+// Package loader loads SAM templates the way SAM Local does: it parses JSON or YAML templates,
+// resolves intrinsic functions and parameter overrides, and applies the Globals section to
+// the template's resources. Tools built on SAM Local use it to see the same functions and
+// events as the CLI:
+//
+//	template, err := loader.Open("template.yaml", loader.Options{})
+//	if err != nil {
+//		log.Fatal(err) // e.g. a *loader.SyntaxError, with the line and column
+//	}
+//	for name, function := range template.Functions {
+//		fmt.Println(name, function.Runtime)
+//	}
+//
+// Validate reports the problems that don't stop a template from loading, such as functions
+// without a CodeUri, as typed errors with their position in the template.
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/awslabs/goformation/intrinsics"
+)
+
+// Options customise how templates are loaded
+type Options struct {
+	// ParameterOverrides replace the default values of the template's Parameters
+	ParameterOverrides map[string]interface{}
+}
+
+// Template is a loaded SAM template
+type Template struct {
+	*cloudformation.Template
+
+	// Filename is the file the template was loaded from, if any
+	Filename string
+
+	// Functions are the template's AWS::Serverless::Function resources, by logical ID
+	Functions map[string]cloudformation.AWSServerlessFunction
+
+	// Apis are the template's AWS::Serverless::Api resources, by logical ID
+	Apis map[string]cloudformation.AWSServerlessApi
+
+	// source is the template as it was written, for finding the positions of problems
+	source []byte
+
+	// processed is the template after intrinsic functions and Globals were applied
+	processed map[string]interface{}
+
+	// options are the options the template was loaded with
+	options Options
+
+	// references is the template with references to other resources kept, which is only
+	// worked out if Resource is called
+	references     map[string]interface{}
+	referencesOnce sync.Once
+}
+
+// Open loads a template from a JSON or YAML file
+func Open(filename string, opt Options) (*Template, error) {
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := Parse(data, opt)
+	if err != nil {
+		if serr, ok := err.(*SyntaxError); ok {
+			serr.Filename = filename
+		}
+		return nil, err
+	}
+
+	template.Filename = filename
+	return template, nil
+
+}
+
+// Parse loads a template from JSON or YAML data. Syntax errors are returned as *SyntaxError.
+func Parse(data []byte, opt Options) (*Template, error) {
+
+	processorOpt := &intrinsics.ProcessorOptions{ParameterOverrides: opt.ParameterOverrides}
+
+	var processed []byte
+	var err error
+	if isJSON(data) {
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, newSyntaxError(data, err)
+		}
+		processed, err = intrinsics.ProcessJSON(data, processorOpt)
+	} else {
+		processed, err = intrinsics.ProcessYAML(data, processorOpt)
+	}
+	if err != nil {
+		return nil, newSyntaxError(data, err)
+	}
+
+	template := &Template{source: data, options: opt}
+	if err := json.Unmarshal(processed, &template.processed); err != nil || template.processed == nil {
+		return nil, &SyntaxError{Err: errNotAnObject}
+	}
+
+	applyGlobals(template.processed)
+
+	if template.Template, err = newCloudFormationTemplate(template.processed); err != nil {
+		return nil, &SyntaxError{Err: err}
+	}
+
+	template.Functions = map[string]cloudformation.AWSServerlessFunction{}
+	template.Apis = map[string]cloudformation.AWSServerlessApi{}
+	var lock sync.Mutex
+	materialize(template.Resources, func(name string, resourceType string, data []byte) {
+		switch resourceType {
+		case "AWS::Serverless::Function":
+			var function cloudformation.AWSServerlessFunction
+			if err := json.Unmarshal(data, &function); err == nil {
+				lock.Lock()
+				template.Functions[name] = function
+				lock.Unlock()
+			}
+		case "AWS::Serverless::Api":
+			var api cloudformation.AWSServerlessApi
+			if err := json.Unmarshal(data, &api); err == nil {
+				lock.Lock()
+				template.Apis[name] = api
+				lock.Unlock()
+			}
+		}
+	})
+
+	return template, nil
+
+}
+
+// newCloudFormationTemplate returns the GoFormation template of a processed template. It's
+// made from the sections of the processed template, rather than by encoding the whole thing
+// as JSON again and decoding that, which took a while for templates with thousands of
+// resources.
+func newCloudFormationTemplate(processed map[string]interface{}) (*cloudformation.Template, error) {
+
+	template := &cloudformation.Template{}
+
+	text := map[string]*string{
+		"AWSTemplateFormatVersion": &template.AWSTemplateFormatVersion,
+		"Description":              &template.Description,
+	}
+	for section, field := range text {
+		switch value := processed[section].(type) {
+		case nil:
+		case string:
+			*field = value
+		default:
+			return nil, fmt.Errorf("the %s section must be a string", section)
+		}
+	}
+
+	sections := map[string]*map[string]interface{}{
+		"Metadata":   &template.Metadata,
+		"Parameters": &template.Parameters,
+		"Mappings":   &template.Mappings,
+		"Conditions": &template.Conditions,
+		"Resources":  &template.Resources,
+		"Outputs":    &template.Outputs,
+	}
+	for section, field := range sections {
+		switch value := processed[section].(type) {
+		case nil:
+		case map[string]interface{}:
+			*field = value
+		default:
+			return nil, fmt.Errorf("the %s section must be an object", section)
+		}
+	}
+
+	return template, nil
+
+}
+
+// materialize calls decode with the JSON of each resource in a template, from a worker per
+// CPU. Decoding the resources into GoFormation's types is most of the time it takes to load
+// templates with thousands of them.
+func materialize(resources map[string]interface{}, decode func(name string, resourceType string, data []byte)) {
+
+	names := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				resource, ok := resources[name].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				resourceType, _ := resource["Type"].(string)
+				if data, err := json.Marshal(resource); err == nil {
+					decode(name, resourceType, data)
+				}
+			}
+		}()
+	}
+
+	for name := range resources {
+		names <- name
+	}
+	close(names)
+	wg.Wait()
+
+}
+
+// Metadata returns the Metadata of a resource, such as the build settings of a function, or
+// nil if it doesn't have any
+func (t *Template) Metadata(logicalID string) map[string]interface{} {
+	resources, _ := t.processed["Resources"].(map[string]interface{})
+	resource, _ := resources[logicalID].(map[string]interface{})
+	metadata, _ := resource["Metadata"].(map[string]interface{})
+	return metadata
+}
+
+// Resource returns a resource (its Type, Properties and so on) with its intrinsic functions
+// resolved, except for references to other resources, which are kept as they're written,
+// e.g. {"Fn::GetAtt": ["Role", "Arn"]} or {"Ref": "Bucket"}. These would otherwise resolve
+// to nothing, as the resources don't exist locally. In Fn::Sub strings, which can't hold
+// them, they're replaced with *. It returns nil if there's no such resource.
+func (t *Template) Resource(logicalID string) map[string]interface{} {
+
+	t.referencesOnce.Do(func() {
+		t.references = processReferences(t.source, t.options)
+	})
+
+	resources, _ := t.references["Resources"].(map[string]interface{})
+	resource, _ := resources[logicalID].(map[string]interface{})
+	return resource
+
+}
+
+// processReferences processes a template's intrinsic functions like Parse does, but keeps
+// references to its resources
+func processReferences(data []byte, opt Options) map[string]interface{} {
+
+	isResource := func(template interface{}, name string) bool {
+		t, _ := template.(map[string]interface{})
+		resources, _ := t["Resources"].(map[string]interface{})
+		_, ok := resources[name]
+		return ok
+	}
+
+	// getAtt returns the resource and attribute of a Fn::GetAtt, which is either a list or
+	// (as in Fn::Sub) "Resource.Attribute"
+	getAtt := func(input interface{}) []interface{} {
+		if s, ok := input.(string); ok {
+			parts := strings.SplitN(s, ".", 2)
+			input = []interface{}{parts[0], parts[len(parts)-1]}
+		}
+		list, _ := input.([]interface{})
+		return list
+	}
+
+	ref := func(name string, input interface{}, template interface{}) interface{} {
+		if s, ok := input.(string); ok && isResource(template, s) {
+			return map[string]interface{}{"Ref": s}
+		}
+		return intrinsics.Ref(name, input, template)
+	}
+
+	sub := func(name string, input interface{}, template interface{}) interface{} {
+		if s, ok := input.(string); ok {
+			input = subVariableEx.ReplaceAllStringFunc(s, func(variable string) string {
+				target := strings.SplitN(variable[2:len(variable)-1], ".", 2)[0]
+				if isResource(template, target) {
+					return "*"
+				}
+				return variable
+			})
+		}
+		if list, ok := input.([]interface{}); ok && len(list) == 2 {
+			if variables, ok := list[1].(map[string]interface{}); ok {
+				for key, value := range variables {
+					if _, ok := value.(map[string]interface{}); ok {
+						variables[key] = "*"
+					}
+				}
+			}
+		}
+		return intrinsics.FnSub(name, input, template)
+	}
+
+	processorOpt := &intrinsics.ProcessorOptions{
+		ParameterOverrides: opt.ParameterOverrides,
+		IntrinsicHandlerOverrides: map[string]intrinsics.IntrinsicHandler{
+			"Ref": ref,
+			"Fn::GetAtt": func(name string, input interface{}, template interface{}) interface{} {
+				return map[string]interface{}{"Fn::GetAtt": getAtt(input)}
+			},
+			"Fn::Sub": sub,
+		},
+	}
+
+	var processed []byte
+	var err error
+	if isJSON(data) {
+		processed, err = intrinsics.ProcessJSON(data, processorOpt)
+	} else {
+		processed, err = intrinsics.ProcessYAML(data, processorOpt)
+	}
+
+	var template map[string]interface{}
+	if err != nil || json.Unmarshal(processed, &template) != nil {
+		return nil
+	}
+
+	applyGlobals(template)
+	return template
+
+}
+
+// subVariableEx matches the variables in Fn::Sub strings
+var subVariableEx = regexp.MustCompile(`\$\{[.0-9A-Za-z]+\}`)
+
+// isJSON returns whether data is a JSON template, rather than a YAML one
+func isJSON(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
+
+// globalResourceTypes are the resource types that each section of Globals applies to
+var globalResourceTypes = map[string]string{
+	"Function":    "AWS::Serverless::Function",
+	"Api":         "AWS::Serverless::Api",
+	"SimpleTable": "AWS::Serverless::SimpleTable",
+}
+
+// applyGlobals adds the properties in the template's Globals section to its resources
+func applyGlobals(template map[string]interface{}) {
+
+	globals, _ := template["Globals"].(map[string]interface{})
+	resources, _ := template["Resources"].(map[string]interface{})
+
+	for section, properties := range globals {
+		resourceType, ok := globalResourceTypes[section]
+		if !ok {
+			continue
+		}
+
+		for name, r := range resources {
+			resource, ok := r.(map[string]interface{})
+			if !ok || resource["Type"] != resourceType {
+				continue
+			}
+			resource["Properties"] = mergeGlobals(properties, resource["Properties"])
+			resources[name] = resource
+		}
+	}
+
+}
+
+// mergeGlobals merges a global value with a resource's, like SAM: maps are merged, lists are
+// appended to, and otherwise the resource's value wins
+func mergeGlobals(global, local interface{}) interface{} {
+
+	switch g := global.(type) {
+
+	case map[string]interface{}:
+		if local == nil {
+			local = map[string]interface{}{}
+		}
+		l, ok := local.(map[string]interface{})
+		if !ok {
+			return local
+		}
+		merged := map[string]interface{}{}
+		for k, v := range g {
+			merged[k] = v
+		}
+		for k, v := range l {
+			merged[k] = mergeGlobals(g[k], v)
+		}
+		return merged
+
+	case []interface{}:
+		if l, ok := local.([]interface{}); ok {
+			return append(append([]interface{}{}, g...), l...)
+		}
+	}
+
+	if local == nil {
+		return global
+	}
+	return local
+
+}
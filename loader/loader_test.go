@@ -0,0 +1,235 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Loader", func() {
+
+	It("loads templates from files", func() {
+		template, err := Open("../test/templates/sam-official-samples/hello_world/template.yaml", Options{})
+		Expect(err).To(BeNil())
+		Expect(template.Filename).To(Equal("../test/templates/sam-official-samples/hello_world/template.yaml"))
+		Expect(template.Functions).To(HaveKey("HelloWorldFunction"))
+	})
+
+	It("loads JSON and YAML templates", func() {
+		yaml, err := Parse([]byte(`
+Resources:
+  Hello:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: nodejs8.10
+      Handler: index.handler
+`), Options{})
+		Expect(err).To(BeNil())
+
+		json, err := Parse([]byte(`{"Resources": {"Hello": {"Type": "AWS::Serverless::Function", "Properties": {"Runtime": "nodejs8.10", "Handler": "index.handler"}}}}`), Options{})
+		Expect(err).To(BeNil())
+
+		Expect(yaml.Functions).To(Equal(json.Functions))
+	})
+
+	It("resolves intrinsic functions and parameter overrides", func() {
+		template, err := Parse([]byte(`
+Parameters:
+  Stage:
+    Type: String
+    Default: dev
+Resources:
+  Hello:
+    Type: AWS::Serverless::Function
+    Properties:
+      Handler: !Sub "${Stage}.handler"
+`), Options{ParameterOverrides: map[string]interface{}{"Stage": "prod"}})
+		Expect(err).To(BeNil())
+		Expect(template.Functions["Hello"].Handler).To(Equal("prod.handler"))
+	})
+
+	It("applies the Globals section to resources", func() {
+		template, err := Parse([]byte(`
+Globals:
+  Function:
+    Runtime: python3.6
+    Timeout: 10
+    Environment:
+      Variables:
+        TABLE: global
+        STAGE: dev
+Resources:
+  Hello:
+    Type: AWS::Serverless::Function
+    Properties:
+      Handler: index.handler
+      Timeout: 30
+      Environment:
+        Variables:
+          TABLE: hello
+`), Options{})
+		Expect(err).To(BeNil())
+
+		hello := template.Functions["Hello"]
+		Expect(hello.Runtime).To(Equal("python3.6"))
+		Expect(hello.Timeout).To(Equal(30))
+		Expect(hello.Environment.Variables).To(Equal(map[string]string{"TABLE": "hello", "STAGE": "dev"}))
+	})
+
+	It("appends to lists in the Globals section", func() {
+		Expect(mergeGlobals([]interface{}{"a"}, []interface{}{"b"})).To(Equal([]interface{}{"a", "b"}))
+		Expect(mergeGlobals([]interface{}{"a"}, nil)).To(Equal([]interface{}{"a"}))
+	})
+
+	It("returns the Metadata of resources", func() {
+		template, err := Parse([]byte(`
+Resources:
+  Hello:
+    Type: AWS::Serverless::Function
+    Metadata:
+      BuildMethod: makefile
+    Properties:
+      Runtime: go1.x
+      Handler: hello
+`), Options{})
+		Expect(err).To(BeNil())
+		Expect(template.Metadata("Hello")).To(Equal(map[string]interface{}{"BuildMethod": "makefile"}))
+		Expect(template.Metadata("Missing")).To(BeNil())
+	})
+
+	It("returns resources with their references to other resources kept", func() {
+		template, err := Parse([]byte(`
+Parameters:
+  Stage:
+    Type: String
+    Default: dev
+Globals:
+  Function:
+    Timeout: 10
+Resources:
+  Role:
+    Type: AWS::IAM::Role
+  Bucket:
+    Type: AWS::S3::Bucket
+  Hello:
+    Type: AWS::Serverless::Function
+    Properties:
+      Role: !GetAtt Role.Arn
+      Policies:
+        - Statement:
+            Resource:
+              - !Ref Bucket
+              - !Sub "arn:aws:s3:::${Bucket}/${Stage}/*"
+`), Options{ParameterOverrides: map[string]interface{}{"Stage": "prod"}})
+		Expect(err).To(BeNil())
+
+		properties := template.Resource("Hello")["Properties"].(map[string]interface{})
+		Expect(properties["Role"]).To(Equal(map[string]interface{}{"Fn::GetAtt": []interface{}{"Role", "Arn"}}))
+		Expect(properties["Timeout"]).To(BeEquivalentTo(10))
+
+		statement := properties["Policies"].([]interface{})[0].(map[string]interface{})["Statement"].(map[string]interface{})
+		Expect(statement["Resource"]).To(Equal([]interface{}{
+			map[string]interface{}{"Ref": "Bucket"},
+			"arn:aws:s3:::*/prod/*",
+		}))
+
+		Expect(template.Resource("Missing")).To(BeNil())
+	})
+
+	It("loads the functions and APIs of templates with thousands of resources", func() {
+		var source bytes.Buffer
+		source.WriteString("Resources:\n  Api:\n    Type: AWS::Serverless::Api\n    Properties:\n      StageName: prod\n")
+		for i := 0; i < 5000; i++ {
+			fmt.Fprintf(&source, "  Function%d:\n    Type: AWS::Serverless::Function\n    Properties:\n      Runtime: nodejs8.10\n      Handler: index.handler%d\n", i, i)
+		}
+
+		template, err := Parse(source.Bytes(), Options{})
+		Expect(err).To(BeNil())
+		Expect(template.Functions).To(HaveLen(5000))
+		Expect(template.Functions["Function42"].Handler).To(Equal("index.handler42"))
+		Expect(template.Apis).To(HaveKey("Api"))
+		Expect(template.Functions).To(Equal(template.GetAllAWSServerlessFunctionResources()))
+		Expect(template.Apis).To(Equal(template.GetAllAWSServerlessApiResources()))
+	})
+
+	Context("with invalid templates", func() {
+
+		It("returns sections of the wrong type as syntax errors", func() {
+			_, err := Parse([]byte("Resources:\n  - Hello\n"), Options{})
+			Expect(err).To(BeAssignableToTypeOf(&SyntaxError{}))
+			Expect(err.Error()).To(ContainSubstring("the Resources section must be an object"))
+		})
+
+		It("returns the line of YAML syntax errors", func() {
+			_, err := Parse([]byte("Resources:\n  Hello:\n    Type: [\n"), Options{})
+			Expect(err).To(BeAssignableToTypeOf(&SyntaxError{}))
+			Expect(err.(*SyntaxError).Line).To(BeNumerically(">", 0))
+		})
+
+		It("returns the line and column of JSON syntax errors", func() {
+			_, err := Parse([]byte("{\n  \"Resources\": {\n    \"Hello\": nope\n  }\n}"), Options{})
+			Expect(err).To(BeAssignableToTypeOf(&SyntaxError{}))
+			Expect(err.(*SyntaxError).Position).To(Equal(Position{Line: 3, Column: 15}))
+		})
+
+		It("adds the filename to syntax errors", func() {
+			file, _ := ioutil.TempFile("", "template")
+			defer os.Remove(file.Name())
+			file.WriteString("Resources: [\n")
+			file.Close()
+
+			_, err := Open(file.Name(), Options{})
+			Expect(err).To(BeAssignableToTypeOf(&SyntaxError{}))
+			Expect(err.Error()).To(HavePrefix(file.Name() + ":"))
+		})
+
+	})
+
+	Describe("Position", func() {
+
+		It("leaves out the parts that are unknown", func() {
+			Expect(Position{Filename: "template.yaml", Line: 3, Column: 7}.String()).To(Equal("template.yaml:3:7"))
+			Expect(Position{Filename: "template.yaml"}.String()).To(Equal("template.yaml"))
+			Expect(Position{Line: 3}.String()).To(Equal("3"))
+		})
+
+		It("locates keys in YAML templates", func() {
+			source := []byte(`Resources:
+  Other:
+    Properties:
+      Events: {}
+  Hello:
+    Type: AWS::Serverless::Function
+    Properties:
+      Events:
+        # comment
+        Get:
+          Type: Api
+`)
+			line, column := locate(source, "Resources", "Hello", "Properties", "Events", "Get")
+			Expect([]int{line, column}).To(Equal([]int{10, 9}))
+
+			line, column = locate(source, "Resources", "Hello", "Properties", "Events", "Missing")
+			Expect([]int{line, column}).To(Equal([]int{8, 7}))
+		})
+
+		It("locates keys in JSON templates", func() {
+			source := []byte(`{
+  "Resources": {
+    "Other": {"Properties": {}},
+    "Hello": {
+      "Properties": {"Events": {"Get": {}}}
+    }
+  }
+}`)
+			line, column := locate(source, "Resources", "Hello", "Properties", "Events", "Get")
+			Expect([]int{line, column}).To(Equal([]int{5, 33}))
+		})
+
+	})
+
+})
@@ -0,0 +1,106 @@
+package loader
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// lineColumn returns the line and column of an offset in data
+func lineColumn(data []byte, offset int) (line, column int) {
+	if offset < 0 {
+		offset = 0
+	} else if offset > len(data) {
+		offset = len(data)
+	}
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	return bytes.Count(data[:offset], []byte("\n")) + 1, offset - lineStart + 1
+}
+
+// locate finds the position of the key at the end of a path of keys in a JSON or YAML
+// template, e.g. ("Resources", "Hello", "Properties", "CodeUri"). The templates have been
+// parsed already, so this only needs to be good enough to point people in the right
+// direction. If the key isn't written in the template (e.g. because it's set in Globals),
+// the position of the closest of its parents is returned instead.
+func locate(source []byte, path ...string) (line, column int) {
+
+	json := isJSON(source)
+	start, end, indent := 0, len(source), -1
+
+	for _, key := range path {
+		q := regexp.QuoteMeta(key)
+		keyEx := regexp.MustCompile(`(?:"` + q + `"|'` + q + `'|` + q + `)[ \t]*:`)
+
+		// In YAML, the keys of a mapping are all indented like its first one
+		childIndent := 0
+		if indent >= 0 {
+			childIndent = firstIndent(source, nextLine(source, start))
+		}
+
+		found := false
+		for _, match := range keyEx.FindAllIndex(source[start:end], -1) {
+			offset := start + match[0]
+			before := source[bytes.LastIndexByte(source[:offset], '\n')+1 : offset]
+
+			if json {
+				before = bytes.TrimRight(before, " \t")
+				if len(before) > 0 && !bytes.HasSuffix(before, []byte("{")) && !bytes.HasSuffix(before, []byte(",")) {
+					continue
+				}
+			} else if len(bytes.TrimLeft(before, " ")) > 0 || len(before) != childIndent {
+				continue
+			}
+
+			line, column = lineColumn(source, offset)
+			start = offset
+			if !json {
+				indent = len(before)
+				end = subtreeEnd(source, offset, indent)
+			}
+			found = true
+			break
+		}
+
+		if !found {
+			return
+		}
+	}
+
+	return
+
+}
+
+// nextLine returns the offset of the line after the one that offset is on
+func nextLine(source []byte, offset int) int {
+	if i := bytes.IndexByte(source[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(source)
+}
+
+// isBlank returns whether a YAML line has no content
+func isBlank(line []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	return len(trimmed) == 0 || trimmed[0] == '#'
+}
+
+// firstIndent returns the indentation of the first line with content from offset on
+func firstIndent(source []byte, offset int) int {
+	for _, line := range bytes.Split(source[offset:], []byte("\n")) {
+		if !isBlank(line) {
+			return len(line) - len(bytes.TrimLeft(line, " "))
+		}
+	}
+	return 0
+}
+
+// subtreeEnd returns the offset where the YAML value of the key at offset, which is
+// indented by indent, ends
+func subtreeEnd(source []byte, offset int, indent int) int {
+	for offset = nextLine(source, offset); offset < len(source); offset = nextLine(source, offset) {
+		line := source[offset:nextLine(source, offset)]
+		if !isBlank(line) && len(line)-len(bytes.TrimLeft(line, " ")) <= indent {
+			return offset
+		}
+	}
+	return len(source)
+}
@@ -0,0 +1,235 @@
+package loader
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// supportedIntrinsics are the intrinsic functions that goformation resolves, in long form
+var supportedIntrinsics = map[string]bool{
+	"Fn::Base64": true, "Fn::And": true, "Fn::Equals": true, "Fn::If": true, "Fn::Not": true,
+	"Fn::Or": true, "Fn::FindInMap": true, "Fn::GetAtt": true, "Fn::GetAZs": true,
+	"Fn::ImportValue": true, "Fn::Join": true, "Fn::Select": true, "Fn::Split": true,
+	"Fn::Sub": true,
+}
+
+// supportedTags are the intrinsic functions that can be written in short form in YAML
+// templates. Other tags are silently dropped by the YAML parser, leaving their arguments.
+var supportedTags = map[string]bool{
+	"!Ref": true, "!GetAtt": true, "!Base64": true, "!FindInMap": true, "!GetAZs": true,
+	"!ImportValue": true, "!Join": true, "!Select": true, "!Split": true, "!Sub": true,
+}
+
+var longIntrinsicEx = regexp.MustCompile(`Fn::[A-Za-z0-9]+`)
+var shortIntrinsicEx = regexp.MustCompile(`(?:^|[\s\[{,:])(![A-Za-z][A-Za-z0-9]*)`)
+
+// eventProperties are the event source types and their required properties
+var eventProperties = map[string][]string{
+	"S3":              {"Bucket", "Events"},
+	"SNS":             {"Topic"},
+	"SQS":             {"Queue"},
+	"Kinesis":         {"Stream", "StartingPosition"},
+	"DynamoDB":        {"Stream", "StartingPosition"},
+	"Api":             {"Path", "Method"},
+	"Schedule":        {"Schedule"},
+	"CloudWatchEvent": {"Pattern"},
+	"CloudWatchLogs":  {"LogGroupName", "FilterPattern"},
+	"IoTRule":         {"Sql"},
+	"AlexaSkill":      {},
+}
+
+var apiMethods = []string{"any", "delete", "get", "head", "options", "patch", "post", "put"}
+
+// Validate returns the problems with the template that SAM Local can load, but which
+// won't work as expected: unsupported intrinsic functions (*UnsupportedIntrinsicError),
+// functions without a CodeUri (*MissingCodeUriError), and invalid event sources
+// (*InvalidEventError). It returns nil, or Errors in the order they appear in the template.
+func (t *Template) Validate() error {
+
+	errs := Errors{}
+	errs = append(errs, t.validateIntrinsics()...)
+	errs = append(errs, t.validateFunctions()...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	sortErrors(errs)
+	return errs
+
+}
+
+// validateIntrinsics finds the intrinsic functions in the template's source that aren't
+// supported
+func (t *Template) validateIntrinsics() Errors {
+
+	errs := Errors{}
+	yaml := !isJSON(t.source)
+
+	offset := 0
+	for _, line := range strings.SplitAfter(string(t.source), "\n") {
+		content := line
+		if yaml {
+			content = stripComment(content)
+		}
+
+		matches := [][]int{}
+		for _, m := range longIntrinsicEx.FindAllStringIndex(content, -1) {
+			if !supportedIntrinsics[content[m[0]:m[1]]] {
+				matches = append(matches, m)
+			}
+		}
+		if yaml {
+			for _, m := range shortIntrinsicEx.FindAllStringSubmatchIndex(content, -1) {
+				if !supportedTags[content[m[2]:m[3]]] {
+					matches = append(matches, m[2:4])
+				}
+			}
+		}
+
+		for _, m := range matches {
+			err := &UnsupportedIntrinsicError{Name: content[m[0]:m[1]]}
+			err.Filename = t.Filename
+			err.Line, err.Column = lineColumn(t.source, offset+m[0])
+			errs = append(errs, err)
+		}
+
+		offset += len(line)
+	}
+
+	return errs
+
+}
+
+// stripComment removes the comment from a line of YAML
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// validateFunctions checks the CodeUri and event sources of the template's functions
+func (t *Template) validateFunctions() Errors {
+
+	errs := Errors{}
+	resources, _ := t.processed["Resources"].(map[string]interface{})
+
+	for _, name := range sortedKeys(resources) {
+		if _, ok := t.Functions[name]; !ok {
+			continue
+		}
+
+		resource, _ := resources[name].(map[string]interface{})
+		properties, _ := resource["Properties"].(map[string]interface{})
+
+		if properties["CodeUri"] == nil && properties["ImageUri"] == nil && !t.hasTransform("AWS::CodeStar") {
+			err := &MissingCodeUriError{Function: name}
+			err.Position = t.position("Resources", name)
+			errs = append(errs, err)
+		}
+
+		events, _ := properties["Events"].(map[string]interface{})
+		for _, event := range sortedKeys(events) {
+			if reason := invalidEvent(events[event]); reason != "" {
+				err := &InvalidEventError{Function: name, Event: event, Reason: reason}
+				err.Position = t.position("Resources", name, "Properties", "Events", event)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errs
+
+}
+
+// invalidEvent returns why an event source is invalid, or "" if it's valid
+func invalidEvent(e interface{}) string {
+
+	event, ok := e.(map[string]interface{})
+	if !ok {
+		return "must be an object"
+	}
+
+	eventType, _ := event["Type"].(string)
+	if eventType == "" {
+		return "missing Type"
+	}
+
+	required, ok := eventProperties[eventType]
+	if !ok {
+		return fmt.Sprintf("unsupported Type '%s'", eventType)
+	}
+
+	properties, ok := event["Properties"].(map[string]interface{})
+	if !ok {
+		if len(required) == 0 && event["Properties"] == nil {
+			return ""
+		}
+		return "missing Properties"
+	}
+
+	for _, property := range required {
+		if properties[property] == nil {
+			return fmt.Sprintf("missing %s", property)
+		}
+	}
+
+	if eventType == "Api" {
+		if path, _ := properties["Path"].(string); !strings.HasPrefix(path, "/") {
+			return fmt.Sprintf("Path '%v' must start with /", properties["Path"])
+		}
+		method, _ := properties["Method"].(string)
+		if !isAPIMethod(method) {
+			return fmt.Sprintf("unsupported Method '%v' (must be one of %s)", properties["Method"], strings.Join(apiMethods, ", "))
+		}
+	}
+
+	return ""
+
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isAPIMethod returns whether method is an HTTP method that API events can be mounted on
+func isAPIMethod(method string) bool {
+	for _, m := range apiMethods {
+		if strings.ToLower(method) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTransform returns whether the template uses a transform
+func (t *Template) hasTransform(name string) bool {
+	switch transform := t.processed["Transform"].(type) {
+	case string:
+		return transform == name
+	case []interface{}:
+		for _, tr := range transform {
+			if tr == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// position returns the position of a path of keys in the template
+func (t *Template) position(path ...string) Position {
+	pos := Position{Filename: t.Filename}
+	pos.Line, pos.Column = locate(t.source, path...)
+	return pos
+}
@@ -0,0 +1,112 @@
+package loader
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+
+	validate := func(source string) Errors {
+		template, err := Parse([]byte(source), Options{})
+		Expect(err).To(BeNil())
+		if err := template.Validate(); err != nil {
+			return err.(Errors)
+		}
+		return nil
+	}
+
+	It("accepts the official AWS SAM example templates", func() {
+		for _, filename := range []string{
+			"../test/templates/sam-official-samples/api_backend/template.yaml",
+			"../test/templates/sam-official-samples/schedule/template.yaml",
+			"../test/templates/sam-official-samples/iot_backend/template.yaml",
+			"../test/templates/codestar/nodejs.yml",
+		} {
+			template, err := Open(filename, Options{})
+			Expect(err).To(BeNil())
+			Expect(template.Validate()).To(BeNil(), filename)
+		}
+	})
+
+	It("reports unsupported intrinsic functions", func() {
+		errs := validate(`
+Resources:
+  Subnet:
+    Type: AWS::EC2::Subnet
+    Properties:
+      CidrBlock: !Cidr [10.0.0.0/16, 1, 8]   # !Cidr isn't supported
+      VpcId:
+        Fn::Transform: vpc
+      Tags: !If [HasTags, !Ref Tags, !Ref "AWS::NoValue"]
+`)
+		Expect(errs).To(Equal(Errors{
+			&UnsupportedIntrinsicError{Position: Position{Line: 6, Column: 18}, Name: "!Cidr"},
+			&UnsupportedIntrinsicError{Position: Position{Line: 8, Column: 9}, Name: "Fn::Transform"},
+			&UnsupportedIntrinsicError{Position: Position{Line: 9, Column: 13}, Name: "!If"},
+		}))
+	})
+
+	It("reports functions without a CodeUri", func() {
+		errs := validate(`
+Resources:
+  Hello:
+    Type: AWS::Serverless::Function
+    Properties:
+      Runtime: nodejs8.10
+      Handler: index.handler
+`)
+		Expect(errs).To(Equal(Errors{
+			&MissingCodeUriError{Position: Position{Line: 3, Column: 3}, Function: "Hello"},
+		}))
+	})
+
+	It("accepts CodeUris from the Globals section", func() {
+		Expect(validate(`
+Globals:
+  Function:
+    CodeUri: src/
+Resources:
+  Hello:
+    Type: AWS::Serverless::Function
+    Properties:
+      Handler: index.handler
+`)).To(BeNil())
+	})
+
+	It("reports invalid events", func() {
+		errs := validate(`
+Resources:
+  Hello:
+    Type: AWS::Serverless::Function
+    Properties:
+      CodeUri: .
+      Events:
+        Get:
+          Type: Api
+          Properties:
+            Path: hello
+            Method: get
+        Post:
+          Type: Api
+          Properties:
+            Path: /hello
+            Method: fetch
+        Queue:
+          Type: SQS
+        Custom:
+          Type: Custom
+`)
+		Expect(errs).To(HaveLen(4))
+		Expect(errs[0]).To(Equal(&InvalidEventError{
+			Position: Position{Line: 8, Column: 9},
+			Function: "Hello",
+			Event:    "Get",
+			Reason:   "Path 'hello' must start with /",
+		}))
+		Expect(errs[1].Error()).To(HavePrefix("13:9: invalid event 'Post' of function 'Hello': unsupported Method 'fetch'"))
+		Expect(errs[2].Error()).To(Equal("18:9: invalid event 'Queue' of function 'Hello': missing Properties"))
+		Expect(errs[3].Error()).To(Equal("20:9: invalid event 'Custom' of function 'Hello': unsupported Type 'Custom'"))
+	})
+
+})
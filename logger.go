@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/codegangsta/cli"
+)
+
+// newLogger returns the Logger that the router and invoker packages log to. It writes to the
+// log package's standard logger (so it follows --log-file), with the levels of --log-level.
+func newLogger(c *cli.Context) logging.Logger {
+
+	levels, err := logging.ParseLevels(c.String("log-level"))
+	if err != nil {
+		errMsg.Fprintf(os.Stderr, "ERROR: Invalid --log-level: %s\n", err)
+		os.Exit(1)
+	}
+
+	logger := logging.Filter(logging.Std(nil), levels)
+	invoker.ActiveContainers.Log = logger
+	return logger
+
+}
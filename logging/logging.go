@@ -0,0 +1,205 @@
+// Package logging is the logging interface of SAM Local's packages. Embedders pass their own
+// Logger (e.g. an adapter to their structured logging library) in the options of the router
+// and invoker packages, instead of being stuck with the CLI's output:
+//
+//	logger := logging.LoggerFunc(func(level logging.Level, msg string, fields logging.Fields) {
+//		// e.g. send the entry to your structured logging library
+//	})
+//	r := router.NewServerlessRouter(router.NewServerlessRouterOpt{Log: logger})
+//
+// Each entry has a "subsystem" field, one of Router, Docker, Template or Tracing, so that the
+// verbosity of each subsystem can be set separately with Levels.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+// The levels of log entries, from the most to the least verbose
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+// String implements fmt.Stringer
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level with the given name (debug, info, warn or error)
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.ToLower(name) == n {
+			return Level(i), nil
+		}
+	}
+	return Info, fmt.Errorf("unsupported log level '%s' (must be one of %s)", name, strings.Join(levelNames, ", "))
+}
+
+// Fields holds the structured data of a log entry
+type Fields map[string]interface{}
+
+// The subsystems that SAM Local's packages log as, in the "subsystem" field of every entry
+const (
+	// Router is the emulation of API Gateway
+	Router = "router"
+
+	// Docker is the running of functions, in Docker containers or otherwise
+	Docker = "docker"
+
+	// Template is the parsing and processing of SAM templates
+	Template = "template"
+
+	// Tracing is the export of OpenTelemetry spans
+	Tracing = "tracing"
+)
+
+// SubsystemField is the name of the field that holds the subsystem of an entry
+const SubsystemField = "subsystem"
+
+// Logger receives log entries. Implementations must be safe for concurrent use.
+type Logger interface {
+	Log(level Level, msg string, fields Fields)
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions as Loggers
+type LoggerFunc func(level Level, msg string, fields Fields)
+
+// Log implements Logger
+func (f LoggerFunc) Log(level Level, msg string, fields Fields) {
+	f(level, msg, fields)
+}
+
+// Std returns a Logger that writes the messages of entries (without their fields) to a
+// standard library logger, or to the log package's standard logger if it's nil. This is
+// how the CLI logs, and what the packages use when they aren't given a Logger.
+func Std(logger *log.Logger) Logger {
+	return LoggerFunc(func(level Level, msg string, fields Fields) {
+		if logger == nil {
+			log.Print(msg)
+			return
+		}
+		logger.Print(msg)
+	})
+}
+
+// Levels sets the minimum level of the entries of each subsystem that are logged
+type Levels struct {
+	// Default is the minimum level of subsystems that aren't in Subsystems
+	Default Level
+
+	Subsystems map[string]Level
+}
+
+// ParseLevels parses a comma separated list of levels, where each is either a default level,
+// or a subsystem's level, e.g. "warn,docker=debug"
+func ParseLevels(spec string) (*Levels, error) {
+
+	levels := &Levels{Default: Info, Subsystems: map[string]Level{}}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		subsystem := ""
+		if i := strings.Index(part, "="); i >= 0 {
+			subsystem, part = part[:i], part[i+1:]
+		}
+
+		level, err := ParseLevel(part)
+		if err != nil {
+			return nil, err
+		}
+
+		if subsystem == "" {
+			levels.Default = level
+		} else {
+			levels.Subsystems[subsystem] = level
+		}
+	}
+
+	return levels, nil
+
+}
+
+// Enabled returns whether entries of the subsystem at the level are logged
+func (l *Levels) Enabled(subsystem string, level Level) bool {
+	if min, ok := l.Subsystems[subsystem]; ok {
+		return level >= min
+	}
+	return level >= l.Default
+}
+
+// Filter returns a Logger that only passes the entries that the levels enable on to logger
+func Filter(logger Logger, levels *Levels) Logger {
+	return LoggerFunc(func(level Level, msg string, fields Fields) {
+		subsystem, _ := fields[SubsystemField].(string)
+		if levels.Enabled(subsystem, level) {
+			logger.Log(level, msg, fields)
+		}
+	})
+}
+
+// Entry builds log entries with a set of fields, and sends them to a Logger
+type Entry struct {
+	logger Logger
+	fields Fields
+}
+
+// For returns an Entry that logs to logger (or Std(nil) if it's nil) as a subsystem
+func For(logger Logger, subsystem string) *Entry {
+	if logger == nil {
+		logger = Std(nil)
+	}
+	return &Entry{logger: logger, fields: Fields{SubsystemField: subsystem}}
+}
+
+// With returns an Entry that adds the fields to those of e
+func (e *Entry) With(fields Fields) *Entry {
+	merged := Fields{}
+	for k, v := range e.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Entry{logger: e.logger, fields: merged}
+}
+
+// Debugf logs a formatted message at the Debug level
+func (e *Entry) Debugf(format string, v ...interface{}) {
+	e.logf(Debug, format, v...)
+}
+
+// Infof logs a formatted message at the Info level
+func (e *Entry) Infof(format string, v ...interface{}) {
+	e.logf(Info, format, v...)
+}
+
+// Warnf logs a formatted message at the Warn level
+func (e *Entry) Warnf(format string, v ...interface{}) {
+	e.logf(Warn, format, v...)
+}
+
+// Errorf logs a formatted message at the Error level
+func (e *Entry) Errorf(format string, v ...interface{}) {
+	e.logf(Error, format, v...)
+}
+
+func (e *Entry) logf(level Level, format string, v ...interface{}) {
+	e.logger.Log(level, strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"), e.fields)
+}
@@ -0,0 +1,94 @@
+package logging_test
+
+import (
+	"bytes"
+	"log"
+
+	"github.com/awslabs/aws-sam-local/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// entry is a log entry received by a recorder
+type entry struct {
+	Level  logging.Level
+	Msg    string
+	Fields logging.Fields
+}
+
+// recorder returns a Logger that records its entries
+func recorder(entries *[]entry) logging.Logger {
+	return logging.LoggerFunc(func(level logging.Level, msg string, fields logging.Fields) {
+		*entries = append(*entries, entry{level, msg, fields})
+	})
+}
+
+var _ = Describe("Logging", func() {
+
+	It("parses levels", func() {
+		level, err := logging.ParseLevel("WARN")
+		Expect(err).To(BeNil())
+		Expect(level).To(Equal(logging.Warn))
+		Expect(level.String()).To(Equal("warn"))
+
+		_, err = logging.ParseLevel("loud")
+		Expect(err).To(MatchError(ContainSubstring("unsupported log level 'loud'")))
+	})
+
+	It("parses a default level and subsystem levels", func() {
+		levels, err := logging.ParseLevels("warn, docker=debug")
+		Expect(err).To(BeNil())
+		Expect(levels.Default).To(Equal(logging.Warn))
+		Expect(levels.Subsystems).To(Equal(map[string]logging.Level{logging.Docker: logging.Debug}))
+
+		Expect(levels.Enabled(logging.Docker, logging.Debug)).To(BeTrue())
+		Expect(levels.Enabled(logging.Router, logging.Info)).To(BeFalse())
+		Expect(levels.Enabled(logging.Router, logging.Error)).To(BeTrue())
+	})
+
+	It("logs at info by default", func() {
+		levels, err := logging.ParseLevels("")
+		Expect(err).To(BeNil())
+		Expect(levels.Enabled(logging.Template, logging.Info)).To(BeTrue())
+		Expect(levels.Enabled(logging.Template, logging.Debug)).To(BeFalse())
+	})
+
+	It("rejects invalid levels", func() {
+		_, err := logging.ParseLevels("info,router=chatty")
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("logs entries with their subsystem and fields", func() {
+		entries := []entry{}
+		log := logging.For(recorder(&entries), logging.Docker).With(logging.Fields{"function": "Hello"})
+		log.Infof("Invoking %s\n", "index.handler")
+		log.With(logging.Fields{"container": "abc"}).Errorf("failed")
+
+		Expect(entries).To(Equal([]entry{
+			{logging.Info, "Invoking index.handler", logging.Fields{"subsystem": "docker", "function": "Hello"}},
+			{logging.Error, "failed", logging.Fields{"subsystem": "docker", "function": "Hello", "container": "abc"}},
+		}))
+	})
+
+	It("filters entries by subsystem", func() {
+		entries := []entry{}
+		levels, _ := logging.ParseLevels("error,router=debug")
+		logger := logging.Filter(recorder(&entries), levels)
+
+		logging.For(logger, logging.Router).Debugf("routed")
+		logging.For(logger, logging.Docker).Warnf("slow")
+		logging.For(logger, logging.Docker).Errorf("broken")
+
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Msg).To(Equal("routed"))
+		Expect(entries[1].Msg).To(Equal("broken"))
+	})
+
+	It("writes messages to standard library loggers", func() {
+		out := &bytes.Buffer{}
+		logging.For(logging.Std(log.New(out, "", 0)), logging.Template).Warnf("Parsed %d functions", 2)
+		Expect(out.String()).To(Equal("Parsed 2 functions\n"))
+	})
+
+})
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"hash/fnv"
+	"io"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// functionColors are used to tell the output of different functions apart in the console
+var functionColors = []*color.Color{
+	color.New(color.FgCyan),
+	color.New(color.FgMagenta),
+	color.New(color.FgBlue),
+	color.New(color.FgGreen),
+	color.New(color.FgYellow),
+	color.New(color.FgHiCyan),
+	color.New(color.FgHiMagenta),
+	color.New(color.FgHiBlue),
+}
+
+// logMutex makes sure lines written by concurrently running functions
+// are not interleaved with each other
+var logMutex sync.Mutex
+
+// prefixWriter is an io.Writer that prefixes every line written to it with
+// [FunctionName][requestId], so the logs of multiple functions can be told apart.
+// Partial lines are buffered until they are completed, or Flush() is called.
+type prefixWriter struct {
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+// newPrefixWriter creates a prefixWriter for a single invocation of a function.
+// Each function is consistently given the same color.
+func newPrefixWriter(out io.Writer, function string, requestID string) *prefixWriter {
+
+	prefix := "[" + function + "]"
+	if requestID != "" {
+		prefix += "[" + requestID + "]"
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(function))
+	c := functionColors[hash.Sum32()%uint32(len(functionColors))]
+
+	return &prefixWriter{
+		out:    out,
+		prefix: c.Sprint(prefix) + " ",
+	}
+
+}
+
+// Write implements io.Writer
+func (w *prefixWriter) Write(p []byte) (int, error) {
+
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return len(p), err
+		}
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+
+}
+
+// Flush writes out any buffered partial line
+func (w *prefixWriter) Flush() error {
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	line := append(w.buf, '\n')
+	w.buf = nil
+	return w.writeLine(line)
+
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	_, err := w.out.Write(append([]byte(w.prefix), line...))
+	return err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/fatih/color"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Function logs", func() {
+
+	Context("with a prefix writer", func() {
+
+		var out *bytes.Buffer
+		var noColor bool
+
+		BeforeEach(func() {
+			noColor = color.NoColor
+			color.NoColor = true
+			out = new(bytes.Buffer)
+		})
+
+		AfterEach(func() {
+			color.NoColor = noColor
+		})
+
+		It("prefixes every line with the function name and request ID", func() {
+			w := newPrefixWriter(out, "HelloWorld", "abc-123")
+			w.Write([]byte("first\nsecond\n"))
+			Expect(out.String()).To(Equal("[HelloWorld][abc-123] first\n[HelloWorld][abc-123] second\n"))
+		})
+
+		It("buffers partial lines until they are completed", func() {
+			w := newPrefixWriter(out, "HelloWorld", "")
+			w.Write([]byte("hel"))
+			Expect(out.String()).To(BeEmpty())
+			w.Write([]byte("lo\nwor"))
+			Expect(out.String()).To(Equal("[HelloWorld] hello\n"))
+			w.Flush()
+			Expect(out.String()).To(Equal("[HelloWorld] hello\n[HelloWorld] wor\n"))
+		})
+
+	})
+
+})
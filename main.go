@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/codegangsta/cli"
 	"github.com/fatih/color"
@@ -78,15 +79,22 @@ func main() {
 							Usage: "Any static assets (e.g. CSS/Javascript/HTML) files located in this directory will be presented at /",
 							Value: "public",
 						},
-						cli.StringFlag{
+						cli.StringSliceFlag{
 							Name:  "port, p",
-							Value: "3000",
-							Usage: "Local port number to listen on",
+							Usage: "Local port number to listen on (default: 3000). Can be repeated along with --host to listen on multiple addresses",
 						},
-						cli.StringFlag{
+						cli.StringSliceFlag{
 							Name:  "host",
-							Value: "127.0.0.1",
-							Usage: "Local hostname or IP address to bind to",
+							Usage: "Local hostname or IP address to bind to (default: 127.0.0.1). Can be repeated along with --port to listen on multiple addresses",
+						},
+						cli.StringSliceFlag{
+							Name:  "api-listener",
+							Usage: "Optional. Binds an AWS::Serverless::Api resource to one of the listeners, e.g. 'MyApi=127.0.0.1:3001'. Can be repeated. Apis that aren't bound are served on the first listener",
+						},
+						cli.StringFlag{
+							Name:   "tunnel",
+							Usage:  "Optional. Shares the (first) local listener at a public HTTPS URL through a tunnel, so that webhooks can call your functions. Either ngrok or cloudflare, which need the ngrok or cloudflared command to be installed",
+							EnvVar: "SAM_TUNNEL",
 						},
 						cli.StringFlag{
 							Name:  "env-vars, n",
@@ -97,6 +105,35 @@ func main() {
 							Usage:  "Optional. When specified, Lambda function container will start in debug mode and will expose this port on localhost.",
 							EnvVar: "SAM_DEBUG_PORT",
 						},
+						cli.BoolFlag{
+							Name:  "tui",
+							Usage: "Optional. Shows a live dashboard of the mounted routes, running containers, recent requests and function logs instead of plain logs.",
+						},
+						cli.StringSliceFlag{
+							Name:   "plugin",
+							Usage:  "Optional. Path to a plugin executable, which hooks into the template, routes and invocations. Can be repeated",
+							EnvVar: "SAM_PLUGINS",
+						},
+						cli.StringFlag{
+							Name:   "log-level",
+							Usage:  "Optional. The minimum level (debug, info, warn or error) of SAM Local's logs, optionally per subsystem (router, docker, template or tracing), e.g. 'warn,docker=debug'",
+							EnvVar: "SAM_LOG_LEVEL",
+						},
+						cli.StringFlag{
+							Name:   "runtime-backend",
+							Usage:  "Optional. The backend that runs functions: docker (the default) or native (the same as --no-docker).",
+							EnvVar: "SAM_RUNTIME_BACKEND",
+						},
+						cli.BoolFlag{
+							Name:   "no-docker",
+							Usage:  "Optional. Runs Node.js and Python functions directly on this machine, with the interpreters found in PATH, instead of in Docker containers. Useful when Docker isn't available, but the environment won't match Lambda's.",
+							EnvVar: "SAM_NO_DOCKER",
+						},
+						cli.StringFlag{
+							Name:   "debug-adapter-port",
+							Usage:  "Optional. When specified along with --debug-port, starts a Debug Adapter Protocol server on this port. Editors connected to it are asked to attach to each function as it starts in debug mode.",
+							EnvVar: "SAM_DEBUG_ADAPTER_PORT",
+						},
 						cli.StringFlag{
 							Name: "docker-volume-basedir, v",
 							Usage: "Optional. Specifies the location basedir where the SAM file exists. If the Docker is running on a remote machine, " +
@@ -110,25 +147,140 @@ func main() {
 								"the lambdadocker containers will only connect to the default bridge docker network.",
 							EnvVar: "SAM_DOCKER_NETWORK",
 						},
+						cli.BoolFlag{
+							Name:   "simulate-iam",
+							Usage:  "Optional. Check the AWS API calls of functions against the policies of their execution role in the template, and answer the ones it wouldn't allow with AccessDenied, as AWS would. The others are sent on to AWS (or --aws-endpoint-url)",
+							EnvVar: "SAM_SIMULATE_IAM",
+						},
+						cli.StringFlag{
+							Name:   "kms-keyring",
+							Usage:  "Optional. JSON file of the plaintexts of KMS ciphertexts, by ciphertext, that functions' encrypted environment variables are decrypted with locally, instead of by KMS",
+							EnvVar: "SAM_KMS_KEYRING",
+						},
+						cli.StringSliceFlag{
+							Name:   "aws-endpoint-url",
+							Usage:  "Optional. Endpoint URL that the AWS SDKs in functions call instead of AWS, e.g. LocalStack's 'http://localhost:4566', or a service's own, e.g. 's3=http://localhost:9000'. Passed to functions as AWS_ENDPOINT_URL variables, with localhost pointed at the Docker host. Can be repeated",
+							EnvVar: "SAM_AWS_ENDPOINT_URL",
+						},
 						cli.BoolFlag{
 							Name:   "skip-pull-image",
 							Usage:  "Optional. Specify whether SAM should skip pulling down the latest Docker image. Default is false.",
 							EnvVar: "SAM_SKIP_PULL_IMAGE",
 						},
+						cli.BoolFlag{
+							Name:   "build",
+							Usage:  "Optional. Build functions from their source code before invoking them (e.g. compile go1.x functions), like 'sam build'. Functions are only built again when their code changes.",
+							EnvVar: "SAM_BUILD",
+						},
+						cli.StringFlag{
+							Name:   "build-dir",
+							Usage:  "Optional. Directory to keep built functions in. By default, they're kept in SAM Local's cache.",
+							EnvVar: "SAM_BUILD_DIR",
+						},
+						cli.BoolFlag{
+							Name:   "dependency-layer",
+							Usage:  "Optional. With --build, build the dependencies of functions whose builder supports it (e.g. a requirements.txt or Gemfile) into a layer of their own, mounted at /opt, and run the functions from their source code. Editing a function then doesn't rebuild its dependencies.",
+							EnvVar: "SAM_DEPENDENCY_LAYER",
+						},
+						cli.IntFlag{
+							Name:   "startup-workers",
+							Value:  8,
+							Usage:  "Optional. Number of functions whose Docker images are pulled and runtimes prepared at the same time when starting up.",
+							EnvVar: "SAM_STARTUP_WORKERS",
+						},
+						cli.BoolFlag{
+							Name:   "lazy",
+							Usage:  "Optional. Only pull the Docker image of each function (and get its runtime ready) when the function is first invoked, so that templates with many functions start quickly.",
+							EnvVar: "SAM_LAZY",
+						},
+						cli.StringSliceFlag{
+							Name:  "eager",
+							Usage: "Optional. Logical ID of a function to get ready on startup even with --lazy. Can be repeated.",
+						},
+						cli.IntFlag{
+							Name:   "max-buffered-body",
+							Value:  6,
+							Usage:  "Optional. Size in MB of the largest request body that's read into memory. Larger bodies are streamed to the function. Use -1 to always read bodies into memory.",
+							EnvVar: "SAM_MAX_BUFFERED_BODY",
+						},
 						cli.StringFlag{
 							Name:  "profile",
 							Usage: "Optional. Specify which AWS credentials profile to use.",
 						},
+						cli.BoolFlag{
+							Name:  "dry-run",
+							Usage: "Optional. Print the routes and container configuration (image, mounts, environment) that would be used, without starting Docker",
+						},
+						cli.BoolFlag{
+							Name:  "estimate-cost",
+							Usage: "Optional. After each invocation, print an estimate of what it would have cost on AWS Lambda (excluding the free tier)",
+						},
+						cli.StringFlag{
+							Name:  "record",
+							Usage: "Optional. Directory to save every request, generated Lambda event and function response to, for use with 'sam local replay'",
+						},
+						cli.StringFlag{
+							Name:  "fault-config",
+							Usage: "Optional. JSON file describing latency and failures to inject into specific functions or routes. See the README for the file structure",
+						},
+						cli.StringFlag{
+							Name:  "inject-latency",
+							Usage: "Optional. Latency to add to every request (e.g. '500ms')",
+						},
+						cli.StringFlag{
+							Name:  "inject-jitter",
+							Usage: "Optional. Upper bound of a random latency to add to every request, on top of --inject-latency (e.g. '200ms')",
+						},
+						cli.Float64Flag{
+							Name:  "inject-error-rate",
+							Usage: "Optional. Probability (0-1) of a request failing with HTTP 503 instead of invoking the function",
+						},
+						cli.Float64Flag{
+							Name:  "inject-drop-rate",
+							Usage: "Optional. Probability (0-1) of a request's connection being closed without a response",
+						},
+						cli.BoolFlag{
+							Name:   "no-color",
+							Usage:  "Optional. Disables colored output, including the [FunctionName][requestId] prefix of function logs.",
+							EnvVar: "SAM_NO_COLOR",
+						},
 						cli.BoolFlag{
 							Name:   "prefix-routing",
 							Usage:  "Optional. Specify whether SAM routing is based on prefix or exact matching (e.g. given a function mounted at '/' with prefix routing calls to '/beers' will be routed the function).",
 							EnvVar: "SAM_PREFIX_ROUTING",
 						},
+						cli.BoolFlag{
+							Name:   "deterministic",
+							Usage:  "Optional. Derive request IDs, trace IDs, request times and injected faults from --seed, and leave timestamps out of logs, so the same requests give the same events and logs on every run (e.g. for golden-file tests)",
+							EnvVar: "SAM_DETERMINISTIC",
+						},
+						cli.IntFlag{
+							Name:   "seed",
+							Usage:  "Optional. Seed of --deterministic runs (default: 0)",
+							EnvVar: "SAM_SEED",
+						},
+						cli.StringFlag{
+							Name:   "validate-responses",
+							Usage:  "Optional. Check responses against the response schemas of the APIs' OpenAPI definitions: warn logs mismatches, and fail also replaces the response with a 502 error",
+							EnvVar: "SAM_VALIDATE_RESPONSES",
+						},
+						cli.StringFlag{
+							Name:   "otel-endpoint",
+							Usage:  "Optional. Export OpenTelemetry traces of requests (the request, building its event, invoking the function and its response) to the OTLP/HTTP endpoint of a collector, such as Jaeger's (e.g. 'http://localhost:4318')",
+							EnvVar: "OTEL_EXPORTER_OTLP_ENDPOINT",
+						},
+						cli.StringFlag{
+							Name:   "otel-service-name",
+							Value:  "sam-local",
+							Usage:  "Optional. The service name of the exported traces",
+							EnvVar: "OTEL_SERVICE_NAME",
+						},
 					},
 				},
 				cli.Command{
-					Name:   "invoke",
-					Action: invoke,
+					Name:         "invoke",
+					Action:       invoke,
+					BashComplete: completeFunctionNames,
 					Usage: "Invokes a local Lambda function once and quits after invocation completes. \n\n" +
 						"Useful for developing serverless functions that handle asynchronous events (such as S3/Kinesis etc), or if you want to compose a script of test cases. " +
 						"Event body can be passed in either by stdin (default), or by using the --event parameter. Runtime output (logs etc) will be outputted to stderr, and the Lambda function result will be outputted to stdout.\n",
@@ -157,11 +309,67 @@ func main() {
 							Name:  "event, e",
 							Usage: "JSON file containing event data passed to the Lambda function during invoke",
 						},
+						cli.StringFlag{
+							Name:  "event-schema",
+							Usage: "Optional. JSON Schema file that events must match before the function is invoked (overrides SamLocal.EventSchema in the function's Metadata)",
+						},
+						cli.StringFlag{
+							Name:  "event-dir",
+							Usage: "Optional. Directory of JSON event files. The function is invoked once per event, and a summary of the results is printed",
+						},
+						cli.IntFlag{
+							Name:  "parallel",
+							Value: 1,
+							Usage: "Optional. Number of events from --event-dir to invoke in parallel",
+						},
+						cli.StringFlag{
+							Name:  "invocation-type",
+							Value: "RequestResponse",
+							Usage: "Optional. One of RequestResponse, Event (the function result is discarded) or DryRun (only checks the function could be invoked), as with the Lambda Invoke API",
+						},
+						cli.StringFlag{
+							Name:  "qualifier",
+							Usage: "Optional. Function version or alias to invoke. Only $LATEST and the function's AutoPublishAlias exist locally",
+						},
+						cli.StringFlag{
+							Name:  "log-type",
+							Value: "None",
+							Usage: "Optional. Set to Tail to include the last 4 KB of the function logs, base64 encoded, in the invocation metadata",
+						},
+						cli.StringFlag{
+							Name:  "outfile, o",
+							Usage: "Optional. File to write the function result to. The invocation metadata is then written to stdout, like 'aws lambda invoke'",
+						},
 						cli.StringFlag{
 							Name:   "debug-port, d",
 							Usage:  "Optional. When specified, Lambda function container will start in debug mode and will expose this port on localhost.",
 							EnvVar: "SAM_DEBUG_PORT",
 						},
+						cli.StringSliceFlag{
+							Name:   "plugin",
+							Usage:  "Optional. Path to a plugin executable, which hooks into the template, routes and invocations. Can be repeated",
+							EnvVar: "SAM_PLUGINS",
+						},
+						cli.StringFlag{
+							Name:   "log-level",
+							Usage:  "Optional. The minimum level (debug, info, warn or error) of SAM Local's logs, optionally per subsystem (router, docker, template or tracing), e.g. 'warn,docker=debug'",
+							EnvVar: "SAM_LOG_LEVEL",
+						},
+						cli.StringFlag{
+							Name:   "runtime-backend",
+							Usage:  "Optional. The backend that runs functions: docker (the default) or native (the same as --no-docker).",
+							EnvVar: "SAM_RUNTIME_BACKEND",
+						},
+						cli.BoolFlag{
+							Name:   "no-docker",
+							Usage:  "Optional. Runs Node.js and Python functions directly on this machine, with the interpreters found in PATH, instead of in Docker containers. Useful when Docker isn't available, but the environment won't match Lambda's.",
+							EnvVar: "SAM_NO_DOCKER",
+						},
+						cli.StringFlag{
+							Name:   "debug-adapter-port",
+							Usage:  "Optional. When specified along with --debug-port, starts a Debug Adapter Protocol server on this port. Editors connected to it are asked to attach to each function as it starts in debug mode.",
+							EnvVar: "SAM_DEBUG_ADAPTER_PORT",
+						},
 						cli.StringFlag{
 							Name: "docker-volume-basedir, v",
 							Usage: "Optional. Specifies the location basedir where the SAM file exists. If the Docker is running on a remote machine, " +
@@ -175,6 +383,369 @@ func main() {
 								"the lambdadocker containers will only connect to the default bridge docker network.",
 							EnvVar: "SAM_DOCKER_NETWORK",
 						},
+						cli.BoolFlag{
+							Name:   "simulate-iam",
+							Usage:  "Optional. Check the AWS API calls of functions against the policies of their execution role in the template, and answer the ones it wouldn't allow with AccessDenied, as AWS would. The others are sent on to AWS (or --aws-endpoint-url)",
+							EnvVar: "SAM_SIMULATE_IAM",
+						},
+						cli.StringFlag{
+							Name:   "kms-keyring",
+							Usage:  "Optional. JSON file of the plaintexts of KMS ciphertexts, by ciphertext, that functions' encrypted environment variables are decrypted with locally, instead of by KMS",
+							EnvVar: "SAM_KMS_KEYRING",
+						},
+						cli.StringSliceFlag{
+							Name:   "aws-endpoint-url",
+							Usage:  "Optional. Endpoint URL that the AWS SDKs in functions call instead of AWS, e.g. LocalStack's 'http://localhost:4566', or a service's own, e.g. 's3=http://localhost:9000'. Passed to functions as AWS_ENDPOINT_URL variables, with localhost pointed at the Docker host. Can be repeated",
+							EnvVar: "SAM_AWS_ENDPOINT_URL",
+						},
+						cli.BoolFlag{
+							Name:   "skip-pull-image",
+							Usage:  "Optional. Specify whether SAM should skip pulling down the latest Docker image. Default is false.",
+							EnvVar: "SAM_SKIP_PULL_IMAGE",
+						},
+						cli.BoolFlag{
+							Name:   "build",
+							Usage:  "Optional. Build functions from their source code before invoking them (e.g. compile go1.x functions), like 'sam build'. Functions are only built again when their code changes.",
+							EnvVar: "SAM_BUILD",
+						},
+						cli.StringFlag{
+							Name:   "build-dir",
+							Usage:  "Optional. Directory to keep built functions in. By default, they're kept in SAM Local's cache.",
+							EnvVar: "SAM_BUILD_DIR",
+						},
+						cli.BoolFlag{
+							Name:   "dependency-layer",
+							Usage:  "Optional. With --build, build the dependencies of functions whose builder supports it (e.g. a requirements.txt or Gemfile) into a layer of their own, mounted at /opt, and run the functions from their source code. Editing a function then doesn't rebuild its dependencies.",
+							EnvVar: "SAM_DEPENDENCY_LAYER",
+						},
+						cli.StringFlag{
+							Name:  "profile",
+							Usage: "Optional. Specify which AWS credentials profile to use.",
+						},
+						cli.BoolFlag{
+							Name:  "dry-run",
+							Usage: "Optional. Print the container configuration (image, mounts, environment) that would be used to invoke the function, without starting Docker",
+						},
+						cli.BoolFlag{
+							Name:  "estimate-cost",
+							Usage: "Optional. After each invocation, print an estimate of what it would have cost on AWS Lambda (excluding the free tier)",
+						},
+						cli.StringFlag{
+							Name:   "otel-endpoint",
+							Usage:  "Optional. Export OpenTelemetry traces of the invocation to the OTLP/HTTP endpoint of a collector, such as Jaeger's (e.g. 'http://localhost:4318')",
+							EnvVar: "OTEL_EXPORTER_OTLP_ENDPOINT",
+						},
+						cli.StringFlag{
+							Name:   "otel-service-name",
+							Value:  "sam-local",
+							Usage:  "Optional. The service name of the exported traces",
+							EnvVar: "OTEL_SERVICE_NAME",
+						},
+					},
+				},
+				cli.Command{
+					Name:   "replay",
+					Action: replay,
+					Usage: "Re-sends requests recorded with 'sam local start-api --record' to a running local API, and compares the responses with the recorded ones. " +
+						"Returns a non-zero exit code if any of the responses differ.\n",
+					ArgsUsage: "<recording-directory>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "url, u",
+							Value: "http://127.0.0.1:3000",
+							Usage: "Base URL of the local API to send the requests to",
+						},
+						cli.BoolFlag{
+							Name:  "status-only",
+							Usage: "Optional. Only compare the HTTP status codes of the responses, not the bodies",
+						},
+					},
+				},
+				cli.Command{
+					Name:   "list-endpoints",
+					Action: listEndpoints,
+					Usage:  "Lists every route that 'sam local start-api' would mount for your SAM template (method, local URL, function, authorizer and binary media types), without starting Docker.\n",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:   "template, t",
+							Value:  "template.[yaml|yml]",
+							Usage:  "AWS SAM template file",
+							EnvVar: "SAM_TEMPLATE_FILE",
+						},
+						cli.StringFlag{
+							Name:   "parameter-values",
+							Usage:  "Optional. A string that contains CloudFormation parameter overrides encoded as key-value pairs. Use the same format as the AWS CLI, e.g. 'ParameterKey=KeyPairName,ParameterValue=MyKey ParameterKey=InstanceType,ParameterValue=t1.micro'. In case of parsing errors all values are ignored",
+							EnvVar: "SAM_TEMPLATE_PARAM_ARG",
+						},
+						cli.StringSliceFlag{
+							Name:  "port, p",
+							Usage: "Local port number that start-api listens on (default: 3000). Can be repeated along with --host",
+						},
+						cli.StringSliceFlag{
+							Name:  "host",
+							Usage: "Local hostname or IP address that start-api binds to (default: 127.0.0.1). Can be repeated along with --port",
+						},
+						cli.StringSliceFlag{
+							Name:  "api-listener",
+							Usage: "Optional. Binds an AWS::Serverless::Api resource to one of the listeners, e.g. 'MyApi=127.0.0.1:3001'. Can be repeated",
+						},
+						cli.BoolFlag{
+							Name:   "prefix-routing",
+							Usage:  "Optional. Specify whether SAM routing is based on prefix or exact matching",
+							EnvVar: "SAM_PREFIX_ROUTING",
+						},
+						cli.StringFlag{
+							Name:  "format, f",
+							Value: "table",
+							Usage: "Output format, either table or json",
+						},
+					},
+				},
+				cli.Command{
+					Name:   "export-openapi",
+					Action: exportOpenAPI,
+					Usage:  "Generates an OpenAPI 3 document describing the routes that 'sam local start-api' would mount for your SAM template (paths, methods, path parameters, binary media types and CORS), for frontend and contract testing tools.\n",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:   "template, t",
+							Value:  "template.[yaml|yml]",
+							Usage:  "AWS SAM template file",
+							EnvVar: "SAM_TEMPLATE_FILE",
+						},
+						cli.StringFlag{
+							Name:   "parameter-values",
+							Usage:  "Optional. A string that contains CloudFormation parameter overrides encoded as key-value pairs. Use the same format as the AWS CLI, e.g. 'ParameterKey=KeyPairName,ParameterValue=MyKey ParameterKey=InstanceType,ParameterValue=t1.micro'. In case of parsing errors all values are ignored",
+							EnvVar: "SAM_TEMPLATE_PARAM_ARG",
+						},
+						cli.StringSliceFlag{
+							Name:  "port, p",
+							Usage: "Local port number that start-api listens on (default: 3000). Can be repeated along with --host",
+						},
+						cli.StringSliceFlag{
+							Name:  "host",
+							Usage: "Local hostname or IP address that start-api binds to (default: 127.0.0.1). Can be repeated along with --port",
+						},
+						cli.StringSliceFlag{
+							Name:  "api-listener",
+							Usage: "Optional. Binds an AWS::Serverless::Api resource to one of the listeners, e.g. 'MyApi=127.0.0.1:3001'. Can be repeated",
+						},
+						cli.BoolFlag{
+							Name:   "prefix-routing",
+							Usage:  "Optional. Specify whether SAM routing is based on prefix or exact matching",
+							EnvVar: "SAM_PREFIX_ROUTING",
+						},
+						cli.StringFlag{
+							Name:  "format, f",
+							Value: "json",
+							Usage: "Output format, either json or yaml",
+						},
+						cli.StringFlag{
+							Name:  "output, o",
+							Usage: "Optional. File to write the document to, instead of stdout",
+						},
+					},
+				},
+				cli.Command{
+					Name:   "export-requests",
+					Action: exportRequests,
+					Usage:  "Generates a Postman collection (or a shell script of curl commands) with an example request for every route that 'sam local start-api' would mount for your SAM template, to explore the local API with.\n",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:   "template, t",
+							Value:  "template.[yaml|yml]",
+							Usage:  "AWS SAM template file",
+							EnvVar: "SAM_TEMPLATE_FILE",
+						},
+						cli.StringFlag{
+							Name:   "parameter-values",
+							Usage:  "Optional. A string that contains CloudFormation parameter overrides encoded as key-value pairs. Use the same format as the AWS CLI, e.g. 'ParameterKey=KeyPairName,ParameterValue=MyKey ParameterKey=InstanceType,ParameterValue=t1.micro'. In case of parsing errors all values are ignored",
+							EnvVar: "SAM_TEMPLATE_PARAM_ARG",
+						},
+						cli.StringSliceFlag{
+							Name:  "port, p",
+							Usage: "Local port number that start-api listens on (default: 3000). Can be repeated along with --host",
+						},
+						cli.StringSliceFlag{
+							Name:  "host",
+							Usage: "Local hostname or IP address that start-api binds to (default: 127.0.0.1). Can be repeated along with --port",
+						},
+						cli.StringSliceFlag{
+							Name:  "api-listener",
+							Usage: "Optional. Binds an AWS::Serverless::Api resource to one of the listeners, e.g. 'MyApi=127.0.0.1:3001'. Can be repeated",
+						},
+						cli.BoolFlag{
+							Name:   "prefix-routing",
+							Usage:  "Optional. Specify whether SAM routing is based on prefix or exact matching",
+							EnvVar: "SAM_PREFIX_ROUTING",
+						},
+						cli.StringFlag{
+							Name:  "format, f",
+							Value: "postman",
+							Usage: "Output format, either postman or curl",
+						},
+						cli.StringFlag{
+							Name:  "output, o",
+							Usage: "Optional. File to write the requests to, instead of stdout",
+						},
+					},
+				},
+				cli.Command{
+					Name:      "bench",
+					Action:    bench,
+					Usage:     "Sends a request to the local API over and over, with a number of concurrent requests, and reports the latency percentiles, error rate and cold and warm starts. Requests go straight to the local router, as 'sam local start-api' would serve them.\n",
+					ArgsUsage: "METHOD /path",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:   "template, t",
+							Value:  "template.[yaml|yml]",
+							Usage:  "AWS SAM template file",
+							EnvVar: "SAM_TEMPLATE_FILE",
+						},
+						cli.StringFlag{
+							Name:   "parameter-values",
+							Usage:  "Optional. A string that contains CloudFormation parameter overrides encoded as key-value pairs. Use the same format as the AWS CLI, e.g. 'ParameterKey=KeyPairName,ParameterValue=MyKey ParameterKey=InstanceType,ParameterValue=t1.micro'. In case of parsing errors all values are ignored",
+							EnvVar: "SAM_TEMPLATE_PARAM_ARG",
+						},
+						cli.IntFlag{
+							Name:  "concurrency, c",
+							Value: 1,
+							Usage: "Number of requests to send at the same time",
+						},
+						cli.DurationFlag{
+							Name:  "duration",
+							Value: 30 * time.Second,
+							Usage: "How long to send requests for",
+						},
+						cli.IntFlag{
+							Name:  "requests",
+							Usage: "Optional. Stop after this many requests, even if the duration isn't up",
+						},
+						cli.StringFlag{
+							Name:  "body, b",
+							Usage: "Optional. Body of the request",
+						},
+						cli.StringSliceFlag{
+							Name:  "header, H",
+							Usage: "Optional. A header to send with the request, e.g. 'Content-Type: application/json'. Can be repeated",
+						},
+						cli.StringFlag{
+							Name:  "log-file, l",
+							Usage: "Optional. Logfile to send the function logs to, which are otherwise discarded",
+						},
+						cli.StringFlag{
+							Name:  "env-vars, n",
+							Usage: "Optional. JSON file containing values for Lambda function's environment variables.",
+						},
+						cli.StringFlag{
+							Name:   "log-level",
+							Usage:  "Optional. The minimum level (debug, info, warn or error) of SAM Local's logs, optionally per subsystem (router, docker, template or tracing), e.g. 'warn,docker=debug'",
+							EnvVar: "SAM_LOG_LEVEL",
+						},
+						cli.StringFlag{
+							Name:   "runtime-backend",
+							Usage:  "Optional. The backend that runs functions: docker (the default) or native (the same as --no-docker).",
+							EnvVar: "SAM_RUNTIME_BACKEND",
+						},
+						cli.BoolFlag{
+							Name:   "no-docker",
+							Usage:  "Optional. Runs Node.js and Python functions directly on this machine, with the interpreters found in PATH, instead of in Docker containers.",
+							EnvVar: "SAM_NO_DOCKER",
+						},
+						cli.StringFlag{
+							Name: "docker-volume-basedir, v",
+							Usage: "Optional. Specifies the location basedir where the SAM file exists. If the Docker is running on a remote machine, " +
+								"you must mount the path where the SAM file exists on the docker machine and modify this value to match the remote machine.",
+							EnvVar: "SAM_DOCKER_VOLUME_BASEDIR",
+						},
+						cli.StringFlag{
+							Name:   "docker-network",
+							Usage:  "Optional. Specifies the name or id of an existing docker network to lambda docker containers should connect to.",
+							EnvVar: "SAM_DOCKER_NETWORK",
+						},
+						cli.BoolFlag{
+							Name:   "skip-pull-image",
+							Usage:  "Optional. Specify whether SAM should skip pulling down the latest Docker image. Default is false.",
+							EnvVar: "SAM_SKIP_PULL_IMAGE",
+						},
+						cli.StringFlag{
+							Name:  "profile",
+							Usage: "Optional. Specify which AWS credentials profile to use.",
+						},
+						cli.BoolFlag{
+							Name:   "prefix-routing",
+							Usage:  "Optional. Specify whether SAM routing is based on prefix or exact matching",
+							EnvVar: "SAM_PREFIX_ROUTING",
+						},
+					},
+				},
+				cli.Command{
+					Name:   "test",
+					Action: testAPI,
+					Usage: "Sends an example request to every route of the local API (as 'sam local export-requests' generates them), and fails if any of them return a 5xx error. " +
+						"With --snapshot, each route's response is saved to a snapshot file the first time, and compared with it after that. Requests go straight to the local router, as 'sam local start-api' would serve them.\n",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:   "template, t",
+							Value:  "template.[yaml|yml]",
+							Usage:  "AWS SAM template file",
+							EnvVar: "SAM_TEMPLATE_FILE",
+						},
+						cli.StringFlag{
+							Name:   "parameter-values",
+							Usage:  "Optional. A string that contains CloudFormation parameter overrides encoded as key-value pairs. Use the same format as the AWS CLI, e.g. 'ParameterKey=KeyPairName,ParameterValue=MyKey ParameterKey=InstanceType,ParameterValue=t1.micro'. In case of parsing errors all values are ignored",
+							EnvVar: "SAM_TEMPLATE_PARAM_ARG",
+						},
+						cli.BoolFlag{
+							Name:  "snapshot",
+							Usage: "Optional. Compare each route's response (status, headers and body, with UUIDs and timestamps normalized) with its snapshot file, which is saved the first time",
+						},
+						cli.BoolFlag{
+							Name:  "update-snapshots",
+							Usage: "Optional. With --snapshot, save the responses to the snapshot files, instead of comparing them",
+						},
+						cli.StringFlag{
+							Name:  "snapshot-dir",
+							Usage: "Optional. Directory of the snapshot files (default: 'snapshots' next to the template)",
+						},
+						cli.StringFlag{
+							Name:   "validate-responses",
+							Usage:  "Optional. Check responses against the response schemas of the APIs' OpenAPI definitions: warn logs mismatches, and fail also replaces the response with a 502 error",
+							EnvVar: "SAM_VALIDATE_RESPONSES",
+						},
+						cli.StringFlag{
+							Name:  "log-file, l",
+							Usage: "Optional. Logfile to send the function logs to, which are otherwise discarded",
+						},
+						cli.StringFlag{
+							Name:  "env-vars, n",
+							Usage: "Optional. JSON file containing values for Lambda function's environment variables.",
+						},
+						cli.StringFlag{
+							Name:   "log-level",
+							Usage:  "Optional. The minimum level (debug, info, warn or error) of SAM Local's logs, optionally per subsystem (router, docker, template or tracing), e.g. 'warn,docker=debug'",
+							EnvVar: "SAM_LOG_LEVEL",
+						},
+						cli.StringFlag{
+							Name:   "runtime-backend",
+							Usage:  "Optional. The backend that runs functions: docker (the default) or native (the same as --no-docker).",
+							EnvVar: "SAM_RUNTIME_BACKEND",
+						},
+						cli.BoolFlag{
+							Name:   "no-docker",
+							Usage:  "Optional. Runs Node.js and Python functions directly on this machine, with the interpreters found in PATH, instead of in Docker containers.",
+							EnvVar: "SAM_NO_DOCKER",
+						},
+						cli.StringFlag{
+							Name: "docker-volume-basedir, v",
+							Usage: "Optional. Specifies the location basedir where the SAM file exists. If the Docker is running on a remote machine, " +
+								"you must mount the path where the SAM file exists on the docker machine and modify this value to match the remote machine.",
+							EnvVar: "SAM_DOCKER_VOLUME_BASEDIR",
+						},
+						cli.StringFlag{
+							Name:   "docker-network",
+							Usage:  "Optional. Specifies the name or id of an existing docker network to lambda docker containers should connect to.",
+							EnvVar: "SAM_DOCKER_NETWORK",
+						},
 						cli.BoolFlag{
 							Name:   "skip-pull-image",
 							Usage:  "Optional. Specify whether SAM should skip pulling down the latest Docker image. Default is false.",
@@ -184,6 +755,59 @@ func main() {
 							Name:  "profile",
 							Usage: "Optional. Specify which AWS credentials profile to use.",
 						},
+						cli.BoolFlag{
+							Name:   "prefix-routing",
+							Usage:  "Optional. Specify whether SAM routing is based on prefix or exact matching",
+							EnvVar: "SAM_PREFIX_ROUTING",
+						},
+					},
+				},
+				cli.Command{
+					Name:   "generate-debug-config",
+					Action: generateDebugConfig,
+					Usage:  "Generates editor configurations (VS Code launch.json or IntelliJ run configurations) that attach a debugger to each function in your SAM template, with the right debug port, debugger type and path mappings into the container.\n",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:   "template, t",
+							Value:  "template.[yaml|yml]",
+							Usage:  "AWS SAM template file",
+							EnvVar: "SAM_TEMPLATE_FILE",
+						},
+						cli.StringFlag{
+							Name:   "parameter-values",
+							Usage:  "Optional. A string that contains CloudFormation parameter overrides encoded as key-value pairs. Use the same format as the AWS CLI, e.g. 'ParameterKey=KeyPairName,ParameterValue=MyKey ParameterKey=InstanceType,ParameterValue=t1.micro'. In case of parsing errors all values are ignored",
+							EnvVar: "SAM_TEMPLATE_PARAM_ARG",
+						},
+						cli.StringFlag{
+							Name:   "debug-port, d",
+							Value:  "5858",
+							Usage:  "The port passed to --debug-port when invoking functions",
+							EnvVar: "SAM_DEBUG_PORT",
+						},
+						cli.StringFlag{
+							Name:  "editor, e",
+							Value: "vscode",
+							Usage: "The editor to generate configurations for, either vscode or intellij",
+						},
+						cli.StringFlag{
+							Name:  "output-dir, o",
+							Usage: "Optional. The project directory to write the configurations to (.vscode/launch.json or .idea/runConfigurations). By default, they are printed to stdout",
+						},
+					},
+				},
+				cli.Command{
+					Name:   "cleanup",
+					Action: cleanup,
+					Usage:  "Removes everything SAM Local created on this machine: stopped and running function and builder containers, decompressed ZIP/JAR archives, recorded requests and cached files.\n",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "dry-run",
+							Usage: "Optional. Only list what would be removed",
+						},
+						cli.BoolFlag{
+							Name:  "images",
+							Usage: "Optional. Also remove the Lambda runtime and build Docker images that were pulled",
+						},
 					},
 				},
 				cli.Command{
@@ -293,7 +917,7 @@ func main() {
 								cli.StringFlag{
 									Name:  "body, b",
 									Usage: "HTTP body",
-									Value: `{ \"test\": \"body\"}`,
+									Value: apiEventBody,
 								},
 								cli.StringFlag{
 									Name:  "resource, r",
@@ -331,7 +955,7 @@ func main() {
 
 		cli.Command{
 			Name:   "validate",
-			Usage:  "Validates an AWS SAM template. If valid, will print a summary of the resources found within the SAM template. If the template is invalid, returns a non-zero exit code.",
+			Usage:  "Validates an AWS SAM template. If valid, will print a summary of the resources found within the SAM template. If the template is invalid, prints each problem with its line and column, and returns a non-zero exit code.",
 			Action: validate,
 			Flags: []cli.Flag{
 				cli.StringFlag{
@@ -340,9 +964,154 @@ func main() {
 					Usage:  "AWS SAM template file",
 					EnvVar: "SAM_TEMPLATE_FILE",
 				},
+				cli.StringFlag{
+					Name:   "parameter-values",
+					Usage:  "Optional. A string that contains CloudFormation parameter overrides encoded as key-value pairs. Use the same format as the AWS CLI, e.g. 'ParameterKey=KeyPairName,ParameterValue=MyKey ParameterKey=InstanceType,ParameterValue=t1.micro'. In case of parsing errors all values are ignored",
+					EnvVar: "SAM_TEMPLATE_PARAM_ARG",
+				},
+			},
+		},
+
+		cli.Command{
+			Name:      "build",
+			Usage:     "Builds functions from their source code, such as by compiling go1.x functions, into SAM Local's cache. Functions are only built again when their code or build configuration changes.",
+			ArgsUsage: "[function-identifier...]",
+			Action:    buildFunctions,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "template, t",
+					Value:  "template.[yaml|yml]",
+					Usage:  "AWS SAM template file",
+					EnvVar: "SAM_TEMPLATE_FILE",
+				},
+				cli.StringFlag{
+					Name:   "parameter-values",
+					Usage:  "Optional. A string that contains CloudFormation parameter overrides encoded as key-value pairs. Use the same format as the AWS CLI, e.g. 'ParameterKey=KeyPairName,ParameterValue=MyKey ParameterKey=InstanceType,ParameterValue=t1.micro'. In case of parsing errors all values are ignored",
+					EnvVar: "SAM_TEMPLATE_PARAM_ARG",
+				},
+				cli.StringFlag{
+					Name:   "build-dir",
+					Usage:  "Optional. Directory to keep built functions in. By default, they're kept in SAM Local's cache.",
+					EnvVar: "SAM_BUILD_DIR",
+				},
+				cli.BoolFlag{
+					Name:   "dependency-layer",
+					Usage:  "Optional. Build the dependencies of functions whose builder supports it (e.g. a requirements.txt or Gemfile) into a layer of their own, rather than next to their code.",
+					EnvVar: "SAM_DEPENDENCY_LAYER",
+				},
+			},
+		},
+
+		cli.Command{
+			Name:   "sync",
+			Usage:  "Uploads the code of your functions straight to an already deployed stack with UpdateFunctionCode, skipping 'sam package' and 'sam deploy'. Only code changes are synced: changes to the template still need a full deploy.",
+			Action: syncCode,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "template, t",
+					Value:  "template.[yaml|yml]",
+					Usage:  "AWS SAM template file",
+					EnvVar: "SAM_TEMPLATE_FILE",
+				},
+				cli.StringFlag{
+					Name:   "parameter-values",
+					Usage:  "Optional. A string that contains CloudFormation parameter overrides encoded as key-value pairs. Use the same format as the AWS CLI, e.g. 'ParameterKey=KeyPairName,ParameterValue=MyKey ParameterKey=InstanceType,ParameterValue=t1.micro'. In case of parsing errors all values are ignored",
+					EnvVar: "SAM_TEMPLATE_PARAM_ARG",
+				},
+				cli.StringFlag{
+					Name:  "stack-name",
+					Usage: "Name of the deployed CloudFormation stack",
+				},
+				cli.StringSliceFlag{
+					Name:  "function, f",
+					Usage: "Optional. Logical ID of a function to sync. Can be repeated. By default, every function with local code is synced",
+				},
+				cli.BoolFlag{
+					Name:  "watch, w",
+					Usage: "Optional. Keep running, and sync functions again whenever their code changes",
+				},
+				cli.StringFlag{
+					Name:  "interval",
+					Value: "1s",
+					Usage: "Optional. How often to check for changes with --watch",
+				},
+				cli.StringFlag{
+					Name:  "profile",
+					Usage: "Optional. Specify which AWS credentials profile to use.",
+				},
+				cli.StringFlag{
+					Name:  "region",
+					Usage: "Optional. The AWS region the stack is deployed to.",
+				},
 			},
 		},
 
+		cli.Command{
+			Name:   "publish",
+			Usage:  "Publishes a packaged AWS SAM application to the AWS Serverless Application Repository, using the AWS::ServerlessRepo::Application metadata of the template. Creates the application the first time, and a new version after that.",
+			Action: publish,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "template, t",
+					Value:  "template.[yaml|yml]",
+					Usage:  "Packaged AWS SAM template file",
+					EnvVar: "SAM_TEMPLATE_FILE",
+				},
+				cli.StringFlag{
+					Name:  "semantic-version",
+					Usage: "Optional. The version to publish, overriding the SemanticVersion in the template metadata",
+				},
+				cli.StringFlag{
+					Name:  "profile",
+					Usage: "Optional. Specify which AWS credentials profile to use.",
+				},
+				cli.StringFlag{
+					Name:  "region",
+					Usage: "Optional. The AWS region to publish the application to.",
+				},
+			},
+		},
+
+		cli.Command{
+			Name:   "delete",
+			Usage:  "Deletes a deployed stack, and then the artifacts in S3 and images in ECR that 'sam package' created for it. Asks for confirmation first, unless --no-prompts is set.",
+			Action: deleteStack,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "stack-name",
+					Usage: "The name of the stack to delete",
+				},
+				cli.StringFlag{
+					Name:  "s3-bucket",
+					Usage: "Optional. The deployment bucket 'sam package' uploaded artifacts to. Everything in it (or under --s3-prefix) is deleted too, not just the artifacts the stack refers to",
+				},
+				cli.StringFlag{
+					Name:  "s3-prefix",
+					Usage: "Optional. Only delete everything under this prefix of --s3-bucket",
+				},
+				cli.BoolFlag{
+					Name:  "no-prompts",
+					Usage: "Optional. Don't ask for confirmation, e.g. in CI",
+				},
+				cli.StringFlag{
+					Name:  "profile",
+					Usage: "Optional. Specify which AWS credentials profile to use.",
+				},
+				cli.StringFlag{
+					Name:  "region",
+					Usage: "Optional. The AWS region the stack is deployed to.",
+				},
+			},
+		},
+
+		cli.Command{
+			Name:         "completion",
+			Usage:        "Prints a completion script for your shell (bash, zsh or fish), covering commands, flags and the functions in your SAM template, e.g. 'source <(sam completion bash)'.",
+			ArgsUsage:    "bash|zsh|fish",
+			Action:       completion,
+			BashComplete: completeShells,
+		},
+
 		cli.Command{
 			// This is just here for consistent usage and --help
 			Name:   "package",
@@ -358,6 +1127,8 @@ func main() {
 		},
 	}
 
+	addFlagCompletion(app.Commands)
+
 	// For 'package' and 'deploy' CLI options, we want to intercept
 	// and just pass all arguments through to the AWS CLI commands.
 	if len(os.Args) > 1 && os.Args[1] == "package" {
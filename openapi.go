@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/codegangsta/cli"
+	yamlwrapper "github.com/sanathkr/yaml"
+)
+
+// pathParameter matches the parameters in a route's path, e.g. {id} or {proxy+}
+var pathParameter = regexp.MustCompile(`{([^}+]+)\+?}`)
+
+// nonAlphanumeric splits a path into the words of an operation ID
+var nonAlphanumeric = regexp.MustCompile("[^a-zA-Z0-9]+")
+
+// corsConfig is the Cors setting of an AWS::Serverless::Api
+type corsConfig struct {
+	AllowOrigin  string
+	AllowMethods string
+	AllowHeaders string
+	MaxAge       string
+}
+
+func exportOpenAPI(c *cli.Context) {
+
+	filename := getTemplateFilename(c.String("template"))
+	template := openTemplate(c, filename, nil)
+
+	format := c.String("format")
+	if format != "json" && format != "yaml" {
+		fmt.Fprintf(os.Stderr, "ERROR: Invalid --format '%s' (must be one of json or yaml)\n", format)
+		os.Exit(1)
+	}
+
+	listeners, err := parseListeners(c.StringSlice("host"), c.StringSlice("port"))
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	apiListeners, err := parseAPIBindings(c.StringSlice("api-listener"), listeners)
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	endpoints, err := getEndpoints(template, listeners, apiListeners, c.Bool("prefix-routing"))
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	document := getOpenAPIDocument(template, endpoints, getAPITitle(template, filename))
+
+	data, err := json.MarshalIndent(document, "", "    ")
+	if err == nil && format == "yaml" {
+		data, err = yamlwrapper.JSONToYAML(data)
+	}
+	if err != nil {
+		errMsg.Printf("Failed to write the OpenAPI document: %s\n\n", err)
+		os.Exit(1)
+	}
+
+	if output := c.String("output"); output != "" {
+		if err := ioutil.WriteFile(output, data, 0644); err != nil {
+			errMsg.Printf("Failed to write the OpenAPI document: %s\n\n", err)
+			os.Exit(1)
+		}
+		successMsg.Fprintf(os.Stderr, "Wrote an OpenAPI document with %d routes to %s\n", len(endpoints), output)
+		return
+	}
+
+	fmt.Fprintf(os.Stdout, "%s\n", data)
+
+}
+
+// getAPITitle names the local API after the template's description, or else the
+// project's directory
+func getAPITitle(template *cloudformation.Template, filename string) string {
+	if template.Description != "" {
+		return template.Description
+	}
+	if abs, err := filepath.Abs(filepath.Dir(filename)); err == nil {
+		return filepath.Base(abs)
+	}
+	return "AWS SAM Local"
+}
+
+// getOpenAPIDocument describes the endpoints that 'sam local start-api' serves as an OpenAPI 3
+// document. Each listener is a server, and each route an operation with its path parameters,
+// binary media types and, for Apis with Cors set, the CORS preflight and response headers.
+// Routes keep API Gateway's syntax for greedy path parameters (e.g. /{proxy+}), as API
+// Gateway's own OpenAPI exports do.
+func getOpenAPIDocument(template *cloudformation.Template, endpoints []*endpoint, title string) map[string]interface{} {
+
+	routeApis := getRouteApis(template.GetAllAWSServerlessApiResources())
+
+	servers := []string{}
+	pathServers := map[string][]string{}
+	paths := map[string]map[string]interface{}{}
+	cors := map[string]*corsConfig{}
+
+	for _, e := range endpoints {
+
+		server := strings.TrimSuffix(e.URL, e.Path)
+		if !containsString(servers, server) {
+			servers = append(servers, server)
+		}
+		if !containsString(pathServers[e.Path], server) {
+			pathServers[e.Path] = append(pathServers[e.Path], server)
+		}
+
+		if paths[e.Path] == nil {
+			paths[e.Path] = map[string]interface{}{}
+		}
+
+		api := routeApis[e.Method+" "+e.Path]
+		if config := getCorsConfig(template, api); config != nil {
+			cors[e.Path] = config
+		}
+
+		methods := []string{e.Method}
+		if e.Method == "ANY" {
+			methods = router.AnyMethods()
+		}
+
+		for _, method := range methods {
+			paths[e.Path][strings.ToLower(method)] = getOpenAPIOperation(e, method)
+		}
+
+	}
+
+	for path, item := range paths {
+
+		if config := cors[path]; config != nil {
+			for _, operation := range item {
+				addCorsHeaders(operation.(map[string]interface{}), config)
+			}
+			if _, found := item["options"]; !found {
+				item["options"] = getCorsPreflight(path, config)
+			}
+		}
+
+		// Only say which servers a route is on when they don't all serve it
+		if len(servers) > 1 && len(pathServers[path]) < len(servers) {
+			item["servers"] = openAPIServers(pathServers[path])
+		}
+
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.1",
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": "1.0",
+		},
+		"servers": openAPIServers(servers),
+		"paths":   paths,
+	}
+
+}
+
+// getOpenAPIOperation describes the operation for one method of an endpoint
+func getOpenAPIOperation(e *endpoint, method string) map[string]interface{} {
+
+	operation := map[string]interface{}{
+		"operationId": openAPIOperationID(method, e.Path),
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "The response from the function",
+			},
+		},
+	}
+
+	if e.Function != "" {
+		operation["summary"] = fmt.Sprintf("Invokes %s", e.Function)
+		operation["tags"] = []string{e.Function}
+	}
+
+	if parameters := openAPIPathParameters(e.Path); len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	// Bodies and responses of the Api's binary media types are passed through as they are
+	if len(e.BinaryMediaTypes) > 0 {
+		content := map[string]interface{}{}
+		for _, mediaType := range e.BinaryMediaTypes {
+			content[mediaType] = map[string]interface{}{
+				"schema": map[string]interface{}{"type": "string", "format": "binary"},
+			}
+		}
+		if method != "GET" && method != "HEAD" && method != "DELETE" && method != "OPTIONS" {
+			operation["requestBody"] = map[string]interface{}{"content": content}
+		}
+		getMap(getMap(operation["responses"])["200"])["content"] = content
+	}
+
+	return operation
+
+}
+
+// openAPIPathParameters describes the parameters in a route's path
+func openAPIPathParameters(path string) []interface{} {
+
+	parameters := []interface{}{}
+	for _, match := range pathParameter.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+
+	return parameters
+
+}
+
+// openAPIOperationID makes an operation ID from the method and path, e.g. getUsersId
+// for GET /users/{id}
+func openAPIOperationID(method string, path string) string {
+
+	id := strings.ToLower(method)
+	for _, word := range nonAlphanumeric.Split(path, -1) {
+		if word != "" {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+
+	return id
+
+}
+
+// getCorsConfig returns the Cors setting of an Api, or nil if it doesn't have one. Cors can
+// either be the allowed origin, or a map of settings. Like in API Gateway, the values are
+// quoted strings (e.g. "'*'"). GoFormation doesn't support Cors yet, so it's read from the
+// raw template.
+func getCorsConfig(template *cloudformation.Template, api string) *corsConfig {
+
+	if api == "" {
+		return nil
+	}
+
+	value, found := getResourceProperty(template, api, "Cors")
+	if !found {
+		return nil
+	}
+
+	unquote := func(value interface{}) string {
+		return strings.Trim(fmt.Sprintf("%v", value), "'")
+	}
+
+	if origin, ok := value.(string); ok {
+		return &corsConfig{AllowOrigin: unquote(origin)}
+	}
+
+	settings := getMap(value)
+	config := &corsConfig{}
+	if origin, found := settings["AllowOrigin"]; found {
+		config.AllowOrigin = unquote(origin)
+	}
+	if methods, found := settings["AllowMethods"]; found {
+		config.AllowMethods = unquote(methods)
+	}
+	if headers, found := settings["AllowHeaders"]; found {
+		config.AllowHeaders = unquote(headers)
+	}
+	if age, found := settings["MaxAge"]; found {
+		config.MaxAge = unquote(age)
+	}
+
+	if config.AllowOrigin == "" {
+		return nil
+	}
+
+	return config
+
+}
+
+// addCorsHeaders adds the Access-Control-Allow-Origin header to an operation's response
+func addCorsHeaders(operation map[string]interface{}, config *corsConfig) {
+	response := getMap(getMap(operation["responses"])["200"])
+	if response["headers"] == nil {
+		response["headers"] = map[string]interface{}{}
+	}
+	getMap(response["headers"])["Access-Control-Allow-Origin"] = openAPIHeader(config.AllowOrigin)
+}
+
+// getCorsPreflight describes the OPTIONS operation API Gateway adds to Apis with Cors set
+func getCorsPreflight(path string, config *corsConfig) map[string]interface{} {
+
+	headers := map[string]interface{}{
+		"Access-Control-Allow-Origin": openAPIHeader(config.AllowOrigin),
+	}
+	if config.AllowMethods != "" {
+		headers["Access-Control-Allow-Methods"] = openAPIHeader(config.AllowMethods)
+	}
+	if config.AllowHeaders != "" {
+		headers["Access-Control-Allow-Headers"] = openAPIHeader(config.AllowHeaders)
+	}
+	if config.MaxAge != "" {
+		headers["Access-Control-Max-Age"] = openAPIHeader(config.MaxAge)
+	}
+
+	preflight := map[string]interface{}{
+		"operationId": openAPIOperationID("options", path),
+		"summary":     "CORS preflight",
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "The allowed origin, methods and headers",
+				"headers":     headers,
+			},
+		},
+	}
+
+	if parameters := openAPIPathParameters(path); len(parameters) > 0 {
+		preflight["parameters"] = parameters
+	}
+
+	return preflight
+
+}
+
+func openAPIHeader(example string) map[string]interface{} {
+	return map[string]interface{}{
+		"schema":  map[string]interface{}{"type": "string"},
+		"example": example,
+	}
+}
+
+func openAPIServers(urls []string) []interface{} {
+	sort.Strings(urls)
+	servers := []interface{}{}
+	for _, url := range urls {
+		servers = append(servers, map[string]interface{}{"url": url})
+	}
+	return servers
+}
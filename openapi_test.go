@@ -0,0 +1,128 @@
+package main
+
+import (
+	"github.com/awslabs/goformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exporting OpenAPI", func() {
+
+	template, _ := goformation.ParseJSON([]byte(`{
+		"Resources": {
+			"Users": {
+				"Type": "AWS::Serverless::Function",
+				"Properties": {
+					"Runtime": "nodejs6.10",
+					"Handler": "users.handler",
+					"Events": {
+						"GetUser": {
+							"Type": "Api",
+							"Properties": { "Path": "/users/{id}", "Method": "get" }
+						},
+						"Proxy": {
+							"Type": "Api",
+							"Properties": { "Path": "/files/{proxy+}", "Method": "any" }
+						}
+					}
+				}
+			},
+			"Images": {
+				"Type": "AWS::Serverless::Function",
+				"Properties": {
+					"Runtime": "nodejs6.10",
+					"Handler": "images.handler",
+					"Events": {
+						"Upload": {
+							"Type": "Api",
+							"Properties": { "Path": "/images", "Method": "post", "RestApiId": { "Ref": "Media" } }
+						}
+					}
+				}
+			},
+			"Media": {
+				"Type": "AWS::Serverless::Api",
+				"Properties": {
+					"StageName": "prod",
+					"Cors": { "AllowOrigin": "'https://example.com'", "AllowMethods": "'POST'", "MaxAge": "'600'" },
+					"DefinitionBody": {
+						"swagger": "2.0",
+						"x-amazon-apigateway-binary-media-types": ["image/png"],
+						"paths": {
+							"/images": {
+								"post": {
+									"x-amazon-apigateway-integration": {
+										"type": "aws_proxy",
+										"uri": "arn:aws:apigateway:us-east-1:lambda:path/2015-03-31/functions/arn:aws:lambda:us-east-1:123456789012:function:Images/invocations"
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}`))
+
+	var document map[string]interface{}
+	var paths map[string]map[string]interface{}
+
+	BeforeEach(func() {
+		listeners, _ := parseListeners(nil, nil)
+		endpoints, err := getEndpoints(template, listeners, map[string]*listener{}, false)
+		Expect(err).To(BeNil())
+
+		document = getOpenAPIDocument(template, endpoints, "Shop")
+		paths = document["paths"].(map[string]map[string]interface{})
+	})
+
+	It("describes the servers and routes", func() {
+		Expect(document["openapi"]).To(Equal("3.0.1"))
+		Expect(getMap(document["info"])["title"]).To(Equal("Shop"))
+		Expect(document["servers"]).To(Equal([]interface{}{
+			map[string]interface{}{"url": "http://127.0.0.1:3000"},
+		}))
+
+		Expect(paths).To(HaveLen(3))
+		Expect(paths["/users/{id}"]).To(HaveLen(1))
+
+		operation := getMap(paths["/users/{id}"]["get"])
+		Expect(operation["operationId"]).To(Equal("getUsersId"))
+		Expect(operation["tags"]).To(Equal([]string{"Users"}))
+		Expect(operation["parameters"]).To(Equal([]interface{}{
+			map[string]interface{}{
+				"name":     "id",
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			},
+		}))
+	})
+
+	It("lists ANY routes once for each method, with greedy path parameters", func() {
+		Expect(paths["/files/{proxy+}"]).To(HaveLen(7))
+		Expect(paths["/files/{proxy+}"]).To(HaveKey("delete"))
+
+		parameters := getMap(paths["/files/{proxy+}"]["get"])["parameters"].([]interface{})
+		Expect(getMap(parameters[0])["name"]).To(Equal("proxy"))
+	})
+
+	It("describes binary media types and CORS", func() {
+		post := getMap(paths["/images"]["post"])
+		Expect(getMap(getMap(post["requestBody"])["content"])).To(HaveKey("image/png"))
+
+		response := getMap(getMap(post["responses"])["200"])
+		Expect(getMap(response["content"])).To(HaveKey("image/png"))
+		Expect(getMap(getMap(response["headers"])["Access-Control-Allow-Origin"])["example"]).To(Equal("https://example.com"))
+
+		preflight := getMap(getMap(getMap(paths["/images"]["options"])["responses"])["200"])
+		headers := getMap(preflight["headers"])
+		Expect(headers).To(HaveLen(3))
+		Expect(getMap(headers["Access-Control-Allow-Methods"])["example"]).To(Equal("POST"))
+		Expect(getMap(headers["Access-Control-Max-Age"])["example"]).To(Equal("600"))
+
+		Expect(paths["/users/{id}"]).NotTo(HaveKey("options"))
+	})
+
+})
@@ -9,8 +9,16 @@ import (
 
 func pkg() {
 
+	// Container image functions are built and pushed to ECR first, as
+	// 'aws cloudformation package' only uploads local code to S3
+	packageArgs, done, err := packageImages(os.Args[2:])
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	defer done()
+
 	args := []string{"cloudformation", "package"}
-	for _, arg := range os.Args[2:] {
+	for _, arg := range packageArgs {
 		args = append(args, arg)
 	}
 
@@ -34,6 +42,7 @@ func pkg() {
 	go io.Copy(os.Stdout, stdout)
 
 	if err := cmd.Wait(); err != nil {
+		done()
 		os.Exit(1)
 	}
 }
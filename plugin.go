@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/goformation"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// pluginTimeout is how long a plugin has to answer a hook
+const pluginTimeout = 30 * time.Second
+
+// The hooks that plugins can implement
+const (
+	// hookTemplate post-processes the template, once it has been parsed
+	hookTemplate = "template"
+
+	// hookRoutes adds API routes to the functions of the template, before they're mounted
+	hookRoutes = "routes"
+
+	// hookPreInvoke can replace the event, and add environment variables, before every invocation
+	hookPreInvoke = "pre-invoke"
+
+	// hookPostInvoke is told the result of every invocation
+	hookPostInvoke = "post-invoke"
+)
+
+// plugin is an external executable that extends SAM Local, without changes to SAM Local
+// itself. For each hook, it's run with the name of the hook as its only argument, reads a
+// JSON request from stdin, and writes a JSON response to stdout. Anything it writes to
+// stderr is shown in the logs. Run with "describe", it responds with its name and the
+// hooks it implements, e.g. {"Name": "secrets", "Hooks": ["pre-invoke"]}.
+type plugin struct {
+	Path  string   `json:"-"`
+	Name  string   `json:"Name"`
+	Hooks []string `json:"Hooks"`
+}
+
+// plugins are the plugins given to --plugin, in the order they're run
+type plugins []*plugin
+
+// pluginRoute is an API route of a function
+type pluginRoute struct {
+	Function string `json:"Function"`
+	Method   string `json:"Method"`
+	Path     string `json:"Path"`
+}
+
+// pluginInvocation is the request of the pre-invoke and post-invoke hooks. Only the
+// post-invoke hook is given the result of the invocation.
+type pluginInvocation struct {
+	Function string          `json:"Function"`
+	Event    json.RawMessage `json:"Event"`
+	Payload  json.RawMessage `json:"Payload,omitempty"`
+	Outcome  string          `json:"Outcome,omitempty"`
+	Duration float64         `json:"DurationMs,omitempty"`
+}
+
+// pluginOutcomes are the names of the outcomes given to the post-invoke hook
+var pluginOutcomes = map[invoker.Outcome]string{
+	invoker.OutcomeSuccess:      "Success",
+	invoker.OutcomeHandledError: "HandledError",
+	invoker.OutcomeCrash:        "Crash",
+	invoker.OutcomeTimeout:      "Timeout",
+	invoker.OutcomeCancelled:    "Cancelled",
+}
+
+// pluginPreInvokeResponse is the response of the pre-invoke hook. An empty event leaves the
+// event unchanged.
+type pluginPreInvokeResponse struct {
+	Event       json.RawMessage   `json:"Event,omitempty"`
+	Environment map[string]string `json:"Environment,omitempty"`
+}
+
+// loadPlugins asks each plugin executable which hooks it implements
+func loadPlugins(paths []string) (plugins, error) {
+
+	result := plugins{}
+	for _, path := range paths {
+		p := &plugin{Path: path}
+		if err := p.call("describe", struct{}{}, p); err != nil {
+			return nil, err
+		}
+		if p.Name == "" {
+			p.Name = path
+		}
+		for _, hook := range p.Hooks {
+			switch hook {
+			case hookTemplate, hookRoutes, hookPreInvoke, hookPostInvoke:
+			default:
+				return nil, fmt.Errorf("plugin %s implements unsupported hook '%s' (must be one of %s)", p.Name, hook, strings.Join([]string{hookTemplate, hookRoutes, hookPreInvoke, hookPostInvoke}, ", "))
+			}
+		}
+		result = append(result, p)
+	}
+
+	return result, nil
+
+}
+
+// implements returns whether the plugin implements a hook
+func (p *plugin) implements(hook string) bool {
+	for _, h := range p.Hooks {
+		if h == hook {
+			return true
+		}
+	}
+	return false
+}
+
+// call runs the plugin for a hook, and decodes its response
+func (p *plugin) call(hook string, request interface{}, response interface{}) error {
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(p.Path, hook)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	output := &bytes.Buffer{}
+	cmd.Stdout = output
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("could not run plugin %s: %s", p.Path, err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err = <-done:
+	case <-time.After(pluginTimeout):
+		cmd.Process.Kill()
+		return fmt.Errorf("plugin %s timed out in the %s hook", p.Name, hook)
+	}
+	if err != nil {
+		return fmt.Errorf("plugin %s failed in the %s hook: %s", p.Name, hook, err)
+	}
+
+	if len(bytes.TrimSpace(output.Bytes())) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(output.Bytes(), response); err != nil {
+		return fmt.Errorf("plugin %s returned an invalid response to the %s hook: %s", p.Name, hook, err)
+	}
+
+	return nil
+
+}
+
+// Template passes the template through each plugin's template hook, and returns the result
+func (ps plugins) Template(template *cloudformation.Template) (*cloudformation.Template, error) {
+
+	for _, p := range ps {
+		if !p.implements(hookTemplate) {
+			continue
+		}
+
+		response := map[string]interface{}{}
+		if err := p.call(hookTemplate, template, &response); err != nil {
+			return nil, err
+		}
+		if len(response) == 0 {
+			continue
+		}
+
+		data, err := json.Marshal(response)
+		if err != nil {
+			return nil, err
+		}
+		if template, err = goformation.ParseJSON(data); err != nil {
+			return nil, fmt.Errorf("plugin %s returned an invalid template: %s", p.Name, err)
+		}
+	}
+
+	return template, nil
+
+}
+
+// Routes gives each plugin's routes hook the API routes of the functions, and adds the
+// routes it returns to the functions as Api events
+func (ps plugins) Routes(functions map[string]cloudformation.AWSServerlessFunction) error {
+
+	for _, p := range ps {
+		if !p.implements(hookRoutes) {
+			continue
+		}
+
+		response := struct {
+			Routes []*pluginRoute `json:"Routes"`
+		}{}
+		if err := p.call(hookRoutes, map[string]interface{}{"Routes": functionRoutes(functions)}, &response); err != nil {
+			return err
+		}
+
+		for i, route := range response.Routes {
+			function, found := functions[route.Function]
+			if !found {
+				return fmt.Errorf("plugin %s added a route to %s, which isn't a function of the template", p.Name, route.Function)
+			}
+
+			events := map[string]cloudformation.AWSServerlessFunction_EventSource{}
+			for name, event := range function.Events {
+				events[name] = event
+			}
+			events[fmt.Sprintf("%sRoute%d", p.Name, i)] = cloudformation.AWSServerlessFunction_EventSource{
+				Type: "Api",
+				Properties: &cloudformation.AWSServerlessFunction_Properties{
+					ApiEvent: &cloudformation.AWSServerlessFunction_ApiEvent{Method: route.Method, Path: route.Path},
+				},
+			}
+			function.Events = events
+			functions[route.Function] = function
+		}
+	}
+
+	return nil
+
+}
+
+// functionRoutes returns the API routes of the functions, sorted by function and path
+func functionRoutes(functions map[string]cloudformation.AWSServerlessFunction) []*pluginRoute {
+
+	routes := []*pluginRoute{}
+	for name, function := range functions {
+		for _, event := range function.Events {
+			if event.Type != "Api" || event.Properties == nil || event.Properties.ApiEvent == nil {
+				continue
+			}
+			routes = append(routes, &pluginRoute{Function: name, Method: event.Properties.ApiEvent.Method, Path: event.Properties.ApiEvent.Path})
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Function != routes[j].Function {
+			return routes[i].Function < routes[j].Function
+		}
+		return routes[i].Path < routes[j].Path
+	})
+
+	return routes
+
+}
+
+// PreInvoke passes an event through each plugin's pre-invoke hook, and returns the event
+// and the environment variables that the plugins add
+func (ps plugins) PreInvoke(function string, event string) (string, map[string]string, error) {
+
+	environment := map[string]string{}
+	for _, p := range ps {
+		if !p.implements(hookPreInvoke) {
+			continue
+		}
+
+		response := &pluginPreInvokeResponse{}
+		if err := p.call(hookPreInvoke, &pluginInvocation{Function: function, Event: rawEvent(event)}, response); err != nil {
+			return "", nil, err
+		}
+		if len(response.Event) > 0 {
+			event = string(response.Event)
+		}
+		for name, value := range response.Environment {
+			environment[name] = value
+		}
+	}
+
+	return event, environment, nil
+
+}
+
+// PostInvoke tells each plugin's post-invoke hook the result of an invocation. Plugins that
+// fail are only logged, as the invocation is over.
+func (ps plugins) PostInvoke(function string, event string, payload []byte, outcome invoker.Outcome, duration time.Duration) {
+
+	invocation := &pluginInvocation{
+		Function: function,
+		Event:    rawEvent(event),
+		Payload:  rawEvent(string(bytes.TrimSpace(payload))),
+		Outcome:  pluginOutcomes[outcome],
+		Duration: float64(duration) / float64(time.Millisecond),
+	}
+
+	for _, p := range ps {
+		if !p.implements(hookPostInvoke) {
+			continue
+		}
+		if err := p.call(hookPostInvoke, invocation, &struct{}{}); err != nil {
+			warnMsg.Fprintf(os.Stderr, "%s\n", err)
+		}
+	}
+
+}
+
+// rawEvent returns an event as raw JSON, or as a JSON string if it isn't valid JSON
+func rawEvent(event string) json.RawMessage {
+	if json.Valid([]byte(event)) {
+		return json.RawMessage(event)
+	}
+	data, _ := json.Marshal(event)
+	return data
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/goformation"
+	"github.com/awslabs/goformation/cloudformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plugins", func() {
+
+	var dir string
+
+	// writePlugin writes a shell script plugin, which runs the given commands for each hook
+	writePlugin := func(name string, hooks map[string]string) string {
+		script := "#!/bin/sh\ncase \"$1\" in\n"
+		for hook, command := range hooks {
+			script += hook + ") " + command + " ;;\n"
+		}
+		script += "esac\n"
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(script), 0755)
+		return path
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "plugins")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("loads the hooks that plugins implement", func() {
+		path := writePlugin("secrets", map[string]string{
+			"describe": `echo '{"Name": "secrets", "Hooks": ["pre-invoke"]}'`,
+		})
+		hooks, err := loadPlugins([]string{path})
+		Expect(err).To(BeNil())
+		Expect(hooks).To(HaveLen(1))
+		Expect(hooks[0].Name).To(Equal("secrets"))
+		Expect(hooks[0].implements(hookPreInvoke)).To(BeTrue())
+		Expect(hooks[0].implements(hookTemplate)).To(BeFalse())
+	})
+
+	It("rejects unsupported hooks", func() {
+		path := writePlugin("unknown", map[string]string{
+			"describe": `echo '{"Hooks": ["pre-deploy"]}'`,
+		})
+		_, err := loadPlugins([]string{path})
+		Expect(err).To(MatchError(ContainSubstring("unsupported hook 'pre-deploy'")))
+	})
+
+	It("fails when a plugin fails or returns invalid JSON", func() {
+		failing := writePlugin("failing", map[string]string{"describe": "exit 1"})
+		_, err := loadPlugins([]string{failing})
+		Expect(err).ToNot(BeNil())
+
+		invalid := writePlugin("invalid", map[string]string{"describe": "echo nope"})
+		_, err = loadPlugins([]string{invalid})
+		Expect(err).To(MatchError(ContainSubstring("invalid response")))
+	})
+
+	It("post-processes the template", func() {
+		path := writePlugin("stub", map[string]string{
+			"describe": `echo '{"Hooks": ["template"]}'`,
+			"template": `cat > /dev/null; echo '{"Resources": {"Stub": {"Type": "AWS::Serverless::Function", "Properties": {"Runtime": "nodejs6.10", "Handler": "stub.handler"}}}}'`,
+		})
+		hooks, _ := loadPlugins([]string{path})
+
+		template, _ := goformation.ParseJSON([]byte(`{"Resources": {}}`))
+		template, err := hooks.Template(template)
+		Expect(err).To(BeNil())
+		Expect(template.GetAllAWSServerlessFunctionResources()).To(HaveKey("Stub"))
+	})
+
+	It("adds routes to functions", func() {
+		path := writePlugin("auth", map[string]string{
+			"describe": `echo '{"Name": "auth", "Hooks": ["routes"]}'`,
+			"routes":   `cat > ` + filepath.Join(dir, "routes.json") + `; echo '{"Routes": [{"Function": "Hello", "Method": "post", "Path": "/login"}]}'`,
+		})
+		hooks, _ := loadPlugins([]string{path})
+
+		functions := map[string]cloudformation.AWSServerlessFunction{
+			"Hello": {Events: map[string]cloudformation.AWSServerlessFunction_EventSource{
+				"Get": {Type: "Api", Properties: &cloudformation.AWSServerlessFunction_Properties{
+					ApiEvent: &cloudformation.AWSServerlessFunction_ApiEvent{Method: "get", Path: "/hello"},
+				}},
+			}},
+		}
+		Expect(hooks.Routes(functions)).To(BeNil())
+		Expect(functionRoutes(functions)).To(ConsistOf(
+			&pluginRoute{Function: "Hello", Method: "get", Path: "/hello"},
+			&pluginRoute{Function: "Hello", Method: "post", Path: "/login"},
+		))
+
+		request, _ := ioutil.ReadFile(filepath.Join(dir, "routes.json"))
+		Expect(request).To(MatchJSON(`{"Routes": [{"Function": "Hello", "Method": "get", "Path": "/hello"}]}`))
+	})
+
+	It("rejects routes to functions that aren't in the template", func() {
+		path := writePlugin("auth", map[string]string{
+			"describe": `echo '{"Name": "auth", "Hooks": ["routes"]}'`,
+			"routes":   `echo '{"Routes": [{"Function": "Missing", "Method": "get", "Path": "/"}]}'`,
+		})
+		hooks, _ := loadPlugins([]string{path})
+		Expect(hooks.Routes(map[string]cloudformation.AWSServerlessFunction{})).ToNot(BeNil())
+	})
+
+	It("replaces events and injects environment variables before invocations", func() {
+		path := writePlugin("secrets", map[string]string{
+			"describe":   `echo '{"Hooks": ["pre-invoke"]}'`,
+			"pre-invoke": `cat > /dev/null; echo '{"Event": {"injected": true}, "Environment": {"SECRET": "s3cret"}}'`,
+		})
+		hooks, _ := loadPlugins([]string{path})
+
+		event, environment, err := hooks.PreInvoke("Hello", `{"value": 42}`)
+		Expect(err).To(BeNil())
+		Expect(event).To(MatchJSON(`{"injected": true}`))
+		Expect(environment).To(Equal(map[string]string{"SECRET": "s3cret"}))
+	})
+
+	It("leaves the event unchanged when the pre-invoke hook doesn't return one", func() {
+		path := writePlugin("quiet", map[string]string{
+			"describe":   `echo '{"Hooks": ["pre-invoke"]}'`,
+			"pre-invoke": `cat > /dev/null`,
+		})
+		hooks, _ := loadPlugins([]string{path})
+
+		event, environment, err := hooks.PreInvoke("Hello", `{"value": 42}`)
+		Expect(err).To(BeNil())
+		Expect(event).To(Equal(`{"value": 42}`))
+		Expect(environment).To(BeEmpty())
+	})
+
+	It("tells plugins the result of invocations", func() {
+		path := writePlugin("telemetry", map[string]string{
+			"describe":    `echo '{"Hooks": ["post-invoke"]}'`,
+			"post-invoke": `cat > ` + filepath.Join(dir, "result.json"),
+		})
+		hooks, _ := loadPlugins([]string{path})
+
+		hooks.PostInvoke("Hello", `{"value": 42}`, []byte("{\"ok\": true}\n"), invoker.OutcomeHandledError, 1500*time.Microsecond)
+
+		result, _ := ioutil.ReadFile(filepath.Join(dir, "result.json"))
+		Expect(result).To(MatchJSON(`{
+			"Function": "Hello",
+			"Event": {"value": 42},
+			"Payload": {"ok": true},
+			"Outcome": "HandledError",
+			"DurationMs": 1.5
+		}`))
+	})
+
+})
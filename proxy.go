@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/awslabs/aws-sam-local/tracing"
+	"github.com/fatih/color"
+	"golang.org/x/net/context"
+)
+
+// invocationSetup prepares the copy of a runtime that a request is invoked with (e.g. to run
+// the function from the artifact it was just built into), and returns a function that's
+// called once the invocation is over
+type invocationSetup func(ctx context.Context, r *invoker.Runtime) (func(), error)
+
+// invokeHTTP returns a handler that invokes a Lambda function with the API Gateway proxy
+// events of the router, and writes the function's proxy response. Each invocation goes
+// through the pre-invoke and post-invoke hooks of the plugins. The invocation is stopped
+// if the client disconnects before it finishes. Each request is invoked with its own copy
+// of the runtime, as a Runtime keeps track of a single invocation at a time, and requests
+// are served concurrently. If setup is set, it prepares each copy before it's invoked.
+func invokeHTTP(runt *invoker.Runtime, profile string, hooks plugins, setup invocationSetup) func(http.ResponseWriter, *router.Event) {
+
+	return func(w http.ResponseWriter, event *router.Event) {
+		invocation := *runt
+		r := &invocation
+
+		if setup != nil {
+			release, err := setup(event.Context(), r)
+			if err != nil {
+				log.Printf("Error invoking %s: %s\n", r.LogicalID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{ "message": "Internal server error" }`))
+				return
+			}
+			defer release()
+		}
+
+		var wg sync.WaitGroup
+		w.Header().Set("Content-Type", "application/json")
+		acceptHeader, ok := event.Headers["Accept"]
+		if !ok {
+			acceptHeader = ""
+		}
+
+		// Large bodies are streamed to the function rather than read into memory, so the
+		// plugins get events without them, and can't change them
+		var eventJSON string
+		var err error
+		if event.Streamed() {
+			var data []byte
+			data, err = json.Marshal(event)
+			eventJSON = string(data)
+		} else {
+			eventJSON, err = event.JSON()
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Error invoking %s runtime: %s", r.Function.Runtime, err)
+			log.Println(msg)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{ "message": "Internal server error" }`))
+			return
+		}
+
+		input, environment, err := hooks.PreInvoke(r.LogicalID, eventJSON)
+		if err != nil {
+			log.Printf("Error invoking %s: %s\n", r.LogicalID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{ "message": "Internal server error" }`))
+			return
+		}
+		r.Environment = environment
+
+		var stdoutTxt, stderrTxt io.Reader
+		if event.Streamed() {
+			var reader io.Reader
+			if reader, err = event.Reader(); err == nil {
+				stdoutTxt, stderrTxt, err = r.InvokeReader(event.Context(), reader, profile)
+			}
+		} else {
+			eventJSON = input
+			stdoutTxt, stderrTxt, err = r.InvokeContext(event.Context(), eventJSON, profile)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Error invoking %s runtime: %s", r.Function.Runtime, err)
+			log.Println(msg)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{ "message": "Internal server error" }`))
+			return
+		}
+
+		// Keep a copy of the function's output for the post-invoke hooks
+		payload := &bytes.Buffer{}
+		if len(hooks) > 0 {
+			stdoutTxt = io.TeeReader(stdoutTxt, payload)
+		}
+
+		// The response's span lasts until the function's response has been written
+		_, span := tracing.Start(event.Context(), "response", tracing.Internal, nil)
+
+		// parseOutput is done once wg is, but its output is only returned after that
+		wg.Add(1)
+		outputs := make(chan []byte, 1)
+		go func() {
+			outputs <- parseOutput(w, stdoutTxt, r.Function.Runtime, &wg, acceptHeader)
+			span.End()
+		}()
+
+		// Copy the container stderr (runtime logs) to the console, with each line
+		// prefixed by the function name and request ID
+		logs := newPrefixWriter(r.Logger, r.LogicalID, event.RequestContext.RequestID)
+
+		wg.Add(1)
+		go func() {
+			buf := getCopyBuffer()
+			io.CopyBuffer(logs, stderrTxt, *buf)
+			copyBuffers.Put(buf)
+			wg.Done()
+		}()
+
+		wg.Wait()
+		output := <-outputs
+
+		// Finally, copy anything the function wrote to stdout before its response
+		logs.Flush()
+		logs.Write(output)
+		logs.Flush()
+
+		report := r.Report(event.RequestContext.RequestID)
+		fmt.Fprintf(logs, "%s\n", report)
+		if r.EstimateCost {
+			fmt.Fprintf(logs, "Estimated cost: $%.9f (excluding free tier)\n", report.EstimatedCost())
+		}
+
+		if len(hooks) > 0 {
+			hooks.PostInvoke(r.LogicalID, eventJSON, payload.Bytes(), r.Outcome(bytes.TrimSpace(payload.Bytes())), report.Duration)
+		}
+
+		r.CleanUp()
+	}
+
+}
+
+// parseOutput decodes the proxy response from the output of the function and returns
+// the rest
+func parseOutput(w http.ResponseWriter, stdoutTxt io.Reader, runtime string, wg *sync.WaitGroup, acceptHeader string) (output []byte) {
+	defer wg.Done()
+
+	result, body, err := readOutput(stdoutTxt)
+	if err != nil {
+		msg := fmt.Sprintf("Error invoking %s runtime: %s", runtime, err)
+		log.Println(msg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{ "message": "Internal server error" }`))
+		return
+	}
+
+	if body != nil {
+		return streamOutput(w, result, body)
+	}
+
+	// At this point, we need to see whether the response is in the format
+	// of a Lambda proxy response (inc statusCode / body), and if so, handle it
+	// otherwise just copy the whole output back to the http.ResponseWriter
+	proxy := &struct {
+		StatusCode      jsonScalar        `json:"statusCode"`
+		Headers         map[string]string `json:"headers"`
+		Body            jsonScalar        `json:"body"`
+		IsBase64Encoded bool              `json:"isBase64Encoded"`
+	}{}
+
+	// We only want the last line of stdout, because it's possible that
+	// the function may have written directly to stdout using
+	// System.out.println or similar, before docker-lambda output the result
+	lastNewlineIx := bytes.LastIndexByte(bytes.TrimRight(result, "\n"), '\n')
+	if lastNewlineIx > 0 {
+		output = result[:lastNewlineIx]
+		result = result[lastNewlineIx:]
+	}
+
+	if err := json.Unmarshal(result, proxy); err != nil || (proxy.StatusCode == "" && len(proxy.Headers) == 0 && proxy.Body == "") {
+		// This is not a proxy integration function, as the response doesn't container headers, statusCode or body.
+		// Return HTTP 502 (Bad Gateway) to match API Gateway behaviour: http://docs.aws.amazon.com/apigateway/latest/developerguide/api-gateway-set-up-simple-proxy.html#api-gateway-simple-proxy-for-lambda-output-format
+		log.Printf(color.RedString("Function returned an invalid response (must include one of: body, headers or statusCode in the response object): %s\n"), err)
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`{ "message": "Internal server error" }`))
+		return
+	}
+
+	// Set any HTTP headers requested by the proxy function
+	if len(proxy.Headers) > 0 {
+		for key, value := range proxy.Headers {
+			w.Header().Add(key, value)
+		}
+	}
+
+	// This is a proxy function, so set the http status code and return the body
+	if statusCode, err := strconv.ParseInt(string(proxy.StatusCode), 10, 64); err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+	} else {
+		w.WriteHeader(int(statusCode))
+	}
+
+	acceptMediaTypeMatched := false
+	if acceptHeader != "" {
+		//API Gateway only honors the first Accept media type.
+		acceptMediaType := strings.Split(acceptHeader, ",")[0]
+		contentType := proxy.Headers["Content-Type"]
+		contentMediaType, _, err := mime.ParseMediaType(contentType)
+		acceptMediaTypeMatched = err == nil && acceptMediaType == contentMediaType
+	}
+
+	if proxy.IsBase64Encoded && acceptMediaTypeMatched {
+		if decodedBytes, err := base64.StdEncoding.DecodeString(string(proxy.Body)); err != nil {
+			log.Printf(color.RedString("Function returned an invalid base64 body: %s\n"), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{ "message": "Internal server error" }`))
+		} else {
+			w.Write(decodedBytes)
+		}
+	} else {
+		w.Write([]byte(proxy.Body))
+	}
+
+	return
+}
+
+// jsonScalar holds a JSON string or number as text. Functions are not strict about
+// whether statusCode and body are strings or numbers in a proxy response, and
+// json.Number refuses strings that aren't valid numbers.
+type jsonScalar string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *jsonScalar) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = jsonScalar(str)
+		return nil
+	}
+
+	if string(data) != "null" {
+		*s = jsonScalar(data)
+	}
+	return nil
+}
+
+// copyBuffers are the buffers that the output of functions is read and copied with, which are
+// reused between invocations rather than allocated for each one
+var copyBuffers = sync.Pool{New: func() interface{} {
+	buf := make([]byte, 32*1024)
+	return &buf
+}}
+
+// getCopyBuffer returns a buffer from copyBuffers, to put back once it's been copied with
+func getCopyBuffer() *[]byte {
+	return copyBuffers.Get().(*[]byte)
+}
+
+// streamingDelimiter separates the prelude of a streamed response (its status code and
+// headers, as JSON) from its body, as in Lambda's HTTP integration response streams
+var streamingDelimiter = make([]byte, 8)
+
+// readOutput reads the output of a function until it ends, or until the prelude of a streamed
+// response. For streamed responses, it returns the output up to the end of the prelude, and
+// the rest of the output, which is the body.
+func readOutput(stdout io.Reader) ([]byte, io.Reader, error) {
+
+	buf := getCopyBuffer()
+	defer copyBuffers.Put(buf)
+
+	result := []byte{}
+	chunk := *buf
+	searched := 0
+
+	for {
+		n, err := stdout.Read(chunk)
+		result = append(result, chunk[:n]...)
+
+		for {
+			i := bytes.Index(result[searched:], streamingDelimiter)
+			if i < 0 {
+				break
+			}
+			i += searched
+			// A prelude is a JSON object, which rules out NUL bytes in anything else
+			if bytes.HasSuffix(bytes.TrimSpace(result[:i]), []byte("}")) {
+				rest := result[i+len(streamingDelimiter):]
+				return result[:i], io.MultiReader(bytes.NewReader(rest), stdout), nil
+			}
+			searched = i + 1
+		}
+		if len(result) >= len(streamingDelimiter) && len(result)-len(streamingDelimiter)+1 > searched {
+			searched = len(result) - len(streamingDelimiter) + 1
+		}
+
+		if err == io.EOF {
+			return result, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+}
+
+// streamOutput responds with a streamed response, whose prelude is the last line of result,
+// copying the body to the client as the function writes it. It returns the rest of result.
+func streamOutput(w http.ResponseWriter, result []byte, body io.Reader) (output []byte) {
+
+	buf := getCopyBuffer()
+	defer copyBuffers.Put(buf)
+
+	// Whatever happens, let the function write the rest of its response
+	defer io.CopyBuffer(ioutil.Discard, body, *buf)
+
+	prelude := result
+	if i := bytes.LastIndexByte(bytes.TrimRight(result, "\n"), '\n'); i > 0 {
+		output = result[:i]
+		prelude = result[i:]
+	}
+
+	response := &struct {
+		StatusCode jsonScalar        `json:"statusCode"`
+		Headers    map[string]string `json:"headers"`
+		Cookies    []string          `json:"cookies"`
+	}{}
+
+	if err := json.Unmarshal(prelude, response); err != nil {
+		log.Printf(color.RedString("Function returned an invalid response stream prelude: %s\n"), err)
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`{ "message": "Internal server error" }`))
+		return
+	}
+
+	for key, value := range response.Headers {
+		w.Header().Set(key, value)
+	}
+	for _, cookie := range response.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+
+	status := http.StatusOK
+	if response.StatusCode != "" {
+		code, err := strconv.Atoi(string(response.StatusCode))
+		if err != nil {
+			code = http.StatusBadGateway
+		}
+		status = code
+	}
+	w.WriteHeader(status)
+
+	io.CopyBuffer(flushWriter{w}, body, *buf)
+	return
+
+}
+
+// flushWriter sends each write to the client straight away, if the ResponseWriter can
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
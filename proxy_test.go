@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/aws-sam-local/router"
+	"github.com/awslabs/goformation/cloudformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Proxy integration", func() {
+
+	Context("parse output", func() {
+		var wg sync.WaitGroup
+
+		inputs := []struct {
+			name         string
+			output       io.Reader
+			body         []byte
+			status       int
+			headers      http.Header
+			trailing     string
+			acceptHeader string
+		}{
+			{
+				name:    "only proxy response",
+				output:  strings.NewReader(`{"statusCode":202,"headers":{"foo":"bar"},"body":"{\"nextToken\":null,\"beers\":[]}","isBase64Encoded":false}`),
+				body:    []byte(`{"nextToken":null,"beers":[]}`),
+				status:  202,
+				headers: http.Header(map[string][]string{"Foo": []string{"bar"}}),
+			},
+			{
+				name: "proxy response with extra output",
+				output: strings.NewReader(`Foo
+				Bar
+				{"statusCode":200,"headers":null,"body":"{\"nextToken\":null,\"beers\":[]}","isBase64Encoded":false}`),
+				body:    []byte(`{"nextToken":null,"beers":[]}`),
+				status:  200,
+				headers: make(http.Header),
+				trailing: `Foo
+				Bar`,
+			},
+			{
+				name:    "no output",
+				output:  strings.NewReader(``),
+				body:    []byte(`{ "message": "Internal server error" }`),
+				status:  502,
+				headers: make(http.Header),
+			},
+			{
+				name:    "bad status code",
+				output:  strings.NewReader(`{"statusCode":"xxx","headers":null,"body":"{\"nextToken\":null,\"beers\":[]}","isBase64Encoded":false}`),
+				body:    []byte(`{"nextToken":null,"beers":[]}`),
+				status:  502,
+				headers: make(http.Header),
+			},
+			{
+				name:    "io error",
+				output:  &errReader{},
+				body:    []byte(`{ "message": "Internal server error" }`),
+				status:  500,
+				headers: make(http.Header),
+			},
+			{
+				name:         "base64 encoded response and Accept and Content-Type media types are matched",
+				output:       strings.NewReader(fmt.Sprintf(`{"statusCode":200,"headers":{"Content-Type": "multipart/form-data; boundary=something"},"body": "%v","isBase64Encoded":true}`, base64.StdEncoding.EncodeToString([]byte("abc")))),
+				body:         []byte("abc"),
+				status:       200,
+				headers:      http.Header(map[string][]string{"Content-Type": []string{"multipart/form-data; boundary=something"}}),
+				acceptHeader: "multipart/form-data, application/foo",
+			},
+			{
+				name:         "invalid base64 encoded response and Accept and Content-Type media types are matched",
+				output:       strings.NewReader(fmt.Sprintf(`{"statusCode":200,"headers":{"Content-Type":"multipart/form-data; boundary=something"},"body": "a","isBase64Encoded":true}`)),
+				body:         []byte(`{ "message": "Internal server error" }`),
+				status:       500,
+				headers:      http.Header(map[string][]string{"Content-Type": []string{"multipart/form-data; boundary=something"}}),
+				acceptHeader: "multipart/form-data, application/foo",
+			},
+			{
+				name:         "base64 encoded response but Accept and Content-Type media types are not matched",
+				output:       strings.NewReader(fmt.Sprintf(`{"statusCode":200,"headers":{"Content-Type": "multipart/form-data; boundary=something"},"body": "%v","isBase64Encoded":true}`, base64.StdEncoding.EncodeToString([]byte("abc")))),
+				body:         []byte(base64.StdEncoding.EncodeToString([]byte("abc"))),
+				status:       200,
+				headers:      http.Header(map[string][]string{"Content-Type": []string{"multipart/form-data; boundary=something"}}),
+				acceptHeader: "application/foo",
+			},
+			{
+				name:         "base64 encoded response and empty Accept and Content-Type headers",
+				output:       strings.NewReader(fmt.Sprintf(`{"statusCode":200,"headers":{"Content-Type": ""},"body": "%v","isBase64Encoded":true}`, base64.StdEncoding.EncodeToString([]byte("abc")))),
+				body:         []byte(base64.StdEncoding.EncodeToString([]byte("abc"))),
+				status:       200,
+				headers:      http.Header(map[string][]string{"Content-Type": []string{""}}),
+				acceptHeader: "",
+			},
+			{
+				name:     "streamed response",
+				output:   io.MultiReader(strings.NewReader("Foo\n{\"statusCode\":201,\"headers\":{\"Content-Type\":\"text/plain\"},\"cookies\":[\"a=b\"]}\x00\x00\x00\x00"), strings.NewReader("\x00\x00\x00\x00hello "), strings.NewReader("\x00world")),
+				body:     []byte("hello \x00world"),
+				status:   201,
+				headers:  http.Header(map[string][]string{"Content-Type": []string{"text/plain"}, "Set-Cookie": []string{"a=b"}}),
+				trailing: "Foo",
+			},
+			{
+				name:    "streamed response with an invalid prelude",
+				output:  strings.NewReader("{\"statusCode\":}\x00\x00\x00\x00\x00\x00\x00\x00hello"),
+				body:    []byte(`{ "message": "Internal server error" }`),
+				status:  502,
+				headers: make(http.Header),
+			},
+		}
+
+		for _, input := range inputs {
+
+			Context(input.name, func() {
+				wg.Add(1)
+				r := newResponse()
+				out := parseOutput(r, input.output, "foo", &wg, input.acceptHeader)
+
+				It("should have the expected output", func() {
+					Expect(r.status).To(Equal(input.status))
+					Expect(r.body).To(Equal(input.body))
+					Expect(r.headers).To(Equal(input.headers))
+					Expect(string(out)).To(Equal(input.trailing))
+				})
+
+			})
+
+		}
+
+	})
+
+	Context("concurrent requests", func() {
+
+		invocations := &sync.WaitGroup{}
+		invoker.RegisterRuntimeBackend("concurrent", concurrentBackend{invocations: invocations})
+
+		It("invokes each request with its own environment", func() {
+			dir, _ := ioutil.TempDir("", "plugins")
+			defer os.RemoveAll(dir)
+
+			// The plugin injects a secret of each caller, named in the path
+			plugin := filepath.Join(dir, "secrets")
+			ioutil.WriteFile(plugin, []byte(`#!/bin/sh
+case "$1" in
+describe) echo '{"Hooks": ["pre-invoke"]}' ;;
+pre-invoke) sed -n 's/.*"path":"\/\([a-z]*\)".*/{"Environment": {"SECRET": "secret of \1"}}/p' ;;
+esac
+`), 0755)
+			hooks, err := loadPlugins([]string{plugin})
+			Expect(err).To(BeNil())
+
+			runt, err := invoker.NewRuntime(invoker.NewRuntimeOpt{
+				LogicalID: "Secrets",
+				Function:  cloudformation.AWSServerlessFunction{Runtime: "nodejs8.10", Handler: "index.handler", Timeout: 3},
+				Logger:    ioutil.Discard,
+				Backend:   "concurrent",
+			})
+			Expect(err).To(BeNil())
+
+			mount := &router.Mount{Path: "/{name}", Method: "get", Handler: invokeHTTP(runt, "", hooks, nil)}
+			handler := mount.WrappedHandler()
+
+			names := []string{"alice", "bob", "carol"}
+			invocations.Add(len(names))
+
+			var lock sync.Mutex
+			var requests sync.WaitGroup
+			bodies := map[string]string{}
+			for _, name := range names {
+				requests.Add(1)
+				go func(name string) {
+					defer GinkgoRecover()
+					defer requests.Done()
+					w := httptest.NewRecorder()
+					handler.ServeHTTP(w, httptest.NewRequest("GET", "/"+name, nil))
+					lock.Lock()
+					bodies[name] = w.Body.String()
+					lock.Unlock()
+				}(name)
+			}
+			requests.Wait()
+
+			Expect(bodies).To(Equal(map[string]string{
+				"alice": "secret of alice",
+				"bob":   "secret of bob",
+				"carol": "secret of carol",
+			}))
+		})
+
+	})
+
+})
+
+// concurrentBackend is a runtime backend whose invocations wait until all the invocations it
+// expects are in progress, and then respond with their SECRET environment variable
+type concurrentBackend struct {
+	invocations *sync.WaitGroup
+}
+
+func (b concurrentBackend) Start(r *invoker.Runtime) error {
+	return nil
+}
+
+func (b concurrentBackend) Invoke(r *invoker.Runtime, event string, profile string) (io.ReadCloser, error) {
+	b.invocations.Done()
+	b.invocations.Wait()
+	return ioutil.NopCloser(strings.NewReader(`{"statusCode": 200, "body": "` + r.Environment["SECRET"] + `"}`)), nil
+}
+
+func (b concurrentBackend) Logs(r *invoker.Runtime) io.ReadCloser {
+	return ioutil.NopCloser(strings.NewReader(""))
+}
+
+func (b concurrentBackend) Wait(r *invoker.Runtime) (int, error) {
+	return 0, nil
+}
+
+func (b concurrentBackend) Stop(r *invoker.Runtime) {}
+
+type errReader struct{}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+type fakeResponse struct {
+	headers http.Header
+	body    []byte
+	status  int
+}
+
+func newResponse() *fakeResponse {
+	return &fakeResponse{
+		headers: make(http.Header),
+	}
+}
+
+func (r *fakeResponse) Header() http.Header {
+	return r.headers
+}
+
+func (r *fakeResponse) Write(body []byte) (int, error) {
+	r.body = append(r.body, body...)
+	return len(body), nil
+}
+
+func (r *fakeResponse) WriteHeader(status int) {
+	r.status = status
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/codegangsta/cli"
+)
+
+// sarApplication is the AWS::ServerlessRepo::Application metadata of a template, which
+// describes how the application is listed in the Serverless Application Repository:
+//
+//	Metadata:
+//	  AWS::ServerlessRepo::Application:
+//	    Name: my-app
+//	    Description: hello world
+//	    Author: user1
+//	    SpdxLicenseId: Apache-2.0
+//	    LicenseUrl: s3://bucket/LICENSE
+//	    ReadmeUrl: s3://bucket/README.md
+//	    Labels: ['tests']
+//	    HomePageUrl: https://github.com/user1/my-app
+//	    SemanticVersion: 0.0.1
+//	    SourceCodeUrl: https://github.com/user1/my-app
+type sarApplication struct {
+	Name            string
+	Description     string
+	Author          string
+	SpdxLicenseId   string
+	LicenseUrl      string
+	ReadmeUrl       string
+	Labels          []string
+	HomePageUrl     string
+	SemanticVersion string
+	SourceCodeUrl   string
+}
+
+func publish(c *cli.Context) {
+
+	aws := &awsCLI{Profile: c.String("profile"), Region: c.String("region")}
+
+	filename := getTemplateFilename(c.String("template"))
+	template, err := readRawTemplate(filename)
+	if err != nil {
+		log.Fatalf("Failed to parse template: %s\n", err)
+	}
+
+	app, err := getApplicationMetadata(template)
+	if err != nil {
+		errMsg.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	if version := c.String("semantic-version"); version != "" {
+		app.SemanticVersion = version
+	}
+
+	// Applications are looked up by name, as the Serverless Application
+	// Repository doesn't allow two applications with the same name in an account
+	id, err := aws.Run("serverlessrepo", "list-applications",
+		"--output", "text",
+		"--query", fmt.Sprintf("Applications[?Name=='%s'].ApplicationId | [0]", app.Name),
+	)
+	if err != nil {
+		log.Fatalf("Could not list applications: %s\n", err)
+	}
+	id = strings.TrimSpace(id)
+
+	if id == "" || id == "None" {
+
+		id, err = aws.Run(app.createArgs(filename)...)
+		if err != nil {
+			log.Fatalf("Could not create application %s: %s\n", app.Name, err)
+		}
+		id = strings.TrimSpace(id)
+		successMsg.Fprintf(os.Stderr, "Created application %s\n", app.Name)
+
+	} else {
+
+		if _, err := aws.Run(app.updateArgs(id)...); err != nil {
+			log.Fatalf("Could not update application %s: %s\n", app.Name, err)
+		}
+		successMsg.Fprintf(os.Stderr, "Updated application %s\n", app.Name)
+
+		if app.SemanticVersion != "" {
+			if _, err := aws.Run(app.versionArgs(id, filename)...); err != nil {
+				log.Fatalf("Could not create version %s of %s: %s\n", app.SemanticVersion, app.Name, err)
+			}
+			successMsg.Fprintf(os.Stderr, "Created version %s\n", app.SemanticVersion)
+		}
+
+	}
+
+	fmt.Fprintf(os.Stdout, "%s\n", id)
+
+}
+
+// getApplicationMetadata reads the AWS::ServerlessRepo::Application metadata of a packaged
+// template. Templates that still reference local code or documents must be packaged first.
+func getApplicationMetadata(template map[string]interface{}) (*sarApplication, error) {
+
+	metadata, ok := getMap(template["Metadata"])["AWS::ServerlessRepo::Application"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the template has no AWS::ServerlessRepo::Application metadata to publish the application with")
+	}
+
+	str := func(name string) string {
+		if value, ok := metadata[name]; ok && value != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return ""
+	}
+
+	app := &sarApplication{
+		Name:            str("Name"),
+		Description:     str("Description"),
+		Author:          str("Author"),
+		SpdxLicenseId:   str("SpdxLicenseId"),
+		LicenseUrl:      str("LicenseUrl"),
+		ReadmeUrl:       str("ReadmeUrl"),
+		HomePageUrl:     str("HomePageUrl"),
+		SemanticVersion: str("SemanticVersion"),
+		SourceCodeUrl:   str("SourceCodeUrl"),
+	}
+
+	if labels, ok := metadata["Labels"].([]interface{}); ok {
+		for _, label := range labels {
+			app.Labels = append(app.Labels, fmt.Sprintf("%v", label))
+		}
+	}
+
+	for _, name := range []string{"Name", "Description", "Author"} {
+		if str(name) == "" {
+			return nil, fmt.Errorf("AWS::ServerlessRepo::Application metadata is missing %s", name)
+		}
+	}
+
+	for _, name := range []string{"LicenseUrl", "ReadmeUrl"} {
+		if value := str(name); value != "" && !strings.HasPrefix(value, "s3://") {
+			return nil, fmt.Errorf("%s '%s' is a local file: run 'sam package' on the template first", name, value)
+		}
+	}
+
+	for name, resource := range getMap(template["Resources"]) {
+		resource := getMap(resource)
+		if resource["Type"] != "AWS::Serverless::Function" {
+			continue
+		}
+		if uri, ok := getMap(resource["Properties"])["CodeUri"].(string); !ok || !strings.HasPrefix(uri, "s3://") {
+			return nil, fmt.Errorf("the code of %s hasn't been uploaded to S3: run 'sam package' on the template first", name)
+		}
+	}
+
+	return app, nil
+
+}
+
+// createArgs returns the AWS CLI arguments to create the application
+func (app *sarApplication) createArgs(templateFile string) []string {
+
+	args := []string{"serverlessrepo", "create-application",
+		"--name", app.Name,
+		"--description", app.Description,
+		"--author", app.Author,
+		"--output", "text",
+		"--query", "ApplicationId",
+	}
+
+	args = appendOptionalArgs(args, map[string]string{
+		"--spdx-license-id":  app.SpdxLicenseId,
+		"--license-url":      app.LicenseUrl,
+		"--readme-url":       app.ReadmeUrl,
+		"--home-page-url":    app.HomePageUrl,
+		"--semantic-version": app.SemanticVersion,
+		"--source-code-url":  app.SourceCodeUrl,
+	})
+
+	if app.SemanticVersion != "" {
+		args = append(args, "--template-body", "file://"+templateFile)
+	}
+
+	if len(app.Labels) > 0 {
+		args = append(append(args, "--labels"), app.Labels...)
+	}
+
+	return args
+
+}
+
+// updateArgs returns the AWS CLI arguments to update the details of an existing application.
+// The name and license of an application can't be changed once it's created.
+func (app *sarApplication) updateArgs(id string) []string {
+
+	args := []string{"serverlessrepo", "update-application",
+		"--application-id", id,
+		"--description", app.Description,
+		"--author", app.Author,
+	}
+
+	args = appendOptionalArgs(args, map[string]string{
+		"--readme-url":    app.ReadmeUrl,
+		"--home-page-url": app.HomePageUrl,
+	})
+
+	if len(app.Labels) > 0 {
+		args = append(append(args, "--labels"), app.Labels...)
+	}
+
+	return args
+
+}
+
+// versionArgs returns the AWS CLI arguments to create a new version of an existing application
+func (app *sarApplication) versionArgs(id string, templateFile string) []string {
+
+	args := []string{"serverlessrepo", "create-application-version",
+		"--application-id", id,
+		"--semantic-version", app.SemanticVersion,
+		"--template-body", "file://" + templateFile,
+	}
+
+	return appendOptionalArgs(args, map[string]string{
+		"--source-code-url": app.SourceCodeUrl,
+	})
+
+}
+
+// appendOptionalArgs appends the options that have a value, in order
+func appendOptionalArgs(args []string, options map[string]string) []string {
+
+	names := []string{}
+	for name, value := range options {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		args = append(args, name, options[name])
+	}
+
+	return args
+
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Publish", func() {
+
+	template := func(metadata map[string]interface{}, codeUri string) map[string]interface{} {
+		return map[string]interface{}{
+			"Metadata": map[string]interface{}{
+				"AWS::ServerlessRepo::Application": metadata,
+			},
+			"Resources": map[string]interface{}{
+				"Hello": map[string]interface{}{
+					"Type":       "AWS::Serverless::Function",
+					"Properties": map[string]interface{}{"CodeUri": codeUri},
+				},
+			},
+		}
+	}
+
+	metadata := func() map[string]interface{} {
+		return map[string]interface{}{
+			"Name":            "my-app",
+			"Description":     "hello world",
+			"Author":          "user1",
+			"SpdxLicenseId":   "Apache-2.0",
+			"LicenseUrl":      "s3://bucket/LICENSE",
+			"Labels":          []interface{}{"tests", "demo"},
+			"SemanticVersion": "0.0.1",
+		}
+	}
+
+	It("reads the application metadata", func() {
+		app, err := getApplicationMetadata(template(metadata(), "s3://bucket/code.zip"))
+		Expect(err).To(BeNil())
+		Expect(app.Name).To(Equal("my-app"))
+		Expect(app.Labels).To(Equal([]string{"tests", "demo"}))
+		Expect(app.SemanticVersion).To(Equal("0.0.1"))
+	})
+
+	It("rejects templates that aren't packaged or publishable", func() {
+		_, err := getApplicationMetadata(map[string]interface{}{})
+		Expect(err).ToNot(BeNil())
+
+		missing := metadata()
+		delete(missing, "Author")
+		_, err = getApplicationMetadata(template(missing, "s3://bucket/code.zip"))
+		Expect(err.Error()).To(ContainSubstring("missing Author"))
+
+		local := metadata()
+		local["LicenseUrl"] = "LICENSE"
+		_, err = getApplicationMetadata(template(local, "s3://bucket/code.zip"))
+		Expect(err.Error()).To(ContainSubstring("LicenseUrl"))
+
+		_, err = getApplicationMetadata(template(metadata(), "./src"))
+		Expect(err.Error()).To(ContainSubstring("Hello"))
+	})
+
+	It("builds the AWS CLI arguments", func() {
+		app, _ := getApplicationMetadata(template(metadata(), "s3://bucket/code.zip"))
+
+		Expect(app.createArgs("packaged.yaml")).To(Equal([]string{
+			"serverlessrepo", "create-application",
+			"--name", "my-app",
+			"--description", "hello world",
+			"--author", "user1",
+			"--output", "text",
+			"--query", "ApplicationId",
+			"--license-url", "s3://bucket/LICENSE",
+			"--semantic-version", "0.0.1",
+			"--spdx-license-id", "Apache-2.0",
+			"--template-body", "file://packaged.yaml",
+			"--labels", "tests", "demo",
+		}))
+
+		Expect(app.updateArgs("arn:app")).To(Equal([]string{
+			"serverlessrepo", "update-application",
+			"--application-id", "arn:app",
+			"--description", "hello world",
+			"--author", "user1",
+			"--labels", "tests", "demo",
+		}))
+
+		Expect(app.versionArgs("arn:app", "packaged.yaml")).To(Equal([]string{
+			"serverlessrepo", "create-application-version",
+			"--application-id", "arn:app",
+			"--semantic-version", "0.0.1",
+			"--template-body", "file://packaged.yaml",
+		}))
+	})
+
+})
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/awslabs/aws-sam-local/router"
+)
+
+// recording is a single request/response exchange captured with 'sam local start-api --record'
+type recording struct {
+	Function string           `json:"function"`
+	Time     time.Time        `json:"time"`
+	Request  recordedRequest  `json:"request"`
+	Event    *router.Event    `json:"event"`
+	Response recordedResponse `json:"response"`
+}
+
+// recordedRequest is the HTTP request that was received by the local API Gateway
+type recordedRequest struct {
+	Method          string            `json:"method"`
+	Path            string            `json:"path"`
+	Query           map[string]string `json:"query,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body,omitempty"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// recordedResponse is the HTTP response that was sent back to the client
+type recordedResponse struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body,omitempty"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// recorder saves every request handled by a function to a directory, one JSON file per request
+type recorder struct {
+	dir string
+	seq uint64
+
+	// eventTimes records the time of each event's requestContext, instead of the current
+	// time, for --deterministic
+	eventTimes bool
+}
+
+// newRecorder creates a recorder, creating the recording directory if needed
+func newRecorder(dir string) (*recorder, error) {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create recording directory %s: %s", dir, err)
+	}
+
+	// Remember where recordings are, so 'sam local cleanup' can remove them
+	if err := rememberRecordingDir(dir); err != nil {
+		log.Printf("Could not save the location of the recordings: %s\n", err)
+	}
+
+	return &recorder{dir: dir}, nil
+
+}
+
+// Wrap returns an event handler that records every request, event and response of the function
+func (rec *recorder) Wrap(function string, handler router.EventHandlerFunc) router.EventHandlerFunc {
+
+	return func(w http.ResponseWriter, event *router.Event) {
+
+		capture := &responseCapture{ResponseWriter: w}
+		started := time.Now()
+		if rec.eventTimes {
+			started = time.Unix(0, event.RequestContext.RequestTimeEpoch*int64(time.Millisecond)).UTC()
+		}
+
+		handler(capture, event)
+
+		r := &recording{
+			Function: function,
+			Time:     started,
+			Request: recordedRequest{
+				Method:          event.HTTPMethod,
+				Path:            event.Path,
+				Query:           event.QueryStringParams,
+				Headers:         event.Headers,
+				Body:            event.Body,
+				IsBase64Encoded: event.IsBase64Encoded,
+			},
+			Event:    event,
+			Response: capture.Recorded(),
+		}
+
+		if err := rec.save(r); err != nil {
+			log.Printf("Could not record request to %s: %s\n", event.Path, err)
+		}
+
+	}
+
+}
+
+func (rec *recorder) save(r *recording) error {
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Files are named so that they sort in the order the requests were received
+	seq := atomic.AddUint64(&rec.seq, 1)
+	name := fmt.Sprintf("%s-%06d-%s.json", r.Time.Format("20060102T150405.000"), seq, r.Function)
+
+	return ioutil.WriteFile(filepath.Join(rec.dir, name), data, 0644)
+
+}
+
+// responseCapture is a http.ResponseWriter that keeps a copy of the response it writes
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+
+	// hijacked is whether the connection was taken over, e.g. to drop it
+	hijacked bool
+}
+
+// WriteHeader implements http.ResponseWriter
+func (c *responseCapture) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter
+func (c *responseCapture) Write(data []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(data)
+	return c.ResponseWriter.Write(data)
+}
+
+// Flush implements http.Flusher, so that streamed responses are still sent as they're written
+func (c *responseCapture) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, so that injected faults can still drop the connection
+func (c *responseCapture) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := c.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the connection can't be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		c.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Recorded returns the captured response. Binary bodies are base64 encoded.
+func (c *responseCapture) Recorded() recordedResponse {
+
+	response := recordedResponse{
+		StatusCode: c.status,
+		Headers:    map[string]string{},
+	}
+
+	if response.StatusCode == 0 {
+		response.StatusCode = http.StatusOK
+	}
+
+	for name := range c.Header() {
+		response.Headers[name] = c.Header().Get(name)
+	}
+
+	if utf8.Valid(c.body.Bytes()) {
+		response.Body = c.body.String()
+	} else {
+		response.Body = base64.StdEncoding.EncodeToString(c.body.Bytes())
+		response.IsBase64Encoded = true
+	}
+
+	return response
+
+}
+
+// loadRecordings reads all of the recordings from a directory, in the order they were recorded
+func loadRecordings(dir string) ([]*recording, []string, error) {
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recordings := []*recording{}
+	for _, file := range files {
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		r := &recording{}
+		if err := json.Unmarshal(data, r); err != nil {
+			return nil, nil, fmt.Errorf("invalid recording %s: %s", file, err)
+		}
+
+		recordings = append(recordings, r)
+
+	}
+
+	return recordings, files, nil
+
+}
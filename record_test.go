@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/router"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recording and replay", func() {
+
+	var dir, cache string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "aws-sam-local-record")
+
+		// Keep track of the recording directories in a temporary cache, not the user's
+		cache, _ = ioutil.TempDir("", "aws-sam-local-cache")
+		os.Setenv("XDG_CACHE_HOME", cache)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+		os.RemoveAll(cache)
+		os.Unsetenv("XDG_CACHE_HOME")
+	})
+
+	It("streams responses through the handlers that capture them", func() {
+
+		rec, err := newRecorder(dir)
+		Expect(err).To(BeNil())
+
+		release := make(chan struct{})
+		handler := func(w http.ResponseWriter, e *router.Event) {
+			w.WriteHeader(200)
+			flushWriter{w}.Write([]byte("first"))
+			<-release
+			w.Write([]byte("second"))
+		}
+		wrapped := (&responseContract{}).Wrap("Stream", rec.Wrap("Stream", handler))
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped(w, &router.Event{HTTPMethod: "GET", Path: "/stream"})
+		}))
+		defer server.Close()
+		defer close(release)
+
+		// Nothing, not even the headers, reaches the client unless the response is flushed
+		chunks := make(chan string, 1)
+		go func() {
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			chunk := make([]byte, len("first"))
+			io.ReadFull(resp.Body, chunk)
+			chunks <- string(chunk)
+		}()
+		Eventually(chunks, time.Second).Should(Receive(Equal("first")))
+
+	})
+
+	It("records the request, event and response of each invocation", func() {
+
+		rec, err := newRecorder(dir)
+		Expect(err).To(BeNil())
+
+		handler := rec.Wrap("HelloWorld", func(w http.ResponseWriter, e *router.Event) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(201)
+			w.Write([]byte(`{"hello":"world"}`))
+		})
+
+		event := &router.Event{
+			HTTPMethod:        "POST",
+			Path:              "/hello",
+			Body:              "ping",
+			QueryStringParams: map[string]string{"name": "sam"},
+			Headers:           map[string]string{"Host": "localhost", "X-Custom": "value"},
+		}
+
+		rr := httptest.NewRecorder()
+		handler(rr, event)
+		Expect(rr.Code).To(Equal(201))
+
+		recordings, files, err := loadRecordings(dir)
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(1))
+
+		r := recordings[0]
+		Expect(r.Function).To(Equal("HelloWorld"))
+		Expect(r.Event.Path).To(Equal("/hello"))
+		Expect(r.Response.StatusCode).To(Equal(201))
+		Expect(r.Response.Body).To(Equal(`{"hello":"world"}`))
+		Expect(r.Response.Headers).To(HaveKeyWithValue("Content-Type", "application/json"))
+
+		req, err := newReplayRequest("http://127.0.0.1:3000/", r)
+		Expect(err).To(BeNil())
+		Expect(req.Method).To(Equal("POST"))
+		Expect(req.URL.String()).To(Equal("http://127.0.0.1:3000/hello?name=sam"))
+		Expect(req.Header.Get("X-Custom")).To(Equal("value"))
+		Expect(req.Header.Get("Host")).To(BeEmpty())
+
+		Expect(compareReplay(r.Response, 201, []byte(`{"hello":"world"}`), false)).To(BeEmpty())
+		Expect(compareReplay(r.Response, 500, []byte(`{"hello":"world"}`), false)).ToNot(BeEmpty())
+		Expect(compareReplay(r.Response, 201, []byte(`{}`), false)).ToNot(BeEmpty())
+		Expect(compareReplay(r.Response, 201, []byte(`{}`), true)).To(BeEmpty())
+
+	})
+
+})
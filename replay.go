@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+)
+
+// replayTimeout is how long to wait for each replayed request
+const replayTimeout = 5 * time.Minute
+
+// replayHeadersToSkip are added by the local API Gateway or the HTTP client,
+// so they shouldn't be sent again when replaying a request
+var replayHeadersToSkip = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"X-Forwarded-Proto": true,
+	"X-Forwarded-Port":  true,
+}
+
+func replay(c *cli.Context) {
+
+	dir := c.Args().First()
+	if dir == "" {
+		fmt.Fprintf(os.Stderr, "ERROR: You must provide the directory containing the recordings as the first argument.\n")
+		os.Exit(1)
+	}
+
+	recordings, files, err := loadRecordings(dir)
+	if err != nil {
+		errMsg.Fprintf(os.Stderr, "ERROR: Could not load recordings from %s: %s\n", dir, err)
+		os.Exit(1)
+	}
+
+	if len(recordings) == 0 {
+		errMsg.Fprintf(os.Stderr, "ERROR: No recordings were found in %s\n", dir)
+		os.Exit(1)
+	}
+
+	client := &http.Client{Timeout: replayTimeout}
+	failures := 0
+
+	for i, r := range recordings {
+
+		name := filepath.Base(files[i])
+
+		req, err := newReplayRequest(c.String("url"), r)
+		if err != nil {
+			errMsg.Fprintf(os.Stderr, "ERROR %s: %s\n", name, err)
+			failures++
+			continue
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			errMsg.Fprintf(os.Stderr, "ERROR %s: %s %s: %s\n", name, req.Method, req.URL.Path, err)
+			failures++
+			continue
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			errMsg.Fprintf(os.Stderr, "ERROR %s: %s %s: %s\n", name, req.Method, req.URL.Path, err)
+			failures++
+			continue
+		}
+
+		if diff := compareReplay(r.Response, resp.StatusCode, body, c.Bool("status-only")); diff != "" {
+			errMsg.Fprintf(os.Stderr, "MISMATCH %s: %s %s: %s\n", name, req.Method, req.URL.Path, diff)
+			failures++
+			continue
+		}
+
+		successMsg.Fprintf(os.Stderr, "OK %s: %s %s (%d)\n", name, req.Method, req.URL.Path, resp.StatusCode)
+
+	}
+
+	fmt.Fprintf(os.Stderr, "\nReplayed %d requests, %d failed\n", len(recordings), failures)
+	if failures > 0 {
+		os.Exit(1)
+	}
+
+}
+
+// newReplayRequest rebuilds the HTTP request of a recording, against the provided base URL
+func newReplayRequest(base string, r *recording) (*http.Request, error) {
+
+	u, err := url.Parse(strings.TrimSuffix(base, "/") + r.Request.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	for name, value := range r.Request.Query {
+		query.Set(name, value)
+	}
+	u.RawQuery = query.Encode()
+
+	body := []byte(r.Request.Body)
+	if r.Request.IsBase64Encoded {
+		// Binary media types are only base64 encoded by the local API Gateway if they aren't valid UTF-8
+		if decoded, err := base64.StdEncoding.DecodeString(r.Request.Body); err == nil {
+			body = decoded
+		}
+	}
+
+	req, err := http.NewRequest(r.Request.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range r.Request.Headers {
+		if !replayHeadersToSkip[http.CanonicalHeaderKey(name)] {
+			req.Header.Set(name, value)
+		}
+	}
+
+	return req, nil
+
+}
+
+// compareReplay compares a replayed response with the recorded one, and returns
+// a description of the difference, or an empty string if they match
+func compareReplay(recorded recordedResponse, status int, body []byte, statusOnly bool) string {
+
+	if recorded.StatusCode != status {
+		return fmt.Sprintf("expected status %d, got %d", recorded.StatusCode, status)
+	}
+
+	if statusOnly {
+		return ""
+	}
+
+	expected := []byte(recorded.Body)
+	if recorded.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(recorded.Body)
+		if err != nil {
+			return fmt.Sprintf("recorded body is not valid base64: %s", err)
+		}
+		expected = decoded
+	}
+
+	if !bytes.Equal(expected, body) {
+		return fmt.Sprintf("expected body %q, got %q", truncate(expected, 200), truncate(body, 200))
+	}
+
+	return ""
+
+}
+
+// truncate shortens data to at most n bytes for display
+func truncate(data []byte, n int) string {
+	if len(data) <= n {
+		return string(data)
+	}
+	return string(data[:n]) + "..."
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// getResource returns the raw (untyped) definition of a resource in the template
+func getResource(template *cloudformation.Template, name string) map[string]interface{} {
+	if resource, ok := template.Resources[name].(map[string]interface{}); ok {
+		return resource
+	}
+	return map[string]interface{}{}
+}
+
+// getResourceMetadata returns the Metadata section of a resource in the template.
+// GoFormation doesn't keep resource metadata on the typed resources.
+func getResourceMetadata(template *cloudformation.Template, name string) map[string]interface{} {
+	if metadata, ok := getResource(template, name)["Metadata"].(map[string]interface{}); ok {
+		return metadata
+	}
+	return map[string]interface{}{}
+}
+
+// getResourceProperty returns a raw property of a resource in the template,
+// for properties that GoFormation doesn't support yet.
+func getResourceProperty(template *cloudformation.Template, name string, property string) (interface{}, bool) {
+	properties, ok := getResource(template, name)["Properties"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := properties[property]
+	return value, ok
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"strconv"
 
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/awslabs/aws-sam-local/logging"
 	"github.com/awslabs/goformation/cloudformation"
 	"github.com/go-openapi/spec"
 	"github.com/sanathkr/go-yaml"
@@ -19,22 +21,40 @@ const apiGatewayIntegrationExtension = "x-amazon-apigateway-integration"
 const apiGatewayAnyMethodExtension = "x-amazon-apigateway-any-method"
 const apiGatewayBinaryMediaTypesExtension = "x-amazon-apigateway-binary-media-types"
 
-// temporary object. This is just used to marshal and unmarshal the any method
-// API Gateway swagger extension
+// ApiGatewayAnyMethod is a temporary object. This is just used to marshal and unmarshal
+// the any method API Gateway swagger extension
 type ApiGatewayAnyMethod struct {
 	IntegrationSettings interface{} `json:"x-amazon-apigateway-integration"`
 }
 
 // AWSServerlessApi wraps GoFormation's AWS::Serverless::Api definition
-// and adds some convenience methods for extracting the ServerlessRouterMount's
+// and adds some convenience methods for extracting the Mounts
 // from the swagger defintion etc.
 type AWSServerlessApi struct {
 	*cloudformation.AWSServerlessApi
+
+	// Log receives problems with the definition's integrations. If nil, ErrorLog is used.
+	Log logging.Logger
+
+	// ErrorLog logs problems with the definition's integrations. If nil, the log
+	// package's standard logger is used.
+	//
+	// Deprecated: use Log, which has levels and fields.
+	ErrorLog *log.Logger
+}
+
+// log returns the entry that the API logs to
+func (api *AWSServerlessApi) log() *logging.Entry {
+	logger := api.Log
+	if logger == nil {
+		logger = logging.Std(api.ErrorLog)
+	}
+	return logging.For(logger, logging.Router)
 }
 
-// Mounts fetches an array of the ServerlessRouterMount's for this API.
+// Mounts fetches an array of the Mounts for this API.
 // These contain the path, method and handler function for each mount point.
-func (api *AWSServerlessApi) Mounts() ([]*ServerlessRouterMount, error) {
+func (api *AWSServerlessApi) Mounts() ([]*Mount, error) {
 	jsonDefinition, err := api.Swagger()
 
 	if err != nil {
@@ -49,7 +69,7 @@ func (api *AWSServerlessApi) Mounts() ([]*ServerlessRouterMount, error) {
 		return nil, fmt.Errorf("Cannot parse Swagger definition: %s", err.Error())
 	}
 
-	mounts := []*ServerlessRouterMount{}
+	mounts := []*Mount{}
 
 	binaryMediaTypes, ok := swagger.VendorExtensible.Extensions.GetStringSlice(apiGatewayBinaryMediaTypesExtension)
 	if !ok {
@@ -62,7 +82,7 @@ func (api *AWSServerlessApi) Mounts() ([]*ServerlessRouterMount, error) {
 		// the err from JSONLookup did not work as expected
 		mappedMethods := map[string]bool{}
 
-		for _, method := range HttpMethods {
+		for _, method := range anyMethods {
 
 			if operationIface, err := pathItem.JSONLookup(strings.ToLower(method)); err == nil {
 				operation := spec.Operation{}
@@ -103,7 +123,7 @@ func (api *AWSServerlessApi) Mounts() ([]*ServerlessRouterMount, error) {
 				return nil, fmt.Errorf("Could not unmarshal any method josn to object model")
 			}
 
-			for _, method := range HttpMethods {
+			for _, method := range anyMethods {
 				if _, ok := mappedMethods[method]; !ok {
 					mounts = append(mounts, api.createMount(
 						path,
@@ -123,7 +143,7 @@ func (api *AWSServerlessApi) Mounts() ([]*ServerlessRouterMount, error) {
 func (api *AWSServerlessApi) parseIntegrationSettings(integrationData interface{}) *ApiGatewayIntegration {
 	integrationJSON, err := json.Marshal(integrationData)
 	if err != nil {
-		log.Printf("Could not parse integration data to json")
+		api.log().Warnf("Could not parse integration data to json")
 		return nil
 	}
 
@@ -131,15 +151,15 @@ func (api *AWSServerlessApi) parseIntegrationSettings(integrationData interface{
 	err = json.Unmarshal(integrationJSON, &integration)
 
 	if err != nil {
-		log.Printf("Could not unmarshal integration data to ApiGatewayIntegration model")
+		api.log().Warnf("Could not unmarshal integration data to ApiGatewayIntegration model")
 		return nil
 	}
 
 	return &integration
 }
 
-func (api *AWSServerlessApi) createMount(path string, verb string, integration *ApiGatewayIntegration, binaryMediaTypes []string) *(ServerlessRouterMount) {
-	newMount := &ServerlessRouterMount{
+func (api *AWSServerlessApi) createMount(path string, verb string, integration *ApiGatewayIntegration, binaryMediaTypes []string) *(Mount) {
+	newMount := &Mount{
 		Name:             path,
 		Path:             path,
 		Method:           verb,
@@ -147,14 +167,14 @@ func (api *AWSServerlessApi) createMount(path string, verb string, integration *
 	}
 
 	if integration == nil {
-		log.Printf("No integration defined for method")
+		api.log().Warnf("No integration defined for method")
 		return newMount
 	}
 
 	functionName, err := integration.GetFunctionArn()
 
 	if err != nil {
-		log.Printf("Could not extract Lambda function ARN: %s", err.Error())
+		api.log().Warnf("Could not extract Lambda function ARN: %s", err.Error())
 	}
 	newMount.IntegrationArn = functionName
 
@@ -242,7 +262,7 @@ func (api *AWSServerlessApi) getSwaggerFromS3Location(loc cloudformation.AWSServ
 	sess := session.Must(session.NewSession())
 	client := s3.New(sess)
 
-	objectVersion := string(loc.Version)
+	objectVersion := strconv.Itoa(loc.Version)
 	s3Input := s3.GetObjectInput{
 		Bucket:    &loc.Bucket,
 		Key:       &loc.Key,
@@ -0,0 +1,21 @@
+// Package router emulates API Gateway's Lambda proxy integration. It mounts the Api event
+// sources of AWS::Serverless::Function resources, and the Swagger definitions of
+// AWS::Serverless::Api resources, on an http.Handler that turns each request into an
+// API Gateway proxy Event for the function's EventHandlerFunc:
+//
+//	r := router.NewServerlessRouter(router.NewServerlessRouterOpt{})
+//	r.AddAPI(api)
+//	r.AddFunction(function, func(w http.ResponseWriter, event *router.Event) {
+//		// invoke the function with the event, and write its response to w
+//	})
+//	http.ListenAndServe(":3000", r.Router())
+//
+// Events marshal to the same JSON as aws-lambda-go's events.APIGatewayProxyRequest, and
+// Event.V2 converts them to the HTTP API payload (events.APIGatewayV2HTTPRequest), so events
+// can be passed between local tests and Go handlers as they are.
+//
+// The exported API of this package is stable, and follows semantic versioning along with
+// SAM Local's releases: it only changes in backwards compatible ways, except in major
+// releases. Anything that's going to be removed is marked as deprecated first. The router
+// has no global state, so any number of them can be used at the same time.
+package router
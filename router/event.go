@@ -1,73 +1,140 @@
 package router
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
+	"golang.org/x/net/context"
 )
 
-// Event represents an event passed to an AWS Lambda function by the runtime
+// Event represents an event passed to an AWS Lambda function by the runtime. It marshals to
+// (and from) the same JSON as the APIGatewayProxyRequest struct from aws-lambda-go's events
+// package, so it can be passed straight to a Go handler.
 type Event struct {
-	HTTPMethod        string            `json:"httpMethod"`
-	Body              string            `json:"body"`
-	Resource          string            `json:"resource"`
-	RequestContext    RequestContext    `json:"requestContext"`
-	QueryStringParams map[string]string `json:"queryStringParameters"`
-	Headers           map[string]string `json:"headers"`
-	PathParameters    map[string]string `json:"pathParameters"`
-	StageVariables    map[string]string `json:"stageVariables"`
-	Path              string            `json:"path"`
-	IsBase64Encoded   bool              `json:"isBase64Encoded"`
+	Resource                    string              `json:"resource"`
+	Path                        string              `json:"path"`
+	HTTPMethod                  string              `json:"httpMethod"`
+	Headers                     map[string]string   `json:"headers"`
+	MultiValueHeaders           map[string][]string `json:"multiValueHeaders"`
+	QueryStringParams           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParams map[string][]string `json:"multiValueQueryStringParameters"`
+	PathParameters              map[string]string   `json:"pathParameters"`
+	StageVariables              map[string]string   `json:"stageVariables"`
+	RequestContext              RequestContext      `json:"requestContext"`
+	Body                        string              `json:"body"`
+	IsBase64Encoded             bool                `json:"isBase64Encoded"`
+
+	ctx context.Context
+
+	// body is the body of requests that are too large to be read into memory. Body is
+	// empty for them, and Reader streams this into the event's JSON instead.
+	body io.Reader
 }
 
 // RequestContext represents the context object that gets passed to an AWS Lambda function
 type RequestContext struct {
-	ResourceID   string          `json:"resourceId,omitempty"`
-	APIID        string          `json:"apiId,omitempty"`
-	ResourcePath string          `json:"resourcePath,omitempty"`
-	HTTPMethod   string          `json:"httpMethod,omitempty"`
-	RequestID    string          `json:"requestId,omitempty"`
-	AccountsID   string          `json:"accountId,omitempty"`
-	Stage        string          `json:"stage,omitempty"`
-	Identity     ContextIdentity `json:"identity,omitempty"`
+	AccountsID        string                 `json:"accountId"`
+	ResourceID        string                 `json:"resourceId"`
+	OperationName     string                 `json:"operationName,omitempty"`
+	Stage             string                 `json:"stage"`
+	DomainName        string                 `json:"domainName"`
+	DomainPrefix      string                 `json:"domainPrefix"`
+	RequestID         string                 `json:"requestId"`
+	ExtendedRequestID string                 `json:"extendedRequestId"`
+	Protocol          string                 `json:"protocol"`
+	Identity          ContextIdentity        `json:"identity"`
+	ResourcePath      string                 `json:"resourcePath"`
+	Path              string                 `json:"path"`
+	Authorizer        map[string]interface{} `json:"authorizer"`
+	HTTPMethod        string                 `json:"httpMethod"`
+	RequestTime       string                 `json:"requestTime"`
+	RequestTimeEpoch  int64                  `json:"requestTimeEpoch"`
+	APIID             string                 `json:"apiId"`
 }
 
 // ContextIdentity represents the identity section of the context object that gets passed to an AWS Lambda function
 type ContextIdentity struct {
-	APIKey                        string `json:"apiKey,omitempty"`
-	UserARN                       string `json:"userArn,omitempty"`
-	CognitoAuthenticationType     string `json:"cognitoAuthenticationType,omitempty"`
-	Caller                        string `json:"caller,omitempty"`
-	UserAgent                     string `json:"userAgent,omitempty"`
-	User                          string `json:"user,omitempty"`
-	CognitoIdentityPoolID         string `json:"cognitoIdentityPoolId,omitempty"`
-	CognitoIdentityID             string `json:"cognitoIdentityId,omitempty"`
-	CognitoAuthenticationProvider string `json:"cognitoAuthenticationProvider,omitempty"`
-	SourceIP                      string `json:"sourceIp,omitempty"`
-	AccountID                     string `json:"accountId,omitempty"`
+	CognitoIdentityPoolID         string `json:"cognitoIdentityPoolId"`
+	AccountID                     string `json:"accountId"`
+	CognitoIdentityID             string `json:"cognitoIdentityId"`
+	Caller                        string `json:"caller"`
+	APIKey                        string `json:"apiKey"`
+	APIKeyID                      string `json:"apiKeyId"`
+	AccessKey                     string `json:"accessKey"`
+	SourceIP                      string `json:"sourceIp"`
+	CognitoAuthenticationType     string `json:"cognitoAuthenticationType"`
+	CognitoAuthenticationProvider string `json:"cognitoAuthenticationProvider"`
+	UserARN                       string `json:"userArn"`
+	UserAgent                     string `json:"userAgent"`
+	User                          string `json:"user"`
+}
+
+// buffers are reused between requests, to read their bodies and encode their events, so that
+// load tests measure the functions rather than the garbage collector
+var buffers = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
+
+// maxPooledBuffer is the capacity of the largest buffer that's put back in the pool, so that
+// the occasional large request doesn't hold on to its memory
+const maxPooledBuffer = 1024 * 1024
+
+// getBuffer returns an empty buffer from the pool
+func getBuffer() *bytes.Buffer {
+	buf := buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns a buffer to the pool, once nothing refers to its contents
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		buffers.Put(buf)
+	}
 }
 
+// requestTimeFormat is the format of the request time in API Gateway's request context
+const requestTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
 // NewEvent initalises and populates a new ApiEvent with
 // event details from a http.Request and isBase64Encoded value
 func NewEvent(req *http.Request, isBase64Encoded bool) (*Event, error) {
 
-	var body []byte
+	var body string
 	if req.Body != nil {
-		var err error
-		body, err = ioutil.ReadAll(req.Body)
-		if err != nil {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if _, err := buf.ReadFrom(req.Body); err != nil {
 			return nil, err
 		}
+		body = buf.String()
 	}
 
+	return newEvent(req, body, isBase64Encoded), nil
+
+}
+
+// newEvent creates an event for a request whose body has already been read
+func newEvent(req *http.Request, body string, isBase64Encoded bool) *Event {
+
 	headers := map[string]string{}
+	multiValueHeaders := map[string][]string{}
 	for name, values := range req.Header {
 		for _, value := range values {
 			headers[name] = value
 		}
+		multiValueHeaders[name] = values
 	}
 
 	// add the forwarded headers we expect to see from an API Gateway request
@@ -80,47 +147,213 @@ func NewEvent(req *http.Request, isBase64Encoded bool) (*Event, error) {
 	}
 	headers["X-Forwarded-Proto"] = req.URL.Scheme
 	headers["X-Forwarded-Port"] = req.URL.Port()
+	for _, name := range []string{"Host", "X-Forwarded-Proto", "X-Forwarded-Port"} {
+		multiValueHeaders[name] = []string{headers[name]}
+	}
 
 	query := map[string]string{}
+	multiValueQuery := map[string][]string{}
 	for name, values := range req.URL.Query() {
 		for _, value := range values {
 			query[name] = value
 		}
+		multiValueQuery[name] = values
 	}
 
-	pathParams := mux.Vars(req)
+	pathParams := pathParameters(req)
 	if len(pathParams) == 0 {
 		pathParams = nil
 	}
 
 	event := &Event{
-		HTTPMethod:        req.Method,
-		Body:              string(body),
-		Headers:           headers,
-		QueryStringParams: query,
-		Path:              req.URL.Path,
-		Resource:          req.URL.Path,
-		PathParameters:    pathParams,
-		IsBase64Encoded:   isBase64Encoded,
+		ctx:                         req.Context(),
+		HTTPMethod:                  req.Method,
+		Body:                        body,
+		Headers:                     headers,
+		MultiValueHeaders:           multiValueHeaders,
+		QueryStringParams:           query,
+		MultiValueQueryStringParams: multiValueQuery,
+		Path:                        req.URL.Path,
+		Resource:                    req.URL.Path,
+		PathParameters:              pathParams,
+		IsBase64Encoded:             isBase64Encoded,
 	}
 
+	now := time.Now()
+	event.RequestContext.RequestID = newRequestID()
 	event.RequestContext.Identity.SourceIP = req.RemoteAddr
+	event.RequestContext.Identity.UserAgent = req.UserAgent()
 	event.RequestContext.ResourcePath = req.URL.Path
+	event.RequestContext.Path = req.URL.Path
 	event.RequestContext.HTTPMethod = req.Method
+	event.RequestContext.Protocol = req.Proto
+	event.RequestContext.DomainName = headers["Host"]
+	event.RequestContext.DomainPrefix = strings.SplitN(headers["Host"], ".", 2)[0]
+	event.RequestContext.RequestTime = now.Format(requestTimeFormat)
+	event.RequestContext.RequestTimeEpoch = now.UnixNano() / int64(time.Millisecond)
 	event.RequestContext.Stage = "prod"
 
-	return event, nil
+	return event
+
+}
+
+// pathParameters returns the values of the path parameters that the router matched for a
+// request, or those that gorilla/mux did for handlers that are mounted on a mux.Router
+func pathParameters(req *http.Request) map[string]string {
+	if params, ok := req.Context().Value(pathParametersKey{}).(map[string]string); ok {
+		return params
+	}
+	return mux.Vars(req)
+}
+
+// newRequestID generates a random (version 4) UUID, in the same format that
+// API Gateway uses for request IDs
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	var id [36]byte
+	hex.Encode(id[0:8], b[0:4])
+	id[8] = '-'
+	hex.Encode(id[9:13], b[4:6])
+	id[13] = '-'
+	hex.Encode(id[14:18], b[6:8])
+	id[18] = '-'
+	hex.Encode(id[19:23], b[8:10])
+	id[23] = '-'
+	hex.Encode(id[24:], b[10:])
+	return string(id[:])
+}
+
+// Context returns the context of the request that the event was created from, which is
+// cancelled when the client disconnects. Handlers should stop invoking the function once
+// it's done. For events that weren't created by NewEvent, it's context.Background().
+func (e *Event) Context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
 
+// WithContext returns a shallow copy of the event with its context changed to ctx
+func (e *Event) WithContext(ctx context.Context) *Event {
+	event := *e
+	event.ctx = ctx
+	return &event
 }
 
-// JSON returns the event as a JSON string
+// JSON returns the event as a JSON string. For events with a streamed body, it reads the
+// whole body into memory; use Reader to avoid that.
 func (e *Event) JSON() (string, error) {
 
+	if e.body != nil {
+		reader, err := e.Reader()
+		if err != nil {
+			return "", err
+		}
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := json.NewEncoder(buf).Encode(e); err != nil {
+		return "", err
+	}
+
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+
+}
+
+// Streamed returns whether the event's body is streamed, because the request's was too large
+// to read into memory (see NewServerlessRouterOpt.MaxBufferedBody). Body is empty for these
+// events, and the body is only available through Reader (or JSON).
+func (e *Event) Streamed() bool {
+	return e.body != nil
+}
+
+// Reader returns the event as a stream of JSON. The body of streamed events is read from the
+// request as the stream is read, so that it's never held in memory, and it can only be read
+// once. It's encoded as base64 if IsBase64Encoded is set.
+func (e *Event) Reader() (io.Reader, error) {
+
 	data, err := json.Marshal(e)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if e.body == nil {
+		return bytes.NewReader(data), nil
+	}
+
+	// The body is empty in the marshalled event, so stream it in between the quotes. It's
+	// the last field but one, so the last match is the body field itself, even if a header
+	// happens to be called body.
+	field := []byte(`"body":"`)
+	i := bytes.LastIndex(data, append(field, '"'))
+	if i < 0 {
+		return nil, fmt.Errorf("can't find the body of the event")
+	}
+	i += len(field)
+
+	body, writer := io.Pipe()
+	go func() {
+		var err error
+		if e.IsBase64Encoded {
+			encoder := base64.NewEncoder(base64.StdEncoding, writer)
+			if _, err = io.Copy(encoder, e.body); err == nil {
+				err = encoder.Close()
+			}
+		} else {
+			err = writeJSONString(writer, e.body)
+		}
+		writer.CloseWithError(err)
+	}()
+
+	return io.MultiReader(bytes.NewReader(data[:i]), body, bytes.NewReader(data[i:])), nil
+
+}
+
+// writeJSONString writes the text read from r to w, escaped for a JSON string (without the
+// quotes). Invalid UTF-8 is replaced with U+FFFD, as encoding/json does.
+func writeJSONString(w io.Writer, r io.Reader) error {
+
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+
+	for {
+		c, size, err := in.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case c == '"' || c == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(c)
+		case c == '\n':
+			out.WriteString(`\n`)
+		case c == '\r':
+			out.WriteString(`\r`)
+		case c == '\t':
+			out.WriteString(`\t`)
+		case c < 0x20 || c == '\u2028' || c == '\u2029':
+			fmt.Fprintf(out, `\u%04x`, c)
+		case c == utf8.RuneError && size == 1:
+			out.WriteString(`\ufffd`)
+		default:
+			out.WriteRune(c)
+		}
 	}
 
-	return string(data), nil
+	return out.Flush()
 
 }
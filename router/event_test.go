@@ -2,10 +2,15 @@ package router
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 
 	"github.com/awslabs/goformation/cloudformation"
+	"golang.org/x/net/context"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -15,7 +20,7 @@ var _ = Describe("Event", func() {
 	Describe("PathParameters", func() {
 		var r *ServerlessRouter
 		BeforeEach(func() {
-			r = NewServerlessRouter(false)
+			r = NewServerlessRouter(NewServerlessRouterOpt{})
 		})
 
 		Context("with path parameters on the route", func() {
@@ -145,4 +150,177 @@ var _ = Describe("Event", func() {
 			})
 		})
 	})
+
+	Describe("JSON", func() {
+		It("has the same shape as aws-lambda-go's APIGatewayProxyRequest", func() {
+			req, _ := http.NewRequest("GET", "http://localhost:3000/get?name=a&name=b", nil)
+			req.Header.Add("Accept", "text/html")
+			req.Header.Add("Accept", "application/json")
+
+			event, err := NewEvent(req, false)
+			Expect(err).To(BeNil())
+
+			data, err := event.JSON()
+			Expect(err).To(BeNil())
+
+			var fields map[string]interface{}
+			json.Unmarshal([]byte(data), &fields)
+			Expect(fields).To(HaveKey("multiValueHeaders"))
+			Expect(fields).To(HaveKey("multiValueQueryStringParameters"))
+			Expect(fields["multiValueQueryStringParameters"]).To(HaveKeyWithValue("name", []interface{}{"a", "b"}))
+			Expect(fields["multiValueHeaders"]).To(HaveKeyWithValue("Accept", []interface{}{"text/html", "application/json"}))
+
+			context := fields["requestContext"].(map[string]interface{})
+			for _, key := range []string{"accountId", "resourceId", "stage", "domainName", "domainPrefix", "requestId", "extendedRequestId", "protocol", "identity", "resourcePath", "path", "authorizer", "httpMethod", "requestTime", "requestTimeEpoch", "apiId"} {
+				Expect(context).To(HaveKey(key))
+			}
+			Expect(context["domainName"]).To(Equal("localhost"))
+		})
+
+		It("round trips", func() {
+			req, _ := http.NewRequest("POST", "http://localhost:3000/post?name=a", bytes.NewBufferString("body"))
+			event, _ := NewEvent(req, false)
+
+			data, _ := event.JSON()
+			decoded := &Event{}
+			Expect(json.Unmarshal([]byte(data), decoded)).To(BeNil())
+
+			// The context isn't part of the JSON
+			Expect(decoded.WithContext(event.Context())).To(Equal(event))
+		})
+
+		It("encodes events as json.Marshal does, reusing its buffers", func() {
+			req, _ := http.NewRequest("POST", "http://localhost:3000/post", bytes.NewBufferString("<b>body</b> & more"))
+			event, _ := NewEvent(req, false)
+
+			expected, _ := json.Marshal(event)
+			for i := 0; i < 3; i++ {
+				data, err := event.JSON()
+				Expect(err).To(BeNil())
+				Expect(data).To(Equal(string(expected)))
+			}
+		})
+
+		It("has request IDs in the format of API Gateway's", func() {
+			Expect(newRequestID()).To(MatchRegexp("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$"))
+			Expect(newRequestID()).ToNot(Equal(newRequestID()))
+		})
+
+		It("has the context of the request", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			req, _ := http.NewRequest("GET", "http://localhost:3000/get", nil)
+			event, _ := NewEvent(req.WithContext(ctx), false)
+
+			Expect(event.Context().Err()).To(BeNil())
+			cancel()
+			Expect(event.Context().Err()).ToNot(BeNil())
+
+			Expect((&Event{}).Context()).ToNot(BeNil())
+		})
+	})
+
+	Describe("Streamed bodies", func() {
+
+		// serve passes a request to a mount with a 16 byte in-memory limit, and returns
+		// the event its handler receives as JSON
+		serve := func(body string, contentType string) (*Event, string) {
+			var event *Event
+			var data []byte
+			m := &Mount{
+				BinaryMediaTypes: []string{"application/octet-stream"},
+				Handler: func(w http.ResponseWriter, e *Event) {
+					event = e
+					reader, err := e.Reader()
+					Expect(err).To(BeNil())
+					data, _ = ioutil.ReadAll(reader)
+				},
+			}
+			req, _ := http.NewRequest("POST", "http://localhost:3000/upload", strings.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			m.wrappedHandler(NewServerlessRouterOpt{MaxBufferedBody: 16}).ServeHTTP(httptest.NewRecorder(), req)
+			return event, string(data)
+		}
+
+		It("buffers small bodies", func() {
+			event, _ := serve("small", "text/plain")
+			Expect(event.Streamed()).To(BeFalse())
+			Expect(event.Body).To(Equal("small"))
+
+			// The buffers the bodies were read into are reused by later requests
+			serve("other", "text/plain")
+			Expect(event.Body).To(Equal("small"))
+		})
+
+		It("encodes small binary bodies as base64", func() {
+			event, _ := serve("\xff\xfe", "application/octet-stream")
+			Expect(event.IsBase64Encoded).To(BeTrue())
+			Expect(event.Body).To(Equal(base64.StdEncoding.EncodeToString([]byte("\xff\xfe"))))
+
+			event, _ = serve("text", "application/octet-stream")
+			Expect(event.Body).To(Equal("text"))
+		})
+
+		It("streams large bodies into the JSON", func() {
+			body := "a \"large\" body\n\twith \\ escapes, \x01 control characters, ünicode and \xff"
+			event, data := serve(body, "text/plain")
+			Expect(event.Streamed()).To(BeTrue())
+			Expect(event.Body).To(Equal(""))
+
+			decoded := &Event{}
+			Expect(json.Unmarshal([]byte(data), decoded)).To(BeNil())
+			Expect(decoded.Body).To(Equal(strings.Replace(body, "\xff", "\ufffd", 1)))
+			Expect(decoded.HTTPMethod).To(Equal("POST"))
+		})
+
+		It("streams large binary bodies as base64", func() {
+			body := string([]byte{0, 1, 2, 0xff, 0xfe}) + strings.Repeat("binary", 10)
+			event, data := serve(body, "application/octet-stream")
+			Expect(event.Streamed()).To(BeTrue())
+
+			decoded := &Event{}
+			Expect(json.Unmarshal([]byte(data), decoded)).To(BeNil())
+			Expect(decoded.IsBase64Encoded).To(BeTrue())
+			Expect(decoded.Body).To(Equal(base64.StdEncoding.EncodeToString([]byte(body))))
+		})
+
+	})
+
+	Describe("V2", func() {
+		req, _ := http.NewRequest("GET", "http://localhost:3000/get?name=a&name=b", nil)
+		req.Header.Add("Accept", "text/html")
+		req.Header.Add("Accept", "application/json")
+		req.Header.Add("Cookie", "a=1; b=2")
+		event, _ := NewEvent(req, false)
+
+		It("converts events to the version 2.0 payload format", func() {
+			v2 := event.V2()
+			Expect(v2.Version).To(Equal("2.0"))
+			Expect(v2.RouteKey).To(Equal("GET /get"))
+			Expect(v2.RawPath).To(Equal("/get"))
+			Expect(v2.RawQueryString).To(Equal("name=a&name=b"))
+			Expect(v2.QueryStringParameters).To(HaveKeyWithValue("name", "a,b"))
+			Expect(v2.Headers).To(HaveKeyWithValue("accept", "text/html,application/json"))
+			Expect(v2.Headers).ToNot(HaveKey("cookie"))
+			Expect(v2.Cookies).To(Equal([]string{"a=1", "b=2"}))
+			Expect(v2.RequestContext.HTTP.Method).To(Equal("GET"))
+			Expect(v2.RequestContext.RequestID).To(Equal(event.RequestContext.RequestID))
+
+			data, err := json.Marshal(v2)
+			Expect(err).To(BeNil())
+			var fields map[string]interface{}
+			json.Unmarshal(data, &fields)
+			Expect(fields).To(HaveKeyWithValue("version", "2.0"))
+			Expect(fields["requestContext"]).To(HaveKey("http"))
+		})
+
+		It("converts version 2.0 events back", func() {
+			v1 := event.V2().V1()
+			Expect(v1.HTTPMethod).To(Equal("GET"))
+			Expect(v1.Resource).To(Equal("/get"))
+			Expect(v1.Path).To(Equal("/get"))
+			Expect(v1.MultiValueQueryStringParams).To(HaveKeyWithValue("name", []string{"a", "b"}))
+			Expect(v1.Headers).To(HaveKeyWithValue("cookie", "a=1; b=2"))
+			Expect(v1.RequestContext.RequestID).To(Equal(event.RequestContext.RequestID))
+		})
+	})
 })
@@ -0,0 +1,192 @@
+package router
+
+import (
+	"net/url"
+	"strings"
+)
+
+// EventV2 represents an event passed to an AWS Lambda function by an HTTP API, with version 2.0
+// of the payload format. It marshals to (and from) the same JSON as the APIGatewayV2HTTPRequest
+// struct from aws-lambda-go's events package.
+type EventV2 struct {
+	Version               string            `json:"version"`
+	RouteKey              string            `json:"routeKey"`
+	RawPath               string            `json:"rawPath"`
+	RawQueryString        string            `json:"rawQueryString"`
+	Cookies               []string          `json:"cookies,omitempty"`
+	Headers               map[string]string `json:"headers"`
+	QueryStringParameters map[string]string `json:"queryStringParameters,omitempty"`
+	PathParameters        map[string]string `json:"pathParameters,omitempty"`
+	RequestContext        RequestContextV2  `json:"requestContext"`
+	StageVariables        map[string]string `json:"stageVariables,omitempty"`
+	Body                  string            `json:"body,omitempty"`
+	IsBase64Encoded       bool              `json:"isBase64Encoded"`
+}
+
+// RequestContextV2 represents the context object of a version 2.0 event
+type RequestContextV2 struct {
+	RouteKey     string                 `json:"routeKey"`
+	AccountID    string                 `json:"accountId"`
+	Stage        string                 `json:"stage"`
+	RequestID    string                 `json:"requestId"`
+	Authorizer   map[string]interface{} `json:"authorizer,omitempty"`
+	APIID        string                 `json:"apiId"`
+	DomainName   string                 `json:"domainName"`
+	DomainPrefix string                 `json:"domainPrefix"`
+	Time         string                 `json:"time"`
+	TimeEpoch    int64                  `json:"timeEpoch"`
+	HTTP         HTTPDescriptionV2      `json:"http"`
+}
+
+// HTTPDescriptionV2 represents the request section of a version 2.0 event's context
+type HTTPDescriptionV2 struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Protocol  string `json:"protocol"`
+	SourceIP  string `json:"sourceIp"`
+	UserAgent string `json:"userAgent"`
+}
+
+// V2 converts the event to version 2.0 of the payload format, the way an HTTP API would send
+// the same request. Header names are lower case, headers and query string parameters with
+// several values are joined with commas, and cookies are moved out of the headers.
+func (e *Event) V2() *EventV2 {
+
+	event := &EventV2{
+		Version:         "2.0",
+		RouteKey:        e.HTTPMethod + " " + e.Resource,
+		RawPath:         e.Path,
+		Headers:         map[string]string{},
+		PathParameters:  e.PathParameters,
+		StageVariables:  e.StageVariables,
+		Body:            e.Body,
+		IsBase64Encoded: e.IsBase64Encoded,
+	}
+
+	headers := e.MultiValueHeaders
+	if headers == nil {
+		headers = singleValues(e.Headers)
+	}
+	for name, values := range headers {
+		if strings.ToLower(name) == "cookie" {
+			for _, value := range values {
+				for _, cookie := range strings.Split(value, ";") {
+					event.Cookies = append(event.Cookies, strings.TrimSpace(cookie))
+				}
+			}
+			continue
+		}
+		event.Headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	query := url.Values(e.MultiValueQueryStringParams)
+	if query == nil {
+		query = url.Values(singleValues(e.QueryStringParams))
+	}
+	event.RawQueryString = query.Encode()
+	if len(query) > 0 {
+		event.QueryStringParameters = map[string]string{}
+		for name, values := range query {
+			event.QueryStringParameters[name] = strings.Join(values, ",")
+		}
+	}
+
+	context := e.RequestContext
+	event.RequestContext = RequestContextV2{
+		RouteKey:     event.RouteKey,
+		AccountID:    context.AccountsID,
+		Stage:        context.Stage,
+		RequestID:    context.RequestID,
+		Authorizer:   context.Authorizer,
+		APIID:        context.APIID,
+		DomainName:   context.DomainName,
+		DomainPrefix: context.DomainPrefix,
+		Time:         context.RequestTime,
+		TimeEpoch:    context.RequestTimeEpoch,
+		HTTP: HTTPDescriptionV2{
+			Method:    e.HTTPMethod,
+			Path:      e.Path,
+			Protocol:  context.Protocol,
+			SourceIP:  context.Identity.SourceIP,
+			UserAgent: context.Identity.UserAgent,
+		},
+	}
+
+	return event
+
+}
+
+// V1 converts a version 2.0 event to the REST API (version 1.0) payload format. Cookies are
+// moved back into the Cookie header.
+func (e *EventV2) V1() *Event {
+
+	resource := e.RawPath
+	if parts := strings.SplitN(e.RouteKey, " ", 2); len(parts) == 2 {
+		resource = parts[1]
+	}
+
+	event := &Event{
+		Resource:          resource,
+		Path:              e.RawPath,
+		HTTPMethod:        e.RequestContext.HTTP.Method,
+		Headers:           map[string]string{},
+		MultiValueHeaders: map[string][]string{},
+		PathParameters:    e.PathParameters,
+		StageVariables:    e.StageVariables,
+		Body:              e.Body,
+		IsBase64Encoded:   e.IsBase64Encoded,
+	}
+
+	for name, value := range e.Headers {
+		event.Headers[name] = value
+		event.MultiValueHeaders[name] = []string{value}
+	}
+	if len(e.Cookies) > 0 {
+		event.Headers["cookie"] = strings.Join(e.Cookies, "; ")
+		event.MultiValueHeaders["cookie"] = []string{event.Headers["cookie"]}
+	}
+
+	if query, err := url.ParseQuery(e.RawQueryString); err == nil && len(query) > 0 {
+		event.QueryStringParams = map[string]string{}
+		event.MultiValueQueryStringParams = map[string][]string(query)
+		for name, values := range query {
+			event.QueryStringParams[name] = values[len(values)-1]
+		}
+	}
+
+	context := e.RequestContext
+	event.RequestContext = RequestContext{
+		AccountsID:       context.AccountID,
+		Stage:            context.Stage,
+		DomainName:       context.DomainName,
+		DomainPrefix:     context.DomainPrefix,
+		RequestID:        context.RequestID,
+		Protocol:         context.HTTP.Protocol,
+		ResourcePath:     resource,
+		Path:             e.RawPath,
+		Authorizer:       context.Authorizer,
+		HTTPMethod:       context.HTTP.Method,
+		RequestTime:      context.Time,
+		RequestTimeEpoch: context.TimeEpoch,
+		APIID:            context.APIID,
+		Identity: ContextIdentity{
+			SourceIP:  context.HTTP.SourceIP,
+			UserAgent: context.HTTP.UserAgent,
+		},
+	}
+
+	return event
+
+}
+
+// singleValues turns a map of single values into a map of lists
+func singleValues(values map[string]string) map[string][]string {
+	if values == nil {
+		return nil
+	}
+	result := map[string][]string{}
+	for name, value := range values {
+		result[name] = []string{value}
+	}
+	return result
+}
@@ -5,28 +5,28 @@ import (
 )
 
 // AWSServerlessFunction wraps GoFormation's AWS::Serverless::Function definition
-// and adds some convenience methods for extracting the ServerlessRouterMount's
+// and adds some convenience methods for extracting the Mounts
 // from the event sources.
 type AWSServerlessFunction struct {
 	*cloudformation.AWSServerlessFunction
 	handler EventHandlerFunc
 }
 
-// Mounts fetches an array of the ServerlessRouterMount's for this API.
+// Mounts fetches an array of the Mounts for this API.
 // These contain the path, method and handler function for each mount point.
-func (f *AWSServerlessFunction) Mounts() ([]*ServerlessRouterMount, error) {
+func (f *AWSServerlessFunction) Mounts() ([]*Mount, error) {
 
-	mounts := []*ServerlessRouterMount{}
+	mounts := []*Mount{}
 
 	for name, event := range f.Events {
 		if event.Type == "Api" {
 			if event.Properties != nil && event.Properties.ApiEvent != nil {
-				mounts = append(mounts, &ServerlessRouterMount{
-					Name:      name,
-					Path:      event.Properties.ApiEvent.Path,
-					Method:    event.Properties.ApiEvent.Method,
-					Handler:   f.handler,
-					Function:  f,
+				mounts = append(mounts, &Mount{
+					Name:     name,
+					Path:     event.Properties.ApiEvent.Path,
+					Method:   event.Properties.ApiEvent.Method,
+					Handler:  f.handler,
+					Function: f,
 				})
 			}
 		}
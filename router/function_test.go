@@ -16,7 +16,7 @@ var _ = Describe("Function", func() {
 
 	Context("with a GoFormation AWS::Serverless::Function", func() {
 
-		r := router.NewServerlessRouter(false)
+		r := router.NewServerlessRouter(router.NewServerlessRouterOpt{})
 
 		function := &cloudformation.AWSServerlessFunction{
 			Runtime: "nodejs6.10",
@@ -173,7 +173,7 @@ var _ = Describe("Function", func() {
 
 	Context("with a GoFormation AWS::Serverless::Function and prefix matching", func() {
 
-		r := router.NewServerlessRouter(true)
+		r := router.NewServerlessRouter(router.NewServerlessRouterOpt{UsePrefix: true})
 
 		function := &cloudformation.AWSServerlessFunction{
 			Runtime: "nodejs6.10",
@@ -240,7 +240,7 @@ var _ = Describe("Function", func() {
 
 	Context("with a GoFormation AWS::Serverless::Function that has no 'Api' event sources", func() {
 
-		r := router.NewServerlessRouter(false)
+		r := router.NewServerlessRouter(router.NewServerlessRouterOpt{})
 
 		function := &cloudformation.AWSServerlessFunction{
 			Runtime: "nodejs6.10",
@@ -1,16 +1,18 @@
 package router
 
 import (
-	"regexp"
 	"fmt"
+	"regexp"
 )
 
+// ApiGatewayIntegration is the x-amazon-apigateway-integration of an operation in an API definition
 type ApiGatewayIntegration struct {
-	Uri string `json:"uri"`
+	Uri                 string `json:"uri"`
 	PassthroughBehavior string `json:"passthroughBehavior"`
-	Type string `json:"type"`
+	Type                string `json:"type"`
 }
 
+// GetFunctionArn returns the ARN of the Lambda function the integration invokes
 func (i *ApiGatewayIntegration) GetFunctionArn() (*LambdaFunctionArn, error) {
 	// arn:aws:apigateway:us-west-2:lambda:path//2015-03-31/functions/arn:aws:lambda:us-west-2:123456789012:function:Calc/invocations
 	firstMatch, err := getFirstMatch(`.*/functions/(.*)/invocations`, i.Uri)
@@ -19,13 +21,15 @@ func (i *ApiGatewayIntegration) GetFunctionArn() (*LambdaFunctionArn, error) {
 		return nil, err
 	}
 
-	return &LambdaFunctionArn{ Arn: firstMatch }, nil
+	return &LambdaFunctionArn{Arn: firstMatch}, nil
 }
 
+// LambdaFunctionArn is the ARN of a Lambda function that an API integration invokes
 type LambdaFunctionArn struct {
 	Arn string
 }
 
+// GetFunctionName returns the name of the function
 func (a *LambdaFunctionArn) GetFunctionName() (string, error) {
 	firstMatch, err := getFirstMatch(`.*:function:(.*)/invocations`, a.Arn)
 
@@ -45,4 +49,4 @@ func getFirstMatch(regex string, value string) (string, error) {
 	}
 
 	return match[1], nil
-}
\ No newline at end of file
+}
@@ -1,26 +1,45 @@
 package router
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/base64"
-	"io/ioutil"
+	"errors"
+	"io"
 	"mime"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 	"unicode/utf8"
-	"log"
-	"fmt"
+
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/aws-sam-local/tracing"
 )
 
+// MuxPathRegex is the pattern greedy path parameters (e.g. /{proxy+}) are matched with
 const MuxPathRegex = ".+"
-var HttpMethods = []string{"OPTIONS", "GET", "HEAD", "POST", "PUT", "DELETE", "PATCH"}
+
+// anyMethods are the HTTP methods that a mount for the 'any' method is mounted on
+var anyMethods = []string{"OPTIONS", "GET", "HEAD", "POST", "PUT", "DELETE", "PATCH"}
+
+// AnyMethods returns the HTTP methods that API Gateway's 'any' method stands for
+func AnyMethods() []string {
+	return append([]string{}, anyMethods...)
+}
+
+// HttpMethods are the HTTP methods that API Gateway's 'any' method stands for.
+//
+// Deprecated: use AnyMethods.
+var HttpMethods = AnyMethods()
 
 // EventHandlerFunc is similar to Go http.Handler but it receives an event from API Gateway
-// instead of http.Request
+// instead of http.Request. The event's Context is the request's.
 type EventHandlerFunc func(http.ResponseWriter, *Event)
 
-// ServerlessRouterMount represents a single mount point on the API
+// Mount represents a single mount point on the API
 // Such as '/path', the HTTP method, and the function to resolve it
-type ServerlessRouterMount struct {
+type Mount struct {
 	Name             string
 	Function         *AWSServerlessFunction
 	Handler          EventHandlerFunc
@@ -34,10 +53,39 @@ type ServerlessRouterMount struct {
 	IntegrationArn *LambdaFunctionArn
 }
 
-// Returns the wrapped handler to encode the body as base64 when binary
-// media types contains Content-Type
-func (m *ServerlessRouterMount) WrappedHandler() http.HandlerFunc {
+// ServerlessRouterMount is the previous name of Mount.
+//
+// Deprecated: use Mount.
+type ServerlessRouterMount = Mount
+
+// WrappedHandler returns the mount's handler as an http.HandlerFunc, which turns requests into
+// events. The body is encoded as base64 when binary media types contains the Content-Type.
+func (m *Mount) WrappedHandler() http.HandlerFunc {
+	return m.wrappedHandler(NewServerlessRouterOpt{})
+}
+
+// wrappedHandler returns the mount's handler, with the router's options
+func (m *Mount) wrappedHandler(opt NewServerlessRouterOpt) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+		// The request's span is the parent of the spans of building its event and invoking
+		// the function, which get it from the event's context
+		if opt.Tracer != nil {
+			ctx, span := opt.Tracer.StartRequest(req, req.Method+" "+m.Path, tracing.Attributes{
+				"http.method": req.Method,
+				"http.route":  m.Path,
+				"http.target": req.URL.RequestURI(),
+			})
+			status := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				span.SetAttributes(tracing.Attributes{"http.status_code": status.status})
+				span.End()
+			}()
+			w, req = status, req.WithContext(ctx)
+		}
+
+		_, build := tracing.Start(req.Context(), "build event", tracing.Internal, nil)
+
 		contentType := req.Header.Get("Content-Type")
 		mediaType, _, err := mime.ParseMediaType(contentType)
 		binaryContent := false
@@ -51,45 +99,123 @@ func (m *ServerlessRouterMount) WrappedHandler() http.HandlerFunc {
 			}
 		}
 
-		if binaryContent {
-			if body, err := ioutil.ReadAll(req.Body); err == nil && !utf8.Valid(body) {
-				req.Body = ioutil.NopCloser(strings.NewReader(base64.StdEncoding.EncodeToString(body)))
-			} else {
-				req.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+		// Bodies that are too large to keep in memory are streamed into the event, and always
+		// base64 encoded when binary, as they can't be checked for valid UTF-8 up front
+		buf := getBuffer()
+		var stream io.Reader
+		if req.Body != nil {
+			body, max := io.Reader(req.Body), opt.maxBufferedBody()
+			if max >= 0 {
+				body = io.LimitReader(req.Body, max+1)
+			}
+			if _, err := buf.ReadFrom(body); err != nil {
+				logging.For(opt.logger(), logging.Router).Errorf("Error reading the request body: %s", err)
+				build.SetError(err)
+				build.End()
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{ "message": "Internal server error" }`))
+				return
+			}
+			if max >= 0 && int64(buf.Len()) > max {
+				stream = io.MultiReader(bytes.NewReader(buf.Bytes()), req.Body)
 			}
 		}
 
-		event, err := NewEvent(req, binaryContent)
-		if err != nil {
-			msg := fmt.Sprintf("Error creating a new event: %s", err)
-			log.Println(msg)
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(`{ "message": "Internal server error" }`))
+		// The function may still be reading a streamed body after the handler returns, so
+		// only the buffers of bodies that were read into the event are reused
+		var event *Event
+		if stream == nil {
+			defer putBuffer(buf)
+
+			body := buf.Bytes()
+			if binaryContent && !utf8.Valid(body) {
+				encoded := getBuffer()
+				defer putBuffer(encoded)
+				encoder := base64.NewEncoder(base64.StdEncoding, encoded)
+				encoder.Write(body)
+				encoder.Close()
+				body = encoded.Bytes()
+			}
+			event = newEvent(req, string(body), binaryContent)
 		} else {
-			m.Handler(w, event)
+			event = newEvent(req, "", binaryContent)
+			event.body = stream
+		}
+
+		// API Gateway gives the resource's template (e.g. /users/{id}), rather than the path
+		event.Resource = m.Path
+		event.RequestContext.ResourcePath = m.Path
+
+		if opt.NewRequestID != nil {
+			event.RequestContext.RequestID = opt.NewRequestID()
+		}
+
+		if opt.Now != nil {
+			now := opt.Now()
+			event.RequestContext.RequestTime = now.Format(requestTimeFormat)
+			event.RequestContext.RequestTimeEpoch = now.UnixNano() / int64(time.Millisecond)
+		}
+
+		if _, ok := event.Headers["X-Amzn-Trace-Id"]; !ok && opt.NewTraceID != nil {
+			traceID := opt.NewTraceID()
+			event.Headers["X-Amzn-Trace-Id"] = traceID
+			event.MultiValueHeaders["X-Amzn-Trace-Id"] = []string{traceID}
 		}
+
+		tracing.SpanFromContext(event.Context()).SetAttributes(tracing.Attributes{"aws.request_id": event.RequestContext.RequestID})
+		build.End()
+
+		m.Handler(w, event)
 	})
 }
 
 // Methods gets an array of HTTP methods from a AWS::Serverless::Function
 // API event source method declaration (which could include 'any')
-func (m *ServerlessRouterMount) Methods() []string {
+func (m *Mount) Methods() []string {
 	switch strings.ToUpper(m.Method) {
 	case "ANY":
-		return HttpMethods
+		return AnyMethods()
 	default:
 		return []string{strings.ToUpper(m.Method)}
 	}
 }
 
-// Returns the mount path adjusted for mux syntax. For example, if the
+// GetMuxPath returns the mount path adjusted for mux syntax. For example, if the
 // SAM template specifies /{proxy+} we replace that with /{proxy:.*}
-func (m *ServerlessRouterMount) GetMuxPath() string {
+func (m *Mount) GetMuxPath() string {
 	outputPath := m.Path
 
 	if strings.Contains(outputPath, "+") {
-		outputPath = strings.Replace(outputPath, "+", ":" + MuxPathRegex, -1)
+		outputPath = strings.Replace(outputPath, "+", ":"+MuxPathRegex, -1)
 	}
 
 	return outputPath
-}
\ No newline at end of file
+}
+
+// statusWriter keeps the status code of a response, for the request's span
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush implements http.Flusher, so that streamed responses are still sent as they're written
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, so that handlers can still drop the connection
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the connection can't be hijacked")
+	}
+	return hijacker.Hijack()
+}
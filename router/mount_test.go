@@ -7,11 +7,11 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("ServerlessRouterMount", func() {
+var _ = Describe("Mount", func() {
 
 	Context("with a method in uppercase", func() {
 
-		m := ServerlessRouterMount{
+		m := Mount{
 			Path:   "/test",
 			Method: "GET",
 		}
@@ -26,7 +26,7 @@ var _ = Describe("ServerlessRouterMount", func() {
 
 	Context("with a method in lowercase", func() {
 
-		m := ServerlessRouterMount{
+		m := Mount{
 			Path:   "/test",
 			Method: "get",
 		}
@@ -41,7 +41,7 @@ var _ = Describe("ServerlessRouterMount", func() {
 
 	Context("with method 'any'", func() {
 
-		m := ServerlessRouterMount{
+		m := Mount{
 			Path:   "/test",
 			Method: "any",
 		}
@@ -58,10 +58,14 @@ var _ = Describe("ServerlessRouterMount", func() {
 			Expect(methods).To(ContainElement("PATCH"))
 		})
 
+		It("should still be listed by the deprecated HttpMethods", func() {
+			Expect(HttpMethods).To(Equal(methods))
+		})
+
 	})
 
 	Context("Catch-all resource path", func() {
-		m := ServerlessRouterMount{
+		m := Mount{
 			Path: "/{proxy+}",
 			Method: "any",
 		}
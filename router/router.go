@@ -2,12 +2,14 @@ package router
 
 import (
 	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/aws-sam-local/tracing"
 	"github.com/awslabs/goformation/cloudformation"
-	"github.com/gorilla/mux"
-	//"github.com/docker/docker/api/types/mount"
 )
 
 // ErrNoEventsFound is thrown if a AWS::Serverless::Function is added to this
@@ -17,19 +19,91 @@ var ErrNoEventsFound = errors.New("no events with type 'Api' were found")
 // ServerlessRouter takes AWS::Serverless::Function and AWS::Serverless::API objects
 // and creates a Go http.Handler with the correct paths/methods mounted
 type ServerlessRouter struct {
-	mux       *mux.Router
-	mounts    []*ServerlessRouterMount
-	usePrefix bool
+	mounts   []*Mount
+	opt      NewServerlessRouterOpt
+	notFound http.Handler
+
+	// index holds the mounts by method and path, so that templates with thousands of
+	// routes are merged quickly
+	index map[string]*Mount
+}
+
+// NewServerlessRouterOpt contains the options that are passed to NewServerlessRouter.
+// The zero value gives a router that behaves like API Gateway.
+type NewServerlessRouterOpt struct {
+
+	// UsePrefix asks for routes to match any path that starts with their path. It's kept
+	// for the --prefix-routing flag, but routes currently always match exactly.
+	UsePrefix bool
+
+	// MissingFunctionHandler handles requests to the routes of an API that no function has
+	// been added for. By default it responds with a 502, as API Gateway does.
+	MissingFunctionHandler EventHandlerFunc
+
+	// NewRequestID generates the request ID of each event. By default it's a random UUID.
+	NewRequestID func() string
+
+	// Now returns the time of each event, for its requestContext. By default it's the
+	// current time.
+	Now func() time.Time
+
+	// NewTraceID generates the X-Amzn-Trace-Id header of events whose requests don't have
+	// one, as API Gateway does when tracing is enabled. By default events don't get one.
+	NewTraceID func() string
+
+	// Tracer records spans of each request, of building its event, and (through the
+	// event's context) of invoking its function. By default requests aren't traced.
+	Tracer *tracing.Tracer
+
+	// MaxBufferedBody is the size in bytes of the largest request body that's read into
+	// memory. Larger bodies are streamed to the function instead (see Event.Streamed).
+	// If 0, DefaultMaxBufferedBody is used, and if negative, bodies are always read.
+	MaxBufferedBody int64
+
+	// Log receives the router's log entries, such as problems with the API definitions
+	// and requests. If nil, ErrorLog is used.
+	Log logging.Logger
+
+	// ErrorLog logs problems with the API definitions and requests. If nil, the log
+	// package's standard logger is used.
+	//
+	// Deprecated: use Log, which has levels and fields.
+	ErrorLog *log.Logger
+}
+
+// DefaultMaxBufferedBody is the default NewServerlessRouterOpt.MaxBufferedBody, which is the
+// size of the largest payload that AWS Lambda accepts for synchronous invocations
+const DefaultMaxBufferedBody = 6 * 1024 * 1024
+
+// maxBufferedBody returns the MaxBufferedBody of the options, or the default
+func (opt NewServerlessRouterOpt) maxBufferedBody() int64 {
+	if opt.MaxBufferedBody == 0 {
+		return DefaultMaxBufferedBody
+	}
+	return opt.MaxBufferedBody
 }
 
-// NewServerlessRouter creates a new instance of ServerlessRouter.
-// If usePrefix is true then route matching is done using prefix instead of exact match
-func NewServerlessRouter(usePrefix bool) *ServerlessRouter {
+// logger returns the Logger of the options
+func (opt NewServerlessRouterOpt) logger() logging.Logger {
+	if opt.Log != nil {
+		return opt.Log
+	}
+	return logging.Std(opt.ErrorLog)
+}
+
+// NewServerlessRouter creates a new instance of ServerlessRouter
+func NewServerlessRouter(opt NewServerlessRouterOpt) *ServerlessRouter {
+
+	if opt.MissingFunctionHandler == nil {
+		opt.MissingFunctionHandler = missingFunctionHandler
+	}
+
 	return &ServerlessRouter{
-		mux:       mux.NewRouter(),
-		mounts:    []*ServerlessRouterMount{},
-		usePrefix: usePrefix,
+		mounts: []*Mount{},
+		opt:    opt,
+		index:  map[string]*Mount{},
 	}
+
 }
 
 // AddFunction adds a AWS::Serverless::Function to the router and mounts all of it's
@@ -37,7 +111,7 @@ func NewServerlessRouter(usePrefix bool) *ServerlessRouter {
 func (r *ServerlessRouter) AddFunction(f *cloudformation.AWSServerlessFunction, handler EventHandlerFunc) error {
 
 	// Wrap GoFormation's AWS::Serverless::Function definition in our own, which provides
-	// convenience methods for extracting the ServerlessRouterMount(s) from it.
+	// convenience methods for extracting the Mount(s) from it.
 	function := &AWSServerlessFunction{f, handler}
 	mounts, err := function.Mounts()
 	if err != nil {
@@ -48,13 +122,7 @@ func (r *ServerlessRouter) AddFunction(f *cloudformation.AWSServerlessFunction,
 		return ErrNoEventsFound
 	}
 
-	//r.mounts = append(r.mounts, mounts...)
-	err = r.mergeMounts(mounts)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return r.mergeMounts(mounts)
 
 }
 
@@ -62,75 +130,78 @@ func (r *ServerlessRouter) AddFunction(f *cloudformation.AWSServerlessFunction,
 func (r *ServerlessRouter) AddAPI(a *cloudformation.AWSServerlessApi) error {
 
 	// Wrap GoFormation's AWS::Serverless::Api definition in our own, which provides
-	// convenience methods for extracting the ServerlessRouterMount(s) from it.
-	api := &AWSServerlessApi{a}
+	// convenience methods for extracting the Mount(s) from it.
+	api := &AWSServerlessApi{AWSServerlessApi: a, Log: r.opt.logger()}
 	mounts, err := api.Mounts()
 	if err != nil {
 		return err
 	}
 
-	//r.mounts = append(r.mounts, mounts...)
-	err = r.mergeMounts(mounts)
-	if err != nil {
-		return err
-	}
+	return r.mergeMounts(mounts)
 
-	return nil
 }
 
 // merges the various mount paths. mounts could be coming from a function as well as API
 // definition. Mounts defined by an API do not have a handler, only a function ARN.
-func (r *ServerlessRouter) mergeMounts(newMounts []*ServerlessRouterMount) error {
+func (r *ServerlessRouter) mergeMounts(newMounts []*Mount) error {
 	for _, newMount := range newMounts {
-		newMountExists := false
-
-		for _, existingMount := range r.mounts {
-			if newMount.Path == existingMount.Path && strings.ToLower(newMount.Method) == strings.ToLower(existingMount.Method) {
-				newMountExists = true
-				// if the new mount has a valid handler I override the existing one anyway
-				if newMount.Handler != nil {
-					existingMount.Handler = newMount.Handler
-					existingMount.Function = newMount.Function
-				}
+		key := mountKey(newMount.Path, newMount.Method)
+
+		if existingMount, ok := r.index[key]; ok {
+			// if the new mount has a valid handler I override the existing one anyway
+			if newMount.Handler != nil {
+				existingMount.Handler = newMount.Handler
+				existingMount.Function = newMount.Function
 			}
+			continue
 		}
 
-		if !newMountExists {
-			if newMount.Handler == nil {
-				newMount.Handler = r.missingFunctionHandler()
-			}
-			r.mounts = append(r.mounts, newMount)
+		if newMount.Handler == nil {
+			newMount.Handler = r.opt.MissingFunctionHandler
 		}
+		r.mounts = append(r.mounts, newMount)
+		r.index[key] = newMount
 	}
 	return nil
 }
 
 // AddStaticDir mounts a static directory provided, at the mount point also provided
 func (r *ServerlessRouter) AddStaticDir(dirname string) {
-	r.mux.NotFoundHandler = http.FileServer(http.Dir(dirname))
+	r.notFound = http.FileServer(http.Dir(dirname))
 }
 
 // Router returns the Go http.Handler for the router, to be passed to http.ListenAndServe()
 func (r *ServerlessRouter) Router() http.Handler {
 
 	// Mount all of the things!
+	tree := newRouteTree(r.notFound)
 	for _, mount := range r.Mounts() {
-		r.mux.Handle(mount.GetMuxPath(), mount.WrappedHandler()).Methods(mount.Methods()...)
+		tree.add(mount.Path, mount.Methods(), strings.ToUpper(mount.Method) == "ANY", mount.wrappedHandler(r.opt))
 	}
 
-	return r.mux
+	return tree
 
 }
 
 // Mounts returns a list of the mounts associated with this router
-func (r *ServerlessRouter) Mounts() []*ServerlessRouterMount {
+func (r *ServerlessRouter) Mounts() []*Mount {
 	return r.mounts
 }
 
-func (r *ServerlessRouter) missingFunctionHandler() func(http.ResponseWriter, *Event) {
-	return func(w http.ResponseWriter, event *Event) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadGateway)
-		w.Write([]byte(`{ "message": "No function defined for resource method" }`))
-	}
+// HasMount returns whether a path and method have been mounted on the router
+func (r *ServerlessRouter) HasMount(path string, method string) bool {
+	_, ok := r.index[mountKey(path, method)]
+	return ok
+}
+
+// mountKey is the key of a mount in the router's index
+func mountKey(path string, method string) string {
+	return strings.ToLower(method) + " " + path
+}
+
+// missingFunctionHandler responds to requests for routes without a function
+func missingFunctionHandler(w http.ResponseWriter, event *Event) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadGateway)
+	w.Write([]byte(`{ "message": "No function defined for resource method" }`))
 }
@@ -6,7 +6,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/aws-sam-local/tracing"
 	"github.com/awslabs/goformation"
 	"github.com/awslabs/goformation/cloudformation"
 
@@ -21,7 +24,7 @@ var _ = Describe("ServerlessRouter", func() {
 
 			Expect(err).To(BeNil())
 			Expect(len(template.Resources)).To(Equal(2))
-			mux := NewServerlessRouter(false)
+			mux := NewServerlessRouter(NewServerlessRouterOpt{})
 			templateApis := template.GetAllAWSServerlessApiResources()
 
 			Expect(len(templateApis)).To(Equal(1))
@@ -41,6 +44,255 @@ var _ = Describe("ServerlessRouter", func() {
 			mux.Router().ServeHTTP(rr, req)
 			Expect(rr.Code).To(Equal(http.StatusBadGateway))
 		})
+
+		It("uses the MissingFunctionHandler option", func() {
+			template, err := goformation.Open("../test/templates/api-missing-method.yaml")
+			Expect(err).To(BeNil())
+
+			mux := NewServerlessRouter(NewServerlessRouterOpt{
+				MissingFunctionHandler: func(w http.ResponseWriter, e *Event) {
+					w.WriteHeader(http.StatusNotImplemented)
+				},
+			})
+			for _, api := range template.GetAllAWSServerlessApiResources() {
+				Expect(mux.AddAPI(&api)).To(BeNil())
+			}
+
+			req, _ := http.NewRequest("GET", "/badget", nil)
+			rr := httptest.NewRecorder()
+			mux.Router().ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(http.StatusNotImplemented))
+		})
+	})
+
+	Context("with the NewRequestID option", func() {
+		It("sets the request ID of each event", func() {
+			mux := NewServerlessRouter(NewServerlessRouterOpt{
+				NewRequestID: func() string { return "request-1" },
+			})
+
+			var requestID string
+			mux.AddFunction(&cloudformation.AWSServerlessFunction{
+				Events: map[string]cloudformation.AWSServerlessFunction_EventSource{
+					"GetRequest": {
+						Type: "Api",
+						Properties: &cloudformation.AWSServerlessFunction_Properties{
+							ApiEvent: &cloudformation.AWSServerlessFunction_ApiEvent{
+								Path:   "/get",
+								Method: "get",
+							},
+						},
+					},
+				},
+			}, func(w http.ResponseWriter, e *Event) {
+				requestID = e.RequestContext.RequestID
+			})
+
+			req, _ := http.NewRequest("GET", "/get", nil)
+			mux.Router().ServeHTTP(httptest.NewRecorder(), req)
+			Expect(requestID).To(Equal("request-1"))
+		})
+	})
+
+	It("sets the resource of each event to the template of its path", func() {
+		mux := NewServerlessRouter(NewServerlessRouterOpt{})
+
+		var event *Event
+		mux.AddFunction(&cloudformation.AWSServerlessFunction{
+			Events: map[string]cloudformation.AWSServerlessFunction_EventSource{
+				"GetUser": {
+					Type: "Api",
+					Properties: &cloudformation.AWSServerlessFunction_Properties{
+						ApiEvent: &cloudformation.AWSServerlessFunction_ApiEvent{
+							Path:   "/users/{id}",
+							Method: "get",
+						},
+					},
+				},
+			},
+		}, func(w http.ResponseWriter, e *Event) {
+			event = e
+		})
+
+		req, _ := http.NewRequest("GET", "/users/42", nil)
+		mux.Router().ServeHTTP(httptest.NewRecorder(), req)
+		Expect(event.Path).To(Equal("/users/42"))
+		Expect(event.Resource).To(Equal("/users/{id}"))
+		Expect(event.RequestContext.ResourcePath).To(Equal("/users/{id}"))
+		Expect(event.RequestContext.Path).To(Equal("/users/42"))
+		Expect(event.V2().RouteKey).To(Equal("GET /users/{id}"))
+	})
+
+	Context("with the Now and NewTraceID options", func() {
+
+		var event *Event
+		var mux *ServerlessRouter
+
+		BeforeEach(func() {
+			mux = NewServerlessRouter(NewServerlessRouterOpt{
+				Now:        func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) },
+				NewTraceID: func() string { return "Root=1-5e0be100-000000000000000000000001" },
+			})
+			mux.AddFunction(&cloudformation.AWSServerlessFunction{
+				Events: map[string]cloudformation.AWSServerlessFunction_EventSource{
+					"GetRequest": {
+						Type: "Api",
+						Properties: &cloudformation.AWSServerlessFunction_Properties{
+							ApiEvent: &cloudformation.AWSServerlessFunction_ApiEvent{
+								Path:   "/get",
+								Method: "get",
+							},
+						},
+					},
+				},
+			}, func(w http.ResponseWriter, e *Event) {
+				event = e
+			})
+		})
+
+		It("sets the time and trace ID of each event", func() {
+			req, _ := http.NewRequest("GET", "/get", nil)
+			mux.Router().ServeHTTP(httptest.NewRecorder(), req)
+			Expect(event.RequestContext.RequestTime).To(Equal("01/Jan/2020:00:00:00 +0000"))
+			Expect(event.RequestContext.RequestTimeEpoch).To(Equal(int64(1577836800000)))
+			Expect(event.Headers["X-Amzn-Trace-Id"]).To(Equal("Root=1-5e0be100-000000000000000000000001"))
+			Expect(event.MultiValueHeaders["X-Amzn-Trace-Id"]).To(Equal([]string{"Root=1-5e0be100-000000000000000000000001"}))
+		})
+
+		It("keeps the trace ID of requests that have one", func() {
+			req, _ := http.NewRequest("GET", "/get", nil)
+			req.Header.Set("X-Amzn-Trace-Id", "Root=1-00000000-client")
+			mux.Router().ServeHTTP(httptest.NewRecorder(), req)
+			Expect(event.Headers["X-Amzn-Trace-Id"]).To(Equal("Root=1-00000000-client"))
+		})
+
+	})
+
+	Context("with the Tracer option", func() {
+
+		It("records spans of each request and of building its event", func() {
+			spans := []*tracing.Span{}
+			mux := NewServerlessRouter(NewServerlessRouterOpt{
+				NewRequestID: func() string { return "request-1" },
+				Tracer: tracing.NewTracer(tracing.ExporterFunc(func(span *tracing.Span) {
+					spans = append(spans, span)
+				})),
+			})
+			mux.AddFunction(&cloudformation.AWSServerlessFunction{
+				Events: map[string]cloudformation.AWSServerlessFunction_EventSource{
+					"GetUser": {
+						Type: "Api",
+						Properties: &cloudformation.AWSServerlessFunction_Properties{
+							ApiEvent: &cloudformation.AWSServerlessFunction_ApiEvent{
+								Path:   "/users/{id}",
+								Method: "get",
+							},
+						},
+					},
+				},
+			}, func(w http.ResponseWriter, e *Event) {
+				_, invoke := tracing.Start(e.Context(), "invoke", tracing.Client, nil)
+				invoke.End()
+				w.WriteHeader(http.StatusNotFound)
+			})
+
+			req, _ := http.NewRequest("GET", "/users/1?expand=true", nil)
+			mux.Router().ServeHTTP(httptest.NewRecorder(), req)
+
+			Expect(spans).To(HaveLen(3))
+			build, invoke, request := spans[0], spans[1], spans[2]
+
+			Expect(request.Name).To(Equal("GET /users/{id}"))
+			Expect(request.Kind).To(Equal(tracing.Server))
+			Expect(request.Attributes).To(Equal(tracing.Attributes{
+				"http.method":      "GET",
+				"http.route":       "/users/{id}",
+				"http.target":      "/users/1?expand=true",
+				"http.status_code": http.StatusNotFound,
+				"aws.request_id":   "request-1",
+			}))
+
+			Expect(build.Name).To(Equal("build event"))
+			Expect(build.ParentID).To(Equal(request.SpanID))
+			Expect(invoke.ParentID).To(Equal(request.SpanID))
+			Expect(invoke.TraceID).To(Equal(request.TraceID))
+		})
+
+		It("lets handlers flush responses and drop connections", func() {
+			mux := NewServerlessRouter(NewServerlessRouterOpt{
+				Tracer: tracing.NewTracer(tracing.ExporterFunc(func(span *tracing.Span) {})),
+			})
+			mux.AddFunction(&cloudformation.AWSServerlessFunction{
+				Events: map[string]cloudformation.AWSServerlessFunction_EventSource{
+					"Stream": {
+						Type: "Api",
+						Properties: &cloudformation.AWSServerlessFunction_Properties{
+							ApiEvent: &cloudformation.AWSServerlessFunction_ApiEvent{
+								Path:   "/{action}",
+								Method: "get",
+							},
+						},
+					},
+				},
+			}, func(w http.ResponseWriter, e *Event) {
+				if e.Path == "/drop" {
+					conn, _, err := w.(http.Hijacker).Hijack()
+					Expect(err).To(BeNil())
+					conn.Close()
+					return
+				}
+				w.(http.Flusher).Flush()
+			})
+
+			server := httptest.NewServer(mux.Router())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL + "/flush")
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+
+			_, err = http.Get(server.URL + "/drop")
+			Expect(err).NotTo(BeNil())
+		})
+
+	})
+
+	Context("with the Log option", func() {
+		It("logs problems with the API definition as the router subsystem", func() {
+			template, _ := goformation.ParseJSON([]byte(`{
+				"Resources": {
+					"MyApi": {
+						"Type": "AWS::Serverless::Api",
+						"Properties": {
+							"DefinitionBody": { "swagger": "2.0", "paths": { "/get": { "get": { "x-amazon-apigateway-integration": { "type": "aws_proxy", "uri": "not-an-arn" } } } } }
+						}
+					}
+				}
+			}`))
+
+			var messages []string
+			var subsystems []interface{}
+			mux := NewServerlessRouter(NewServerlessRouterOpt{
+				Log: logging.LoggerFunc(func(level logging.Level, msg string, fields logging.Fields) {
+					messages = append(messages, msg)
+					subsystems = append(subsystems, fields[logging.SubsystemField])
+				}),
+			})
+
+			for _, api := range template.GetAllAWSServerlessApiResources() {
+				mux.AddAPI(&api)
+			}
+			Expect(messages).To(ContainElement(ContainSubstring("Could not extract Lambda function ARN")))
+			Expect(subsystems).To(ConsistOf(logging.Router))
+		})
+	})
+
+	Context("AnyMethods", func() {
+		It("returns a copy of the methods", func() {
+			methods := AnyMethods()
+			methods[0] = "FOO"
+			Expect(AnyMethods()).ToNot(ContainElement("FOO"))
+		})
 	})
 
 	Context("with SAM template and x-amazon-apigateway-binary-media-types defined in it", func() {
@@ -90,7 +342,7 @@ var _ = Describe("ServerlessRouter", func() {
 		templateApis := template.GetAllAWSServerlessApiResources()
 
 		It("returns the base64 encoded body on a binary request", func() {
-			mux := NewServerlessRouter(false)
+			mux := NewServerlessRouter(NewServerlessRouterOpt{})
 
 			for _, api := range templateApis {
 				err := mux.AddAPI(&api)
@@ -109,7 +361,7 @@ var _ = Describe("ServerlessRouter", func() {
 		})
 
 		It("returns the text body on a text request", func() {
-			mux := NewServerlessRouter(false)
+			mux := NewServerlessRouter(NewServerlessRouterOpt{})
 
 			for _, api := range templateApis {
 				err := mux.AddAPI(&api)
@@ -0,0 +1,191 @@
+package router
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// routeTree routes requests to the handlers of mounts. It's a radix tree of path segments,
+// whose nodes keep their static children in a map and the routes mounted on them by HTTP
+// method. Matching a request takes a few map lookups per segment of its path, however many
+// routes the API has, where gorilla/mux tries the regular expression of every route in turn.
+type routeTree struct {
+	root     *routeNode
+	notFound http.Handler
+}
+
+// routeNode is a path segment in a routeTree
+type routeNode struct {
+	static map[string]*routeNode
+
+	// param matches any segment (e.g. /{id}) and greedy any number of them (e.g. /{proxy+})
+	param  *routeNode
+	greedy *routeNode
+
+	// routes are the routes that end at this node, by HTTP method
+	routes map[string]*route
+}
+
+// route is a handler mounted on a path of the tree
+type route struct {
+	handler http.Handler
+
+	// params are the names of the path's parameters, in the order they appear in it
+	params []string
+
+	// any is whether the route was mounted for the 'any' method, so that routes for the
+	// methods themselves take precedence, as they do in API Gateway
+	any bool
+}
+
+func newRouteNode() *routeNode {
+	return &routeNode{static: map[string]*routeNode{}, routes: map[string]*route{}}
+}
+
+// newRouteTree returns an empty tree, which responds to requests that don't match any of
+// its routes with notFound (or a 404 if it's nil)
+func newRouteTree(notFound http.Handler) *routeTree {
+	if notFound == nil {
+		notFound = http.NotFoundHandler()
+	}
+	return &routeTree{root: newRouteNode(), notFound: notFound}
+}
+
+// add mounts a handler on a path with API Gateway syntax, e.g. /pets/{id} or /{proxy+}
+func (t *routeTree) add(mountPath string, methods []string, any bool, handler http.Handler) {
+
+	node := t.root
+	params := []string{}
+
+	for _, segment := range splitPath(mountPath) {
+		switch {
+
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "+}"):
+			if node.greedy == nil {
+				node.greedy = newRouteNode()
+			}
+			node = node.greedy
+			params = append(params, segment[1:len(segment)-2])
+
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			if node.param == nil {
+				node.param = newRouteNode()
+			}
+			node = node.param
+			params = append(params, segment[1:len(segment)-1])
+
+		default:
+			child, ok := node.static[segment]
+			if !ok {
+				child = newRouteNode()
+				node.static[segment] = child
+			}
+			node = child
+		}
+	}
+
+	r := &route{handler: handler, params: params, any: any}
+	for _, method := range methods {
+		if existing, ok := node.routes[method]; ok && (any || !existing.any) {
+			// the first route mounted for a method wins, as it did with gorilla/mux
+			continue
+		}
+		node.routes[method] = r
+	}
+
+}
+
+// splitPath splits a path into its segments
+func splitPath(p string) []string {
+	return strings.Split(strings.TrimPrefix(p, "/"), "/")
+}
+
+// match finds the route for the method and the remaining segments of a path, and the values
+// of its parameters. Static segments take precedence over parameters, and parameters over
+// greedy ones.
+func (n *routeNode) match(method string, segments []string, values []string) (*route, []string) {
+
+	if len(segments) == 0 {
+		if r, ok := n.routes[method]; ok {
+			return r, values
+		}
+		return nil, nil
+	}
+
+	if child, ok := n.static[segments[0]]; ok {
+		if r, v := child.match(method, segments[1:], values); r != nil {
+			return r, v
+		}
+	}
+
+	if n.param != nil && segments[0] != "" {
+		if r, v := n.param.match(method, segments[1:], append(values, segments[0])); r != nil {
+			return r, v
+		}
+	}
+
+	if n.greedy != nil {
+		// try the longest match first, as the greedy regular expression did
+		for i := len(segments); i > 0; i-- {
+			value := strings.Join(segments[:i], "/")
+			if value == "" {
+				continue
+			}
+			if r, v := n.greedy.match(method, segments[i:], append(values, value)); r != nil {
+				return r, v
+			}
+		}
+	}
+
+	return nil, nil
+
+}
+
+// pathParametersKey is the context key of the path parameters of requests
+type pathParametersKey struct{}
+
+// ServeHTTP implements http.Handler
+func (t *routeTree) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	// Redirect to the canonical form of paths, like gorilla/mux
+	if p := cleanPath(req.URL.Path); p != req.URL.Path {
+		url := *req.URL
+		url.Path = p
+		w.Header().Set("Location", url.String())
+		w.WriteHeader(http.StatusMovedPermanently)
+		return
+	}
+
+	r, values := t.root.match(req.Method, splitPath(req.URL.Path), nil)
+	if r == nil {
+		t.notFound.ServeHTTP(w, req)
+		return
+	}
+
+	params := map[string]string{}
+	for i, name := range r.params {
+		params[name] = values[i]
+	}
+
+	r.handler.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), pathParametersKey{}, params)))
+
+}
+
+// cleanPath returns the canonical path for p, eliminating . and .. elements
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	// path.Clean removes trailing slashes, except for the root
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
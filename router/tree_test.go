@@ -0,0 +1,99 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("routeTree", func() {
+
+	// handler responds with its name and the path parameters of the request
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(w, "%s %v", name, pathParameters(req))
+		})
+	}
+
+	serve := func(tree *routeTree, method string, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		rr := httptest.NewRecorder()
+		tree.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var tree *routeTree
+
+	BeforeEach(func() {
+		tree = newRouteTree(nil)
+		tree.add("/pets", []string{"GET"}, false, handler("list"))
+		tree.add("/pets/new", []string{"POST"}, false, handler("new"))
+		tree.add("/pets/{id}", []string{"GET"}, false, handler("get"))
+		tree.add("/pets/{id}/toys/{toy}", []string{"GET"}, false, handler("toy"))
+		tree.add("/files/{proxy+}", AnyMethods(), true, handler("files"))
+		tree.add("/files/{proxy+}", []string{"DELETE"}, false, handler("delete"))
+	})
+
+	It("matches static paths", func() {
+		Expect(serve(tree, "GET", "/pets").Body.String()).To(Equal("list map[]"))
+		Expect(serve(tree, "POST", "/pets/new").Body.String()).To(Equal("new map[]"))
+	})
+
+	It("matches path parameters", func() {
+		Expect(serve(tree, "GET", "/pets/42").Body.String()).To(Equal("get map[id:42]"))
+		Expect(serve(tree, "GET", "/pets/42/toys/ball").Body.String()).To(Equal("toy map[id:42 toy:ball]"))
+	})
+
+	It("falls back to parameters when the static path doesn't have the method", func() {
+		Expect(serve(tree, "GET", "/pets/new").Body.String()).To(Equal("get map[id:new]"))
+	})
+
+	It("matches greedy path parameters", func() {
+		Expect(serve(tree, "PUT", "/files/a/b/c.txt").Body.String()).To(Equal("files map[proxy:a/b/c.txt]"))
+	})
+
+	It("prefers routes for a method to routes for any method", func() {
+		Expect(serve(tree, "DELETE", "/files/a").Body.String()).To(Equal("delete map[proxy:a]"))
+	})
+
+	It("responds with a 404 to requests that don't match a route", func() {
+		Expect(serve(tree, "GET", "/files").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(tree, "GET", "/pets/").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(tree, "DELETE", "/pets").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(tree, "TRACE", "/files/a").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("redirects to the canonical form of paths", func() {
+		rr := serve(tree, "GET", "/pets/../pets?page=2")
+		Expect(rr.Code).To(Equal(http.StatusMovedPermanently))
+		Expect(rr.Header().Get("Location")).To(Equal("/pets?page=2"))
+	})
+
+	It("routes templates with thousands of routes", func() {
+		r := NewServerlessRouter(NewServerlessRouterOpt{})
+		mounts := []*Mount{}
+		for i := 0; i < 5000; i++ {
+			mounts = append(mounts, &Mount{
+				Path:   fmt.Sprintf("/resource%d/{id}", i),
+				Method: "get",
+				Handler: func(i int) EventHandlerFunc {
+					return func(w http.ResponseWriter, e *Event) {
+						fmt.Fprintf(w, "%d %s", i, e.PathParameters["id"])
+					}
+				}(i),
+			})
+		}
+		Expect(r.mergeMounts(mounts)).To(BeNil())
+		Expect(r.mergeMounts(mounts[:10])).To(BeNil())
+		Expect(r.Mounts()).To(HaveLen(5000))
+
+		req, _ := http.NewRequest("GET", "/resource4321/abc", nil)
+		rr := httptest.NewRecorder()
+		r.Router().ServeHTTP(rr, req)
+		Expect(rr.Body.String()).To(Equal("4321 abc"))
+	})
+
+})
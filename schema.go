@@ -0,0 +1,393 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// eventSchema is a JSON Schema that the events passed to a function must match.
+//
+// Only the commonly used validation keywords are supported: type, enum, const,
+// properties, required, additionalProperties, items, minItems, maxItems,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, minLength, maxLength,
+// pattern, allOf, anyOf, oneOf, not and local $refs (e.g. #/definitions/Order).
+// Other keywords (such as format) are ignored.
+type eventSchema struct {
+	root interface{}
+
+	// resolving is the $refs being resolved at each path, during one validation, which
+	// catches $refs that refer back to themselves without going deeper into the value
+	resolving map[string]bool
+}
+
+// loadEventSchema reads a JSON Schema from a file
+func loadEventSchema(filename string) (*eventSchema, error) {
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema %s: %s", filename, err)
+	}
+
+	return &eventSchema{root: root}, nil
+
+}
+
+// getEventSchemaFilename returns the JSON Schema file for a function's events.
+// The --event-schema flag takes priority over the EventSchema set in the function's
+// Metadata, which is relative to the template:
+//
+//	Metadata:
+//	  SamLocal:
+//	    EventSchema: schemas/order.json
+func getEventSchemaFilename(template *cloudformation.Template, name string, flag string, dir string) string {
+
+	if flag != "" {
+		return flag
+	}
+
+	if samLocal, ok := getResourceMetadata(template, name)["SamLocal"].(map[string]interface{}); ok {
+		if schema, ok := samLocal["EventSchema"].(string); ok && schema != "" {
+			return filepath.Join(dir, schema)
+		}
+	}
+
+	return ""
+
+}
+
+// Validate checks an event against the schema, and returns every validation error found
+func (s *eventSchema) Validate(event []byte) []string {
+
+	var value interface{}
+	if err := json.Unmarshal(event, &value); err != nil {
+		return []string{fmt.Sprintf("$: invalid JSON: %s", err)}
+	}
+
+	return s.check(value, s.root)
+
+}
+
+// check validates a value against a (sub) schema, from the root of the value
+func (s *eventSchema) check(value interface{}, schema interface{}) []string {
+	validation := &eventSchema{root: s.root, resolving: map[string]bool{}}
+	return validation.validate(value, schema, "$")
+}
+
+// validate checks a value against a (sub) schema
+func (s *eventSchema) validate(value interface{}, schema interface{}, path string) []string {
+
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			return []string{path + ": no value is allowed here"}
+		}
+		return nil
+	case map[string]interface{}:
+		return s.validateObject(value, schema, path)
+	}
+
+	return nil
+
+}
+
+func (s *eventSchema) validateObject(value interface{}, schema map[string]interface{}, path string) []string {
+
+	if ref, ok := schema["$ref"].(string); ok {
+		key := path + " " + ref
+		if s.resolving[key] {
+			return []string{fmt.Sprintf("%s: $ref '%s' refers to itself", path, ref)}
+		}
+		resolved, err := s.resolve(ref)
+		if err != nil {
+			return []string{path + ": " + err.Error()}
+		}
+		s.resolving[key] = true
+		defer delete(s.resolving, key)
+		return s.validate(value, resolved, path)
+	}
+
+	errs := []string{}
+
+	if expected, ok := schema["type"]; ok && !matchesType(value, expected) {
+		return append(errs, fmt.Sprintf("%s: expected %s, got %s", path, describeType(expected), jsonType(value)))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(value, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %s is not one of %s", path, toJSON(value), toJSON(enum)))
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(value, constant) {
+		errs = append(errs, fmt.Sprintf("%s: expected %s, got %s", path, toJSON(constant), toJSON(value)))
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		errs = append(errs, s.validateProperties(value, schema, path)...)
+	case []interface{}:
+		errs = append(errs, s.validateItems(value, schema, path)...)
+	case string:
+		errs = append(errs, validateString(value, schema, path)...)
+	case float64:
+		errs = append(errs, validateNumber(value, schema, path)...)
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, s.validate(value, sub, path)...)
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		if s.countMatches(value, anyOf, path) == 0 {
+			errs = append(errs, path+": doesn't match any of the schemas in anyOf")
+		}
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if matches := s.countMatches(value, oneOf, path); matches != 1 {
+			errs = append(errs, fmt.Sprintf("%s: matches %d of the schemas in oneOf (expected exactly 1)", path, matches))
+		}
+	}
+
+	if not, ok := schema["not"]; ok && len(s.validate(value, not, path)) == 0 {
+		errs = append(errs, path+": must not match the schema in not")
+	}
+
+	return errs
+
+}
+
+func (s *eventSchema) validateProperties(value map[string]interface{}, schema map[string]interface{}, path string) []string {
+
+	errs := []string{}
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, found := value[name]; !found {
+					errs = append(errs, fmt.Sprintf("%s: missing required property '%s'", path, name))
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+
+	// Validate properties in order, so the errors are always reported in the same order
+	names := []string{}
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if sub, ok := properties[name]; ok {
+			errs = append(errs, s.validate(value[name], sub, path+"."+name)...)
+		} else if hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				errs = append(errs, fmt.Sprintf("%s: property '%s' is not allowed", path, name))
+			} else {
+				errs = append(errs, s.validate(value[name], additional, path+"."+name)...)
+			}
+		}
+	}
+
+	return errs
+
+}
+
+func (s *eventSchema) validateItems(value []interface{}, schema map[string]interface{}, path string) []string {
+
+	errs := []string{}
+
+	if min, ok := schema["minItems"].(float64); ok && float64(len(value)) < min {
+		errs = append(errs, fmt.Sprintf("%s: expected at least %v items, got %d", path, min, len(value)))
+	}
+
+	if max, ok := schema["maxItems"].(float64); ok && float64(len(value)) > max {
+		errs = append(errs, fmt.Sprintf("%s: expected at most %v items, got %d", path, max, len(value)))
+	}
+
+	if items, ok := schema["items"]; ok {
+		for i, item := range value {
+			errs = append(errs, s.validate(item, items, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return errs
+
+}
+
+func validateString(value string, schema map[string]interface{}, path string) []string {
+
+	errs := []string{}
+	length := float64(utf8.RuneCountInString(value))
+
+	if min, ok := schema["minLength"].(float64); ok && length < min {
+		errs = append(errs, fmt.Sprintf("%s: expected at least %v characters, got %v", path, min, length))
+	}
+
+	if max, ok := schema["maxLength"].(float64); ok && length > max {
+		errs = append(errs, fmt.Sprintf("%s: expected at most %v characters, got %v", path, max, length))
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: invalid pattern '%s' in schema: %s", path, pattern, err))
+		} else if !re.MatchString(value) {
+			errs = append(errs, fmt.Sprintf("%s: %s doesn't match the pattern '%s'", path, toJSON(value), pattern))
+		}
+	}
+
+	return errs
+
+}
+
+func validateNumber(value float64, schema map[string]interface{}, path string) []string {
+
+	errs := []string{}
+
+	if min, ok := schema["minimum"].(float64); ok && value < min {
+		errs = append(errs, fmt.Sprintf("%s: %v is less than the minimum of %v", path, value, min))
+	}
+
+	if max, ok := schema["maximum"].(float64); ok && value > max {
+		errs = append(errs, fmt.Sprintf("%s: %v is greater than the maximum of %v", path, value, max))
+	}
+
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && value <= min {
+		errs = append(errs, fmt.Sprintf("%s: %v must be greater than %v", path, value, min))
+	}
+
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && value >= max {
+		errs = append(errs, fmt.Sprintf("%s: %v must be less than %v", path, value, max))
+	}
+
+	return errs
+
+}
+
+// countMatches returns how many of the schemas the value matches
+func (s *eventSchema) countMatches(value interface{}, schemas []interface{}, path string) int {
+	matches := 0
+	for _, sub := range schemas {
+		if len(s.validate(value, sub, path)) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// resolve finds the schema referenced by a local $ref (e.g. #/definitions/Order)
+func (s *eventSchema) resolve(ref string) (interface{}, error) {
+
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only local $refs are supported (got '%s')", ref)
+	}
+
+	current := s.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.Replace(strings.Replace(part, "~1", "/", -1), "~0", "~", -1)
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("could not resolve $ref '%s'", ref)
+		}
+		if current, ok = object[part]; !ok {
+			return nil, fmt.Errorf("could not resolve $ref '%s'", ref)
+		}
+	}
+
+	return current, nil
+
+}
+
+// matchesType checks a value against the type keyword, which is either a type or a list of types
+func matchesType(value interface{}, expected interface{}) bool {
+
+	types := []interface{}{expected}
+	if list, ok := expected.([]interface{}); ok {
+		types = list
+	}
+
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		// Integers are numbers too
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// describeType formats the type keyword for validation errors
+func describeType(expected interface{}) string {
+	if list, ok := expected.([]interface{}); ok {
+		names := []string{}
+		for _, t := range list {
+			names = append(names, fmt.Sprintf("%v", t))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprintf("%v", expected)
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func toJSON(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
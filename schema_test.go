@@ -0,0 +1,103 @@
+package main
+
+import (
+	"github.com/awslabs/goformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Event schemas", func() {
+
+	schema := &eventSchema{}
+
+	BeforeEach(func() {
+		schema.root = map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"orderId", "items"},
+			"properties": map[string]interface{}{
+				"orderId": map[string]interface{}{"type": "string", "pattern": "^ord-[0-9]+$"},
+				"status":  map[string]interface{}{"enum": []interface{}{"new", "paid"}},
+				"items": map[string]interface{}{
+					"type":     "array",
+					"minItems": 1.0,
+					"items":    map[string]interface{}{"$ref": "#/definitions/item"},
+				},
+			},
+			"additionalProperties": false,
+			"definitions": map[string]interface{}{
+				"item": map[string]interface{}{
+					"type":     "object",
+					"required": []interface{}{"quantity"},
+					"properties": map[string]interface{}{
+						"quantity": map[string]interface{}{"type": "integer", "minimum": 1.0},
+					},
+				},
+			},
+		}
+	})
+
+	It("accepts valid events", func() {
+		Expect(schema.Validate([]byte(`{"orderId": "ord-1", "status": "paid", "items": [{"quantity": 2}]}`))).To(BeEmpty())
+	})
+
+	It("reports every error with its path", func() {
+		errs := schema.Validate([]byte(`{"orderId": "1", "status": "lost", "items": [{"quantity": 0}, {}], "extra": true}`))
+		Expect(errs).To(Equal([]string{
+			"$: property 'extra' is not allowed",
+			"$.items[0].quantity: 0 is less than the minimum of 1",
+			"$.items[1]: missing required property 'quantity'",
+			`$.orderId: "1" doesn't match the pattern '^ord-[0-9]+$'`,
+			`$.status: "lost" is not one of ["new","paid"]`,
+		}))
+	})
+
+	It("reports missing properties and wrong types", func() {
+		Expect(schema.Validate([]byte(`{"items": []}`))).To(ConsistOf(
+			"$: missing required property 'orderId'",
+			"$.items: expected at least 1 items, got 0",
+		))
+		Expect(schema.Validate([]byte(`[]`))).To(Equal([]string{"$: expected object, got array"}))
+	})
+
+	It("rejects invalid JSON", func() {
+		Expect(schema.Validate([]byte(`{`))).To(HaveLen(1))
+	})
+
+	It("reports $refs that refer to themselves, and follows recursive ones into the event", func() {
+		cyclic := &eventSchema{root: map[string]interface{}{
+			"$ref": "#/definitions/a",
+			"definitions": map[string]interface{}{
+				"a": map[string]interface{}{"allOf": []interface{}{map[string]interface{}{"$ref": "#/definitions/b"}}},
+				"b": map[string]interface{}{"$ref": "#/definitions/a"},
+			},
+		}}
+		Expect(cyclic.Validate([]byte(`{}`))).To(Equal([]string{"$: $ref '#/definitions/a' refers to itself"}))
+
+		tree := &eventSchema{root: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"name"},
+			"properties": map[string]interface{}{
+				"children": map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#"}},
+			},
+		}}
+		Expect(tree.Validate([]byte(`{"name": "a", "children": [{"name": "b", "children": [{}]}]}`))).To(Equal([]string{
+			"$.children[0].children[0]: missing required property 'name'",
+		}))
+	})
+
+	It("finds the schema in the function's metadata", func() {
+		template, _ := goformation.ParseJSON([]byte(`{
+			"Resources": {
+				"Orders": {
+					"Type": "AWS::Serverless::Function",
+					"Properties": { "Runtime": "nodejs6.10", "Handler": "index.handler" },
+					"Metadata": { "SamLocal": { "EventSchema": "schemas/order.json" } }
+				}
+			}
+		}`))
+		Expect(getEventSchemaFilename(template, "Orders", "", "/project")).To(Equal("/project/schemas/order.json"))
+		Expect(getEventSchemaFilename(template, "Orders", "other.json", "/project")).To(Equal("other.json"))
+	})
+
+})
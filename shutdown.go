@@ -0,0 +1,128 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+	"github.com/awslabs/goformation/cloudformation"
+	"golang.org/x/net/context"
+)
+
+// shutdownGracePeriod is added on top of the longest function timeout when
+// waiting for in-flight invocations to finish, to allow for container start up
+const shutdownGracePeriod = 5 * time.Second
+
+// interrupted returns a channel that receives SIGINT and SIGTERM
+func interrupted() chan os.Signal {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	return signals
+}
+
+// drainTimeout returns how long to wait for in-flight invocations to finish when
+// shutting down, which is the longest timeout of any of the functions.
+func drainTimeout(functions map[string]cloudformation.AWSServerlessFunction) time.Duration {
+
+	// Functions without a timeout default to 3 seconds (as per SAM specification)
+	longest := 3
+	for _, function := range functions {
+		if function.Timeout > longest {
+			longest = function.Timeout
+		}
+	}
+
+	return time.Duration(longest)*time.Second + shutdownGracePeriod
+
+}
+
+// serve starts a HTTP server for each of the listeners and blocks until either one
+// of them fails, or SAM Local is interrupted. On interrupt, the servers stop accepting
+// new connections and in-flight invocations are given up to the drain timeout to finish
+// (a second interrupt skips the wait). Requests that are still in flight then have
+// their context cancelled, which stops their invocations. Finally, any remaining
+// containers are removed. It returns an error if a listener failed.
+func serve(listeners []*listener, drain time.Duration) error {
+
+	servers := []*http.Server{}
+	errs := make(chan error, len(listeners))
+
+	base, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	for _, l := range listeners {
+		server := &http.Server{
+			Addr:    l.Addr(),
+			Handler: withCancel(base, l.Router.Router()),
+		}
+		servers = append(servers, server)
+
+		go func(server *http.Server) {
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				errs <- err
+			}
+		}(server)
+	}
+
+	signals := interrupted()
+
+	var failure error
+	select {
+	case failure = <-errs:
+		log.Printf("Shutting down: %s\n", failure)
+	case sig := <-signals:
+		log.Printf("Received %s, waiting up to %s for in-flight requests to finish (press Ctrl-C again to stop immediately)\n", sig, drain)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				log.Printf("Stopped waiting for in-flight requests on %s: %s\n", server.Addr, err)
+			}
+		}(server)
+	}
+	wg.Wait()
+
+	cancelRequests()
+	invoker.ActiveContainers.CleanUp()
+
+	return failure
+
+}
+
+// withCancel returns a handler whose requests' contexts are also cancelled when ctx is
+func withCancel(ctx context.Context, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		reqCtx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		go func() {
+			select {
+			case <-ctx.Done():
+				cancel()
+			case <-reqCtx.Done():
+			}
+		}()
+
+		handler.ServeHTTP(w, req.WithContext(reqCtx))
+	})
+}
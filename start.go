@@ -4,16 +4,16 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 
-	"github.com/awslabs/goformation/intrinsics"
+	"github.com/awslabs/aws-sam-local/invoker"
 	"github.com/fatih/color"
 
 	"github.com/awslabs/aws-sam-local/router"
-	"github.com/awslabs/goformation"
 	"github.com/codegangsta/cli"
+	"github.com/docker/docker/pkg/term"
 )
 
 // messages color
@@ -36,22 +36,50 @@ func start(c *cli.Context) {
 		}
 	}
 
+	// Colors are only useful in a terminal
+	if c.Bool("no-color") || len(logarg) > 0 {
+		color.NoColor = true
+	}
+
+	// With --deterministic, request IDs, trace IDs and times come from a seed, and logs
+	// don't have timestamps, so runs with the same requests give the same events and logs
+	var deterministic *deterministicSource
+	if c.Bool("deterministic") {
+		deterministic = newDeterministicSource(int64(c.Int("seed")))
+		log.SetFlags(0)
+	}
+
+	logger := newLogger(c)
+
 	filename := getTemplateFilename(c.String("template"))
-	template, err := goformation.OpenWithOptions(filename, &intrinsics.ProcessorOptions{
-		ParameterOverrides: parseParameters(c.String("parameter-values")),
-	})
+	loaded := loadTemplate(c, filename, logger)
+	template := loaded.Template
+
+	hooks, err := loadPlugins(c.StringSlice("plugin"))
 	if err != nil {
-		log.Fatalf("Failed to parse template: %s\n", err)
+		log.Fatalf("Failed to load plugins: %s\n", err)
+	}
+	if template, err = hooks.Template(template); err != nil {
+		log.Fatalf("Failed to process template: %s\n", err)
 	}
 
+	// A dry run only prints the routes and containers, so doesn't need Docker
+	dryRun := c.Bool("dry-run")
+	plans := []*containerPlan{}
+
 	// Check connectivity to docker
-	dockerVersion, err := getDockerVersion()
-	if err != nil {
-		log.Printf("Running AWS SAM projects locally requires Docker. Have you got it installed?\n")
-		log.Printf("%s\n", err)
-		os.Exit(1)
+	backend := runtimeBackendName(c)
+	if backend == "native" && !dryRun {
+		warnMsg.Fprintf(os.Stderr, "Running functions on this machine without Docker: their environment won't match Lambda's\n")
+	} else if backend == "docker" && !dryRun {
+		dockerVersion, err := invoker.DockerVersion()
+		if err != nil {
+			log.Printf("Running AWS SAM projects locally requires Docker. Have you got it installed?\n")
+			log.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		log.Printf("Connected to Docker %s", dockerVersion)
 	}
-	log.Printf("Connected to Docker %s", dockerVersion)
 
 	// Get the working directory for the project based on
 	// the template directory. Also, give an opportunity for
@@ -61,13 +89,86 @@ func start(c *cli.Context) {
 		cwd = c.String("docker-volume-basedir")
 	}
 
-	// Create a new router
-	mux := router.NewServerlessRouter(c.Bool("prefix-routing"))
+	// Work out which addresses to listen on, and which Api resources are bound to them
+	listeners, err := parseListeners(c.StringSlice("host"), c.StringSlice("port"))
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	apiListeners, err := parseAPIBindings(c.StringSlice("api-listener"), listeners)
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	// Optionally export OpenTelemetry traces of requests and invocations
+	tracer, shutdownTracer := newTracer(c, logger)
+
+	// Create a new router for each listener
+	for _, l := range listeners {
+		opt := router.NewServerlessRouterOpt{
+			UsePrefix:       c.Bool("prefix-routing"),
+			Log:             logger,
+			MaxBufferedBody: int64(c.Int("max-buffered-body")) * 1024 * 1024,
+			Tracer:          tracer,
+		}
+		if deterministic != nil {
+			opt.NewRequestID = deterministic.RequestID
+			opt.Now = deterministic.Now
+			opt.NewTraceID = deterministic.TraceID
+		}
+		l.Router = router.NewServerlessRouter(opt)
+	}
 
 	templateApis := template.GetAllAWSServerlessApiResources()
 
-	for _, api := range templateApis {
-		err := mux.AddAPI(&api)
+	for name := range apiListeners {
+		if _, found := templateApis[name]; !found {
+			warnMsg.Printf("Ignoring listener binding for %s as no AWS::Serverless::Api with that logical ID exists\n", name)
+		}
+	}
+
+	if err := mountAPIs(templateApis, listeners, apiListeners); err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	// Optionally record every request, event and response to disk
+	var rec *recorder
+	if c.String("record") != "" {
+		rec, err = newRecorder(c.String("record"))
+		if err != nil {
+			errMsg.Printf("%s\n\n", err.Error())
+			os.Exit(1)
+		}
+		rec.eventTimes = deterministic != nil
+		log.Printf("Recording requests to %s\n", c.String("record"))
+	}
+
+	// Optionally check responses against the response schemas of the OpenAPI definitions
+	contract, err := newResponseContract(template, c.String("validate-responses"))
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	// Optionally inject latency and failures into requests
+	faults, err := newFaultConfig(c.String("fault-config"), &faultRule{
+		Latency:   c.String("inject-latency"),
+		Jitter:    c.String("inject-jitter"),
+		ErrorRate: c.Float64("inject-error-rate"),
+		DropRate:  c.Float64("inject-drop-rate"),
+	})
+	if err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	// Optionally share the local API through a public tunnel, once it's mounted
+	var tunnel tunnelProvider
+	if c.String("tunnel") != "" && !dryRun {
+		tunnel, err = newTunnelProvider(c.String("tunnel"))
 		if err != nil {
 			errMsg.Printf("%s\n\n", err.Error())
 			os.Exit(1)
@@ -75,29 +176,122 @@ func start(c *cli.Context) {
 	}
 
 	functions := template.GetAllAWSServerlessFunctionResources()
+	if err := hooks.Routes(functions); err != nil {
+		errMsg.Printf("%s\n\n", err.Error())
+		os.Exit(1)
+	}
+
+	endpoints, err := parseEndpointURLs(c.StringSlice("aws-endpoint-url"))
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+
+	adapter := startDebugAdapter(c)
+
+	// With --build, functions are built when they're invoked, if their code has changed
+	var builder *functionBuilder
+	if c.Bool("build") && !dryRun {
+		builder = newFunctionBuilder(c, loaded, filename, cwd, stderr)
+	}
+
+	// The dashboard takes over the terminal, so logs are shown in it instead
+	var dash *dashboard
+	if c.Bool("tui") && !dryRun {
+		if term.IsTerminal(os.Stdout.Fd()) {
+			dash = newDashboard(os.Stdout)
+		} else {
+			warnMsg.Fprintf(os.Stderr, "Ignoring --tui, as the output isn't a terminal\n")
+		}
+	}
+
+	// Work out the runtime options of each function, in a stable order
+	names := []string{}
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	for name, function := range functions {
+	// Encrypted variables are decrypted through SAM Local's KMS endpoint
+	if !dryRun {
+		if endpoints, err = startKMSEmulator(template, names, endpoints, c.String("kms-keyring"), c.String("profile"), backend); err != nil {
+			log.Fatalf("%s\n", err)
+		}
+	}
 
-		cwd := filepath.Dir(filename)
-		if c.String("docker-volume-basedir") != "" {
-			cwd = c.String("docker-volume-basedir")
+	// With --simulate-iam, the functions' AWS calls are checked against their policies first
+	proxies := map[string]*iamProxy{}
+	if c.Bool("simulate-iam") && !dryRun {
+		if proxies, err = startIAMProxies(loaded, names, endpoints, c.String("profile"), backend); err != nil {
+			log.Fatalf("%s\n", err)
 		}
+	}
 
-		// Initiate a new Lambda runtime
-		runt, err := NewRuntime(NewRuntimeOpt{
+	// With --lazy, functions are only prepared when they're first invoked, except for the
+	// --eager ones
+	eager := map[string]bool{}
+	for _, name := range c.StringSlice("eager") {
+		if _, ok := functions[name]; !ok {
+			warnMsg.Printf("Ignoring --eager %s as no function with that logical ID exists\n", name)
+		}
+		eager[name] = true
+	}
+
+	opts := make([]invoker.NewRuntimeOpt, len(names))
+	for i, name := range names {
+
+		opt := invoker.NewRuntimeOpt{
 			Cwd:             cwd,
 			LogicalID:       name,
-			Function:        function,
+			Function:        functions[name],
 			Logger:          stderr,
 			EnvOverrideFile: c.String("env-vars"),
 			DebugPort:       c.String("debug-port"),
 			SkipPullImage:   c.Bool("skip-pull-image"),
 			DockerNetwork:   c.String("docker-network"),
-		})
+			EstimateCost:    c.Bool("estimate-cost"),
+			Backend:         backend,
+			Endpoints:       endpoints,
+			Log:             logger,
+			Lazy:            c.Bool("lazy") && !eager[name],
+		}
+
+		if adapter != nil {
+			opt.Debugger = adapter
+		}
+
+		if proxy, ok := proxies[name]; ok {
+			opt.Endpoints = proxy.endpoints
+			opt.Credentials = &proxy.credentials
+		}
+
+		if dash != nil && len(logarg) > 0 {
+			opt.Logger = io.MultiWriter(stderr, dash.Logger(name))
+		} else if dash != nil {
+			opt.Logger = dash.Logger(name)
+		}
+
+		opts[i] = opt
+	}
+
+	// Initiate the Lambda runtimes. Their images are pulled concurrently, unless this is a
+	// dry run, which only works out their container configuration.
+	runtimes := make([]*invoker.Runtime, len(names))
+	errs := make([]error, len(names))
+	if dryRun {
+		for i, opt := range opts {
+			runtimes[i], errs[i] = invoker.New(opt)
+		}
+	} else {
+		runtimes, errs = invoker.NewRuntimes(opts, c.Int("startup-workers"))
+	}
+
+	for i, name := range names {
+
+		function, runt, err := functions[name], runtimes[i], errs[i]
 
 		// Check there wasn't a problem initiating the Lambda runtime
 		if err != nil {
-			if err == ErrRuntimeNotSupported {
+			if err == invoker.ErrRuntimeNotSupported {
 				warnMsg.Printf("Ignoring %s (%s) due to unsupported runtime (%s)\n", name, function.Handler, function.Runtime)
 				continue
 			} else {
@@ -106,16 +300,46 @@ func start(c *cli.Context) {
 			}
 		}
 
-		// Add this AWS::Serverless::Function to the HTTP router
-		if err := mux.AddFunction(&function, runt.InvokeHTTP(c.String("profile"))); err != nil {
-			if err == router.ErrNoEventsFound {
-				warnMsg.Printf("Ignoring %s (%s) as no API event sources are defined\n", name, function.Handler)
+		if dryRun {
+			plan, planErr := planContainer(runt, c.String("profile"))
+			if planErr != nil {
+				warnMsg.Printf("Ignoring %s (%s) as its container configuration could not be worked out: %s\n", name, function.Handler, planErr)
+				continue
 			}
+			plans = append(plans, plan)
+		}
+
+		var setup invocationSetup
+		if builder != nil {
+			setup = builder.Setup
+		}
+		handler := invokeHTTP(runt, c.String("profile"), hooks, setup)
+		if contract != nil {
+			handler = contract.Wrap(name, handler)
 		}
+		if rec != nil {
+			handler = rec.Wrap(name, handler)
+		}
+		if faults != nil {
+			handler = faults.Wrap(name, handler)
+		}
+		if dash != nil {
+			handler = dash.Wrap(name, handler)
+		}
+
+		// Split the function's API event sources between the listeners
+		if !mountFunction(function, listeners, apiListeners, handler) {
+			warnMsg.Printf("Ignoring %s (%s) as no API event sources are defined\n", name, function.Handler)
+		}
+	}
+
+	// Check we actually mounted some functions on our HTTP routers
+	mounted := 0
+	for _, l := range listeners {
+		mounted += len(l.Router.Mounts())
 	}
 
-	// Check we actually mounted some functions on our HTTP router
-	if len(mux.Mounts()) < 1 {
+	if mounted < 1 {
 		if len(functions) < 1 {
 			errMsg.Fprintf(stderr, "ERROR: No Serverless functions were found in your SAM template.\n")
 			os.Exit(1)
@@ -124,26 +348,39 @@ func start(c *cli.Context) {
 		os.Exit(1)
 	}
 
+	// Print the routes and containers instead of starting the listeners
+	if dryRun {
+		writeDryRun(os.Stdout, map[string]interface{}{
+			"Routes":     planRoutes(listeners),
+			"Containers": plans,
+		})
+		return
+	}
+
 	fmt.Fprintf(stderr, "\n")
 
-	for _, mount := range mux.Mounts() {
-		if mount.Function == nil || len(mount.Function.Handler) == 0 {
-			msg := warnMsg.Sprint(fmt.Sprintf("WARNING: Could not find function for %s to %s resource", mount.Methods(), mount.Path))
+	for _, l := range listeners {
+		for _, mount := range l.Router.Mounts() {
+			if mount.Function == nil || len(mount.Function.Handler) == 0 {
+				msg := warnMsg.Sprint(fmt.Sprintf("WARNING: Could not find function for %s to %s resource", mount.Methods(), mount.Path))
+				fmt.Fprintf(os.Stderr, "%s\n", msg)
+				continue
+			}
+
+			msg := successMsg.Sprintf("Mounting %s (%s) at http://%s%s %s", mount.Function.Handler, mount.Function.Runtime, l.Addr(), mount.Path, mount.Methods())
 			fmt.Fprintf(os.Stderr, "%s\n", msg)
-			continue
 		}
-
-		msg := successMsg.Sprintf("Mounting %s (%s) at http://%s:%s%s %s", mount.Function.Handler, mount.Function.Runtime, c.String("host"), c.String("port"), mount.Path, mount.Methods())
-		fmt.Fprintf(os.Stderr, "%s\n", msg)
 	}
 
-	// Mount static files
+	// Mount static files on every listener
 	if c.String("static-dir") != "" {
 		static := filepath.Join(cwd, c.String("static-dir"))
 
 		if _, err := os.Stat(static); err == nil {
 			fmt.Fprintf(os.Stderr, "Mounting static files from %s at /\n", static)
-			mux.AddStaticDir(static)
+			for _, l := range listeners {
+				l.Router.AddStaticDir(static)
+			}
 		}
 	}
 
@@ -154,7 +391,37 @@ func start(c *cli.Context) {
 	fmt.Fprintf(stderr, "SAM CLI if you update your AWS SAM template.\n")
 	fmt.Fprintf(stderr, "\n")
 
-	// Start the HTTP listener
-	log.Fatal(http.ListenAndServe(c.String("host")+":"+c.String("port"), mux.Router()))
+	if tunnel != nil {
+		url, err := tunnel.Open(tunnelTarget(listeners[0]))
+		if err != nil {
+			errMsg.Fprintf(os.Stderr, "Failed to open a tunnel: %s\n", err)
+			os.Exit(1)
+		}
+		msg := successMsg.Sprintf("Sharing http://%s publicly at %s", listeners[0].Addr(), url)
+		fmt.Fprintf(os.Stderr, "%s\n\n", msg)
+	}
+
+	if dash != nil {
+		dash.SetRoutes(planRoutes(listeners))
+		if len(logarg) > 0 {
+			log.SetOutput(io.MultiWriter(stderr, dash.Logger(dashboardSystemLogs)))
+		} else {
+			log.SetOutput(dash.Logger(dashboardSystemLogs))
+		}
+		dash.Start()
+	}
+
+	// Start the HTTP listeners, and block until shut down
+	err = serve(listeners, drainTimeout(functions))
+	shutdownTracer()
+	if dash != nil {
+		dash.Close()
+	}
+	if tunnel != nil {
+		tunnel.Close()
+	}
+	if err != nil {
+		os.Exit(1)
+	}
 
 }
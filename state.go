@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/awslabs/aws-sam-local/invoker"
+)
+
+// samLocalLabel is set on every Docker container created by SAM Local (function and
+// builder containers), so that they can be found by 'sam local cleanup'
+const samLocalLabel = invoker.ContainerLabel
+
+// getCacheDir returns the directory SAM Local keeps its local state in
+// (e.g. $HOME/.cache/aws-sam-local)
+func getCacheDir() string {
+
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "aws-sam-local")
+	}
+
+	return filepath.Join(os.Getenv("HOME"), ".cache", "aws-sam-local")
+
+}
+
+// recordingDirsFile lists the directories that requests have been recorded to
+func recordingDirsFile() string {
+	return filepath.Join(getCacheDir(), "recordings")
+}
+
+// rememberRecordingDir adds a directory to the list of recording directories,
+// so that 'sam local cleanup' can find the recordings later
+func rememberRecordingDir(dir string) error {
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	dirs := getRecordingDirs()
+	for _, existing := range dirs {
+		if existing == abs {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(getCacheDir(), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(recordingDirsFile(), []byte(strings.Join(append(dirs, abs), "\n")+"\n"), 0644)
+
+}
+
+// getRecordingDirs returns the directories that requests have been recorded to
+func getRecordingDirs() []string {
+
+	data, err := ioutil.ReadFile(recordingDirsFile())
+	if err != nil {
+		return []string{}
+	}
+
+	dirs := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			dirs = append(dirs, line)
+		}
+	}
+
+	return dirs
+
+}
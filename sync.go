@@ -0,0 +1,330 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/codegangsta/cli"
+)
+
+// syncTarget is a function whose code 'sam sync' uploads directly to Lambda
+type syncTarget struct {
+	LogicalID  string
+	PhysicalID string
+	CodePath   string
+	checksum   string
+}
+
+// syncOptions are the options shared by every AWS CLI command run by 'sam sync'
+type syncOptions struct {
+	awsCLI
+	StackName string
+}
+
+func syncCode(c *cli.Context) {
+
+	opt := &syncOptions{
+		awsCLI:    awsCLI{Profile: c.String("profile"), Region: c.String("region")},
+		StackName: c.String("stack-name"),
+	}
+
+	if opt.StackName == "" {
+		fmt.Fprintf(os.Stderr, "ERROR: You must provide the name of the deployed stack with --stack-name\n")
+		os.Exit(1)
+	}
+
+	filename := getTemplateFilename(c.String("template"))
+	template := openTemplate(c, filename, nil)
+
+	targets, err := getSyncTargets(template, filepath.Dir(filename), c.StringSlice("function"))
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+
+	if len(targets) == 0 {
+		log.Fatalf("No functions with local code were found in %s\n", filename)
+	}
+
+	// Find the functions deployed by the stack
+	for _, target := range targets {
+		target.PhysicalID, err = opt.physicalID(target.LogicalID)
+		if err != nil {
+			log.Fatalf("Could not find function %s in stack %s: %s\n", target.LogicalID, opt.StackName, err)
+		}
+	}
+
+	if !opt.syncChanged(targets) && !c.Bool("watch") {
+		os.Exit(1)
+	}
+
+	if !c.Bool("watch") {
+		return
+	}
+
+	interval, err := time.ParseDuration(c.String("interval"))
+	if err != nil {
+		log.Fatalf("Invalid --interval: %s\n", err)
+	}
+
+	log.Printf("Watching for changes (press Ctrl+C to stop)...\n")
+	templateChecksum, _ := codeChecksum(filename)
+
+	for range time.Tick(interval) {
+
+		// Only code can be synced: changes to the template need a full deploy
+		if checksum, _ := codeChecksum(filename); checksum != templateChecksum {
+			warnMsg.Fprintf(os.Stderr, "%s has changed. Infrastructure changes are not synced: run 'sam package' and 'sam deploy' to deploy them.\n", filename)
+			templateChecksum = checksum
+		}
+
+		opt.syncChanged(targets)
+
+	}
+
+}
+
+// getSyncTargets returns the functions whose code is available locally. If names is
+// not empty, only those functions are returned.
+func getSyncTargets(template *cloudformation.Template, dir string, names []string) ([]*syncTarget, error) {
+
+	functions := template.GetAllAWSServerlessFunctionResources()
+
+	for _, name := range names {
+		if _, found := functions[name]; !found {
+			return nil, fmt.Errorf("could not find a AWS::Serverless::Function with logical ID '%s'", name)
+		}
+	}
+
+	targets := []*syncTarget{}
+	for name, function := range functions {
+
+		if len(names) > 0 && !containsString(names, name) {
+			continue
+		}
+
+		path := dir
+		if function.CodeUri != nil {
+			if function.CodeUri.String == nil || strings.HasPrefix(*function.CodeUri.String, "s3://") {
+				// The code is already in S3, so there is nothing to sync
+				continue
+			}
+			path = filepath.Join(dir, *function.CodeUri.String)
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		targets = append(targets, &syncTarget{LogicalID: name, CodePath: path})
+
+	}
+
+	sort.Sort(byLogicalID(targets))
+	return targets, nil
+
+}
+
+// syncChanged uploads the code of every function that changed since it was last synced.
+// It returns false if any of the uploads failed.
+func (opt *syncOptions) syncChanged(targets []*syncTarget) bool {
+
+	ok := true
+	for _, target := range targets {
+
+		checksum, err := codeChecksum(target.CodePath)
+		if err != nil {
+			errMsg.Fprintf(os.Stderr, "Could not read the code of %s: %s\n", target.LogicalID, err)
+			ok = false
+			continue
+		}
+
+		if checksum == target.checksum {
+			continue
+		}
+
+		started := time.Now()
+		if err := opt.updateFunctionCode(target); err != nil {
+			errMsg.Fprintf(os.Stderr, "Could not sync %s: %s\n", target.LogicalID, err)
+			ok = false
+			continue
+		}
+
+		target.checksum = checksum
+		successMsg.Fprintf(os.Stderr, "Synced %s (%s) in %s\n", target.LogicalID, target.PhysicalID, time.Since(started)/time.Millisecond*time.Millisecond)
+
+	}
+
+	return ok
+
+}
+
+// updateFunctionCode zips the code of a function and uploads it with UpdateFunctionCode
+func (opt *syncOptions) updateFunctionCode(target *syncTarget) error {
+
+	archive, cleanup, err := zipCode(target.CodePath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	_, err = opt.Run("lambda", "update-function-code",
+		"--function-name", target.PhysicalID,
+		"--zip-file", "fileb://"+archive,
+		"--output", "text",
+		"--query", "LastModified",
+	)
+	return err
+
+}
+
+// physicalID returns the name of a function deployed by the stack
+func (opt *syncOptions) physicalID(logicalID string) (string, error) {
+
+	out, err := opt.Run("cloudformation", "describe-stack-resource",
+		"--stack-name", opt.StackName,
+		"--logical-resource-id", logicalID,
+		"--output", "text",
+		"--query", "StackResourceDetail.PhysicalResourceId",
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(out), nil
+
+}
+
+// codeChecksum returns a checksum of a file, or of the names, permissions and contents
+// of every file in a directory
+func codeChecksum(path string) (string, error) {
+
+	hash := sha256.New()
+
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(path, file)
+		fmt.Fprintf(hash, "%s %o\n", filepath.ToSlash(rel), info.Mode().Perm())
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(hash, f)
+		return err
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+
+}
+
+// zipCode returns a ZIP archive of a function's code. ZIP and JAR files are used as they
+// are, and directories are zipped into a temporary file, removed by calling cleanup.
+func zipCode(path string) (archive string, cleanup func(), err error) {
+
+	cleanup = func() {}
+
+	if strings.HasSuffix(path, ".zip") || strings.HasSuffix(path, ".jar") {
+		return path, cleanup, nil
+	}
+
+	tmp, err := ioutil.TempFile("", "aws-sam-local-sync")
+	if err != nil {
+		return "", cleanup, err
+	}
+	defer tmp.Close()
+
+	cleanup = func() {
+		os.Remove(tmp.Name())
+	}
+
+	if err := zipDirectory(tmp, path); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+
+	return tmp.Name(), cleanup, nil
+
+}
+
+// zipDirectory writes a ZIP archive of the contents of a directory, keeping file permissions
+func zipDirectory(w io.Writer, dir string) error {
+
+	archive := zip.NewWriter(w)
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, file)
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+
+		writer, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(writer, f)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// byLogicalID sorts sync targets by their logical ID
+type byLogicalID []*syncTarget
+
+func (t byLogicalID) Len() int           { return len(t) }
+func (t byLogicalID) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t byLogicalID) Less(i, j int) bool { return t[i].LogicalID < t[j].LogicalID }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/awslabs/goformation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sync", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "sync")
+		os.MkdirAll(filepath.Join(dir, "src", "lib"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "src", "index.js"), []byte("exports.handler = () => {}"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "src", "lib", "util.js"), []byte("module.exports = {}"), 0644)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("only syncs functions with local code", func() {
+		template, _ := goformation.ParseJSON([]byte(`{
+			"Resources": {
+				"Local": { "Type": "AWS::Serverless::Function", "Properties": { "Runtime": "nodejs6.10", "Handler": "index.handler", "CodeUri": "src" } },
+				"Remote": { "Type": "AWS::Serverless::Function", "Properties": { "Runtime": "nodejs6.10", "Handler": "index.handler", "CodeUri": "s3://bucket/code.zip" } },
+				"Missing": { "Type": "AWS::Serverless::Function", "Properties": { "Runtime": "nodejs6.10", "Handler": "index.handler", "CodeUri": "nowhere" } }
+			}
+		}`))
+
+		targets, err := getSyncTargets(template, dir, nil)
+		Expect(err).To(BeNil())
+		Expect(targets).To(HaveLen(1))
+		Expect(targets[0].LogicalID).To(Equal("Local"))
+		Expect(targets[0].CodePath).To(Equal(filepath.Join(dir, "src")))
+
+		_, err = getSyncTargets(template, dir, []string{"Unknown"})
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("changes the checksum when the code changes", func() {
+		before, err := codeChecksum(filepath.Join(dir, "src"))
+		Expect(err).To(BeNil())
+
+		same, _ := codeChecksum(filepath.Join(dir, "src"))
+		Expect(same).To(Equal(before))
+
+		ioutil.WriteFile(filepath.Join(dir, "src", "lib", "util.js"), []byte("module.exports = { changed: true }"), 0644)
+		after, _ := codeChecksum(filepath.Join(dir, "src"))
+		Expect(after).ToNot(Equal(before))
+	})
+
+	It("zips directories with paths relative to the code directory", func() {
+		buf := &bytes.Buffer{}
+		Expect(zipDirectory(buf, filepath.Join(dir, "src"))).To(BeNil())
+
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		Expect(err).To(BeNil())
+
+		names := []string{}
+		for _, f := range archive.File {
+			names = append(names, f.Name)
+		}
+		Expect(names).To(ConsistOf("index.js", "lib/util.js"))
+	})
+
+})
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/aws-sam-local/tracing"
+	"github.com/codegangsta/cli"
+)
+
+// newTracer returns the Tracer that records spans of requests and invocations, and exports
+// them to the OpenTelemetry collector at --otel-endpoint, as --otel-service-name. It's nil if
+// no endpoint is set. The returned function sends the spans that haven't been sent yet, so
+// must be called before SAM Local exits.
+func newTracer(c *cli.Context, logger logging.Logger) (*tracing.Tracer, func()) {
+
+	endpoint := c.String("otel-endpoint")
+	if endpoint == "" {
+		return nil, func() {}
+	}
+
+	service := c.String("otel-service-name")
+	if service == "" {
+		service = "sam-local"
+	}
+
+	exporter := tracing.NewOTLPExporter(endpoint, service, logger)
+	log.Printf("Exporting traces to %s as %s\n", endpoint, service)
+
+	return tracing.NewTracer(exporter), exporter.Shutdown
+
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+
+	"github.com/awslabs/aws-sam-local/loader"
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/codegangsta/cli"
+)
+
+// openTemplate loads the SAM template with the --parameter-values overrides, and warns about
+// the problems that would stop it from working as expected
+func openTemplate(c *cli.Context, filename string, logger logging.Logger) *cloudformation.Template {
+	return loadTemplate(c, filename, logger).Template
+}
+
+// loadTemplate is like openTemplate, but returns the loaded template, which also has the
+// Metadata of resources
+func loadTemplate(c *cli.Context, filename string, logger logging.Logger) *loader.Template {
+
+	template, err := loader.Open(filename, loader.Options{
+		ParameterOverrides: parseParameters(c.String("parameter-values")),
+	})
+	if err != nil {
+		log.Fatalf("Failed to parse template: %s\n", err)
+	}
+
+	resolveRestApiIds(template)
+
+	if problems, ok := template.Validate().(loader.Errors); ok {
+		for _, problem := range problems {
+			logging.For(logger, logging.Template).Warnf("WARNING: %s", problem)
+		}
+	}
+
+	return template
+
+}
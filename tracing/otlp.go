@@ -0,0 +1,229 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awslabs/aws-sam-local/logging"
+)
+
+// The limits of OTLPExporter's batches: spans are sent once there are otlpBatchSize of them,
+// or otlpFlushInterval after the first one ended, whichever comes first. At most
+// otlpQueueSize spans wait to be sent, and more are dropped, so that a collector that's down
+// doesn't hold up requests.
+const (
+	otlpBatchSize     = 128
+	otlpFlushInterval = time.Second
+	otlpQueueSize     = 4096
+)
+
+// OTLPExporter sends spans to an OpenTelemetry collector (or anything else that accepts OTLP,
+// such as Jaeger) with OTLP over HTTP, encoded as JSON. Spans are sent in batches in the
+// background.
+type OTLPExporter struct {
+	url     string
+	service string
+	client  *http.Client
+	log     *logging.Entry
+
+	spans chan *Span
+	flush chan chan struct{}
+	once  sync.Once
+	done  chan struct{}
+}
+
+// NewOTLPExporter returns an OTLPExporter that sends spans, as those of the service, to the
+// traces endpoint of a collector. endpoint is the collector's base URL (e.g.
+// http://localhost:4318), as in OTEL_EXPORTER_OTLP_ENDPOINT, or the full URL of its traces
+// endpoint. Failures are logged to log, or to the log package's standard logger if it's nil.
+func NewOTLPExporter(endpoint string, service string, log logging.Logger) *OTLPExporter {
+
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+
+	e := &OTLPExporter{
+		url:     url,
+		service: service,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		log:     logging.For(log, logging.Tracing),
+		spans:   make(chan *Span, otlpQueueSize),
+		flush:   make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go e.run()
+	return e
+
+}
+
+// Export implements Exporter
+func (e *OTLPExporter) Export(span *Span) {
+	select {
+	case e.spans <- span:
+	default:
+		e.log.Warnf("Dropping span %s, as the OpenTelemetry collector at %s isn't keeping up", span.Name, e.url)
+	}
+}
+
+// Flush sends the spans that have ended so far, and waits until they're sent
+func (e *OTLPExporter) Flush() {
+	flushed := make(chan struct{})
+	select {
+	case e.flush <- flushed:
+		<-flushed
+	case <-e.done:
+	}
+}
+
+// Shutdown sends the remaining spans, and stops the exporter
+func (e *OTLPExporter) Shutdown() {
+	e.Flush()
+	e.once.Do(func() { close(e.done) })
+}
+
+// run batches the spans, and sends them
+func (e *OTLPExporter) run() {
+
+	batch := []*Span{}
+	timer := time.NewTimer(otlpFlushInterval)
+	timer.Stop()
+
+	send := func() {
+		if len(batch) > 0 {
+			if err := e.send(batch); err != nil {
+				e.log.Warnf("Could not send %d spans to the OpenTelemetry collector: %s", len(batch), err)
+			}
+			batch = []*Span{}
+		}
+		timer.Stop()
+	}
+
+	for {
+		select {
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) == 1 {
+				timer.Reset(otlpFlushInterval)
+			}
+			if len(batch) >= otlpBatchSize {
+				send()
+			}
+		case <-timer.C:
+			send()
+		case flushed := <-e.flush:
+			for queued := len(e.spans); queued > 0; queued-- {
+				batch = append(batch, <-e.spans)
+			}
+			send()
+			close(flushed)
+		case <-e.done:
+			return
+		}
+	}
+
+}
+
+// send posts a batch of spans to the collector
+func (e *OTLPExporter) send(spans []*Span) error {
+
+	data, err := json.Marshal(otlpRequest(e.service, spans))
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", e.url, resp.Status)
+	}
+	return nil
+
+}
+
+// otlpRequest returns the body of an OTLP/JSON ExportTraceServiceRequest with the spans. IDs
+// are hex encoded, and 64-bit integers are strings, as the OTLP/JSON encoding requires.
+func otlpRequest(service string, spans []*Span) map[string]interface{} {
+
+	encoded := []interface{}{}
+	for _, span := range spans {
+
+		s := map[string]interface{}{
+			"traceId":           span.TraceID.String(),
+			"spanId":            span.SpanID.String(),
+			"name":              span.Name,
+			"kind":              int(span.Kind),
+			"startTimeUnixNano": strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.Attributes),
+		}
+		if !span.ParentID.IsZero() {
+			s["parentSpanId"] = span.ParentID.String()
+		}
+		if span.Err != nil {
+			s["status"] = map[string]interface{}{"code": 2, "message": span.Err.Error()}
+		}
+
+		encoded = append(encoded, s)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(Attributes{"service.name": service}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "github.com/awslabs/aws-sam-local"},
+						"spans": encoded,
+					},
+				},
+			},
+		},
+	}
+
+}
+
+// otlpAttributes encodes attributes as OTLP KeyValues, in order of their keys
+func otlpAttributes(attributes Attributes) []interface{} {
+
+	keys := []string{}
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	encoded := []interface{}{}
+	for _, key := range keys {
+		var value map[string]interface{}
+		switch v := attributes[key].(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, map[string]interface{}{"key": key, "value": value})
+	}
+	return encoded
+
+}
@@ -0,0 +1,133 @@
+package tracing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/awslabs/aws-sam-local/logging"
+	"github.com/awslabs/aws-sam-local/tracing"
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OTLP export", func() {
+
+	var collector *httptest.Server
+	var mutex sync.Mutex
+	var requests []map[string]interface{}
+	var paths []string
+
+	BeforeEach(func() {
+		requests, paths = nil, nil
+		collector = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := ioutil.ReadAll(req.Body)
+			var request map[string]interface{}
+			json.Unmarshal(body, &request)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			requests = append(requests, request)
+			paths = append(paths, req.URL.Path)
+		}))
+	})
+
+	AfterEach(func() {
+		collector.Close()
+	})
+
+	// exported returns the spans that the collector received
+	exported := func() []interface{} {
+		mutex.Lock()
+		defer mutex.Unlock()
+		spans := []interface{}{}
+		for _, request := range requests {
+			resource := request["resourceSpans"].([]interface{})[0].(map[string]interface{})
+			scope := resource["scopeSpans"].([]interface{})[0].(map[string]interface{})
+			spans = append(spans, scope["spans"].([]interface{})...)
+		}
+		return spans
+	}
+
+	It("sends spans to the collector's traces endpoint as OTLP/JSON", func() {
+		exporter := tracing.NewOTLPExporter(collector.URL, "orders", nil)
+		tracer := tracing.NewTracer(exporter)
+
+		ctx, root := tracer.Start(context.Background(), "GET /orders", tracing.Server, tracing.Attributes{"http.method": "GET"})
+		_, child := tracing.Start(ctx, "invoke Orders", tracing.Client, tracing.Attributes{"faas.coldstart": true})
+		child.SetError(errors.New("timed out after 3 seconds"))
+		child.End()
+		root.SetAttributes(tracing.Attributes{"http.status_code": 200})
+		root.End()
+
+		exporter.Shutdown()
+
+		Expect(paths).To(Equal([]string{"/v1/traces"}))
+		resource := requests[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		Expect(resource["resource"]).To(Equal(map[string]interface{}{
+			"attributes": []interface{}{
+				map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "orders"}},
+			},
+		}))
+
+		spans := exported()
+		Expect(spans).To(HaveLen(2))
+
+		invoke := spans[0].(map[string]interface{})
+		Expect(invoke["name"]).To(Equal("invoke Orders"))
+		Expect(invoke["kind"]).To(Equal(float64(3)))
+		Expect(invoke["traceId"]).To(Equal(root.TraceID.String()))
+		Expect(invoke["parentSpanId"]).To(Equal(root.SpanID.String()))
+		Expect(invoke["status"]).To(Equal(map[string]interface{}{"code": float64(2), "message": "timed out after 3 seconds"}))
+		Expect(invoke["attributes"]).To(Equal([]interface{}{
+			map[string]interface{}{"key": "faas.coldstart", "value": map[string]interface{}{"boolValue": true}},
+		}))
+
+		request := spans[1].(map[string]interface{})
+		Expect(request).NotTo(HaveKey("parentSpanId"))
+		Expect(request).NotTo(HaveKey("status"))
+		Expect(request["startTimeUnixNano"]).To(MatchRegexp(`^\d+$`))
+		Expect(request["attributes"]).To(Equal([]interface{}{
+			map[string]interface{}{"key": "http.method", "value": map[string]interface{}{"stringValue": "GET"}},
+			map[string]interface{}{"key": "http.status_code", "value": map[string]interface{}{"intValue": "200"}},
+		}))
+	})
+
+	It("accepts the full URL of the traces endpoint", func() {
+		exporter := tracing.NewOTLPExporter(collector.URL+"/v1/traces/", "orders", nil)
+		_, span := tracing.NewTracer(exporter).Start(context.Background(), "span", tracing.Internal, nil)
+		span.End()
+		exporter.Shutdown()
+		Expect(paths).To(Equal([]string{"/v1/traces"}))
+	})
+
+	It("sends spans in the background", func() {
+		exporter := tracing.NewOTLPExporter(collector.URL, "orders", nil)
+		defer exporter.Shutdown()
+
+		_, span := tracing.NewTracer(exporter).Start(context.Background(), "span", tracing.Internal, nil)
+		span.End()
+		Eventually(exported, "3s").Should(HaveLen(1))
+	})
+
+	It("logs failures to send spans", func() {
+		messages := []string{}
+		logger := logging.LoggerFunc(func(level logging.Level, msg string, fields logging.Fields) {
+			messages = append(messages, msg)
+		})
+
+		exporter := tracing.NewOTLPExporter("http://127.0.0.1:1", "orders", logger)
+		_, span := tracing.NewTracer(exporter).Start(context.Background(), "span", tracing.Internal, nil)
+		span.End()
+		exporter.Shutdown()
+
+		Expect(messages).To(HaveLen(1))
+		Expect(messages[0]).To(HavePrefix("Could not send 1 spans to the OpenTelemetry collector"))
+	})
+
+})
@@ -0,0 +1,270 @@
+// Package tracing records OpenTelemetry spans of local invocations, from the HTTP request that
+// the router receives to the event it builds, the function's invocation and its response.
+// Embedders pass a Tracer in the options of the router, and the invoker's spans are children
+// of the span in the invocation's context:
+//
+//	exporter := tracing.NewOTLPExporter("http://localhost:4318", "my-app", nil)
+//	defer exporter.Shutdown()
+//	r := router.NewServerlessRouter(router.NewServerlessRouterOpt{Tracer: tracing.NewTracer(exporter)})
+//
+// A nil Tracer or Span does nothing, so that code can be instrumented without checking
+// whether tracing is enabled.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the ID in hex
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span in a trace
+type SpanID [8]byte
+
+// String returns the ID in hex
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsZero returns whether the ID is unset (e.g. the parent of a root span)
+func (id SpanID) IsZero() bool {
+	return id == SpanID{}
+}
+
+// Kind is the role of a span in a trace, as in OpenTelemetry
+type Kind int
+
+// The kinds of spans, with OpenTelemetry's values
+const (
+	Internal Kind = iota + 1
+	Server
+	Client
+)
+
+// Attributes hold the data of a span, by their OpenTelemetry semantic convention names
+// (e.g. http.method). Values are strings, ints, float64s or bools.
+type Attributes map[string]interface{}
+
+// Span is a timed operation of a trace
+type Span struct {
+	TraceID  TraceID
+	SpanID   SpanID
+	ParentID SpanID
+	Name     string
+	Kind     Kind
+
+	StartTime time.Time
+	EndTime   time.Time
+
+	Attributes Attributes
+
+	// Err is the error that the operation failed with, if it did
+	Err error
+
+	tracer *Tracer
+	mutex  sync.Mutex
+	ended  bool
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attributes Attributes) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for k, v := range attributes {
+		s.Attributes[k] = v
+	}
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Err = err
+}
+
+// End records the end of the span, and exports it. Spans are only exported once, however
+// many times End is called.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = s.tracer.now()
+	s.mutex.Unlock()
+
+	s.tracer.exporter.Export(s)
+}
+
+// Traceparent returns the W3C Trace Context header of the span, to continue its trace in
+// another service
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID)
+}
+
+// Exporter sends spans somewhere, such as an OpenTelemetry collector
+type Exporter interface {
+
+	// Export is called with each span when it ends. It must not block.
+	Export(span *Span)
+}
+
+// ExporterFunc is an adapter to allow the use of ordinary functions as Exporters
+type ExporterFunc func(span *Span)
+
+// Export implements Exporter
+func (f ExporterFunc) Export(span *Span) {
+	f(span)
+}
+
+// Tracer starts spans, and exports them when they end
+type Tracer struct {
+	exporter Exporter
+
+	// Now returns the time that spans start and end at. By default it's the current time.
+	Now func() time.Time
+}
+
+// NewTracer returns a Tracer that exports spans to exporter
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+func (t *Tracer) now() time.Time {
+	if t.Now != nil {
+		return t.Now()
+	}
+	return time.Now()
+}
+
+// Start starts a span that's a child of the span in ctx, if there's one, and returns a
+// context with the new span
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind, attributes Attributes) (context.Context, *Span) {
+
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		StartTime:  t.now(),
+		Attributes: Attributes{},
+		tracer:     t,
+	}
+	for k, v := range attributes {
+		span.Attributes[k] = v
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.TraceID, span.ParentID = parent.TraceID, parent.SpanID
+	} else if remote, ok := ctx.Value(remoteParentKey{}).(remoteParent); ok {
+		span.TraceID, span.ParentID = remote.traceID, remote.spanID
+	} else {
+		rand.Read(span.TraceID[:])
+	}
+	rand.Read(span.SpanID[:])
+
+	return ContextWithSpan(ctx, span), span
+
+}
+
+// StartRequest starts a server span for an HTTP request, which continues the trace of its
+// W3C traceparent header if it has one
+func (t *Tracer) StartRequest(req *http.Request, name string, attributes Attributes) (context.Context, *Span) {
+	ctx := req.Context()
+	if traceID, parentID, ok := ParseTraceparent(req.Header.Get("traceparent")); ok {
+		ctx = context.WithValue(ctx, remoteParentKey{}, remoteParent{traceID, parentID})
+	}
+	return t.Start(ctx, name, Server, attributes)
+}
+
+// Start starts a span that's a child of the span in ctx, with the same Tracer. If ctx has no
+// span, tracing is disabled for it, and Start returns a nil span.
+func Start(ctx context.Context, name string, kind Kind, attributes Attributes) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, kind, attributes)
+}
+
+type spanKey struct{}
+
+type remoteParentKey struct{}
+
+// remoteParent is the parent of a trace that was started in another service
+type remoteParent struct {
+	traceID TraceID
+	spanID  SpanID
+}
+
+// ContextWithSpan returns a copy of ctx with the span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span in ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ParseTraceparent parses a W3C Trace Context header, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceparent(header string) (TraceID, SpanID, bool) {
+
+	var traceID TraceID
+	var spanID SpanID
+
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return traceID, spanID, false
+	}
+
+	t, err := hex.DecodeString(parts[1])
+	if err != nil || len(t) != len(traceID) {
+		return traceID, spanID, false
+	}
+	s, err := hex.DecodeString(parts[2])
+	if err != nil || len(s) != len(spanID) {
+		return traceID, spanID, false
+	}
+	copy(traceID[:], t)
+	copy(spanID[:], s)
+
+	if traceID == (TraceID{}) || spanID.IsZero() {
+		return traceID, spanID, false
+	}
+	return traceID, spanID, true
+
+}
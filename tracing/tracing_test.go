@@ -0,0 +1,104 @@
+package tracing_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/awslabs/aws-sam-local/tracing"
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// spanRecorder returns a Tracer that records the spans that end
+func spanRecorder(spans *[]*tracing.Span) *tracing.Tracer {
+	var mutex sync.Mutex
+	return tracing.NewTracer(tracing.ExporterFunc(func(span *tracing.Span) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		*spans = append(*spans, span)
+	}))
+}
+
+var _ = Describe("Tracing", func() {
+
+	var spans []*tracing.Span
+	var tracer *tracing.Tracer
+
+	BeforeEach(func() {
+		spans = []*tracing.Span{}
+		tracer = spanRecorder(&spans)
+	})
+
+	It("starts spans that are children of the span in the context", func() {
+		ctx, root := tracer.Start(context.Background(), "root", tracing.Server, tracing.Attributes{"http.method": "GET"})
+		_, child := tracing.Start(ctx, "child", tracing.Internal, nil)
+		child.End()
+		root.End()
+
+		Expect(spans).To(Equal([]*tracing.Span{child, root}))
+		Expect(root.ParentID.IsZero()).To(BeTrue())
+		Expect(root.Attributes).To(Equal(tracing.Attributes{"http.method": "GET"}))
+		Expect(child.TraceID).To(Equal(root.TraceID))
+		Expect(child.ParentID).To(Equal(root.SpanID))
+		Expect(child.SpanID).NotTo(Equal(root.SpanID))
+		Expect(root.EndTime).NotTo(BeTemporally("<", child.EndTime))
+	})
+
+	It("exports spans once, however many times they end", func() {
+		_, span := tracer.Start(context.Background(), "span", tracing.Internal, nil)
+		span.End()
+		span.End()
+		Expect(spans).To(HaveLen(1))
+	})
+
+	It("records errors and attributes", func() {
+		_, span := tracer.Start(context.Background(), "span", tracing.Internal, nil)
+		span.SetAttributes(tracing.Attributes{"http.status_code": 502})
+		span.SetError(errors.New("boom"))
+		span.End()
+		Expect(spans[0].Attributes).To(Equal(tracing.Attributes{"http.status_code": 502}))
+		Expect(spans[0].Err).To(MatchError("boom"))
+	})
+
+	It("does nothing without a tracer", func() {
+		var none *tracing.Tracer
+		ctx, span := none.Start(context.Background(), "span", tracing.Internal, nil)
+		Expect(span).To(BeNil())
+
+		_, child := tracing.Start(ctx, "child", tracing.Internal, nil)
+		Expect(child).To(BeNil())
+		child.SetAttributes(tracing.Attributes{"a": "b"})
+		child.SetError(errors.New("boom"))
+		child.End()
+		Expect(child.Traceparent()).To(Equal(""))
+	})
+
+	It("continues the traces of requests' traceparent headers", func() {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		_, span := tracer.StartRequest(req, "GET /", nil)
+		Expect(span.Kind).To(Equal(tracing.Server))
+		Expect(span.TraceID.String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+		Expect(span.ParentID.String()).To(Equal("00f067aa0ba902b7"))
+		Expect(span.Traceparent()).To(Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-" + span.SpanID.String() + "-01"))
+	})
+
+	It("rejects invalid traceparent headers", func() {
+		for _, header := range []string{
+			"",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-zzf067aa0ba902b7-01",
+		} {
+			_, _, ok := tracing.ParseTraceparent(header)
+			Expect(ok).To(BeFalse(), header)
+		}
+	})
+
+})
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tunnelTimeout is how long to wait for a tunnel provider to report its public URL
+const tunnelTimeout = 30 * time.Second
+
+// tunnelProvider opens public HTTPS tunnels to the local API, so that services on the
+// internet (such as webhooks) can call it
+type tunnelProvider interface {
+
+	// Open starts a tunnel to the local URL, and returns its public URL
+	Open(local string) (string, error)
+
+	// Close shuts the tunnel down
+	Close() error
+}
+
+// tunnelProviders are the supported tunnel providers, by the name given to --tunnel
+var tunnelProviders = map[string]func() tunnelProvider{
+
+	// ngrok, or any tool with the same command line, which logs the public URL as url=https://...
+	"ngrok": func() tunnelProvider {
+		return &commandTunnel{
+			Command: "ngrok",
+			Args:    func(local string) []string { return []string{"http", local, "--log", "stdout"} },
+			URL:     regexp.MustCompile(`url=(https://[^\s"]+)`),
+		}
+	},
+
+	// Cloudflare quick tunnels, which don't need an account
+	"cloudflare": func() tunnelProvider {
+		return &commandTunnel{
+			Command: "cloudflared",
+			Args:    func(local string) []string { return []string{"tunnel", "--no-autoupdate", "--url", local} },
+			URL:     regexp.MustCompile(`(https://[a-zA-Z0-9-]+\.trycloudflare\.com)`),
+		}
+	},
+}
+
+// newTunnelProvider returns the tunnel provider with the given name
+func newTunnelProvider(name string) (tunnelProvider, error) {
+
+	provider, found := tunnelProviders[name]
+	if !found {
+		names := []string{}
+		for name := range tunnelProviders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unsupported tunnel provider '%s' (must be one of %s)", name, strings.Join(names, ", "))
+	}
+
+	return provider(), nil
+
+}
+
+// commandTunnel is a tunnel run by a provider's command line tool, which logs the public URL
+// once the tunnel is up
+type commandTunnel struct {
+	Command string
+	Args    func(local string) []string
+
+	// URL matches the public URL in the tool's output. Its first group is the URL.
+	URL *regexp.Regexp
+
+	cmd *exec.Cmd
+}
+
+// Open implements tunnelProvider
+func (t *commandTunnel) Open(local string) (string, error) {
+
+	path, err := exec.LookPath(t.Command)
+	if err != nil {
+		return "", fmt.Errorf("could not find %s, is it installed and in your PATH?", t.Command)
+	}
+
+	t.cmd = exec.Command(path, t.Args(local)...)
+
+	output, writer := io.Pipe()
+	t.cmd.Stdout = writer
+	t.cmd.Stderr = writer
+
+	if err := t.cmd.Start(); err != nil {
+		return "", err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- t.cmd.Wait()
+		writer.Close()
+	}()
+
+	// Keep reading the output once the URL is found, so the tool never blocks writing to it
+	found := make(chan string, 1)
+	lines := make(chan string, 100)
+	go func() {
+		scanner := bufio.NewScanner(output)
+		for scanner.Scan() {
+			if match := t.URL.FindStringSubmatch(scanner.Text()); match != nil {
+				select {
+				case found <- match[1]:
+				default:
+				}
+			}
+			select {
+			case lines <- scanner.Text():
+			default:
+			}
+		}
+	}()
+
+	select {
+	case url := <-found:
+		return url, nil
+	case err := <-exited:
+		return "", fmt.Errorf("%s exited before the tunnel was up (%v):\n%s", t.Command, err, drainLines(lines))
+	case <-time.After(tunnelTimeout):
+		t.Close()
+		return "", fmt.Errorf("%s didn't report the tunnel's URL within %s:\n%s", t.Command, tunnelTimeout, drainLines(lines))
+	}
+
+}
+
+// Close implements tunnelProvider
+func (t *commandTunnel) Close() error {
+	if t.cmd == nil || t.cmd.Process == nil {
+		return nil
+	}
+	return t.cmd.Process.Kill()
+}
+
+// drainLines returns the lines that have been buffered in a channel
+func drainLines(lines chan string) string {
+	collected := []string{}
+	for {
+		select {
+		case line := <-lines:
+			collected = append(collected, line)
+		default:
+			return strings.Join(collected, "\n")
+		}
+	}
+}
+
+// tunnelTarget returns the local URL a tunnel should forward to for a listener. Listeners
+// on every interface are reached through the loopback address.
+func tunnelTarget(l *listener) string {
+	host := l.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = defaultHost
+	}
+	return "http://" + net.JoinHostPort(host, l.Port)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"regexp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tunnels", func() {
+
+	fakeTunnel := func(script string) *commandTunnel {
+		return &commandTunnel{
+			Command: "sh",
+			Args:    func(local string) []string { return []string{"-c", script, "sh", local} },
+			URL:     regexp.MustCompile(`url=(https://[^\s"]+)`),
+		}
+	}
+
+	It("returns the public URL the provider reports", func() {
+		tunnel := fakeTunnel(`echo "starting tunnel to $1"; echo "msg=started url=https://abc123.example.com"; sleep 30`)
+		url, err := tunnel.Open("http://127.0.0.1:3000")
+		Expect(err).To(BeNil())
+		Expect(url).To(Equal("https://abc123.example.com"))
+		Expect(tunnel.Close()).To(BeNil())
+	})
+
+	It("fails with the provider's output if it exits first", func() {
+		tunnel := fakeTunnel(`echo "authentication failed" >&2; exit 1`)
+		_, err := tunnel.Open("http://127.0.0.1:3000")
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("sh exited before the tunnel was up"))
+		Expect(err.Error()).To(ContainSubstring("authentication failed"))
+	})
+
+	It("finds the providers' public URLs", func() {
+		ngrok := tunnelProviders["ngrok"]().(*commandTunnel)
+		Expect(ngrok.URL.FindStringSubmatch(`t=2018-01-01 lvl=info msg="started tunnel" obj=tunnels name=command_line addr=http://127.0.0.1:3000 url=https://92832de0.ngrok.io`)[1]).To(Equal("https://92832de0.ngrok.io"))
+
+		cloudflare := tunnelProviders["cloudflare"]().(*commandTunnel)
+		Expect(cloudflare.URL.FindStringSubmatch(`2018-01-01T00:00:00Z INF |  https://seasonal-deck-organisms.trycloudflare.com  |`)[1]).To(Equal("https://seasonal-deck-organisms.trycloudflare.com"))
+		Expect(cloudflare.Args("http://127.0.0.1:3000")).To(ContainElement("http://127.0.0.1:3000"))
+	})
+
+	It("rejects unknown providers", func() {
+		_, err := newTunnelProvider("carrier-pigeon")
+		Expect(err).To(MatchError("unsupported tunnel provider 'carrier-pigeon' (must be one of cloudflare, ngrok)"))
+	})
+
+	It("forwards listeners on every interface to the loopback address", func() {
+		Expect(tunnelTarget(&listener{Host: "0.0.0.0", Port: "3000"})).To(Equal("http://127.0.0.1:3000"))
+		Expect(tunnelTarget(&listener{Host: "192.168.1.10", Port: "3001"})).To(Equal("http://192.168.1.10:3001"))
+	})
+
+})
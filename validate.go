@@ -4,13 +4,19 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/awslabs/goformation"
+	"github.com/awslabs/aws-sam-local/loader"
 	"github.com/codegangsta/cli"
 )
 
 func validate(c *cli.Context) {
 
-	_, err := goformation.Open(getTemplateFilename(c.String("template")))
+	template, err := loader.Open(getTemplateFilename(c.String("template")), loader.Options{
+		ParameterOverrides: parseParameters(c.String("parameter-values")),
+	})
+
+	if err == nil {
+		err = template.Validate()
+	}
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)